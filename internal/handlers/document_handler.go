@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"pdf-service/internal/services"
+)
+
+type DocumentHandler struct {
+	documents services.DocumentStoreInterface
+}
+
+func NewDocumentHandler(documents services.DocumentStoreInterface) *DocumentHandler {
+	return &DocumentHandler{documents: documents}
+}
+
+// GetDocumentHandler serves a stored linearized document. It answers Range
+// requests, so a viewer can fetch the first page before the rest, and the
+// ETag lets it confirm with If-Range that later ranges come from the same
+// document.
+func (h *DocumentHandler) GetDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	doc, err := h.documents.Get(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, "Failed to get document: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("ETag", doc.ETag)
+	http.ServeContent(w, r, "", doc.Created, bytes.NewReader(doc.Content))
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"pdf-service/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDocumentRequest(method, id string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/documents/"+id, nil)
+	req.SetPathValue("id", id)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestGetDocumentHandler_RangeRequest(t *testing.T) {
+	store := services.NewDocumentStore(1<<20, time.Minute)
+	handler := NewDocumentHandler(store)
+	id, err := store.Put([]byte("%PDF-1.7 linearized mock"))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.GetDocumentHandler(rr, newDocumentRequest(http.MethodGet, id, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "%PDF-1.7 linearized mock", rr.Body.String())
+	etag := rr.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rr = httptest.NewRecorder()
+	handler.GetDocumentHandler(rr, newDocumentRequest(http.MethodGet, id, map[string]string{"Range": "bytes=0-7", "If-Range": etag}))
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "bytes 0-7/24", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "%PDF-1.7", rr.Body.String())
+
+	// A range for another version of the document gets the whole of it.
+	rr = httptest.NewRecorder()
+	handler.GetDocumentHandler(rr, newDocumentRequest(http.MethodGet, id, map[string]string{"Range": "bytes=0-7", "If-Range": `"stale"`}))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "%PDF-1.7 linearized mock", rr.Body.String())
+}
+
+func TestGetDocumentHandler_Errors(t *testing.T) {
+	handler := NewDocumentHandler(services.NewDocumentStore(1<<20, time.Minute))
+
+	rr := httptest.NewRecorder()
+	handler.GetDocumentHandler(rr, newDocumentRequest(http.MethodGet, "missing", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.GetDocumentHandler(rr, newDocumentRequest(http.MethodPost, "missing", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
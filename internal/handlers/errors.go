@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+)
+
+// writeServiceError answers client errors with 400, unknown templates,
+// schemas and documents with 404 and everything else with 500, prefixed by
+// what failed. Schema violations are listed as JSON.
+func writeServiceError(w http.ResponseWriter, prefix string, err error) {
+	var validationErr *services.ValidationError
+	var tmplErr *services.TemplateError
+	if appErr, ok := err.(*services.AppError); ok {
+		http.Error(w, appErr.Error(), http.StatusBadRequest)
+	} else if errors.As(err, &tmplErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct {
+			Error         string               `json:"error"`
+			TemplateError models.TemplateError `json:"template_error"`
+		}{tmplErr.Summary(), tmplErr.TemplateError})
+	} else if errors.As(err, &validationErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct {
+			Error      string                   `json:"error"`
+			Violations []models.SchemaViolation `json:"violations"`
+		}{"Data does not match the template schema", validationErr.Violations})
+	} else if errors.Is(err, services.ErrTemplateNotFound) || errors.Is(err, services.ErrSchemaNotFound) ||
+		errors.Is(err, services.ErrDocumentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"strconv"
+	"strings"
+)
+
+type FormHandler struct {
+	formService services.FormServiceInterface
+}
+
+func NewFormHandler(formService services.FormServiceInterface) *FormHandler {
+	return &FormHandler{formService: formService}
+}
+
+// FillPDFHandler fills the fields of an uploaded PDF form with JSON data.
+func (h *FormHandler) FillPDFHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := readPDFUpload(w, r)
+	if !ok {
+		return
+	}
+
+	dataStr := r.FormValue("data")
+	if dataStr == "" {
+		http.Error(w, "Data field is required", http.StatusBadRequest)
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(dataStr), &data); err != nil {
+		http.Error(w, "Invalid JSON data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &models.FillRequest{PDF: content, Data: data}
+	if flattenStr := r.FormValue("flatten"); flattenStr != "" {
+		var err error
+		if req.Flatten, err = strconv.ParseBool(flattenStr); err != nil {
+			http.Error(w, "Invalid flatten option: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.formService.FillForm(req)
+	if err != nil {
+		writeServiceError(w, "Failed to fill PDF: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename=filled_form.pdf")
+	if len(result.UnmatchedFields) > 0 {
+		w.Header().Set("X-Unmatched-Fields", strings.Join(result.UnmatchedFields, ", "))
+	}
+	w.Write(result.Content)
+}
+
+// FormFieldsHandler lists the fields of an uploaded PDF form as JSON.
+func (h *FormHandler) FormFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := readPDFUpload(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := h.formService.ListFields(content)
+	if err != nil {
+		writeServiceError(w, "Failed to read form fields: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields)
+}
+
+// readPDFUpload reads the pdf_file field of a multipart request, writing
+// the error response itself when that fails.
+func readPDFUpload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	file, _, err := r.FormFile("pdf_file")
+	if err != nil {
+		http.Error(w, "Failed to get PDF file: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read PDF file: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return content, true
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFormService struct {
+	mock.Mock
+}
+
+func (m *MockFormService) FillForm(req *models.FillRequest) (*models.PDFResult, error) {
+	args := m.Called(req)
+	return args.Get(0).(*models.PDFResult), args.Error(1)
+}
+
+func (m *MockFormService) ListFields(content []byte) ([]models.FormField, error) {
+	args := m.Called(content)
+	return args.Get(0).([]models.FormField), args.Error(1)
+}
+
+func newFormRequest(t *testing.T, target string, fields map[string]string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("pdf_file", "form.pdf")
+	part.Write([]byte("%PDF-1.7 form"))
+	for k, v := range fields {
+		writer.WriteField(k, v)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestFillPDFHandler_Success(t *testing.T) {
+	formService := &MockFormService{}
+	handler := NewFormHandler(formService)
+
+	req := newFormRequest(t, "/fill-pdf", map[string]string{"data": `{"name":"John Doe","extra":1}`, "flatten": "true"})
+	rr := httptest.NewRecorder()
+
+	expected := func(r *models.FillRequest) bool {
+		return string(r.PDF) == "%PDF-1.7 form" && r.Flatten && r.Data["name"] == "John Doe"
+	}
+	formService.On("FillForm", mock.MatchedBy(expected)).Return(&models.PDFResult{
+		Content:         []byte("%PDF-1.7 filled"),
+		UnmatchedFields: []string{"extra"},
+	}, nil)
+
+	handler.FillPDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "extra", rr.Header().Get("X-Unmatched-Fields"))
+	assert.Equal(t, "%PDF-1.7 filled", rr.Body.String())
+	formService.AssertExpectations(t)
+}
+
+func TestFillPDFHandler_BadRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"missing data", map[string]string{}, "Data field is required\n"},
+		{"invalid data", map[string]string{"data": "{"}, "Invalid JSON data: unexpected end of JSON input\n"},
+		{"invalid flatten", map[string]string{"data": "{}", "flatten": "maybe"}, `Invalid flatten option: strconv.ParseBool: parsing "maybe": invalid syntax` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewFormHandler(&MockFormService{})
+			rr := httptest.NewRecorder()
+			handler.FillPDFHandler(rr, newFormRequest(t, "/fill-pdf", tt.fields))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, tt.want, rr.Body.String())
+		})
+	}
+}
+
+func TestFillPDFHandler_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantBody string
+	}{
+		{"client error", &services.AppError{Message: "Invalid PDF: malformed PDF"}, http.StatusBadRequest, "Invalid PDF: malformed PDF\n"},
+		{"internal error", errors.New("disk full"), http.StatusInternalServerError, "Failed to fill PDF: disk full\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formService := &MockFormService{}
+			formService.On("FillForm", mock.Anything).Return((*models.PDFResult)(nil), tt.err)
+			rr := httptest.NewRecorder()
+			NewFormHandler(formService).FillPDFHandler(rr, newFormRequest(t, "/fill-pdf", map[string]string{"data": "{}"}))
+			assert.Equal(t, tt.wantCode, rr.Code)
+			assert.Equal(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestFormFieldsHandler(t *testing.T) {
+	formService := &MockFormService{}
+	handler := NewFormHandler(formService)
+
+	fields := []models.FormField{{Name: "name", Type: "text", Required: true}}
+	formService.On("ListFields", []byte("%PDF-1.7 form")).Return(fields, nil)
+
+	rr := httptest.NewRecorder()
+	handler.FormFieldsHandler(rr, newFormRequest(t, "/form-fields", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var got []models.FormField
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, fields, got)
+}
+
+func TestFormFieldsHandler_MissingFile(t *testing.T) {
+	handler := NewFormHandler(&MockFormService{})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/form-fields", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	handler.FormFieldsHandler(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Failed to get PDF file")
+}
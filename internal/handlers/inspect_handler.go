@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"pdf-service/internal/services"
+	"strings"
+)
+
+type InspectHandler struct {
+	inspectService services.InspectServiceInterface
+}
+
+func NewInspectHandler(inspectService services.InspectServiceInterface) *InspectHandler {
+	return &InspectHandler{inspectService: inspectService}
+}
+
+// InspectHandler describes an uploaded PDF as JSON.
+func (h *InspectHandler) InspectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := readPDFUpload(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := h.inspectService.Inspect(content)
+	if err != nil {
+		writeServiceError(w, "Failed to inspect PDF: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// ExtractTextHandler returns the text of an uploaded PDF. The optional pages
+// field selects a page range; format=text answers with plain text, pages
+// separated by form feeds, instead of JSON.
+func (h *InspectHandler) ExtractTextHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := readPDFUpload(w, r)
+	if !ok {
+		return
+	}
+	format := r.FormValue("format")
+	if format != "" && format != "json" && format != "text" {
+		http.Error(w, "Invalid format: must be json or text", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.inspectService.ExtractText(content, r.FormValue("pages"))
+	if err != nil {
+		writeServiceError(w, "Failed to extract text: ", err)
+		return
+	}
+
+	if format == "text" {
+		texts := make([]string, len(result.Pages))
+		for i, p := range result.Pages {
+			texts[i] = p.Text
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(strings.Join(texts, "\f")))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInspectService struct {
+	mock.Mock
+}
+
+func (m *MockInspectService) Inspect(content []byte) (*models.PDFInfo, error) {
+	args := m.Called(content)
+	return args.Get(0).(*models.PDFInfo), args.Error(1)
+}
+
+func (m *MockInspectService) ExtractText(content []byte, pages string) (*models.TextResult, error) {
+	args := m.Called(content, pages)
+	return args.Get(0).(*models.TextResult), args.Error(1)
+}
+
+func TestInspectHandler(t *testing.T) {
+	inspectService := &MockInspectService{}
+	handler := NewInspectHandler(inspectService)
+
+	info := &models.PDFInfo{
+		Version:   "1.4",
+		PageCount: 1,
+		Pages:     []models.PageInfo{{Number: 1, Width: 595.44, Height: 841.68, Paper: "A4"}},
+		Fonts:     []models.FontInfo{{Name: "Vazir", Type: "TrueType", Embedded: true, Subset: true}},
+		Metadata:  map[string]string{"Producer": "Skia/PDF m120"},
+	}
+	inspectService.On("Inspect", []byte("%PDF-1.7 form")).Return(info, nil)
+
+	rr := httptest.NewRecorder()
+	handler.InspectHandler(rr, newFormRequest(t, "/inspect", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var got models.PDFInfo
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, *info, got)
+}
+
+func TestInspectHandler_MethodNotAllowed(t *testing.T) {
+	handler := NewInspectHandler(&MockInspectService{})
+
+	rr := httptest.NewRecorder()
+	handler.InspectHandler(rr, httptest.NewRequest(http.MethodGet, "/inspect", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestInspectHandler_InternalError(t *testing.T) {
+	inspectService := &MockInspectService{}
+	handler := NewInspectHandler(inspectService)
+	inspectService.On("Inspect", mock.Anything).Return((*models.PDFInfo)(nil), errors.New("boom"))
+
+	rr := httptest.NewRecorder()
+	handler.InspectHandler(rr, newFormRequest(t, "/inspect", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "Failed to inspect PDF: boom\n", rr.Body.String())
+}
+
+func TestExtractTextHandler(t *testing.T) {
+	inspectService := &MockInspectService{}
+	handler := NewInspectHandler(inspectService)
+
+	result := &models.TextResult{PageCount: 3, Pages: []models.PageText{{Number: 2, Text: "فاکتور شماره ۱۲"}, {Number: 3, Text: "Total"}}}
+	inspectService.On("ExtractText", []byte("%PDF-1.7 form"), "2-").Return(result, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ExtractTextHandler(rr, newFormRequest(t, "/extract-text", map[string]string{"pages": "2-"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var got models.TextResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, *result, got)
+
+	rr = httptest.NewRecorder()
+	handler.ExtractTextHandler(rr, newFormRequest(t, "/extract-text", map[string]string{"pages": "2-", "format": "text"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "فاکتور شماره ۱۲\fTotal", rr.Body.String())
+}
+
+func TestExtractTextHandler_InvalidFormat(t *testing.T) {
+	handler := NewInspectHandler(&MockInspectService{})
+
+	rr := httptest.NewRecorder()
+	handler.ExtractTextHandler(rr, newFormRequest(t, "/extract-text", map[string]string{"format": "xml"}))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestExtractTextHandler_ClientError(t *testing.T) {
+	inspectService := &MockInspectService{}
+	handler := NewInspectHandler(inspectService)
+	inspectService.On("ExtractText", mock.Anything, "9").Return((*models.TextResult)(nil), &services.AppError{Message: "invalid page range: page 9 out of range"})
+
+	rr := httptest.NewRecorder()
+	handler.ExtractTextHandler(rr, newFormRequest(t, "/extract-text", map[string]string{"pages": "9"}))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+)
+
+type MarkdownHandler struct {
+	markdownService services.MarkdownServiceInterface
+}
+
+func NewMarkdownHandler(markdownService services.MarkdownServiceInterface) *MarkdownHandler {
+	return &MarkdownHandler{markdownService: markdownService}
+}
+
+// MarkdownPDFHandler prints the Markdown document uploaded as markdown_file
+// in the registered template named by layout, or the built-in layout, with
+// an optional stylesheet file and JSON data. The PDF options of
+// /generate-pdf apply as well.
+func (h *MarkdownHandler) MarkdownPDFHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, err := readFormFile(r, "markdown_file")
+	if err != nil {
+		http.Error(w, "Failed to get Markdown file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &models.MarkdownRequest{Markdown: source, Layout: r.FormValue("layout")}
+
+	if r.MultipartForm.File["stylesheet"] != nil {
+		if req.Stylesheet, err = readFormFile(r, "stylesheet"); err != nil {
+			http.Error(w, "Failed to read stylesheet: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if dataStr := r.FormValue("data"); dataStr != "" {
+		if err := json.Unmarshal([]byte(dataStr), &req.Data); err != nil {
+			http.Error(w, "Invalid JSON data: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !readPDFOptions(w, r, &req.PDF) {
+		return
+	}
+
+	result, err := h.markdownService.GenerateMarkdownPDF(req)
+	if err != nil {
+		writeServiceError(w, "Failed to generate PDF: ", err)
+		return
+	}
+
+	writePDF(w, result, "document.pdf")
+}
+
+// readFormFile reads the whole of the uploaded file field.
+func readFormFile(r *http.Request, field string) (string, error) {
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	return string(content), err
+}
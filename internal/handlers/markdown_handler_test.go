@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMarkdownService struct {
+	mock.Mock
+}
+
+func (m *MockMarkdownService) GenerateMarkdownPDF(req *models.MarkdownRequest) (*models.PDFResult, error) {
+	args := m.Called(req)
+	return args.Get(0).(*models.PDFResult), args.Error(1)
+}
+
+// newMarkdownRequest builds a /markdown-pdf request with the Markdown file,
+// an optional stylesheet file and the given fields.
+func newMarkdownRequest(markdown, stylesheet string, fields map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if markdown != "" {
+		part, _ := writer.CreateFormFile("markdown_file", "letter.md")
+		part.Write([]byte(markdown))
+	}
+	if stylesheet != "" {
+		part, _ := writer.CreateFormFile("stylesheet", "print.css")
+		part.Write([]byte(stylesheet))
+	}
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/markdown-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestMarkdownPDFHandler(t *testing.T) {
+	markdownService := &MockMarkdownService{}
+	handler := NewMarkdownHandler(markdownService)
+	markdownService.On("GenerateMarkdownPDF", mock.MatchedBy(func(r *models.MarkdownRequest) bool {
+		return r.Markdown == "# Policy\n" && r.Stylesheet == "h1 { color: navy; }" && r.Layout == "letter" &&
+			r.Data["customer"] == "Sara" && r.PDF.PDFA == "2b" && r.PDF.Linearize
+	})).Return(&models.PDFResult{
+		Content:  []byte("%PDF-1.7"),
+		Template: &models.TemplateVersion{ID: "letter", Version: 2},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.MarkdownPDFHandler(rr, newMarkdownRequest("# Policy\n", "h1 { color: navy; }", map[string]string{
+		"layout":    "letter",
+		"data":      `{"customer":"Sara"}`,
+		"pdfa":      "2b",
+		"linearize": "true",
+	}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=document.pdf", rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "letter", rr.Header().Get("X-Template-ID"))
+	assert.Equal(t, "2", rr.Header().Get("X-Template-Version"))
+	assert.Equal(t, "%PDF-1.7", rr.Body.String())
+	markdownService.AssertExpectations(t)
+}
+
+func TestMarkdownPDFHandler_BadRequest(t *testing.T) {
+	markdownService := &MockMarkdownService{}
+	handler := NewMarkdownHandler(markdownService)
+
+	rr := httptest.NewRecorder()
+	handler.MarkdownPDFHandler(rr, httptest.NewRequest(http.MethodGet, "/markdown-pdf", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"missing file", newMarkdownRequest("", "", nil), "Failed to get Markdown file"},
+		{"invalid data", newMarkdownRequest("# Policy", "", map[string]string{"data": "{"}), "Invalid JSON data"},
+		{"invalid option", newMarkdownRequest("# Policy", "", map[string]string{"outline": "maybe"}), "Invalid outline option"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.MarkdownPDFHandler(rr, tt.req)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.want)
+		})
+	}
+	markdownService.AssertNotCalled(t, "GenerateMarkdownPDF", mock.Anything)
+}
+
+func TestMarkdownPDFHandler_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"empty", services.ErrEmptyMarkdown, http.StatusBadRequest},
+		{"missing layout", services.ErrTemplateNotFound, http.StatusNotFound},
+		{"generic", errors.New("chromium crashed"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdownService := &MockMarkdownService{}
+			handler := NewMarkdownHandler(markdownService)
+			markdownService.On("GenerateMarkdownPDF", mock.Anything).Return((*models.PDFResult)(nil), tt.err)
+
+			rr := httptest.NewRecorder()
+			handler.MarkdownPDFHandler(rr, newMarkdownRequest("# Policy", "", nil))
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
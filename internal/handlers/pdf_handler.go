@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"pdf-service/internal/models"
 	"pdf-service/internal/services"
+	"strconv"
+	"strings"
 )
 
 type PDFHandler struct {
@@ -22,37 +25,77 @@ func (h *PDFHandler) GeneratePDFHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	req, ok := readRenderForm(w, r)
+	if !ok || !readPDFOptions(w, r, req) {
+		return
+	}
+
+	result, err := h.pdfService.GeneratePDF(req)
+	if err != nil {
+		writeServiceError(w, "Failed to generate PDF: ", err)
+		return
+	}
+
+	writePDF(w, result, "dynamic_document.pdf")
+}
+
+// RenderHTMLHandler renders an uploaded template with its data and returns
+// the HTML that /generate-pdf would convert, for previewing templates. With
+// a base_url field, relative asset references are rewritten against it.
+func (h *PDFHandler) RenderHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, ok := readRenderForm(w, r)
+	if !ok {
+		return
+	}
+	req.AssetBaseURL = r.FormValue("base_url")
+
+	result, err := h.pdfService.RenderHTML(req)
+	if err != nil {
+		writeServiceError(w, "Failed to render HTML: ", err)
+		return
+	}
+	writeHTML(w, result)
+}
+
+// readRenderForm reads the template_file, data and strict fields of a
+// multipart request, writing an error response if they are invalid.
+func readRenderForm(w http.ResponseWriter, r *http.Request) (*models.PDFRequest, bool) {
 	// Parse the multipart form (set a reasonable max memory limit, e.g., 10MB)
 	err := r.ParseMultipartForm(10 << 20) // 10MB
 	if err != nil {
 		http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	file, _, err := r.FormFile("template_file")
 	if err != nil {
 		http.Error(w, "Failed to get template file: "+err.Error(), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	defer file.Close()
 
 	htmlBytes, err := io.ReadAll(file)
 	if err != nil {
 		http.Error(w, "Failed to read template file: "+err.Error(), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	htmlTemplate := string(htmlBytes)
 
 	dataStr := r.FormValue("data")
 	if dataStr == "" {
 		http.Error(w, "Data field is required", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	var data map[string]interface{}
 	if err := json.Unmarshal([]byte(dataStr), &data); err != nil {
 		http.Error(w, "Invalid JSON data: "+err.Error(), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	req := &models.PDFRequest{
@@ -60,21 +103,173 @@ func (h *PDFHandler) GeneratePDFHandler(w http.ResponseWriter, r *http.Request)
 		Data:         data,
 	}
 
-	pdfBuffer, err := h.pdfService.GeneratePDF(req)
-	if err != nil {
-		if appErr, ok := err.(*services.AppError); ok {
-			http.Error(w, appErr.Error(), http.StatusBadRequest)
-		} else {
-			http.Error(w, "Failed to generate PDF: "+err.Error(), http.StatusInternalServerError)
+	if strictStr := r.FormValue("strict"); strictStr != "" {
+		if req.Strict, err = strconv.ParseBool(strictStr); err != nil {
+			http.Error(w, "Invalid strict option: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return req, true
+}
+
+// readPDFOptions reads the fields of a multipart request that set the PDF
+// pipeline options of req, writing an error response if they are invalid.
+func readPDFOptions(w http.ResponseWriter, r *http.Request, req *models.PDFRequest) bool {
+	if watermarkStr := r.FormValue("watermark"); watermarkStr != "" {
+		var watermark models.Watermark
+		if err := json.Unmarshal([]byte(watermarkStr), &watermark); err != nil {
+			http.Error(w, "Invalid watermark: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+		if imageFile, _, err := r.FormFile("watermark_image"); err == nil {
+			defer imageFile.Close()
+			if watermark.Image, err = io.ReadAll(imageFile); err != nil {
+				http.Error(w, "Failed to read watermark image: "+err.Error(), http.StatusBadRequest)
+				return false
+			}
+		}
+		req.Watermark = &watermark
+	}
+	req.PDFA = r.FormValue("pdfa")
+
+	if attachmentsStr := r.FormValue("attachments"); attachmentsStr != "" {
+		if err := json.Unmarshal([]byte(attachmentsStr), &req.Attachments); err != nil {
+			http.Error(w, "Invalid attachments: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+		for i := range req.Attachments {
+			if err := readAttachment(r, &req.Attachments[i]); err != nil {
+				http.Error(w, "Failed to read attachment: "+err.Error(), http.StatusBadRequest)
+				return false
+			}
 		}
-		return
 	}
 
+	if optimizeStr := r.FormValue("optimize"); optimizeStr != "" {
+		req.Optimize = &models.Optimization{}
+		if optimizeStr != "true" {
+			if err := json.Unmarshal([]byte(optimizeStr), req.Optimize); err != nil {
+				http.Error(w, "Invalid optimize options: "+err.Error(), http.StatusBadRequest)
+				return false
+			}
+		}
+	}
+
+	if imposeStr := r.FormValue("impose"); imposeStr != "" {
+		req.Impose = &models.Imposition{}
+		if err := json.Unmarshal([]byte(imposeStr), req.Impose); err != nil {
+			http.Error(w, "Invalid impose options: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if printStr := r.FormValue("print"); printStr != "" {
+		req.Print = &models.PrintMarks{}
+		if err := json.Unmarshal([]byte(printStr), req.Print); err != nil {
+			http.Error(w, "Invalid print options: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if signStr := r.FormValue("sign"); signStr != "" {
+		req.Sign = &models.Signature{}
+		if err := json.Unmarshal([]byte(signStr), req.Sign); err != nil {
+			http.Error(w, "Invalid sign options: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if linearizeStr := r.FormValue("linearize"); linearizeStr != "" {
+		var err error
+		if req.Linearize, err = strconv.ParseBool(linearizeStr); err != nil {
+			http.Error(w, "Invalid linearize option: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if outlineStr := r.FormValue("outline"); outlineStr != "" {
+		var err error
+		if req.Outline, err = strconv.ParseBool(outlineStr); err != nil {
+			http.Error(w, "Invalid outline option: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// writeHTML sends a rendered template. The page is sandboxed so that
+// scripts in it cannot act on behalf of this service's origin.
+func writeHTML(w http.ResponseWriter, result *models.HTMLResult) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if version := result.Template; version != nil {
+		w.Header().Set("X-Template-ID", version.ID)
+		w.Header().Set("X-Template-Version", strconv.Itoa(version.Version))
+	}
+	io.WriteString(w, result.HTML)
+}
+
+// writePDF sends a generated document with headers describing it.
+func writePDF(w http.ResponseWriter, result *models.PDFResult, filename string) {
 	w.Header().Set("Content-Type", "application/pdf")
-	w.Header().Set("Content-Disposition", "attachment; filename=dynamic_document.pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	if len(result.Pages) > 0 {
+		first := result.Pages[0]
+		w.Header().Set("X-Page-Count", strconv.Itoa(len(result.Pages)))
+		w.Header().Set("X-Page-Size", fmt.Sprintf("%gx%g", first.Width, first.Height))
+		if first.Paper != "" {
+			w.Header().Set("X-Paper-Size", first.Paper)
+		}
+	}
+	if report := result.Conformance; report != nil {
+		w.Header().Set("X-PDFA-Conformance", report.Level)
+		w.Header().Set("X-PDFA-Compliant", strconv.FormatBool(report.Compliant))
+		if len(report.Violations) > 0 {
+			w.Header().Set("X-PDFA-Violations", strings.Join(report.Violations, "; "))
+		}
+	}
+	if report := result.Optimization; report != nil {
+		w.Header().Set("X-Original-Size", strconv.Itoa(report.OriginalSize))
+		w.Header().Set("X-Optimized-Size", strconv.Itoa(report.OptimizedSize))
+	}
+	if version := result.Template; version != nil {
+		w.Header().Set("X-Template-ID", version.ID)
+		w.Header().Set("X-Template-Version", strconv.Itoa(version.Version))
+	}
+	// Generating the document again would give different bytes, so range
+	// requests go to the stored copy instead.
+	if result.DocumentID != "" {
+		w.Header().Set("Content-Location", "/documents/"+result.DocumentID)
+	}
 
-	if _, err := w.Write(pdfBuffer); err != nil {
-		http.Error(w, "Failed to write response: "+err.Error(), http.StatusInternalServerError)
-		return
+	w.Header().Set("Content-Length", strconv.Itoa(len(result.Content)))
+	w.Write(result.Content)
+}
+
+// readAttachment loads the content of an attachment uploaded as a file
+// field, filling in its name and type from the upload when not given.
+func readAttachment(r *http.Request, a *models.Attachment) error {
+	if a.Source == models.AttachmentSourceData {
+		return nil
 	}
-}
\ No newline at end of file
+	if a.Source == "" {
+		return fmt.Errorf("attachment %q has no source", a.Name)
+	}
+	file, header, err := r.FormFile(a.Source)
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.Source, err)
+	}
+	defer file.Close()
+
+	if a.Data, err = io.ReadAll(file); err != nil {
+		return fmt.Errorf("%s: %w", a.Source, err)
+	}
+	if a.Name == "" {
+		a.Name = header.Filename
+	}
+	if contentType := header.Header.Get("Content-Type"); a.MIMEType == "" && contentType != "application/octet-stream" {
+		a.MIMEType = contentType
+	}
+	return nil
+}
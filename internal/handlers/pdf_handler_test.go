@@ -19,9 +19,14 @@ type MockPDFService struct {
 	mock.Mock
 }
 
-func (m *MockPDFService) GeneratePDF(req *models.PDFRequest) ([]byte, error) {
+func (m *MockPDFService) GeneratePDF(req *models.PDFRequest) (*models.PDFResult, error) {
 	args := m.Called(req)
-	return args.Get(0).([]byte), args.Error(1)
+	return args.Get(0).(*models.PDFResult), args.Error(1)
+}
+
+func (m *MockPDFService) RenderHTML(req *models.PDFRequest) (*models.HTMLResult, error) {
+	args := m.Called(req)
+	return args.Get(0).(*models.HTMLResult), args.Error(1)
 }
 
 func TestNewPDFHandler(t *testing.T) {
@@ -65,7 +70,7 @@ func TestGeneratePDFHandler_Success(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	expectedPDF := []byte("%PDF-1.4 mock")
-	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return(expectedPDF, nil)
+	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return(&models.PDFResult{Content: expectedPDF}, nil)
 
 	handler.GeneratePDFHandler(rr, req)
 
@@ -151,7 +156,7 @@ func TestGeneratePDFHandler_PDFServiceAppError(t *testing.T) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	rr := httptest.NewRecorder()
 
-	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return([]byte(nil), &services.AppError{Message: "Invalid template"})
+	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return((*models.PDFResult)(nil), &services.AppError{Message: "Invalid template"})
 
 	handler.GeneratePDFHandler(rr, req)
 
@@ -175,11 +180,468 @@ func TestGeneratePDFHandler_PDFServiceGenericError(t *testing.T) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	rr := httptest.NewRecorder()
 
-	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return([]byte(nil), errors.New("internal error"))
+	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return((*models.PDFResult)(nil), errors.New("internal error"))
 
 	handler.GeneratePDFHandler(rr, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Failed to generate PDF: internal error")
 	pdfService.AssertExpectations(t)
-}
\ No newline at end of file
+}
+func TestGeneratePDFHandler_Watermark(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("watermark", `{"opacity":0.5,"rotation":45,"pages":"1-2"}`)
+	imagePart, _ := writer.CreateFormFile("watermark_image", "stamp.png")
+	imagePart.Write([]byte("png bytes"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	expectedPDF := []byte("%PDF-1.4 mock")
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.Watermark != nil &&
+			r.Watermark.Opacity == 0.5 &&
+			r.Watermark.Rotation == 45 &&
+			r.Watermark.Pages == "1-2" &&
+			string(r.Watermark.Image) == "png bytes"
+	})).Return(&models.PDFResult{Content: expectedPDF}, nil)
+
+	handler.GeneratePDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, expectedPDF, rr.Body.Bytes())
+	pdfService.AssertExpectations(t)
+}
+
+func TestGeneratePDFHandler_InvalidWatermark(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("watermark", `{"text":`)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	handler.GeneratePDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid watermark")
+	pdfService.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDFHandler_PDFAConformanceHeaders(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("pdfa", "2b")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.PDFA == "2b"
+	})).Return(&models.PDFResult{
+		Content: []byte("%PDF-1.7 mock"),
+		Conformance: &models.ConformanceReport{
+			Level:      "PDF/A-2b",
+			Violations: []string{"font Helvetica is not embedded", "document is encrypted"},
+		},
+	}, nil)
+
+	handler.GeneratePDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "PDF/A-2b", rr.Header().Get("X-PDFA-Conformance"))
+	assert.Equal(t, "false", rr.Header().Get("X-PDFA-Compliant"))
+	assert.Equal(t, "font Helvetica is not embedded; document is encrypted", rr.Header().Get("X-PDFA-Violations"))
+	pdfService.AssertExpectations(t)
+}
+
+func TestGeneratePDFHandler_Attachments(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("attachments", `[{"source":"data"},{"source":"invoice_xml","description":"E-invoice","relationship":"Alternative"}]`)
+	invoicePart, _ := writer.CreateFormFile("invoice_xml", "invoice.xml")
+	invoicePart.Write([]byte("<Invoice/>"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	expectedPDF := []byte("%PDF-1.4 mock")
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return len(r.Attachments) == 2 &&
+			r.Attachments[0].Source == models.AttachmentSourceData &&
+			r.Attachments[1].Name == "invoice.xml" &&
+			r.Attachments[1].Description == "E-invoice" &&
+			string(r.Attachments[1].Data) == "<Invoice/>"
+	})).Return(&models.PDFResult{Content: expectedPDF}, nil)
+
+	handler.GeneratePDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, expectedPDF, rr.Body.Bytes())
+	pdfService.AssertExpectations(t)
+}
+
+func TestGeneratePDFHandler_MissingAttachmentFile(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("attachments", `[{"source":"invoice_xml"}]`)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	handler.GeneratePDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Failed to read attachment: invoice_xml")
+	pdfService.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDFHandler_OptimizeSizeHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  models.Optimization
+	}{
+		{"defaults", "true", models.Optimization{}},
+		{"options", `{"max_image_dpi":120,"jpeg_quality":70}`, models.Optimization{MaxImageDPI: 120, JPEGQuality: 70}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdfService := &MockPDFService{}
+			handler := NewPDFHandler(pdfService)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile("template_file", "template.html")
+			part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+			writer.WriteField("data", `{"Name":"John Doe"}`)
+			writer.WriteField("optimize", tt.value)
+			writer.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rr := httptest.NewRecorder()
+
+			pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+				return r.Optimize != nil && *r.Optimize == tt.want
+			})).Return(&models.PDFResult{
+				Content:      []byte("%PDF-1.5 mock"),
+				Optimization: &models.OptimizationReport{OriginalSize: 2048, OptimizedSize: 1024},
+			}, nil)
+
+			handler.GeneratePDFHandler(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "2048", rr.Header().Get("X-Original-Size"))
+			assert.Equal(t, "1024", rr.Header().Get("X-Optimized-Size"))
+			pdfService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeneratePDFHandler_InvalidOptimize(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("optimize", `{"max_image_dpi":"high"}`)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	handler.GeneratePDFHandler(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid optimize options")
+	pdfService.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+// newLinearizeRequest builds a generate request asking for a linearized
+// document.
+func TestGeneratePDFHandler_Strict(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool { return r.Strict })).
+		Return((*models.PDFResult)(nil), &services.TemplateError{TemplateError: models.TemplateError{
+			Kind: services.TemplateErrorExecute, Template: "dynamic", Line: 1, Column: 16,
+			Source: "<html><body>{{.Name}}</body></html>", Field: ".Name", Message: `map has no entry for key "Name"`,
+		}})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{}`)
+	writer.WriteField("strict", "true")
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"error": "Failed to render template",
+		"template_error": {
+			"kind": "execute",
+			"template": "dynamic",
+			"line": 1,
+			"column": 16,
+			"source": "<html><body>{{.Name}}</body></html>",
+			"field": ".Name",
+			"message": "map has no entry for key \"Name\""
+		}
+	}`, rr.Body.String())
+	pdfService.AssertExpectations(t)
+}
+
+func newLinearizeRequest(linearize string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("linearize", linearize)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestGeneratePDFHandler_Linearize(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	content := []byte("%PDF-1.7 linearized mock")
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.Linearize
+	})).Return(&models.PDFResult{Content: content, DocumentID: "0f1e"}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newLinearizeRequest("true"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "/documents/0f1e", rr.Header().Get("Content-Location"))
+	assert.Equal(t, "24", rr.Header().Get("Content-Length"))
+	assert.Equal(t, string(content), rr.Body.String())
+	pdfService.AssertExpectations(t)
+}
+
+func TestGeneratePDFHandler_InvalidLinearize(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newLinearizeRequest("maybe"))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid linearize option")
+	pdfService.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDFHandler_PageHeaders(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return(&models.PDFResult{
+		Content: []byte("%PDF-1.4 mock"),
+		Pages: []models.PageInfo{
+			{Number: 1, Width: 595.44, Height: 841.68, Paper: "A4"},
+			{Number: 2, Width: 841.68, Height: 595.44, Paper: "A4"},
+		},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newLinearizeRequest("false"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-Page-Count"))
+	assert.Equal(t, "595.44x841.68", rr.Header().Get("X-Page-Size"))
+	assert.Equal(t, "A4", rr.Header().Get("X-Paper-Size"))
+}
+
+// newOptionRequest builds a generate request with one option field set.
+func newOptionRequest(field, value string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<html><body>{{.Name}}</body></html>"))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField(field, value)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/generate-pdf", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestGeneratePDFHandler_Impose(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.Impose != nil && r.Impose.Layout == "labels" &&
+			r.Impose.Columns == 2 && r.Impose.Rows == 5 && r.Impose.Gutter == 6 && r.Impose.CropMarks
+	})).Return(&models.PDFResult{Content: []byte("%PDF-1.4 mock")}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newOptionRequest("impose", `{"layout":"labels","columns":2,"rows":5,"gutter":6,"crop_marks":true}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pdfService.AssertExpectations(t)
+}
+
+func TestGeneratePDFHandler_InvalidImpose(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newOptionRequest("impose", `{"layout":`))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid impose options")
+	pdfService.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDFHandler_PrintMarks(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.Print != nil && r.Print.Bleed == 8.5 && r.Print.CropMarks && r.Print.RegistrationMarks
+	})).Return(&models.PDFResult{Content: []byte("%PDF-1.4 mock")}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newOptionRequest("print", `{"bleed":8.5,"crop_marks":true,"registration_marks":true}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pdfService.AssertExpectations(t)
+
+	rr = httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newOptionRequest("print", `[]`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid print options")
+}
+
+func TestGeneratePDFHandler_Sign(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+
+	pdfService.On("GeneratePDF", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.Sign != nil && r.Sign.Reason == "Approval" && r.Sign.Location == "Berlin"
+	})).Return(&models.PDFResult{Content: []byte("%PDF-1.4 mock")}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newOptionRequest("sign", `{"reason":"Approval","location":"Berlin"}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pdfService.AssertExpectations(t)
+
+	rr = httptest.NewRecorder()
+	handler.GeneratePDFHandler(rr, newOptionRequest("sign", `true`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid sign options")
+}
+
+func TestRenderHTMLHandler(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+	pdfService.On("RenderHTML", mock.MatchedBy(func(r *models.PDFRequest) bool {
+		return r.HTMLTemplate == `<img src="logo.png">{{.Name}}` && r.Data["Name"] == "John Doe" &&
+			r.Strict && r.AssetBaseURL == "https://cdn.example.com/"
+	})).Return(&models.HTMLResult{HTML: `<img src="https://cdn.example.com/logo.png">John Doe`}, nil)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte(`<img src="logo.png">{{.Name}}`))
+	writer.WriteField("data", `{"Name":"John Doe"}`)
+	writer.WriteField("strict", "true")
+	writer.WriteField("base_url", "https://cdn.example.com/")
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/render-html", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	handler.RenderHTMLHandler(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "sandbox", rr.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, rr.Header().Get("X-Template-ID"))
+	assert.Equal(t, `<img src="https://cdn.example.com/logo.png">John Doe`, rr.Body.String())
+	pdfService.AssertExpectations(t)
+}
+
+func TestRenderHTMLHandler_Errors(t *testing.T) {
+	pdfService := &MockPDFService{}
+	handler := NewPDFHandler(pdfService)
+	pdfService.On("RenderHTML", mock.Anything).Return((*models.HTMLResult)(nil), services.ErrInvalidAssetBaseURL)
+
+	rr := httptest.NewRecorder()
+	handler.RenderHTMLHandler(rr, httptest.NewRequest(http.MethodGet, "/render-html", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte("<p></p>"))
+	writer.WriteField("data", `{}`)
+	writer.WriteField("base_url", "assets/")
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/render-html", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr = httptest.NewRecorder()
+	handler.RenderHTMLHandler(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, services.ErrInvalidAssetBaseURL.Message+"\n", rr.Body.String())
+}
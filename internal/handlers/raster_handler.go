@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"strconv"
+)
+
+type RasterHandler struct {
+	rasterService services.RasterServiceInterface
+}
+
+func NewRasterHandler(rasterService services.RasterServiceInterface) *RasterHandler {
+	return &RasterHandler{rasterService: rasterService}
+}
+
+// RasterizeHandler renders pages of an uploaded PDF as PNG images. A single
+// page is returned as the image itself, several pages as a ZIP archive;
+// format=zip asks for an archive even for one page.
+func (h *RasterHandler) RasterizeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, ok := readPDFUpload(w, r)
+	if !ok {
+		return
+	}
+
+	req := &models.RasterRequest{PDF: content, Pages: r.FormValue("pages")}
+	if widthStr := r.FormValue("width"); widthStr != "" {
+		var err error
+		if req.Width, err = strconv.Atoi(widthStr); err != nil {
+			http.Error(w, "Invalid width: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.FormValue("format")
+	if format != "" && format != "png" && format != "zip" {
+		http.Error(w, "Invalid format: must be png or zip", http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.rasterService.Rasterize(req)
+	if err != nil {
+		writeServiceError(w, "Failed to rasterize PDF: ", err)
+		return
+	}
+	if format == "png" && len(images) != 1 {
+		http.Error(w, "Format png needs a single page; use zip for several", http.StatusBadRequest)
+		return
+	}
+
+	if len(images) == 1 && format != "zip" {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=page-%d.png", images[0].Number))
+		w.Write(images[0].Content)
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, img := range images {
+		// PNG data is already compressed.
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("page-%d.png", img.Number), Method: zip.Store})
+		if err == nil {
+			_, err = f.Write(img.Content)
+		}
+		if err != nil {
+			http.Error(w, "Failed to write ZIP archive: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		http.Error(w, "Failed to write ZIP archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=pages.zip")
+	w.Write(buf.Bytes())
+}
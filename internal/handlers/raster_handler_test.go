@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRasterService struct {
+	mock.Mock
+}
+
+func (m *MockRasterService) Rasterize(req *models.RasterRequest) ([]models.PageImage, error) {
+	args := m.Called(req)
+	return args.Get(0).([]models.PageImage), args.Error(1)
+}
+
+func TestRasterizeHandler_SinglePage(t *testing.T) {
+	rasterService := &MockRasterService{}
+	handler := NewRasterHandler(rasterService)
+	rasterService.On("Rasterize", &models.RasterRequest{PDF: []byte("%PDF-1.7 form"), Width: 320}).
+		Return([]models.PageImage{{Number: 1, Content: []byte("png")}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.RasterizeHandler(rr, newFormRequest(t, "/rasterize", map[string]string{"width": "320"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "inline; filename=page-1.png", rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "png", rr.Body.String())
+}
+
+func TestRasterizeHandler_Zip(t *testing.T) {
+	rasterService := &MockRasterService{}
+	handler := NewRasterHandler(rasterService)
+	rasterService.On("Rasterize", &models.RasterRequest{PDF: []byte("%PDF-1.7 form"), Pages: "all"}).
+		Return([]models.PageImage{{Number: 1, Content: []byte("png1")}, {Number: 2, Content: []byte("png2")}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.RasterizeHandler(rr, newFormRequest(t, "/rasterize", map[string]string{"pages": "all"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	assert.Equal(t, "page-2.png", archive.File[1].Name)
+	f, err := archive.File[1].Open()
+	require.NoError(t, err)
+	data, _ := io.ReadAll(f)
+	assert.Equal(t, "png2", string(data))
+}
+
+func TestRasterizeHandler_InvalidOptions(t *testing.T) {
+	handler := NewRasterHandler(&MockRasterService{})
+
+	for _, fields := range []map[string]string{{"width": "wide"}, {"format": "gif"}} {
+		rr := httptest.NewRecorder()
+		handler.RasterizeHandler(rr, newFormRequest(t, "/rasterize", fields))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestRasterizeHandler_ClientError(t *testing.T) {
+	rasterService := &MockRasterService{}
+	handler := NewRasterHandler(rasterService)
+	rasterService.On("Rasterize", mock.Anything).Return([]models.PageImage(nil), services.ErrInvalidRasterWidth)
+
+	rr := httptest.NewRecorder()
+	handler.RasterizeHandler(rr, newFormRequest(t, "/rasterize", map[string]string{"width": "5"}))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Width must be between")
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"pdf-service/internal/services"
+	"strconv"
+)
+
+// maxTemplateSize bounds uploaded templates, which may embed fonts and
+// images.
+const maxTemplateSize = 10 << 20
+
+type TemplateHandler struct {
+	templateService services.TemplateServiceInterface
+}
+
+func NewTemplateHandler(templateService services.TemplateServiceInterface) *TemplateHandler {
+	return &TemplateHandler{templateService: templateService}
+}
+
+// ListTemplatesHandler lists the registered templates as JSON.
+func (h *TemplateHandler) ListTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.templateService.ListTemplates())
+}
+
+// RegisterTemplateHandler stores the HTML template sent as the request
+// body as a new version of the ID in the path. It answers 201 with the new
+// version, or 200 with the latest one when the source is unchanged.
+func (h *TemplateHandler) RegisterTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTemplateSize))
+	if err != nil {
+		http.Error(w, "Failed to read template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info, created, err := h.templateService.RegisterTemplate(r.PathValue("id"), string(source))
+	if err != nil {
+		writeServiceError(w, "Failed to register template: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(info)
+}
+
+// ListVersionsHandler lists the versions of the template named in the path
+// as JSON, oldest first.
+func (h *TemplateHandler) ListVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	versions, err := h.templateService.ListVersions(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, "Failed to list versions: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// RollbackTemplateHandler makes the version in the JSON request body, such
+// as {"version": 2}, the latest version of the template named in the path.
+func (h *TemplateHandler) RollbackTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Version int `json:"version"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTemplateSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.templateService.RollbackTemplate(r.PathValue("id"), req.Version)
+	if err != nil {
+		writeServiceError(w, "Failed to roll back template: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(info)
+}
+
+// PinVersionHandler pins the version of the template named in the path with
+// PUT, so that it is kept however many versions follow, and unpins it with
+// DELETE. It answers with the version as JSON.
+func (h *TemplateHandler) PinVersionHandler(w http.ResponseWriter, r *http.Request) {
+	var pinned bool
+	switch r.Method {
+	case http.MethodPut:
+		pinned = true
+	case http.MethodDelete:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, ok := parseVersion(r.PathValue("version"))
+	if !ok {
+		http.Error(w, "Invalid version: "+r.PathValue("version"), http.StatusBadRequest)
+		return
+	}
+	info, err := h.templateService.PinVersion(r.PathValue("id"), version, pinned)
+	if err != nil {
+		writeServiceError(w, "Failed to pin version: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// RenderTemplateHandler generates a PDF from the template named in the
+// path, with the JSON data sent as the request body. The version query
+// parameter pins a version; the latest is used without it.
+func (h *TemplateHandler) RenderTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, ok := parseVersion(r.URL.Query().Get("version"))
+	if !ok {
+		http.Error(w, "Invalid version: "+r.URL.Query().Get("version"), http.StatusBadRequest)
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTemplateSize)).Decode(&data); err != nil {
+		http.Error(w, "Invalid JSON data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	result, err := h.templateService.RenderTemplate(id, version, data)
+	if err != nil {
+		writeServiceError(w, "Failed to generate PDF: ", err)
+		return
+	}
+
+	writePDF(w, result, id+".pdf")
+}
+
+// RenderTemplateHTMLHandler renders the template named in the path with the
+// JSON data in the body and returns the HTML instead of a PDF. The query
+// takes the version like RenderTemplateHandler and an optional base_url
+// that relative asset references are rewritten against.
+func (h *TemplateHandler) RenderTemplateHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, ok := parseVersion(r.URL.Query().Get("version"))
+	if !ok {
+		http.Error(w, "Invalid version: "+r.URL.Query().Get("version"), http.StatusBadRequest)
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTemplateSize)).Decode(&data); err != nil {
+		http.Error(w, "Invalid JSON data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.templateService.RenderTemplateHTML(r.PathValue("id"), version, data, r.URL.Query().Get("base_url"))
+	if err != nil {
+		writeServiceError(w, "Failed to render HTML: ", err)
+		return
+	}
+	writeHTML(w, result)
+}
+
+// TemplateSchemaHandler reads (GET), sets (PUT) or removes (DELETE) the
+// JSON Schema of the template named in the path. Data rendered with a
+// template that has a schema must match it.
+func (h *TemplateHandler) TemplateSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		schema, err := h.templateService.TemplateSchema(id)
+		if err != nil {
+			writeServiceError(w, "Failed to get schema: ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(schema)
+	case http.MethodPut:
+		source, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTemplateSize))
+		if err != nil {
+			http.Error(w, "Failed to read schema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.templateService.SetTemplateSchema(id, source); err != nil {
+			writeServiceError(w, "Failed to set schema: ", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := h.templateService.DeleteTemplateSchema(id); err != nil {
+			writeServiceError(w, "Failed to delete schema: ", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ListPartialsHandler lists the registered partials and layouts as JSON.
+func (h *TemplateHandler) ListPartialsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.templateService.ListPartials())
+}
+
+// RegisterPartialHandler stores the partial or layout sent as the request
+// body as the latest version of the name in the path. It answers 201 for a
+// new name and 200 for a new version of an existing partial.
+func (h *TemplateHandler) RegisterPartialHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTemplateSize))
+	if err != nil {
+		http.Error(w, "Failed to read partial: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info, created, err := h.templateService.RegisterPartial(r.PathValue("name"), string(source))
+	if err != nil {
+		writeServiceError(w, "Failed to register partial: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(info)
+}
+
+// TemplateFieldsHandler lists the data fields and functions used by the
+// template uploaded as template_file, as JSON.
+func (h *TemplateHandler) TemplateFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, ok := readTemplateUpload(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := h.templateService.TemplateFields(source)
+	if err != nil {
+		writeServiceError(w, "Failed to inspect template: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields)
+}
+
+// RegisteredTemplateFieldsHandler lists the data fields and functions used
+// by the template named in the path, as JSON. The version query parameter
+// selects a version as for rendering.
+func (h *TemplateHandler) RegisteredTemplateFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, ok := parseVersion(r.URL.Query().Get("version"))
+	if !ok {
+		http.Error(w, "Invalid version: "+r.URL.Query().Get("version"), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := h.templateService.RegisteredTemplateFields(r.PathValue("id"), version)
+	if err != nil {
+		writeServiceError(w, "Failed to inspect template: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields)
+}
+
+// readTemplateUpload reads the template_file of a multipart request,
+// answering the request itself when it cannot.
+func readTemplateUpload(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if err := r.ParseMultipartForm(maxTemplateSize); err != nil {
+		http.Error(w, "Failed to parse multipart form: "+err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+
+	file, _, err := r.FormFile("template_file")
+	if err != nil {
+		http.Error(w, "Failed to get template file: "+err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	defer file.Close()
+
+	source, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read template file: "+err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return string(source), true
+}
+
+// parseVersion reads the version query parameter: a version number, or
+// "latest" or nothing for the latest version.
+func parseVersion(s string) (int, bool) {
+	if s == "" || s == "latest" {
+		return services.LatestVersion, true
+	}
+	version, err := strconv.Atoi(s)
+	return version, err == nil && version >= 1
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"pdf-service/internal/models"
+	"pdf-service/internal/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTemplateService struct {
+	mock.Mock
+}
+
+func (m *MockTemplateService) ListTemplates() []models.TemplateInfo {
+	args := m.Called()
+	return args.Get(0).([]models.TemplateInfo)
+}
+
+func (m *MockTemplateService) RegisterTemplate(id, source string) (*models.TemplateVersion, bool, error) {
+	args := m.Called(id, source)
+	return args.Get(0).(*models.TemplateVersion), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTemplateService) ListVersions(id string) ([]models.TemplateVersion, error) {
+	args := m.Called(id)
+	return args.Get(0).([]models.TemplateVersion), args.Error(1)
+}
+
+func (m *MockTemplateService) RollbackTemplate(id string, version int) (*models.TemplateVersion, error) {
+	args := m.Called(id, version)
+	return args.Get(0).(*models.TemplateVersion), args.Error(1)
+}
+
+func (m *MockTemplateService) PinVersion(id string, version int, pinned bool) (*models.TemplateVersion, error) {
+	args := m.Called(id, version, pinned)
+	return args.Get(0).(*models.TemplateVersion), args.Error(1)
+}
+
+func (m *MockTemplateService) RenderTemplate(id string, version int, data map[string]interface{}) (*models.PDFResult, error) {
+	args := m.Called(id, version, data)
+	return args.Get(0).(*models.PDFResult), args.Error(1)
+}
+
+func (m *MockTemplateService) RenderTemplateHTML(id string, version int, data map[string]interface{}, assetBaseURL string) (*models.HTMLResult, error) {
+	args := m.Called(id, version, data, assetBaseURL)
+	return args.Get(0).(*models.HTMLResult), args.Error(1)
+}
+
+func (m *MockTemplateService) ListPartials() []models.PartialInfo {
+	args := m.Called()
+	return args.Get(0).([]models.PartialInfo)
+}
+
+func (m *MockTemplateService) RegisterPartial(name, source string) (*models.PartialInfo, bool, error) {
+	args := m.Called(name, source)
+	return args.Get(0).(*models.PartialInfo), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTemplateService) TemplateFields(source string) (*models.TemplateFields, error) {
+	args := m.Called(source)
+	return args.Get(0).(*models.TemplateFields), args.Error(1)
+}
+
+func (m *MockTemplateService) RegisteredTemplateFields(id string, version int) (*models.TemplateFields, error) {
+	args := m.Called(id, version)
+	return args.Get(0).(*models.TemplateFields), args.Error(1)
+}
+
+func (m *MockTemplateService) TemplateSchema(id string) (json.RawMessage, error) {
+	args := m.Called(id)
+	return args.Get(0).(json.RawMessage), args.Error(1)
+}
+
+func (m *MockTemplateService) SetTemplateSchema(id string, source []byte) error {
+	return m.Called(id, source).Error(0)
+}
+
+func (m *MockTemplateService) DeleteTemplateSchema(id string) error {
+	return m.Called(id).Error(0)
+}
+
+// newTemplateUpload returns a multipart request to target carrying source
+// as template_file.
+func newTemplateUpload(target, source string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("template_file", "template.html")
+	part.Write([]byte(source))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// newTemplateMux routes requests to handler the way main does.
+func newTemplateMux(handler *TemplateHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/templates", handler.ListTemplatesHandler)
+	mux.HandleFunc("/templates/{id}", handler.RegisterTemplateHandler)
+	mux.HandleFunc("/templates/{id}/render", handler.RenderTemplateHandler)
+	mux.HandleFunc("/templates/{id}/render-html", handler.RenderTemplateHTMLHandler)
+	mux.HandleFunc("/templates/{id}/versions", handler.ListVersionsHandler)
+	mux.HandleFunc("/templates/{id}/rollback", handler.RollbackTemplateHandler)
+	mux.HandleFunc("/templates/{id}/versions/{version}/pin", handler.PinVersionHandler)
+	mux.HandleFunc("/templates/{id}/fields", handler.RegisteredTemplateFieldsHandler)
+	mux.HandleFunc("/templates/{id}/schema", handler.TemplateSchemaHandler)
+	mux.HandleFunc("/template-fields", handler.TemplateFieldsHandler)
+	mux.HandleFunc("/partials", handler.ListPartialsHandler)
+	mux.HandleFunc("/partials/{name}", handler.RegisterPartialHandler)
+	return mux
+}
+
+func TestListTemplatesHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	templateService.On("ListTemplates").Return([]models.TemplateInfo{{ID: "service_request", LatestVersion: 2, Size: 1200, UpdatedAt: updated}})
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{"id":"service_request","latest_version":2,"size":1200,"updated_at":"2024-05-01T10:00:00Z"}]`, rr.Body.String())
+}
+
+func TestRegisterTemplateHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RegisterTemplate", "invoice", "<p>{{.total}}</p>").Return(&models.TemplateVersion{ID: "invoice", Version: 1, Size: 16}, true, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/templates/invoice", strings.NewReader("<p>{{.total}}</p>"))
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var info models.TemplateVersion
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(t, "invoice", info.ID)
+	assert.Equal(t, 1, info.Version)
+	templateService.AssertExpectations(t)
+}
+
+func TestRegisterTemplateHandler_Unchanged(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RegisterTemplate", "invoice", "<p></p>").Return(&models.TemplateVersion{ID: "invoice", Version: 3}, false, nil)
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/invoice", strings.NewReader("<p></p>")))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"version":3`)
+}
+
+func TestRegisterTemplateHandler_Invalid(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RegisterTemplate", "bad", "{{").Return((*models.TemplateVersion)(nil), false, &services.TemplateError{TemplateError: models.TemplateError{
+		Kind: services.TemplateErrorParse, Template: "bad", Line: 1, Source: "{{", Message: "unclosed action",
+	}})
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/bad", strings.NewReader("{{")))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error": "Invalid template", "template_error": {"kind": "parse", "template": "bad", "line": 1, "source": "{{", "message": "unclosed action"}}`, rr.Body.String())
+}
+
+func TestRenderTemplateHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	data := map[string]interface{}{"customer_name": "Sara"}
+	templateService.On("RenderTemplate", "service_request", services.LatestVersion, data).
+		Return(&models.PDFResult{
+			Content:  []byte("%PDF-1.4 mock"),
+			Pages:    []models.PageInfo{{Number: 1, Width: 595.92, Height: 842.88, Paper: "A4"}},
+			Template: &models.TemplateVersion{ID: "service_request", Version: 4},
+		}, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/templates/service_request/render", strings.NewReader(`{"customer_name":"Sara"}`))
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=service_request.pdf", rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "1", rr.Header().Get("X-Page-Count"))
+	assert.Equal(t, "service_request", rr.Header().Get("X-Template-ID"))
+	assert.Equal(t, "4", rr.Header().Get("X-Template-Version"))
+	assert.Equal(t, "%PDF-1.4 mock", rr.Body.String())
+	templateService.AssertExpectations(t)
+}
+
+func TestRenderTemplateHTMLHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	data := map[string]interface{}{"customer_name": "Sara"}
+	templateService.On("RenderTemplateHTML", "service_request", 3, data, "https://cdn.example.com/").
+		Return(&models.HTMLResult{
+			HTML:     `<img src="https://cdn.example.com/logo.png"><p>Sara</p>`,
+			Template: &models.TemplateVersion{ID: "service_request", Version: 3},
+		}, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/templates/service_request/render-html?version=3&base_url=https://cdn.example.com/", strings.NewReader(`{"customer_name":"Sara"}`))
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "sandbox", rr.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "3", rr.Header().Get("X-Template-Version"))
+	assert.Equal(t, `<img src="https://cdn.example.com/logo.png"><p>Sara</p>`, rr.Body.String())
+	templateService.AssertExpectations(t)
+}
+
+func TestRenderTemplateHTMLHandler_Errors(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RenderTemplateHTML", "missing", services.LatestVersion, mock.Anything, "").
+		Return((*models.HTMLResult)(nil), services.ErrTemplateNotFound)
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/missing/render-html", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/missing/render-html", strings.NewReader(`[`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/missing/render-html", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	templateService.AssertExpectations(t)
+}
+
+func TestRenderTemplateHandler_PinnedVersion(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RenderTemplate", "invoice", 2, mock.Anything).
+		Return(&models.PDFResult{Content: []byte("%PDF-1.4 mock"), Template: &models.TemplateVersion{ID: "invoice", Version: 2}}, nil)
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/invoice/render?version=2", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-Template-Version"))
+	templateService.AssertExpectations(t)
+
+	for _, version := range []string{"0", "-1", "v2"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/invoice/render?version="+version, strings.NewReader(`{}`)))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, version)
+		assert.Equal(t, "Invalid version: "+version+"\n", rr.Body.String())
+	}
+}
+
+func TestListVersionsHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	templateService.On("ListVersions", "invoice").Return([]models.TemplateVersion{
+		{ID: "invoice", Version: 1, Size: 9, SHA256: "ab", CreatedAt: created},
+		{ID: "invoice", Version: 2, Size: 9, SHA256: "ab", CreatedAt: created, RestoredFrom: 1},
+	}, nil)
+	templateService.On("ListVersions", "missing").Return([]models.TemplateVersion(nil), fmt.Errorf("%w: missing", services.ErrTemplateNotFound))
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/invoice/versions", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[
+		{"id":"invoice","version":1,"size":9,"sha256":"ab","created_at":"2024-05-01T10:00:00Z"},
+		{"id":"invoice","version":2,"size":9,"sha256":"ab","created_at":"2024-05-01T10:00:00Z","restored_from":1}
+	]`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/missing/versions", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestRollbackTemplateHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RollbackTemplate", "invoice", 1).Return(&models.TemplateVersion{ID: "invoice", Version: 3, RestoredFrom: 1}, nil)
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/invoice/rollback", strings.NewReader(`{"version":1}`)))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var info models.TemplateVersion
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(t, 3, info.Version)
+	assert.Equal(t, 1, info.RestoredFrom)
+	templateService.AssertExpectations(t)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/invoice/rollback", strings.NewReader(`{"version":`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid JSON request")
+}
+
+func TestPinVersionHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("PinVersion", "invoice", 2, true).Return(&models.TemplateVersion{ID: "invoice", Version: 2, Pinned: true}, nil)
+	templateService.On("PinVersion", "invoice", 2, false).Return(&models.TemplateVersion{ID: "invoice", Version: 2}, nil)
+	templateService.On("PinVersion", "invoice", 9, true).Return((*models.TemplateVersion)(nil), fmt.Errorf("%w: invoice version 9", services.ErrTemplateNotFound))
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/invoice/versions/2/pin", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var info models.TemplateVersion
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.True(t, info.Pinned)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/templates/invoice/versions/2/pin", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "pinned")
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/invoice/versions/9/pin", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/invoice/versions/x/pin", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/invoice/versions/2/pin", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	templateService.AssertExpectations(t)
+}
+
+func TestRenderTemplateHandler_NotFound(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RenderTemplate", "missing", services.LatestVersion, mock.Anything).
+		Return((*models.PDFResult)(nil), fmt.Errorf("%w: missing", services.ErrTemplateNotFound))
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/missing/render", strings.NewReader(`{}`)))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "template not found: missing\n", rr.Body.String())
+}
+
+func TestRenderTemplateHandler_InvalidJSON(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(&MockTemplateService{})).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/a/render", strings.NewReader(`{"a":`)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid JSON data")
+}
+
+func TestListPartialsHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	templateService.On("ListPartials").Return([]models.PartialInfo{{Name: "brand_header", Version: 2, Size: 420, UpdatedAt: updated}})
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/partials", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"name":"brand_header","version":2,"size":420,"updated_at":"2024-05-01T10:00:00Z"}]`, rr.Body.String())
+}
+
+func TestRegisterPartialHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RegisterPartial", "brand_header", "<h1>Bank</h1>").Return(&models.PartialInfo{Name: "brand_header", Size: 13}, true, nil).Once()
+	templateService.On("RegisterPartial", "brand_header", "<h1>New Bank</h1>").Return(&models.PartialInfo{Name: "brand_header", Size: 17}, false, nil).Once()
+	templateService.On("RegisterPartial", "bad", "{{").Return((*models.PartialInfo)(nil), false, &services.AppError{Message: "Invalid partial: unclosed action"})
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/partials/brand_header", strings.NewReader("<h1>Bank</h1>")))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"brand_header"`)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/partials/brand_header", strings.NewReader("<h1>New Bank</h1>")))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/partials/bad", strings.NewReader("{{")))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid partial: unclosed action\n", rr.Body.String())
+	templateService.AssertExpectations(t)
+}
+
+func TestTemplateFieldsHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("TemplateFields", "{{range .items}}{{.name | title}}{{end}}").Return(&models.TemplateFields{
+		Fields:    []models.TemplateField{{Path: "items", Type: "list"}, {Path: "items[].name", Type: "value"}},
+		Functions: []string{"title"},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, newTemplateUpload("/template-fields", "{{range .items}}{{.name | title}}{{end}}"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"fields":[{"path":"items","type":"list"},{"path":"items[].name","type":"value"}],"functions":["title"]}`, rr.Body.String())
+	templateService.AssertExpectations(t)
+}
+
+func TestTemplateFieldsHandler_Errors(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("TemplateFields", "{{").Return((*models.TemplateFields)(nil), &services.AppError{Message: "Invalid template: unclosed action"})
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, newTemplateUpload("/template-fields", "{{"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "Invalid template: unclosed action\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, newFormRequest(t, "/template-fields", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Failed to get template file")
+}
+
+func TestRegisteredTemplateFieldsHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RegisteredTemplateFields", "invoice", 2).Return(&models.TemplateFields{
+		Fields:    []models.TemplateField{{Path: "total", Type: "value"}},
+		Functions: []string{},
+	}, nil)
+	templateService.On("RegisteredTemplateFields", "missing", services.LatestVersion).
+		Return((*models.TemplateFields)(nil), fmt.Errorf("%w: missing", services.ErrTemplateNotFound))
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/invoice/fields?version=2", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"fields":[{"path":"total","type":"value"}],"functions":[]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/missing/fields", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/invoice/fields?version=x", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	templateService.AssertExpectations(t)
+}
+
+func TestTemplateSchemaHandler(t *testing.T) {
+	templateService := &MockTemplateService{}
+	schema := `{"required":["total"]}`
+	templateService.On("SetTemplateSchema", "invoice", []byte(schema)).Return(nil)
+	templateService.On("SetTemplateSchema", "invoice", []byte(`{"type":"text"}`)).Return(&services.AppError{Message: `invalid JSON schema: schema root: unknown type "text"`})
+	templateService.On("TemplateSchema", "invoice").Return(json.RawMessage(schema), nil)
+	templateService.On("TemplateSchema", "receipt").Return(json.RawMessage(nil), fmt.Errorf("%w: receipt", services.ErrSchemaNotFound))
+	templateService.On("DeleteTemplateSchema", "invoice").Return(nil)
+	mux := newTemplateMux(NewTemplateHandler(templateService))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/invoice/schema", strings.NewReader(schema)))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/templates/invoice/schema", strings.NewReader(`{"type":"text"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid JSON schema")
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/invoice/schema", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/schema+json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, schema, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/templates/receipt/schema", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/templates/invoice/schema", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	templateService.AssertExpectations(t)
+}
+
+func TestRenderTemplateHandler_SchemaViolations(t *testing.T) {
+	templateService := &MockTemplateService{}
+	templateService.On("RenderTemplate", "invoice", services.LatestVersion, mock.Anything).
+		Return((*models.PDFResult)(nil), &services.ValidationError{Violations: []models.SchemaViolation{
+			{Pointer: "/items/0/price", Message: "expected number, got string"},
+			{Pointer: "/total", Message: "is required"},
+		}})
+
+	rr := httptest.NewRecorder()
+	newTemplateMux(NewTemplateHandler(templateService)).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/templates/invoice/render", strings.NewReader(`{"items":[{"price":"x"}]}`)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"error": "Data does not match the template schema",
+		"violations": [
+			{"pointer": "/items/0/price", "message": "expected number, got string"},
+			{"pointer": "/total", "message": "is required"}
+		]
+	}`, rr.Body.String())
+}
+
+func TestTemplateHandlers_InvalidMethod(t *testing.T) {
+	mux := newTemplateMux(NewTemplateHandler(&MockTemplateService{}))
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/templates", nil),
+		httptest.NewRequest(http.MethodGet, "/templates/a", nil),
+		httptest.NewRequest(http.MethodGet, "/templates/a/render", nil),
+		httptest.NewRequest(http.MethodPost, "/templates/a/versions", nil),
+		httptest.NewRequest(http.MethodGet, "/templates/a/rollback", nil),
+		httptest.NewRequest(http.MethodPost, "/templates/a/fields", nil),
+		httptest.NewRequest(http.MethodGet, "/template-fields", nil),
+		httptest.NewRequest(http.MethodPost, "/templates/a/schema", nil),
+		httptest.NewRequest(http.MethodPost, "/partials", nil),
+		httptest.NewRequest(http.MethodGet, "/partials/a", nil),
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code, req.URL.Path)
+	}
+}
@@ -10,8 +10,27 @@ import (
 
 type PDFGenerator interface {
 	GeneratePDF(htmlContent string) ([]byte, error)
+	GeneratePDFWithOptions(htmlContent string, opts PrintOptions) ([]byte, error)
 }
 
+// PrintOptions adjust the paper Chromium prints on.
+type PrintOptions struct {
+	// Bleed enlarges the A4 paper by this many inches on every side, and
+	// the margins with it, so that the layout keeps its size while the page
+	// background also covers the added area.
+	Bleed float64
+	// Outline builds the document outline from the headings. Chromium
+	// derives it from the structure tree, so the PDF is tagged as well.
+	Outline bool
+}
+
+const (
+	paperWidth  = 8.27
+	paperHeight = 11.69
+	// defaultMargin is Chromium's own margin of 1 cm, in inches.
+	defaultMargin = 1 / 2.54
+)
+
 type ChromedpClient struct {
 	chromePath string
 }
@@ -32,16 +51,22 @@ func NewChromedpClient() *ChromedpClient {
 	return NewChromedpClientWithStat(os.Stat)
 }
 
-func (c *ChromedpClient) GeneratePDF(htmlContent string) ([]byte, error) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+func (c *ChromedpClient) allocatorOptions() []chromedp.ExecAllocatorOption {
+	return append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.ExecPath(c.chromePath),
 		chromedp.Flag("headless", true),
 		chromedp.Flag("no-sandbox", true),
 		chromedp.Flag("disable-gpu", true),
 		chromedp.Flag("disable-dev-shm-usage", true),
 	)
+}
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+func (c *ChromedpClient) GeneratePDF(htmlContent string) ([]byte, error) {
+	return c.GeneratePDFWithOptions(htmlContent, PrintOptions{})
+}
+
+func (c *ChromedpClient) GeneratePDFWithOptions(htmlContent string, opts PrintOptions) ([]byte, error) {
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), c.allocatorOptions()...)
 	defer cancel()
 
 	ctx, cancel := chromedp.NewContext(allocCtx)
@@ -61,11 +86,19 @@ func (c *ChromedpClient) GeneratePDF(htmlContent string) ([]byte, error) {
 		chromedp.WaitVisible("body", chromedp.ByQuery),
 		chromedp.ActionFunc(func(ctx context.Context) error {
 			var err error
-			pdfBuffer, _, err = page.PrintToPDF().
+			params := page.PrintToPDF().
 				WithPrintBackground(true).
-				WithPaperWidth(8.27).
-				WithPaperHeight(11.69).
-				Do(ctx)
+				WithPaperWidth(paperWidth + 2*opts.Bleed).
+				WithPaperHeight(paperHeight + 2*opts.Bleed)
+			if opts.Bleed > 0 {
+				margin := defaultMargin + opts.Bleed
+				params = params.WithMarginTop(margin).WithMarginBottom(margin).
+					WithMarginLeft(margin).WithMarginRight(margin)
+			}
+			if opts.Outline {
+				params = params.WithGenerateTaggedPDF(true).WithGenerateDocumentOutline(true)
+			}
+			pdfBuffer, _, err = params.Do(ctx)
 			return err
 		}),
 	)
@@ -74,4 +107,4 @@ func (c *ChromedpClient) GeneratePDF(htmlContent string) ([]byte, error) {
 	}
 
 	return pdfBuffer, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,210 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// RasterPage selects a page to rasterize, numbered from 1, with its
+// displayed size in points.
+type RasterPage struct {
+	Number        int
+	Width, Height float64
+}
+
+type PageRasterizer interface {
+	RasterizePDF(content []byte, pages []RasterPage, width int) ([][]byte, error)
+}
+
+const (
+	// rasterScale renders pages at twice the requested size; scaling them
+	// down smooths text and lines.
+	rasterScale = 2
+	// framePoll is the interval between the screenshots compared to tell
+	// when the viewer has finished drawing, and renderTimeout how long it
+	// may take.
+	framePoll     = 100 * time.Millisecond
+	renderTimeout = 15 * time.Second
+	// MaxRasterHeight bounds the height of a page image. The width is
+	// chosen by the caller, but the height follows the page's proportions,
+	// which a PDF does not bound.
+	MaxRasterHeight = 8000
+)
+
+// viewerInsets is the room Chromium's PDF viewer leaves around a page for
+// its shadow, in CSS pixels. Fitting a page to the window fits the page
+// with these insets.
+var viewerInsets = struct{ left, top, right, bottom int }{5, 3, 5, 7}
+
+// RasterHeight returns the height in pixels of the image of p width pixels
+// wide.
+func RasterHeight(p RasterPage, width int) int {
+	return max(1, int(math.Round(float64(width)*p.Height/p.Width)))
+}
+
+// RasterizePDF renders the given pages of a PDF as PNG images width pixels
+// wide. The PDF is opened in Chromium's built-in viewer, served from a
+// loopback address for the duration of the call. Pages whose image would
+// be taller than MaxRasterHeight are refused.
+func (c *ChromedpClient) RasterizePDF(content []byte, pages []RasterPage, width int) ([][]byte, error) {
+	for _, p := range pages {
+		if p.Width <= 0 || p.Height <= 0 {
+			return nil, fmt.Errorf("page %d has no size", p.Number)
+		}
+		if h := RasterHeight(p, width); h > MaxRasterHeight {
+			return nil, fmt.Errorf("page %d would be %d pixels high, more than %d", p.Number, h, MaxRasterHeight)
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(content)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), c.allocatorOptions()...)
+	defer cancel()
+
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	defer cancel()
+
+	images := make([][]byte, 0, len(pages))
+	for _, p := range pages {
+		height := RasterHeight(p, width)
+		viewport, bounds := pageLayout(width, height)
+		// A distinct query per page makes the viewer load afresh rather
+		// than only scrolling.
+		url := fmt.Sprintf("http://%s/document.pdf?page=%d#page=%d&toolbar=0&navpanes=0&view=Fit",
+			listener.Addr(), p.Number, p.Number)
+
+		err := chromedp.Run(ctx,
+			chromedp.EmulateViewport(int64(viewport.X), int64(viewport.Y), chromedp.EmulateScale(rasterScale)),
+			chromedp.Navigate(url),
+		)
+		if err != nil {
+			return nil, err
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, renderTimeout)
+		img, err := stableFrame(waitCtx, func() ([]byte, error) {
+			var shot []byte
+			err := chromedp.Run(waitCtx, chromedp.CaptureScreenshot(&shot))
+			return shot, err
+		})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", p.Number, err)
+		}
+
+		out := scaleImage(cropImage(img, bounds), width, height)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, out); err != nil {
+			return nil, err
+		}
+		images = append(images, buf.Bytes())
+	}
+	return images, nil
+}
+
+// pageLayout returns the viewport, in CSS pixels, in which the viewer fits
+// a page of width×height pixels, the size of its crop box, exactly, and
+// the bounds of the page in a screenshot of it.
+func pageLayout(width, height int) (viewport image.Point, bounds image.Rectangle) {
+	in := viewerInsets
+	viewport = image.Pt(in.left+width+in.right, in.top+height+in.bottom)
+	bounds = image.Rect(in.left, in.top, in.left+width, in.top+height)
+	return viewport, image.Rectangle{Min: bounds.Min.Mul(rasterScale), Max: bounds.Max.Mul(rasterScale)}
+}
+
+// stableFrame takes screenshots with capture until two in a row are the
+// same and show more than a blank window: the viewer draws a page
+// asynchronously after it has loaded, and in several passes.
+func stableFrame(ctx context.Context, capture func() ([]byte, error)) (image.Image, error) {
+	var prev []byte
+	for {
+		shot, err := capture()
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil && bytes.Equal(shot, prev) {
+			img, err := png.Decode(bytes.NewReader(shot))
+			if err != nil {
+				return nil, err
+			}
+			if !uniform(img) {
+				return img, nil
+			}
+		}
+		prev = shot
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("viewer did not finish drawing: %w", ctx.Err())
+		case <-time.After(framePoll):
+		}
+	}
+}
+
+// uniform reports whether every pixel of img has the same color.
+func uniform(img image.Image) bool {
+	b := img.Bounds()
+	first := img.At(b.Min.X, b.Min.Y)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.At(x, y) != first {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cropImage returns the part of img within r.
+func cropImage(img image.Image, r image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r.Intersect(img.Bounds()))
+	}
+	return img
+}
+
+// scaleImage resizes img to w×h pixels, averaging the source pixels that
+// each target pixel covers.
+func scaleImage(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	sx, sy := float64(b.Dx())/float64(w), float64(b.Dy())/float64(h)
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + int(float64(y)*sy)
+		y1 := max(y0+1, b.Min.Y+int(math.Ceil(float64(y+1)*sy)))
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + int(float64(x)*sx)
+			x1 := max(x0+1, b.Min.X+int(math.Ceil(float64(x+1)*sx)))
+			var r, g, bl, a, n uint32
+			for yy := y0; yy < min(y1, b.Max.Y); yy++ {
+				for xx := x0; xx < min(x1, b.Max.X); xx++ {
+					cr, cg, cb, ca := img.At(xx, yy).RGBA()
+					r, g, bl, a, n = r+cr, g+cg, bl+cb, a+ca, n+1
+				}
+			}
+			if n > 0 {
+				out.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), uint8(a / n >> 8)})
+			}
+		}
+	}
+	return out
+}
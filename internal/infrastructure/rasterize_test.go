@@ -0,0 +1,116 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageLayout(t *testing.T) {
+	viewport, bounds := pageLayout(200, 283)
+
+	// The viewer fits the page with its insets to the viewport, which
+	// leaves the page at the requested size, doubled by the device scale.
+	assert.Equal(t, image.Pt(210, 293), viewport)
+	assert.Equal(t, image.Rect(10, 6, 410, 572), bounds)
+}
+
+// frame returns a PNG of a gray window, with a white page drawn over rows
+// [0, drawn) of r.
+func frame(t *testing.T, r image.Rectangle, drawn int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{82, 86, 89, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+drawn), image.White, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestStableFrame(t *testing.T) {
+	page := image.Rect(5, 3, 35, 25)
+	// The window stays blank for a while, then the page is drawn in two
+	// passes.
+	frames := [][]byte{frame(t, page, 0), frame(t, page, 0), frame(t, page, 0), frame(t, page, 10), frame(t, page, 22), frame(t, page, 22)}
+	captures := 0
+	img, err := stableFrame(context.Background(), func() ([]byte, error) {
+		shot := frames[min(captures, len(frames)-1)]
+		captures++
+		return shot, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(frames), captures)
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, color.RGBAModel.Convert(img.At(5, 24)))
+}
+
+func TestStableFrame_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*framePoll)
+	defer cancel()
+	blank := frame(t, image.Rectangle{}, 0)
+
+	img, err := stableFrame(ctx, func() ([]byte, error) { return blank, nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, img)
+}
+
+func TestStableFrame_CaptureError(t *testing.T) {
+	captureErr := errors.New("target closed")
+	_, err := stableFrame(context.Background(), func() ([]byte, error) { return nil, captureErr })
+	assert.ErrorIs(t, err, captureErr)
+}
+
+func TestCropImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	assert.Equal(t, image.Rect(5, 3, 35, 25), cropImage(img, image.Rect(5, 3, 35, 25)).Bounds())
+	assert.Equal(t, image.Rect(5, 3, 40, 30), cropImage(img, image.Rect(5, 3, 45, 35)).Bounds())
+}
+
+func TestScaleImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.SetRGBA(x, 0, color.RGBA{255, 255, 255, 255})
+		img.SetRGBA(x, 1, color.RGBA{0, 0, 0, 255})
+	}
+
+	out := scaleImage(img, 2, 1)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), out.Bounds())
+	assert.Equal(t, color.RGBA{127, 127, 127, 255}, out.RGBAAt(0, 0))
+
+	out = scaleImage(img, 8, 4)
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, out.RGBAAt(7, 1))
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, out.RGBAAt(0, 2))
+}
+
+func TestRasterizePDF_TallPage(t *testing.T) {
+	// The page is refused before Chromium is started.
+	images, err := NewChromedpClient().RasterizePDF(nil, []RasterPage{{Number: 1, Width: 1, Height: 14400}}, 200)
+	assert.ErrorContains(t, err, "pixels high")
+	assert.Nil(t, images)
+}
+
+func TestRasterizePDF_Integration(t *testing.T) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration test; set RUN_INTEGRATION_TESTS=true to run")
+	}
+
+	client := NewChromedpClient()
+	pdf, err := client.GeneratePDF(`<html><body><h1>Hello, World!</h1></body></html>`)
+	require.NoError(t, err)
+
+	images, err := client.RasterizePDF(pdf, []RasterPage{{Number: 1, Width: 595.44, Height: 841.68}}, 200)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	img, err := png.Decode(bytes.NewReader(images[0]))
+	require.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+	assert.Equal(t, 283, img.Bounds().Dy())
+}
@@ -0,0 +1,275 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+)
+
+var (
+	ErrSignerNotConfigured   = errors.New("signing certificate and key are not configured")
+	errUnsupportedSigningKey = errors.New("signing key must be an RSA or ECDSA key")
+)
+
+var (
+	oidData                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidSHA256WithRSA        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA256      = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	signerDigestAlgorithm   = pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+)
+
+// Signer produces the CMS signature embedded in a signed PDF.
+type Signer interface {
+	Sign(content []byte) ([]byte, error)
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        []attribute `asn1:"tag:0,set"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      []attribute `asn1:"optional,tag:1,set"`
+}
+
+type essCertIDv2 struct {
+	CertHash []byte
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// PDFSigner signs PDF files with a CAdES detached signature, as PAdES
+// requires. With a TSA client the signature is timestamped, making it a
+// PAdES-T signature.
+type PDFSigner struct {
+	key   crypto.Signer
+	chain []*x509.Certificate
+	tsa   *TSAClient
+}
+
+// NewPDFSignerWithKey returns a signer using key, whose certificate is the
+// first of chain. tsa may be nil for signatures without a timestamp.
+func NewPDFSignerWithKey(key crypto.Signer, chain []*x509.Certificate, tsa *TSAClient) *PDFSigner {
+	return &PDFSigner{key: key, chain: chain, tsa: tsa}
+}
+
+// NewPDFSigner reads the PEM encoded certificate chain, signing certificate
+// first, from SIGNING_CERT_FILE and its private key from SIGNING_KEY_FILE.
+// Signatures are timestamped by the TSA at TSA_URL when it is set.
+func NewPDFSigner() (*PDFSigner, error) {
+	certFile, keyFile := os.Getenv("SIGNING_CERT_FILE"), os.Getenv("SIGNING_KEY_FILE")
+	if certFile == "" || keyFile == "" {
+		return nil, ErrSignerNotConfigured
+	}
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", certFile, err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%s: no certificate found", certFile)
+	}
+
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM encoded key found", keyFile)
+	}
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyFile, err)
+	}
+
+	var tsa *TSAClient
+	if os.Getenv("TSA_URL") != "" {
+		tsa = NewTSAClient()
+	}
+	return NewPDFSignerWithKey(key, chain, tsa), nil
+}
+
+// parsePrivateKey reads a PKCS #8, PKCS #1 or SEC 1 private key.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, errUnsupportedSigningKey
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unrecognised private key format")
+}
+
+// Sign returns a DER encoded CMS SignedData over content, which is not
+// included. The signed attributes hold the content type, the message
+// digest and the signing certificate, as CAdES requires. With a TSA, the
+// timestamp token of the signature value is added as an unsigned
+// attribute; an unreachable TSA fails the signature rather than producing
+// one without a timestamp.
+func (s *PDFSigner) Sign(content []byte) ([]byte, error) {
+	sigAlg, err := signatureAlgorithm(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	cert := s.chain[0]
+	digest := sha256.Sum256(content)
+	certHash := sha256.Sum256(cert.Raw)
+	attrs, err := signedAttributes(
+		oidContentType, oidData,
+		oidMessageDigest, digest[:],
+		oidSigningCertificateV2, signingCertificateV2{Certs: []essCertIDv2{{CertHash: certHash[:]}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the DER SET OF encoding of the signed
+	// attributes, which are stored with an implicit [0] tag instead.
+	attrSet, err := marshalSet(struct {
+		Attrs []attribute `asn1:"set"`
+	}{attrs})
+	if err != nil {
+		return nil, err
+	}
+	attrsHash := sha256.Sum256(attrSet)
+	signature, err := s.key.Sign(rand.Reader, attrsHash[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	info := signerInfo{
+		Version:            1,
+		SID:                issuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+		DigestAlgorithm:    signerDigestAlgorithm,
+		SignedAttrs:        attrs,
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	}
+	if s.tsa != nil {
+		attrDER, err := s.tsa.TimestampSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+		var attr attribute
+		if _, err := asn1.Unmarshal(attrDER, &attr); err != nil {
+			return nil, err
+		}
+		info.UnsignedAttrs = []attribute{attr}
+	}
+	signerInfos, err := marshalSet(struct {
+		Infos []signerInfo `asn1:"set"`
+	}{[]signerInfo{info}})
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []byte
+	for _, c := range s.chain {
+		certs = append(certs, c.Raw...)
+	}
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{signerDigestAlgorithm},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos:      asn1.RawValue{FullBytes: signerInfos},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+// marshalSet returns the encoding of the only field of v, a SET OF.
+func marshalSet(v any) ([]byte, error) {
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var seq asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &seq); err != nil {
+		return nil, err
+	}
+	return seq.Bytes, nil
+}
+
+// signedAttributes builds an attribute of each type and value pair, in the
+// order DER requires of a SET OF.
+func signedAttributes(pairs ...any) ([]attribute, error) {
+	type encoded struct {
+		attr attribute
+		der  []byte
+	}
+	var attrs []encoded
+	for i := 0; i < len(pairs); i += 2 {
+		value, err := asn1.Marshal(pairs[i+1])
+		if err != nil {
+			return nil, err
+		}
+		attr := attribute{Type: pairs[i].(asn1.ObjectIdentifier), Values: []asn1.RawValue{{FullBytes: value}}}
+		der, err := asn1.Marshal(attr)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, encoded{attr, der})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i].der, attrs[j].der) < 0 })
+	sorted := make([]attribute, len(attrs))
+	for i, a := range attrs {
+		sorted[i] = a.attr
+	}
+	return sorted, nil
+}
+
+func signatureAlgorithm(pub crypto.PublicKey) (pkix.AlgorithmIdentifier, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA, Parameters: asn1.NullRawValue}, nil
+	case *ecdsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}, nil
+	}
+	return pkix.AlgorithmIdentifier{}, errUnsupportedSigningKey
+}
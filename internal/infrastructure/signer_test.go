@@ -0,0 +1,219 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"pdf-service/internal/pdf"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parsedSignerInfo keeps the signed attributes as they were encoded, to
+// check the signature over them.
+type parsedSignerInfo struct {
+	Version            int
+	SID                issuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      []attribute `asn1:"optional,tag:1,set"`
+}
+
+type parsedSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue      `asn1:"optional,tag:0"`
+	SignerInfos      []parsedSignerInfo `asn1:"set"`
+}
+
+func newSigningCert(t *testing.T, key crypto.Signer) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Document Signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func newTestSigner(t *testing.T, tsa *TSAClient) (*PDFSigner, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := newSigningCert(t, key)
+	return NewPDFSignerWithKey(key, []*x509.Certificate{cert}, tsa), cert
+}
+
+// parseSignature checks the CMS signature der over content by cert and
+// returns its signer info.
+func parseSignature(t *testing.T, der, content []byte, cert *x509.Certificate, alg x509.SignatureAlgorithm) parsedSignerInfo {
+	t.Helper()
+	var ci contentInfo
+	_, err := asn1.Unmarshal(der, &ci)
+	require.NoError(t, err)
+	require.True(t, oidSignedData.Equal(ci.ContentType))
+	var sd parsedSignedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	require.NoError(t, err)
+	assert.True(t, oidData.Equal(sd.EncapContentInfo.EContentType))
+	assert.Empty(t, sd.EncapContentInfo.EContent, "the signature is detached")
+	assert.Equal(t, cert.Raw, sd.Certificates.Bytes)
+	require.Len(t, sd.SignerInfos, 1)
+	si := sd.SignerInfos[0]
+	assert.Equal(t, cert.SerialNumber, si.SID.SerialNumber)
+
+	var attrs []attribute
+	_, err = asn1.UnmarshalWithParams(si.SignedAttrs.FullBytes, &attrs, "tag:0,set")
+	require.NoError(t, err)
+	values := map[string][]byte{}
+	for _, a := range attrs {
+		values[a.Type.String()] = a.Values[0].FullBytes
+	}
+	var digest []byte
+	_, err = asn1.Unmarshal(values[oidMessageDigest.String()], &digest)
+	require.NoError(t, err)
+	want := sha256.Sum256(content)
+	assert.Equal(t, want[:], digest)
+	var signingCert signingCertificateV2
+	_, err = asn1.Unmarshal(values[oidSigningCertificateV2.String()], &signingCert)
+	require.NoError(t, err)
+	certHash := sha256.Sum256(cert.Raw)
+	assert.Equal(t, certHash[:], signingCert.Certs[0].CertHash)
+
+	// The signature is over the attributes encoded as a SET OF.
+	signed := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	require.NoError(t, cert.CheckSignature(alg, signed, si.Signature))
+	return si
+}
+
+func TestPDFSigner_Sign(t *testing.T) {
+	signer, cert := newTestSigner(t, nil)
+
+	der, err := signer.Sign([]byte("signed bytes"))
+	require.NoError(t, err)
+
+	si := parseSignature(t, der, []byte("signed bytes"), cert, x509.ECDSAWithSHA256)
+	assert.Empty(t, si.UnsignedAttrs)
+}
+
+func TestPDFSigner_SignRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	cert := newSigningCert(t, key)
+	signer := NewPDFSignerWithKey(key, []*x509.Certificate{cert}, nil)
+
+	der, err := signer.Sign([]byte("signed bytes"))
+	require.NoError(t, err)
+	parseSignature(t, der, []byte("signed bytes"), cert, x509.SHA256WithRSA)
+}
+
+func TestPDFSigner_Timestamp(t *testing.T) {
+	tsa := newFakeTSA(t)
+	signer, cert := newTestSigner(t, NewTSAClientWithURL(tsa.URL, tsa.Client()))
+
+	der, err := signer.Sign([]byte("signed bytes"))
+	require.NoError(t, err)
+
+	si := parseSignature(t, der, []byte("signed bytes"), cert, x509.ECDSAWithSHA256)
+	require.Len(t, si.UnsignedAttrs, 1)
+	assert.True(t, oidSignatureTimeStampTok.Equal(si.UnsignedAttrs[0].Type))
+	info, err := parseTimeStampToken(si.UnsignedAttrs[0].Values[0].FullBytes)
+	require.NoError(t, err)
+	digest := sha256.Sum256(si.Signature)
+	assert.Equal(t, digest[:], info.MessageImprint.HashedMessage)
+	assert.Equal(t, tsa.now, info.GenTime)
+}
+
+func TestPDFSigner_TSAUnreachable(t *testing.T) {
+	tsa := newFakeTSA(t)
+	url := tsa.URL
+	tsa.Close()
+	signer, _ := newTestSigner(t, NewTSAClientWithURL(url, nil))
+
+	der, err := signer.Sign([]byte("signed bytes"))
+	assert.ErrorIs(t, err, ErrTSAUnreachable)
+	assert.Nil(t, der)
+}
+
+// TestPDFSigner_PAdEST signs a PDF and reads the timestamp token back out
+// of the signature dictionary of the file.
+func TestPDFSigner_PAdEST(t *testing.T) {
+	tsa := newFakeTSA(t)
+	signer, cert := newTestSigner(t, NewTSAClientWithURL(tsa.URL, tsa.Client()))
+	doc := pdf.New()
+	doc.AddPage(pdf.Rect{URX: 595, URY: 842}, pdf.Dict{}, []byte("q Q"))
+	data, err := doc.Bytes()
+	require.NoError(t, err)
+
+	out, err := pdf.Sign(data, pdf.SignOptions{Reason: "Approval", Sign: signer.Sign})
+	require.NoError(t, err)
+
+	signedDoc, err := pdf.Parse(out)
+	require.NoError(t, err)
+	sigs := signedDoc.Signatures()
+	require.Len(t, sigs, 1)
+	br := sigs[0].ByteRange
+	require.Len(t, br, 4)
+	content := append(append([]byte(nil), out[br[0]:br[0]+br[1]]...), out[br[2]:br[2]+br[3]]...)
+
+	si := parseSignature(t, sigs[0].Contents, content, cert, x509.ECDSAWithSHA256)
+	require.Len(t, si.UnsignedAttrs, 1)
+	assert.True(t, oidSignatureTimeStampTok.Equal(si.UnsignedAttrs[0].Type))
+	info, err := parseTimeStampToken(si.UnsignedAttrs[0].Values[0].FullBytes)
+	require.NoError(t, err)
+	digest := sha256.Sum256(si.Signature)
+	assert.Equal(t, digest[:], info.MessageImprint.HashedMessage)
+}
+
+func TestNewPDFSigner_NotConfigured(t *testing.T) {
+	t.Setenv("SIGNING_CERT_FILE", "")
+	t.Setenv("SIGNING_KEY_FILE", "")
+
+	signer, err := NewPDFSigner()
+	assert.ErrorIs(t, err, ErrSignerNotConfigured)
+	assert.Nil(t, signer)
+}
+
+func TestNewPDFSigner_Files(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := newSigningCert(t, key)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	t.Setenv("SIGNING_CERT_FILE", certFile)
+	t.Setenv("SIGNING_KEY_FILE", keyFile)
+	t.Setenv("TSA_URL", "http://tsa.example.com")
+
+	signer, err := NewPDFSigner()
+	require.NoError(t, err)
+	assert.Equal(t, []*x509.Certificate{cert}, signer.chain)
+	require.NotNil(t, signer.tsa)
+	assert.Equal(t, "http://tsa.example.com", signer.tsa.url)
+}
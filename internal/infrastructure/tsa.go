@@ -0,0 +1,304 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTSANotConfigured = errors.New("timestamp authority URL is not configured")
+	ErrTSAUnreachable   = errors.New("timestamp authority is unreachable")
+	ErrTSARejected      = errors.New("timestamp authority rejected the request")
+	ErrTSAInvalidToken  = errors.New("timestamp authority returned an invalid token")
+)
+
+var (
+	oidSHA256                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidSignatureTimeStampTok = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+)
+
+const (
+	tsaRequestContentType  = "application/timestamp-query"
+	tsaResponseContentType = "application/timestamp-reply"
+	tsaMaxResponseSize     = 1 << 20
+)
+
+// Timestamper obtains RFC 3161 timestamp tokens over arbitrary data.
+type Timestamper interface {
+	Timestamp(data []byte) (*Timestamp, error)
+}
+
+// Timestamp is a timestamp token issued by a TSA together with the fields
+// of its TSTInfo that callers typically need.
+type Timestamp struct {
+	Token        []byte
+	Time         time.Time
+	SerialNumber *big.Int
+	Policy       asn1.ObjectIdentifier
+}
+
+type TSAClient struct {
+	url        string
+	hash       crypto.Hash
+	httpClient *http.Client
+}
+
+func NewTSAClientWithURL(url string, httpClient *http.Client) *TSAClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &TSAClient{url: url, hash: crypto.SHA256, httpClient: httpClient}
+}
+
+func NewTSAClient() *TSAClient {
+	return NewTSAClientWithURL(os.Getenv("TSA_URL"), nil)
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []asn1.RawValue `asn1:"optional"`
+	FailInfo     asn1.BitString  `asn1:"optional"`
+}
+
+func (s pkiStatusInfo) text() string {
+	var parts []string
+	for _, v := range s.StatusString {
+		parts = append(parts, string(v.Bytes))
+	}
+	return strings.Join(parts, "; ")
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional,default:false"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+func hashOID(h crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch h {
+	case crypto.SHA256:
+		return oidSHA256, nil
+	case crypto.SHA384:
+		return oidSHA384, nil
+	case crypto.SHA512:
+		return oidSHA512, nil
+	}
+	return nil, fmt.Errorf("unsupported timestamp hash algorithm %v", h)
+}
+
+// Timestamp hashes data and requests a timestamp token for the digest from
+// the configured TSA. The returned token is checked against the request's
+// message imprint and nonce; validating the TSA certificate chain is left to
+// whoever validates the enclosing signature.
+func (c *TSAClient) Timestamp(data []byte) (*Timestamp, error) {
+	if c.url == "" {
+		return nil, ErrTSANotConfigured
+	}
+
+	oid, err := hashOID(c.hash)
+	if err != nil {
+		return nil, err
+	}
+	h := c.hash.New()
+	h.Write(data)
+
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+
+	imprint := messageImprint{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+		HashedMessage: h.Sum(nil),
+	}
+	reqBytes, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: imprint,
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := c.post(reqBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTimeStampResp(respBytes, imprint, nonce)
+}
+
+// TimestampSignature timestamps a CMS signature value and returns the DER
+// encoded id-aa-signatureTimeStampToken attribute to be added to the
+// unsigned attributes of its SignerInfo, which turns a PAdES-B signature
+// into PAdES-T.
+func (c *TSAClient) TimestampSignature(signature []byte) ([]byte, error) {
+	ts, err := c.Timestamp(signature)
+	if err != nil {
+		return nil, err
+	}
+	return SignatureTimestampAttribute(ts.Token)
+}
+
+// SignatureTimestampAttribute wraps a timestamp token in the unsigned
+// attribute PAdES-T uses to carry it.
+func SignatureTimestampAttribute(token []byte) ([]byte, error) {
+	return asn1.Marshal(attribute{
+		Type:   oidSignatureTimeStampTok,
+		Values: []asn1.RawValue{{FullBytes: token}},
+	})
+}
+
+func (c *TSAClient) post(body []byte) ([]byte, error) {
+	resp, err := c.httpClient.Post(c.url, tsaRequestContentType, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrTSAUnreachable, c.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s responded with %s", ErrTSAUnreachable, c.url, resp.Status)
+	}
+	// Parameters such as a charset do not change the body.
+	ct := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(ct); err != nil || mediaType != tsaResponseContentType {
+		return nil, fmt.Errorf("%w: unexpected content type %q", ErrTSAInvalidToken, ct)
+	}
+
+	respBytes, err := io.ReadAll(io.LimitReader(resp.Body, tsaMaxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrTSAUnreachable, c.url, err)
+	}
+	return respBytes, nil
+}
+
+func parseTimeStampResp(der []byte, imprint messageImprint, nonce *big.Int) (*Timestamp, error) {
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTSAInvalidToken, err)
+	}
+
+	// 0 is granted and 1 is grantedWithMods; everything else carries no token.
+	if resp.Status.Status != 0 && resp.Status.Status != 1 {
+		return nil, fmt.Errorf("%w: status %d %s", ErrTSARejected, resp.Status.Status, resp.Status.text())
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("%w: response has no token", ErrTSAInvalidToken)
+	}
+
+	info, err := parseTimeStampToken(resp.TimeStampToken.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(imprint.HashAlgorithm.Algorithm) ||
+		!bytes.Equal(info.MessageImprint.HashedMessage, imprint.HashedMessage) {
+		return nil, fmt.Errorf("%w: message imprint does not match the request", ErrTSAInvalidToken)
+	}
+	if nonce != nil && (info.Nonce == nil || info.Nonce.Cmp(nonce) != 0) {
+		return nil, fmt.Errorf("%w: nonce does not match the request", ErrTSAInvalidToken)
+	}
+
+	return &Timestamp{
+		Token:        resp.TimeStampToken.FullBytes,
+		Time:         info.GenTime,
+		SerialNumber: info.SerialNumber,
+		Policy:       info.Policy,
+	}, nil
+}
+
+func parseTimeStampToken(der []byte) (*tstInfo, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTSAInvalidToken, err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("%w: token is not CMS SignedData", ErrTSAInvalidToken)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTSAInvalidToken, err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("%w: token does not encapsulate TSTInfo", ErrTSAInvalidToken)
+	}
+
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &info); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTSAInvalidToken, err)
+	}
+	return &info, nil
+}
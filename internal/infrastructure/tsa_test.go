@@ -0,0 +1,323 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var oidFakeTSAPolicy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+// fakeTSA is a minimal RFC 3161 timestamp authority backed by an in-memory
+// ECDSA key and a self-signed certificate.
+type fakeTSA struct {
+	*httptest.Server
+	key    *ecdsa.PrivateKey
+	cert   *x509.Certificate
+	serial int64
+	now    time.Time
+
+	// contentType overrides the Content-Type of every response when set.
+	contentType string
+	// status overrides the PKIStatus of every response when non-zero.
+	status int
+	// tamper, when set, may modify the TSTInfo before it is signed.
+	tamper func(*tstInfo)
+}
+
+func newFakeTSA(t *testing.T) *fakeTSA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake TSA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	tsa := &fakeTSA{key: key, cert: cert, now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	tsa.Server = httptest.NewServer(http.HandlerFunc(tsa.serve))
+	t.Cleanup(tsa.Close)
+	return tsa
+}
+
+func (f *fakeTSA) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != tsaRequestContentType {
+		http.Error(w, "unexpected content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	var req timeStampReq
+	if _, err := asn1.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := f.respond(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if f.contentType != "" {
+		w.Header().Set("Content-Type", f.contentType)
+	} else {
+		w.Header().Set("Content-Type", tsaResponseContentType)
+	}
+	w.Write(resp)
+}
+
+func (f *fakeTSA) respond(req timeStampReq) ([]byte, error) {
+	if f.status != 0 {
+		return asn1.Marshal(timeStampResp{Status: pkiStatusInfo{Status: f.status, StatusString: []asn1.RawValue{{Tag: asn1.TagUTF8String, Bytes: []byte("request rejected")}}}})
+	}
+
+	f.serial++
+	info := tstInfo{
+		Version:        1,
+		Policy:         oidFakeTSAPolicy,
+		MessageImprint: req.MessageImprint,
+		SerialNumber:   big.NewInt(f.serial),
+		GenTime:        f.now,
+		Nonce:          req.Nonce,
+	}
+	if f.tamper != nil {
+		f.tamper(&info)
+	}
+	infoDER, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := f.sign(infoDER)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(timeStampResp{
+		Status:         pkiStatusInfo{Status: 0},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+}
+
+func (f *fakeTSA) sign(content []byte) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	contentTypeValue, _ := asn1.Marshal(oidTSTInfo)
+	digestValue, _ := asn1.Marshal(digest[:])
+	attrs := []attribute{
+		{Type: oidContentType, Values: []asn1.RawValue{{FullBytes: contentTypeValue}}},
+		{Type: oidMessageDigest, Values: []asn1.RawValue{{FullBytes: digestValue}}},
+	}
+
+	// The signature covers the DER SET OF encoding of the signed attributes.
+	attrsDER, err := asn1.Marshal(struct {
+		Attrs []attribute `asn1:"set"`
+	}{attrs})
+	if err != nil {
+		return nil, err
+	}
+	var attrSet asn1.RawValue
+	if _, err := asn1.Unmarshal(attrsDER, &attrSet); err != nil {
+		return nil, err
+	}
+	attrsHash := sha256.Sum256(attrSet.Bytes)
+	sig, err := ecdsa.SignASN1(rand.Reader, f.key, attrsHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sha256Alg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	signerInfos, err := asn1.Marshal(struct {
+		Infos []signerInfo `asn1:"set"`
+	}{[]signerInfo{{
+		Version:            1,
+		SID:                issuerAndSerial{Issuer: asn1.RawValue{FullBytes: f.cert.RawIssuer}, SerialNumber: f.cert.SerialNumber},
+		DigestAlgorithm:    sha256Alg,
+		SignedAttrs:        attrs,
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256},
+		Signature:          sig,
+	}}})
+	if err != nil {
+		return nil, err
+	}
+	var signerInfoSet asn1.RawValue
+	if _, err := asn1.Unmarshal(signerInfos, &signerInfoSet); err != nil {
+		return nil, err
+	}
+
+	sdDER, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Alg},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidTSTInfo, EContent: content},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: f.cert.Raw},
+		SignerInfos:      asn1.RawValue{FullBytes: signerInfoSet.FullBytes},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdDER},
+	})
+}
+
+func TestNewTSAClient_EnvURL(t *testing.T) {
+	os.Setenv("TSA_URL", "http://tsa.example.com")
+	defer os.Unsetenv("TSA_URL")
+
+	client := NewTSAClient()
+	assert.Equal(t, "http://tsa.example.com", client.url)
+	assert.Equal(t, crypto.SHA256, client.hash)
+}
+
+func TestTimestamp_NotConfigured(t *testing.T) {
+	client := NewTSAClientWithURL("", nil)
+
+	ts, err := client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSANotConfigured)
+	assert.Nil(t, ts)
+}
+
+func TestTimestamp_Success(t *testing.T) {
+	tsa := newFakeTSA(t)
+	client := NewTSAClientWithURL(tsa.URL, tsa.Client())
+
+	ts, err := client.Timestamp([]byte("signature bytes"))
+	require.NoError(t, err)
+	assert.Equal(t, tsa.now, ts.Time)
+	assert.Equal(t, big.NewInt(1), ts.SerialNumber)
+	assert.True(t, oidFakeTSAPolicy.Equal(ts.Policy))
+
+	info, err := parseTimeStampToken(ts.Token)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("signature bytes"))
+	assert.Equal(t, digest[:], info.MessageImprint.HashedMessage)
+}
+
+func TestTimestamp_Unreachable(t *testing.T) {
+	tsa := newFakeTSA(t)
+	url := tsa.URL
+	tsa.Close()
+
+	client := NewTSAClientWithURL(url, nil)
+	ts, err := client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSAUnreachable)
+	assert.Contains(t, err.Error(), url)
+	assert.Nil(t, ts)
+}
+
+func TestTimestamp_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewTSAClientWithURL(server.URL, nil)
+	_, err := client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSAUnreachable)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestTimestamp_ContentTypeParameters(t *testing.T) {
+	tsa := newFakeTSA(t)
+	tsa.contentType = "application/timestamp-reply; charset=binary"
+
+	client := NewTSAClientWithURL(tsa.URL, nil)
+	ts, err := client.Timestamp([]byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, tsa.now, ts.Time)
+
+	tsa.contentType = "text/html; charset=utf-8"
+	_, err = client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSAInvalidToken)
+	assert.Contains(t, err.Error(), "text/html")
+}
+
+func TestTimestamp_Rejected(t *testing.T) {
+	tsa := newFakeTSA(t)
+	tsa.status = 2
+
+	client := NewTSAClientWithURL(tsa.URL, nil)
+	_, err := client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSARejected)
+	assert.Contains(t, err.Error(), "request rejected")
+}
+
+func TestTimestamp_ImprintMismatch(t *testing.T) {
+	tsa := newFakeTSA(t)
+	tsa.tamper = func(info *tstInfo) {
+		info.MessageImprint.HashedMessage = make([]byte, 32)
+	}
+
+	client := NewTSAClientWithURL(tsa.URL, nil)
+	_, err := client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSAInvalidToken)
+	assert.Contains(t, err.Error(), "message imprint")
+}
+
+func TestTimestamp_NonceMismatch(t *testing.T) {
+	tsa := newFakeTSA(t)
+	tsa.tamper = func(info *tstInfo) {
+		info.Nonce = big.NewInt(42)
+	}
+
+	client := NewTSAClientWithURL(tsa.URL, nil)
+	_, err := client.Timestamp([]byte("data"))
+	assert.ErrorIs(t, err, ErrTSAInvalidToken)
+	assert.Contains(t, err.Error(), "nonce")
+}
+
+func TestTimestampSignature_Attribute(t *testing.T) {
+	tsa := newFakeTSA(t)
+	client := NewTSAClientWithURL(tsa.URL, nil)
+
+	attrDER, err := client.TimestampSignature([]byte("signature value"))
+	require.NoError(t, err)
+
+	var attr attribute
+	_, err = asn1.Unmarshal(attrDER, &attr)
+	require.NoError(t, err)
+	assert.True(t, oidSignatureTimeStampTok.Equal(attr.Type))
+	require.Len(t, attr.Values, 1)
+
+	info, err := parseTimeStampToken(attr.Values[0].FullBytes)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("signature value"))
+	assert.Equal(t, digest[:], info.MessageImprint.HashedMessage)
+}
+
+func TestTimestamp_InvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", tsaResponseContentType)
+		w.Write([]byte("not asn1"))
+	}))
+	defer server.Close()
+
+	client := NewTSAClientWithURL(server.URL, nil)
+	_, err := client.Timestamp([]byte("data"))
+	assert.True(t, errors.Is(err, ErrTSAInvalidToken))
+}
@@ -0,0 +1,249 @@
+// Package jsonschema validates JSON data against a JSON Schema (draft
+// 2020-12). It covers the keywords used to describe document data: type,
+// enum, const, the numeric, string, array and object constraints, the
+// allOf, anyOf, oneOf and not combinators, and $ref to the root or to
+// $defs and definitions. Other keywords, format among them, are accepted
+// and ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidSchema = errors.New("invalid JSON schema")
+
+// Schema is a parsed JSON Schema. The zero value accepts any value.
+type Schema struct {
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	Type  []string `json:"-"`
+	Enum  []interface{}
+	Const *interface{} `json:"-"`
+
+	MultipleOf       *float64
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum *float64
+	ExclusiveMaximum *float64
+
+	MinLength *int
+	MaxLength *int
+	Pattern   string
+	pattern   *regexp.Regexp
+
+	Items       *Schema
+	MinItems    *int
+	MaxItems    *int
+	UniqueItems bool
+
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *Schema
+	MinProperties        *int
+	MaxProperties        *int
+
+	AllOf []*Schema
+	AnyOf []*Schema
+	OneOf []*Schema
+	Not   *Schema
+
+	Ref         string             `json:"$ref"`
+	Defs        map[string]*Schema `json:"$defs"`
+	Definitions map[string]*Schema
+	ref         *Schema
+}
+
+// Parse reads a schema and resolves its references. Schemas whose $ref
+// leads back to themselves without descending into items or properties
+// are rejected, as validating against them would never end.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if err := s.walk("", func(c *Schema, path string) error { return c.compile(&s, path) }); err != nil {
+		return nil, err
+	}
+	if err := s.walk("", (*Schema).checkCycle); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UnmarshalJSON reads a schema object or a boolean schema.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "true", "false":
+		b := strings.TrimSpace(string(data)) == "true"
+		*s = Schema{always: &b}
+		return nil
+	}
+
+	// schema has the fields of Schema without its methods, so that decoding
+	// into it does not recurse.
+	type schema Schema
+	var raw struct {
+		schema
+		Type  json.RawMessage `json:"type"`
+		Const json.RawMessage `json:"const"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = Schema(raw.schema)
+
+	if len(raw.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(raw.Type, &one); err == nil {
+			s.Type = []string{one}
+		} else if err := json.Unmarshal(raw.Type, &s.Type); err != nil {
+			return fmt.Errorf("type must be a string or an array of strings")
+		}
+	}
+	if len(raw.Const) > 0 {
+		var v interface{}
+		if err := json.Unmarshal(raw.Const, &v); err != nil {
+			return err
+		}
+		s.Const = &v
+	}
+	return nil
+}
+
+var types = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// walk calls fn for s and every schema within it, with the path locating
+// each in the schema.
+func (s *Schema) walk(path string, fn func(s *Schema, path string) error) error {
+	if s == nil {
+		return nil
+	}
+	if err := fn(s, path); err != nil {
+		return err
+	}
+	children := map[string]*Schema{"/items": s.Items, "/additionalProperties": s.AdditionalProperties, "/not": s.Not}
+	for name, c := range s.Properties {
+		children["/properties/"+escape(name)] = c
+	}
+	for name, c := range s.Defs {
+		children["/$defs/"+escape(name)] = c
+	}
+	for name, c := range s.Definitions {
+		children["/definitions/"+escape(name)] = c
+	}
+	for keyword, list := range map[string][]*Schema{"allOf": s.AllOf, "anyOf": s.AnyOf, "oneOf": s.OneOf} {
+		for i, c := range list {
+			children[fmt.Sprintf("/%s/%d", keyword, i)] = c
+		}
+	}
+	// Children are walked in order of their paths, so that the error
+	// reported for a schema with several is always the same.
+	paths := make([]string, 0, len(children))
+	for p := range children {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := children[p].walk(path+p, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile checks s, compiles its pattern and resolves its $ref against
+// root. path locates s in the schema for error messages.
+func (s *Schema) compile(root *Schema, path string) error {
+	for _, t := range s.Type {
+		if !types[t] {
+			return fmt.Errorf("%w: %s: unknown type %q", ErrInvalidSchema, pathOrRoot(path), t)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidSchema, pathOrRoot(path), err)
+		}
+		s.pattern = re
+	}
+	if s.Ref != "" {
+		ref, err := root.resolve(s.Ref)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidSchema, pathOrRoot(path), err)
+		}
+		s.ref = ref
+	}
+	return nil
+}
+
+// inPlace lists the schemas that apply to the same value as s.
+func (s *Schema) inPlace() []*Schema {
+	list := []*Schema{s.ref, s.Not}
+	list = append(list, s.AllOf...)
+	list = append(list, s.AnyOf...)
+	return append(list, s.OneOf...)
+}
+
+// checkCycle rejects a $ref that leads back to s without passing through
+// items or properties, as {"$ref": "#"} does: validating it would never
+// end.
+func (s *Schema) checkCycle(path string) error {
+	seen := map[*Schema]bool{}
+	stack := s.inPlace()
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if c == nil || seen[c] {
+			continue
+		}
+		if c == s {
+			return fmt.Errorf("%w: %s: $ref cycle that never reaches items or properties", ErrInvalidSchema, pathOrRoot(path))
+		}
+		seen[c] = true
+		stack = append(stack, c.inPlace()...)
+	}
+	return nil
+}
+
+// resolve finds the schema a $ref names: the root, or an entry of $defs or
+// definitions.
+func (s *Schema) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return s, nil
+	}
+	for prefix, defs := range map[string]map[string]*Schema{"#/$defs/": s.Defs, "#/definitions/": s.Definitions} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			if def := defs[unescape(name)]; def != nil {
+				return def, nil
+			}
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return nil, fmt.Errorf("unsupported $ref %q", ref)
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "schema root"
+	}
+	return path
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// escape escapes a key for use in a JSON pointer.
+func escape(key string) string { return pointerEscaper.Replace(key) }
+
+func unescape(token string) string { return pointerUnescaper.Replace(token) }
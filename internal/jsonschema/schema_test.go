@@ -0,0 +1,192 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invoiceSchema = `{
+	"type": "object",
+	"required": ["customer_name", "items"],
+	"properties": {
+		"customer_name": {"type": "string", "minLength": 1},
+		"customer_number": {"type": "string", "pattern": "^[0-9A-F]{9}$"},
+		"currency": {"enum": ["IRR", "USD"]},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"$ref": "#/$defs/item"}
+		},
+		"paid": {"type": "boolean"},
+		"notes": {"type": ["string", "null"], "maxLength": 5}
+	},
+	"additionalProperties": false,
+	"$defs": {
+		"item": {
+			"type": "object",
+			"required": ["price"],
+			"properties": {
+				"price": {"type": "number", "exclusiveMinimum": 0},
+				"quantity": {"type": "integer", "minimum": 1, "maximum": 100}
+			}
+		}
+	}
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(invoiceSchema))
+	require.NoError(t, err)
+
+	assert.Empty(t, schema.Validate(decode(t, `{
+		"customer_name": "Sara",
+		"customer_number": "679994AF9",
+		"currency": "IRR",
+		"items": [{"price": 1500, "quantity": 2}],
+		"notes": null
+	}`)))
+
+	violations := schema.Validate(decode(t, `{
+		"customer_number": "x",
+		"currency": "EUR",
+		"items": [{"price": 0}, {"quantity": 1.5}],
+		"paid": "yes",
+		"notes": "too long",
+		"extra": 1
+	}`))
+	assert.Equal(t, []Violation{
+		{Pointer: "/currency", Message: `must be one of "IRR", "USD"`},
+		{Pointer: "/customer_name", Message: "is required"},
+		{Pointer: "/customer_number", Message: `must match the pattern "^[0-9A-F]{9}$"`},
+		{Pointer: "/extra", Message: "is not an allowed property"},
+		{Pointer: "/items/0/price", Message: "must be greater than 0"},
+		{Pointer: "/items/1/price", Message: "is required"},
+		{Pointer: "/items/1/quantity", Message: "expected integer, got number"},
+		{Pointer: "/notes", Message: "must be at most 5 characters long"},
+		{Pointer: "/paid", Message: "expected boolean, got string"},
+	}, violations)
+
+	assert.Equal(t, []Violation{{Pointer: "", Message: "expected object, got array"}}, schema.Validate(decode(t, `[]`)))
+}
+
+func TestValidate_GoValues(t *testing.T) {
+	schema, err := Parse([]byte(`{"properties": {"n": {"type": "integer"}, "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}}}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, schema.Validate(map[string]interface{}{"n": 3, "tags": []string{"a", "b"}}))
+	assert.Equal(t, []Violation{{Pointer: "/tags", Message: "items 0 and 1 are equal"}},
+		schema.Validate(map[string]interface{}{"tags": []string{"a", "a"}}))
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	schema, err := Parse([]byte(`{
+		"properties": {
+			"amount": {"anyOf": [{"type": "number"}, {"type": "string", "pattern": "^[0-9,]+$"}]},
+			"id": {"oneOf": [{"type": "integer"}, {"type": "number", "multipleOf": 0.5}]},
+			"code": {"allOf": [{"minLength": 2}, {"maxLength": 3}], "not": {"const": "XX"}},
+			"any": true,
+			"none": false
+		}
+	}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, schema.Validate(decode(t, `{"amount": "1,500", "id": 2.5, "code": "IRR", "any": [1]}`)))
+	assert.Equal(t, []Violation{
+		{Pointer: "/amount", Message: "does not match any of the allowed schemas"},
+		{Pointer: "/code", Message: "must be at least 2 characters long"},
+		{Pointer: "/id", Message: "must match exactly one of the allowed schemas, matches 2"},
+		{Pointer: "/none", Message: "no value is allowed here"},
+	}, schema.Validate(decode(t, `{"amount": "1.5", "id": 2, "code": "X", "none": 1}`)))
+	assert.Equal(t, []Violation{{Pointer: "/code", Message: "matches a schema it must not match"}},
+		schema.Validate(decode(t, `{"code": "XX"}`)))
+}
+
+func TestValidate_RecursiveRef(t *testing.T) {
+	schema, err := Parse([]byte(`{
+		"type": "object",
+		"properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#"}}}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Violation{{Pointer: "/children/0/children/0/name", Message: "expected string, got integer"}},
+		schema.Validate(decode(t, `{"name": "a", "children": [{"children": [{"name": 1}]}]}`)))
+}
+
+func TestParse_RefCycle(t *testing.T) {
+	for _, src := range []string{
+		`{"$ref": "#"}`,
+		`{"$defs": {"a": {"$ref": "#/$defs/a"}}}`,
+		`{"$defs": {"a": {"allOf": [{"$ref": "#/$defs/b"}]}, "b": {"anyOf": [{"type": "string"}, {"$ref": "#/$defs/a"}]}}}`,
+		`{"definitions": {"x": {"oneOf": [{"not": {"$ref": "#/definitions/x"}}]}}}`,
+	} {
+		_, err := Parse([]byte(src))
+		assert.ErrorIs(t, err, ErrInvalidSchema, src)
+		assert.ErrorContains(t, err, "$ref cycle", src)
+	}
+
+	// A schema that refers to itself through properties is fine, and so is
+	// one reached twice without a cycle.
+	_, err := Parse([]byte(`{"$defs": {"s": {"type": "string"}}, "allOf": [{"$ref": "#/$defs/s"}, {"$ref": "#/$defs/s"}],
+		"properties": {"next": {"$ref": "#"}}}`))
+	assert.NoError(t, err)
+}
+
+func TestValidate_EscapedPointers(t *testing.T) {
+	schema, err := Parse([]byte(`{"required": ["a/b", "c~d"]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Violation{
+		{Pointer: "/a~1b", Message: "is required"},
+		{Pointer: "/c~0d", Message: "is required"},
+	}, schema.Validate(map[string]interface{}{}))
+}
+
+func TestValidate_UniqueItems(t *testing.T) {
+	schema, err := Parse([]byte(`{"type": "array", "uniqueItems": true}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Pointer: "", Message: "items 0 and 2 are equal"},
+		{Pointer: "", Message: "items 1 and 3 are equal"},
+		{Pointer: "", Message: "items 0 and 4 are equal"},
+	}, schema.Validate(decode(t, `[{"a": 1, "b": [2]}, 1, {"b": [2], "a": 1.0}, 1e0, {"a": 1, "b": [2]}, {"a": 1, "b": [3]}]`)))
+
+	items := make([]interface{}, 100000)
+	for i := range items {
+		items[i] = map[string]interface{}{"n": i}
+	}
+	assert.Empty(t, schema.Validate(items))
+}
+
+func TestParse_InvalidDeterministic(t *testing.T) {
+	src := `{"properties": {"a": {"type": "text"}, "b": {"type": "text"}, "c": {"pattern": "("}}}`
+	_, err := Parse([]byte(src))
+	require.Error(t, err)
+	for i := 0; i < 20; i++ {
+		_, again := Parse([]byte(src))
+		assert.Equal(t, err.Error(), again.Error())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, src := range []string{
+		`{"type": "text"}`,
+		`{"type": 1}`,
+		`{"pattern": "("}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"$ref": "http://example.com/schema"}`,
+		`{"properties": {"a": {"items": {"type": "decimal"}}}}`,
+		`[`,
+	} {
+		_, err := Parse([]byte(src))
+		assert.ErrorIs(t, err, ErrInvalidSchema, src)
+	}
+}
@@ -0,0 +1,328 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation is a value that does not satisfy the schema. Pointer is the
+// JSON pointer (RFC 6901) of the value, "" for the whole document.
+type Violation struct {
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Pointer == "" {
+		return v.Message
+	}
+	return v.Pointer + ": " + v.Message
+}
+
+// Validate checks v, as decoded by encoding/json, against s and returns
+// every violation found, ordered by pointer.
+func (s *Schema) Validate(v interface{}) []Violation {
+	var violations []Violation
+	s.validate(normalize(v), "", &violations)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Pointer < violations[j].Pointer })
+	return violations
+}
+
+// normalize converts the Go values callers may pass besides those of
+// encoding/json, such as ints and []string, to their encoding/json form.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, string, float64:
+		return v
+	case json.Number:
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[k] = normalize(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = normalize(e)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			out := make(map[string]interface{}, rv.Len())
+			for _, k := range rv.MapKeys() {
+				out[k.String()] = normalize(rv.MapIndex(k).Interface())
+			}
+			return out
+		}
+	}
+	// Anything else is checked in its JSON form.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if json.Unmarshal(data, &out) != nil {
+		return v
+	}
+	return out
+}
+
+func (s *Schema) validate(v interface{}, ptr string, out *[]Violation) {
+	if s == nil {
+		return
+	}
+	add := func(format string, args ...interface{}) {
+		*out = append(*out, Violation{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.always != nil {
+		if !*s.always {
+			add("no value is allowed here")
+		}
+		return
+	}
+	if s.ref != nil {
+		s.ref.validate(v, ptr, out)
+	}
+
+	if len(s.Type) > 0 && !hasType(v, s.Type) {
+		add("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		// The remaining keywords would only repeat the type mismatch.
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equal(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("must be one of %s", list(s.Enum))
+		}
+	}
+	if s.Const != nil && !equal(v, *s.Const) {
+		add("must be %s", jsonText(*s.Const))
+	}
+
+	switch x := v.(type) {
+	case float64:
+		s.validateNumber(x, add)
+	case string:
+		s.validateString(x, add)
+	case []interface{}:
+		s.validateArray(x, ptr, out, add)
+	case map[string]interface{}:
+		s.validateObject(x, ptr, out, add)
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(v, ptr, out)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if sub.valid(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			add("does not match any of the allowed schemas")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matches := 0
+		for _, sub := range s.OneOf {
+			if sub.valid(v) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			add("must match exactly one of the allowed schemas, matches %d", matches)
+		}
+	}
+	if s.Not != nil && s.Not.valid(v) {
+		add("matches a schema it must not match")
+	}
+}
+
+func (s *Schema) valid(v interface{}) bool {
+	var violations []Violation
+	s.validate(v, "", &violations)
+	return len(violations) == 0
+}
+
+func (s *Schema) validateNumber(x float64, add func(string, ...interface{})) {
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		if q := x / *s.MultipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			add("must be a multiple of %s", number(*s.MultipleOf))
+		}
+	}
+	if s.Minimum != nil && x < *s.Minimum {
+		add("must be at least %s", number(*s.Minimum))
+	}
+	if s.Maximum != nil && x > *s.Maximum {
+		add("must be at most %s", number(*s.Maximum))
+	}
+	if s.ExclusiveMinimum != nil && x <= *s.ExclusiveMinimum {
+		add("must be greater than %s", number(*s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil && x >= *s.ExclusiveMaximum {
+		add("must be less than %s", number(*s.ExclusiveMaximum))
+	}
+}
+
+func (s *Schema) validateString(x string, add func(string, ...interface{})) {
+	n := utf8.RuneCountInString(x)
+	if s.MinLength != nil && n < *s.MinLength {
+		add("must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		add("must be at most %d characters long", *s.MaxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(x) {
+		add("must match the pattern %q", s.Pattern)
+	}
+}
+
+func (s *Schema) validateArray(x []interface{}, ptr string, out *[]Violation, add func(string, ...interface{})) {
+	if s.MinItems != nil && len(x) < *s.MinItems {
+		add("must have at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(x) > *s.MaxItems {
+		add("must have at most %d items", *s.MaxItems)
+	}
+	if s.UniqueItems {
+		// Normalized values encode alike exactly when they are equal, with
+		// object keys sorted, so one pass over the encodings finds the
+		// duplicates.
+		first := make(map[string]int, len(x))
+		for i, e := range x {
+			key := jsonText(normalize(e))
+			if j, ok := first[key]; ok {
+				add("items %d and %d are equal", j, i)
+				continue
+			}
+			first[key] = i
+		}
+	}
+	for i, e := range x {
+		s.Items.validate(e, ptr+"/"+strconv.Itoa(i), out)
+	}
+}
+
+func (s *Schema) validateObject(x map[string]interface{}, ptr string, out *[]Violation, add func(string, ...interface{})) {
+	if s.MinProperties != nil && len(x) < *s.MinProperties {
+		add("must have at least %d properties", *s.MinProperties)
+	}
+	if s.MaxProperties != nil && len(x) > *s.MaxProperties {
+		add("must have at most %d properties", *s.MaxProperties)
+	}
+	for _, name := range s.Required {
+		if _, ok := x[name]; !ok {
+			*out = append(*out, Violation{Pointer: ptr + "/" + escape(name), Message: "is required"})
+		}
+	}
+
+	keys := make([]string, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := ptr + "/" + escape(k)
+		if prop, ok := s.Properties[k]; ok {
+			prop.validate(x[k], p, out)
+		} else if s.AdditionalProperties != nil {
+			if s.AdditionalProperties.always != nil && !*s.AdditionalProperties.always {
+				*out = append(*out, Violation{Pointer: p, Message: "is not an allowed property"})
+				continue
+			}
+			s.AdditionalProperties.validate(x[k], p, out)
+		}
+	}
+}
+
+func hasType(v interface{}, want []string) bool {
+	got := typeOf(v)
+	for _, t := range want {
+		if t == got || (t == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf names the JSON type of v, reporting whole numbers as integer.
+func typeOf(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) && !math.IsInf(x, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func jsonText(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func list(values []interface{}) string {
+	texts := make([]string, len(values))
+	for i, v := range values {
+		texts[i] = jsonText(v)
+	}
+	return strings.Join(texts, ", ")
+}
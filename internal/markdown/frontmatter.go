@@ -0,0 +1,343 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFrontMatter = errors.New("invalid front matter")
+
+// FrontMatter splits the YAML front matter, between --- lines at the start
+// of source, from the Markdown after it. Without front matter, data is nil
+// and body is source.
+//
+// The YAML read is the subset documents need: mappings and sequences by
+// indentation, flow sequences such as [a, b], plain, quoted and block (|
+// and >) scalars, and comments. Numbers decode to float64 and true, false
+// and null to their Go values, as in JSON data; dates stay strings.
+func FrontMatter(source string) (data map[string]interface{}, body string, err error) {
+	source = strings.TrimPrefix(source, "\uFEFF")
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	if !strings.HasPrefix(source, "---\n") {
+		return nil, source, nil
+	}
+	lines := strings.Split(source, "\n")
+	for i := 1; i < len(lines); i++ {
+		if end := strings.TrimRight(lines[i], " \t"); end == "---" || end == "..." {
+			data, err := parseYAML(lines[1:i])
+			if err != nil {
+				return nil, "", err
+			}
+			return data, strings.Join(lines[i+1:], "\n"), nil
+		}
+	}
+	// Without a closing line, the opening --- is a thematic break.
+	return nil, source, nil
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+	// number is the line number in the source, counting the opening ---
+	// as line 1.
+	number int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	// raw holds every line, blank ones included, for block scalars.
+	raw []string
+	pos int
+}
+
+func parseYAML(raw []string) (map[string]interface{}, error) {
+	p := &yamlParser{raw: raw}
+	for i, line := range raw {
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("%w: line %d: tabs cannot indent YAML", ErrInvalidFrontMatter, i+2)
+		}
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{indent: indentation(line), text: text, number: i + 2})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if p.lines[0].indent != 0 || isSequenceItem(p.lines[0].text) {
+		return nil, p.errorf(p.lines[0], "front matter must be a mapping")
+	}
+	m, err := p.mapping(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected indentation")
+	}
+	return m, nil
+}
+
+func (p *yamlParser) errorf(line yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidFrontMatter, line.number, fmt.Sprintf(format, args...))
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+var keyPattern = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s"'#][^:#]*?):(?:\s+|$)`)
+
+// mapping parses the entries at indent.
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || isSequenceItem(line.text) {
+			return nil, p.errorf(line, "unexpected indentation")
+		}
+		k := keyPattern.FindStringSubmatch(line.text)
+		if k == nil {
+			return nil, p.errorf(line, "expected a key: value pair")
+		}
+		key, err := scalarString(k[1])
+		if err != nil {
+			return nil, p.errorf(line, "%v", err)
+		}
+		if _, ok := m[key]; ok {
+			return nil, p.errorf(line, "duplicate key %q", key)
+		}
+		rest := strings.TrimSpace(line.text[len(k[0]):])
+		p.pos++
+		if m[key], err = p.value(line, indent, rest); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// sequence parses the items at indent.
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	var items []interface{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && !isSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, "unexpected indentation")
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if k := keyPattern.FindStringSubmatch(rest); k != nil && !strings.HasPrefix(rest, "[") {
+			// An item that is a mapping starts on the dash's line; the
+			// entries go on at the column of its first key.
+			itemIndent := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{indent: itemIndent, text: rest, number: line.number}
+			m, err := p.mapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, m)
+			continue
+		}
+		p.pos++
+		v, err := p.value(line, indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+// value parses the value given after a key or dash on line, at indent: the
+// rest of the line, or the block nested below it.
+func (p *yamlParser) value(line yamlLine, indent int, rest string) (interface{}, error) {
+	switch {
+	case rest == "" || strings.HasPrefix(rest, "#"):
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			switch {
+			case next.indent > indent && isSequenceItem(next.text):
+				return p.sequence(next.indent)
+			case next.indent > indent:
+				return p.mapping(next.indent)
+			case next.indent == indent && isSequenceItem(next.text) && !isSequenceItem(line.text):
+				// A sequence may sit at its key's indentation.
+				return p.sequence(indent)
+			}
+		}
+		return nil, nil
+	case rest[0] == '|' || rest[0] == '>':
+		return p.blockScalar(line, indent, rest)
+	}
+	v, err := scalar(rest)
+	if err != nil {
+		return nil, p.errorf(line, "%v", err)
+	}
+	return v, nil
+}
+
+// blockScalar reads the literal (|) or folded (>) text indented below line.
+// A - after the indicator strips the final line break.
+func (p *yamlParser) blockScalar(line yamlLine, indent int, header string) (interface{}, error) {
+	folded := header[0] == '>'
+	strip := strings.HasPrefix(header[1:], "-")
+
+	var text []string
+	blockIndent := -1
+	// raw[i] is the source line numbered i+2, so the header's next line is
+	// at line.number-1.
+	i := line.number - 1
+	for ; i < len(p.raw); i++ {
+		raw := p.raw[i]
+		if strings.TrimSpace(raw) == "" {
+			text = append(text, "")
+			continue
+		}
+		n := indentation(raw)
+		if n <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = n
+		}
+		if n < blockIndent {
+			break
+		}
+		text = append(text, raw[blockIndent:])
+	}
+	for len(text) > 0 && text[len(text)-1] == "" {
+		text = text[:len(text)-1]
+	}
+	// Skip the parsed lines that belonged to the block.
+	for p.pos < len(p.lines) && p.lines[p.pos].number < i+2 {
+		p.pos++
+	}
+
+	var s string
+	if folded {
+		var b strings.Builder
+		// Lines are joined by spaces; a blank line stands for a line break.
+		for j, t := range text {
+			if t == "" {
+				b.WriteByte('\n')
+				continue
+			}
+			if j > 0 && text[j-1] != "" {
+				b.WriteByte(' ')
+			}
+			b.WriteString(t)
+		}
+		s = b.String()
+	} else {
+		s = strings.Join(text, "\n")
+	}
+	if !strip && len(text) > 0 {
+		s += "\n"
+	}
+	return s, nil
+}
+
+var numberPattern = regexp.MustCompile(`^[-+]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[eE][-+]?[0-9]+)?$`)
+
+// scalar decodes a value written on one line.
+func scalar(s string) (interface{}, error) {
+	if strings.HasPrefix(s, "[") {
+		return flowSequence(s)
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		return scalarString(s)
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch s {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if numberPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return s, nil
+}
+
+// scalarString decodes a plain or quoted string.
+func scalarString(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := closingQuote(s)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if rest := strings.TrimSpace(s[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after string", rest)
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, "'"):
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\'' {
+				if end+1 < len(s) && s[end+1] == '\'' {
+					end++
+					continue
+				}
+				break
+			}
+		}
+		if end >= len(s) {
+			return "", errors.New("unterminated string")
+		}
+		return strings.ReplaceAll(s[1:end], "''", "'"), nil
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// closingQuote finds the quote ending the double-quoted string s.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// flowSequence decodes a sequence written as [a, b, c].
+func flowSequence(s string) (interface{}, error) {
+	if i := strings.Index(s, "]"); i < 0 || strings.TrimSpace(s[i+1:]) != "" && !strings.HasPrefix(strings.TrimSpace(s[i+1:]), "#") {
+		return nil, errors.New("flow sequences must be written on one line, as [a, b]")
+	}
+	inner := strings.TrimSpace(s[1:strings.Index(s, "]")])
+	items := []interface{}{}
+	if inner == "" {
+		return items, nil
+	}
+	for _, part := range strings.Split(inner, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, errors.New("empty item in flow sequence")
+		}
+		v, err := scalar(part)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontMatter(t *testing.T) {
+	source := `---
+# Letter details
+title: "Policy: renewal"
+customer: Jane Doe
+number: 42
+premium: 1250.50
+active: true
+expires: 2026-12-31
+note: ~
+tags: [home, 'contents', 3]
+address:
+  street: 1 Main St
+  city: Springfield
+items:
+- name: Building
+  amount: 1000
+- name: Contents
+  amount: 250
+notes:
+  - first
+  - second
+intro: |
+  Dear customer,
+
+  thank you.
+summary: >-
+  Folded
+  text
+
+  here
+---
+# Hello
+`
+	data, body, err := FrontMatter(source)
+	require.NoError(t, err)
+	assert.Equal(t, "# Hello\n", body)
+	assert.Equal(t, map[string]interface{}{
+		"title":    "Policy: renewal",
+		"customer": "Jane Doe",
+		"number":   42.0,
+		"premium":  1250.5,
+		"active":   true,
+		"expires":  "2026-12-31",
+		"note":     nil,
+		"tags":     []interface{}{"home", "contents", 3.0},
+		"address":  map[string]interface{}{"street": "1 Main St", "city": "Springfield"},
+		"items": []interface{}{
+			map[string]interface{}{"name": "Building", "amount": 1000.0},
+			map[string]interface{}{"name": "Contents", "amount": 250.0},
+		},
+		"notes":   []interface{}{"first", "second"},
+		"intro":   "Dear customer,\n\nthank you.\n",
+		"summary": "Folded text\nhere",
+	}, data)
+}
+
+func TestFrontMatter_None(t *testing.T) {
+	for _, source := range []string{"# Title\n", "---\n\nNo closing line\n"} {
+		data, body, err := FrontMatter(source)
+		require.NoError(t, err)
+		assert.Nil(t, data)
+		assert.Equal(t, source, body)
+	}
+}
+
+func TestFrontMatter_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"not a mapping", "---\n- a\n---\n", "invalid front matter: line 2: front matter must be a mapping"},
+		{"no key", "---\ntitle: a\njust text\n---\n", "invalid front matter: line 3: expected a key: value pair"},
+		{"duplicate", "---\na: 1\na: 2\n---\n", `invalid front matter: line 3: duplicate key "a"`},
+		{"indentation", "---\na: 1\n  b: 2\n---\n", "invalid front matter: line 3: unexpected indentation"},
+		{"unterminated", "---\na: \"open\n---\n", "invalid front matter: line 2: unterminated string"},
+		{"tab", "---\na:\n\tb: 1\n---\n", "invalid front matter: line 3: tabs cannot indent YAML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := FrontMatter(tt.source)
+			require.ErrorIs(t, err, ErrInvalidFrontMatter)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
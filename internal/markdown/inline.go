@@ -0,0 +1,533 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// inlineNode is converted inline content, or a run of * or _ that may
+// open or close emphasis.
+type inlineNode struct {
+	html string
+
+	delim byte
+	// count is the number of delimiters left in the run, orig the number
+	// it started with.
+	count, orig       int
+	canOpen, canClose bool
+	// opening and closing are the emphasis tags the run turned into,
+	// written after and before what is left of it.
+	opening, closing string
+}
+
+var (
+	entityPattern      = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	uriAutolinkPattern = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^<>\x00-\x20]*)>`)
+	emailAutolink      = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	inlineHTMLPattern  = regexp.MustCompile(`^(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>|(?s:<!--.*?-->))`)
+	linkTitlePattern   = regexp.MustCompile(`^(?:"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)'|\(((?:[^()\\]|\\.)*)\))`)
+)
+
+const (
+	// maxLinkNesting bounds how deeply links and images may nest; brackets
+	// deeper than that are literal text.
+	maxLinkNesting = 32
+	// maxLabelLength is the longest link label CommonMark allows.
+	maxLabelLength = 999
+)
+
+// inlineScanner remembers what has been found out about the text of a block
+// while converting it, so that no part of it is scanned again from every
+// [ or ( that might start a link: the ] matching each [, the ) closing each
+// ( of a link destination, and where the runs of backticks are. Link text
+// is converted as a range of the same text and shares what was found.
+type inlineScanner struct {
+	s string
+	// brackets and parens map the index of each [ and ( scanned to the
+	// index of its match, or -1 if it has none.
+	brackets map[int]int
+	parens   map[int]int
+	// backticks lists the starts of the runs of each length, in order.
+	backticks map[int][]int
+	// angleFrom and angleEnd cache the last search for the end of a
+	// destination in angle brackets.
+	angleFrom, angleEnd int
+}
+
+func newInlineScanner(s string) *inlineScanner {
+	sc := &inlineScanner{s: s, brackets: map[int]int{}, parens: map[int]int{}, backticks: map[int][]int{}, angleFrom: -1}
+	for i := 0; i < len(s); {
+		j := strings.IndexByte(s[i:], '`')
+		if j < 0 {
+			break
+		}
+		n := runLength(s, i+j, '`')
+		sc.backticks[n] = append(sc.backticks[n], i+j)
+		i += j + n
+	}
+	return sc
+}
+
+// inline converts the inline content of a block.
+func (c *converter) inline(s string) string {
+	return c.inlineRange(newInlineScanner(s), 0, len(s))
+}
+
+// inlineRange converts sc.s[from:to].
+func (c *converter) inlineRange(sc *inlineScanner, from, to int) string {
+	s := sc.s[:to]
+	var nodes []inlineNode
+	var text bytes.Buffer
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, inlineNode{html: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := from; i < len(s); {
+		switch ch := s[i]; ch {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				text.WriteString("<br />\n")
+				i = skipSpaces(s, i+2)
+			} else if i+1 < len(s) && isASCIIPunct(s[i+1]) {
+				text.WriteString(escapeHTML(s[i+1 : i+2]))
+				i += 2
+			} else {
+				text.WriteByte('\\')
+				i++
+			}
+		case '`':
+			n := runLength(s, i, '`')
+			if end := sc.closingBackticks(i+n, n); end >= 0 && end+n <= len(s) {
+				text.WriteString("<code>" + escapeHTML(codeSpan(s[i+n:end])) + "</code>")
+				i = end + n
+			} else {
+				text.WriteString(s[i : i+n])
+				i += n
+			}
+		case '*', '_':
+			n := runLength(s, i, ch)
+			prev, _ := utf8.DecodeLastRuneInString(s[from:i])
+			if i == from {
+				prev = '\n'
+			}
+			next, _ := utf8.DecodeRuneInString(s[i+n:])
+			if i+n == len(s) {
+				next = '\n'
+			}
+			left := !unicode.IsSpace(next) && (!isPunct(next) || unicode.IsSpace(prev) || isPunct(prev))
+			right := !unicode.IsSpace(prev) && (!isPunct(prev) || unicode.IsSpace(next) || isPunct(next))
+			node := inlineNode{delim: ch, count: n, orig: n, canOpen: left, canClose: right}
+			if ch == '_' {
+				node.canOpen = left && (!right || isPunct(prev))
+				node.canClose = right && (!left || isPunct(next))
+			}
+			flush()
+			nodes = append(nodes, node)
+			i += n
+		case '!', '[':
+			start := i
+			if ch == '!' {
+				start++
+			}
+			if start < len(s) && s[start] == '[' {
+				if link, end, ok := c.link(sc, start, len(s), ch == '!'); ok {
+					text.WriteString(link)
+					i = end
+					continue
+				}
+			}
+			text.WriteByte(ch)
+			i++
+		case '<':
+			if m := uriAutolinkPattern.FindStringSubmatch(s[i:]); m != nil {
+				text.WriteString(`<a href="` + escapeHTML(m[1]) + `">` + escapeHTML(m[1]) + "</a>")
+				i += len(m[0])
+			} else if m := emailAutolink.FindStringSubmatch(s[i:]); m != nil {
+				text.WriteString(`<a href="mailto:` + escapeHTML(m[1]) + `">` + escapeHTML(m[1]) + "</a>")
+				i += len(m[0])
+			} else if m := inlineHTMLPattern.FindString(s[i:]); m != "" {
+				text.WriteString(m)
+				i += len(m)
+			} else {
+				text.WriteString("&lt;")
+				i++
+			}
+		case '&':
+			if m := entityPattern.FindString(s[i:]); m != "" {
+				text.WriteString(m)
+				i += len(m)
+			} else {
+				text.WriteString("&amp;")
+				i++
+			}
+		case '\n':
+			// Two or more spaces before a line break make it a hard break.
+			hard := i >= from+2 && s[i-1] == ' ' && s[i-2] == ' '
+			text.Truncate(len(bytes.TrimRight(text.Bytes(), " ")))
+			if hard {
+				text.WriteString("<br />")
+			}
+			text.WriteByte('\n')
+			i = skipSpaces(s, i+1)
+		default:
+			j := i + 1
+			for j < len(s) && !strings.ContainsRune("\\`*_![<&\n", rune(s[j])) {
+				j++
+			}
+			text.WriteString(escapeHTML(s[i:j]))
+			i = j
+		}
+	}
+	flush()
+
+	var out strings.Builder
+	for _, n := range processEmphasis(nodes) {
+		if n.delim != 0 {
+			out.WriteString(n.closing)
+			out.WriteString(strings.Repeat(string(n.delim), n.count))
+			out.WriteString(n.opening)
+		} else {
+			out.WriteString(n.html)
+		}
+	}
+	return out.String()
+}
+
+// processEmphasis pairs the delimiter runs in nodes into em and strong
+// elements, following the CommonMark algorithm. Delimiters left over are
+// literal text.
+func processEmphasis(nodes []inlineNode) []inlineNode {
+	// prev links each delimiter run to the one before it. Runs between a
+	// matched pair are unlinked, as they can no longer be matched.
+	prev := make([]int, len(nodes))
+	last := -1
+	for i := range nodes {
+		prev[i] = last
+		if nodes[i].delim != 0 {
+			last = i
+		}
+	}
+	// bottom holds, for each kind of closer, the run at and below which
+	// an earlier search found no opener, so that it is not searched again.
+	bottom := map[[3]int]int{}
+
+	for c := 0; c < len(nodes); c++ {
+		closer := &nodes[c]
+		if closer.delim == 0 || !closer.canClose || closer.count == 0 {
+			continue
+		}
+		kind := [3]int{int(closer.delim), closer.orig % 3, 0}
+		if closer.canOpen {
+			kind[2] = 1
+		}
+		low, ok := bottom[kind]
+		if !ok {
+			low = -1
+		}
+		o := -1
+		for k := prev[c]; k > low; k = prev[k] {
+			opener := nodes[k]
+			if opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+				continue
+			}
+			// The rule of three: a run that can both open and close does
+			// not pair with one whose lengths sum to a multiple of three.
+			if (opener.canClose || closer.canOpen) && (opener.orig+closer.orig)%3 == 0 && !(opener.orig%3 == 0 && closer.orig%3 == 0) {
+				continue
+			}
+			o = k
+			break
+		}
+		if o < 0 {
+			bottom[kind] = prev[c]
+			continue
+		}
+
+		use, tag := 1, "em"
+		if nodes[o].count >= 2 && closer.count >= 2 {
+			use, tag = 2, "strong"
+		}
+		nodes[o].count -= use
+		closer.count -= use
+		// Later pairs enclose earlier ones.
+		nodes[o].opening = "<" + tag + ">" + nodes[o].opening
+		closer.closing += "</" + tag + ">"
+		prev[c] = o
+		if nodes[o].count == 0 {
+			prev[c] = prev[o]
+		}
+		if closer.count > 0 {
+			// Look for another opener for the rest of the closer.
+			c--
+		}
+	}
+	return nodes
+}
+
+// link converts the link or image whose text opens with the [ at s[i],
+// returning the HTML and the index after it. The link must end before to.
+func (c *converter) link(sc *inlineScanner, i, to int, image bool) (string, int, bool) {
+	if c.linkDepth >= maxLinkNesting {
+		return "", 0, false
+	}
+	s := sc.s[:to]
+	closing := sc.closingBracket(i)
+	if closing < 0 || closing >= len(s) {
+		return "", 0, false
+	}
+	text := s[i+1 : closing]
+	end := closing + 1
+
+	var dest, title string
+	found := false
+	if end < len(s) && s[end] == '(' {
+		dest, title, end, found = sc.inlineDestination(end+1, to)
+	}
+	if !found {
+		label := text
+		end = closing + 1
+		if end < len(s) && s[end] == '[' {
+			if k := sc.closingBracket(end); k > end+1 && k < len(s) {
+				label = s[end+1 : k]
+				end = k + 1
+			} else if k == end+1 {
+				end = k + 1
+			}
+		}
+		if len(label) > maxLabelLength {
+			return "", 0, false
+		}
+		ref, ok := c.refs[normalizeLabel(label)]
+		if !ok {
+			return "", 0, false
+		}
+		dest, title = ref.dest, ref.title
+	}
+
+	c.linkDepth++
+	content := c.inlineRange(sc, i+1, closing)
+	c.linkDepth--
+	titleAttr := ""
+	if title != "" {
+		titleAttr = ` title="` + escapeHTML(title) + `"`
+	}
+	if image {
+		return `<img src="` + escapeHTML(dest) + `" alt="` + escapeHTML(plainText(content)) + `"` + titleAttr + " />", end, true
+	}
+	return `<a href="` + escapeHTML(dest) + `"` + titleAttr + ">" + content + "</a>", end, true
+}
+
+// closingBracket finds the ] matching the [ at s[i], skipping escaped
+// brackets and code spans, or returns -1. The match of every [ passed on
+// the way is remembered and skipped over by later searches.
+func (sc *inlineScanner) closingBracket(i int) int {
+	if end, ok := sc.brackets[i]; ok {
+		return end
+	}
+	s := sc.s
+	var open []int
+	unmatched := func() int {
+		for _, k := range open {
+			sc.brackets[k] = -1
+		}
+		return -1
+	}
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			n := runLength(s, j, '`')
+			if end := sc.closingBackticks(j+n, n); end >= 0 {
+				j = end + n - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			if end, ok := sc.brackets[j]; ok {
+				if end < 0 {
+					// Nothing after it closes it, so nothing closes
+					// the brackets still open either.
+					return unmatched()
+				}
+				j = end
+				continue
+			}
+			open = append(open, j)
+		case ']':
+			k := open[len(open)-1]
+			open = open[:len(open)-1]
+			sc.brackets[k] = j
+			if len(open) == 0 {
+				return j
+			}
+		}
+	}
+	return unmatched()
+}
+
+// closingBackticks finds a run of exactly n backticks from s[i], or
+// returns -1.
+func (sc *inlineScanner) closingBackticks(i, n int) int {
+	runs := sc.backticks[n]
+	if k := sort.SearchInts(runs, i); k < len(runs) {
+		return runs[k]
+	}
+	return -1
+}
+
+// inlineDestination parses the destination and optional title of an
+// inline link from s[i], just after the opening parenthesis. The link must
+// end before to.
+func (sc *inlineScanner) inlineDestination(i, to int) (dest, title string, end int, ok bool) {
+	s := sc.s[:to]
+	i = skipWhitespace(s, i)
+	if i < len(s) && s[i] == '<' {
+		j := sc.angleDestinationEnd(i + 1)
+		if j < 0 || j >= len(s) || s[j] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[i+1 : j]
+		i = j + 1
+	} else {
+		j := sc.destinationEnd(i)
+		if j < 0 || j > len(s) {
+			return "", "", 0, false
+		}
+		dest = s[i:j]
+		i = j
+	}
+
+	// A title is separated from the destination by whitespace.
+	if j := skipWhitespace(s, i); j > i {
+		if m := linkTitlePattern.FindStringSubmatch(s[j:]); m != nil {
+			title = m[1] + m[2] + m[3]
+			j = skipWhitespace(s, j+len(m[0]))
+		}
+		i = j
+	}
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return unescape(dest), unescape(title), i + 1, true
+}
+
+// angleDestinationEnd finds the > or line break ending a destination in
+// angle brackets from s[i], or returns -1.
+func (sc *inlineScanner) angleDestinationEnd(i int) int {
+	if sc.angleFrom < 0 || i < sc.angleFrom || (sc.angleEnd >= 0 && sc.angleEnd < i) {
+		sc.angleFrom, sc.angleEnd = i, strings.IndexAny(sc.s[i:], ">\n")
+		if sc.angleEnd >= 0 {
+			sc.angleEnd += i
+		}
+	}
+	return sc.angleEnd
+}
+
+// destinationEnd finds the end of a destination not in angle brackets from
+// s[i]: the first space, control character or unbalanced ). It returns -1
+// if a parenthesis is left open. The ) matching every ( passed on the way
+// is remembered and skipped over by later searches.
+func (sc *inlineScanner) destinationEnd(i int) int {
+	s := sc.s
+	var open []int
+	j := i
+	for ; j < len(s); j++ {
+		ch := s[j]
+		if ch == '\\' && j+1 < len(s) && isASCIIPunct(s[j+1]) {
+			j++
+			continue
+		}
+		if ch <= ' ' || (ch == ')' && len(open) == 0) {
+			break
+		}
+		if ch == '(' {
+			if end, ok := sc.parens[j]; ok {
+				if end < 0 {
+					break
+				}
+				j = end
+				continue
+			}
+			open = append(open, j)
+		} else if ch == ')' {
+			sc.parens[open[len(open)-1]] = j
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 || (j < len(s) && s[j] == '(') {
+		for _, k := range open {
+			sc.parens[k] = -1
+		}
+		return -1
+	}
+	return j
+}
+
+func runLength(s string, i int, ch byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == ch {
+		n++
+	}
+	return n
+}
+
+// codeSpan normalizes the content of a code span: line endings become
+// spaces, and one space is stripped from each end when both have one.
+func codeSpan(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		code = code[1 : len(code)-1]
+	}
+	return code
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func isASCIIPunct(ch byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", ch) >= 0
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func escapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// unescape removes the backslashes escaping ASCII punctuation.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
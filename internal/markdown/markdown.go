@@ -0,0 +1,651 @@
+// Package markdown converts CommonMark to HTML, with the pipe tables of
+// GitHub Flavored Markdown. Raw HTML in the source is passed through, as
+// CommonMark specifies, so the source must be trusted like a template.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Heading is a heading of a converted document.
+type Heading struct {
+	Level int
+	// Text is the heading's plain text.
+	Text string
+	// ID is the id attribute given to the heading element, unique within
+	// the document.
+	ID string
+}
+
+// Document is Markdown converted to HTML.
+type Document struct {
+	HTML string
+	// Headings are the document's headings in order, at every level.
+	Headings []Heading
+}
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	htmlBlock
+	ruleBlock
+	quoteBlock
+	listBlock
+	tableBlock
+)
+
+// block is a parsed block. Paragraphs, headings and table cells keep their
+// raw inline text, which is converted once every link reference definition
+// is known.
+type block struct {
+	kind blockKind
+	// blankBefore is set when a blank line separates the block from the
+	// one before it, which makes the list item holding both loose.
+	blankBefore bool
+
+	level int    // heading level
+	text  string // inline text, code or raw HTML
+	info  string // fenced code info string
+
+	children []*block // blockquote content
+
+	ordered bool
+	start   int
+	tight   bool
+	items   [][]*block
+
+	align  []string
+	header []string
+	rows   [][]string
+}
+
+// maxNesting bounds how deeply lists and blockquotes nest. Each level
+// parses the rest of its line again, so a line of n markers would take time
+// quadratic in n; deeper markers are read as paragraph text instead.
+const maxNesting = 32
+
+type reference struct {
+	dest, title string
+}
+
+type converter struct {
+	refs     map[string]reference
+	ids      map[string]int
+	headings []Heading
+	// linkDepth counts the links being converted around the current text.
+	linkDepth int
+	// depth counts the lists and blockquotes around the blocks being parsed.
+	depth int
+}
+
+// Convert converts source to HTML.
+func Convert(source string) *Document {
+	source = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\x00", "�").Replace(source)
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	c := &converter{refs: make(map[string]reference), ids: make(map[string]int)}
+	blocks := c.parseBlocks(lines)
+	var out strings.Builder
+	c.render(&out, blocks, false)
+	return &Document{HTML: out.String(), Headings: c.headings}
+}
+
+// expandTabs replaces the tabs in a line's indentation with spaces up to
+// the next multiple of four columns.
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			b.WriteByte(' ')
+			col++
+		case '\t':
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		default:
+			return b.String() + line[i:]
+		}
+	}
+	return b.String()
+}
+
+var (
+	atxHeadingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextPattern       = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	rulePattern         = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fencePattern        = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	quotePattern        = regexp.MustCompile(`^ {0,3}> ?`)
+	listMarkerPattern   = regexp.MustCompile(`^( {0,3})([-+*]|[0-9]{1,9}[.)])( *)`)
+	refDefPattern       = regexp.MustCompile(`^ {0,3}\[((?:[^\\\[\]]|\\.){1,999})\]:[ \t]*(<[^<>\n]*>|\S+)(?:[ \t]+("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\)))?[ \t]*$`)
+	delimiterRowPattern = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+
+	htmlBlockStarts = []struct {
+		start, end *regexp.Regexp
+		// interrupts is false for blocks that cannot interrupt a paragraph.
+		interrupts bool
+	}{
+		{regexp.MustCompile(`(?i)^ {0,3}<(?:script|pre|style|textarea)(?:[ \t>]|$)`), regexp.MustCompile(`(?i)</(?:script|pre|style|textarea)>`), true},
+		{regexp.MustCompile(`^ {0,3}<!--`), regexp.MustCompile(`-->`), true},
+		{regexp.MustCompile(`^ {0,3}<\?`), regexp.MustCompile(`\?>`), true},
+		{regexp.MustCompile(`^ {0,3}<![A-Za-z]`), regexp.MustCompile(`>`), true},
+		{regexp.MustCompile(`^ {0,3}<!\[CDATA\[`), regexp.MustCompile(`\]\]>`), true},
+		{regexp.MustCompile(`(?i)^ {0,3}</?(?:address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[1-6]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(?:[ \t>]|/>|$)`), nil, true},
+		{regexp.MustCompile(`^ {0,3}(?:<[A-Za-z][A-Za-z0-9-]*(?:[ \t]+[A-Za-z_:][A-Za-z0-9_.:-]*(?:[ \t]*=[ \t]*(?:[^ \t"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*[ \t]*/?>|</[A-Za-z][A-Za-z0-9-]*[ \t]*>)[ \t]*$`), nil, false},
+	}
+)
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// stripIndent removes up to n leading spaces.
+func stripIndent(line string, n int) string {
+	if i := indentation(line); i < n {
+		n = i
+	}
+	return line[n:]
+}
+
+// listItem describes a list item marker at the start of a line.
+type listItem struct {
+	ordered bool
+	// marker is the bullet character or the ordered list delimiter.
+	marker byte
+	start  int
+	// indent is the column the item's content starts at.
+	indent int
+	// first is the content of the marker's line.
+	first string
+}
+
+func parseListItem(line string) (listItem, bool) {
+	m := listMarkerPattern.FindStringSubmatch(line)
+	if m == nil {
+		return listItem{}, false
+	}
+	// The pattern stops after the marker, so the rest of the line is not
+	// scanned again.
+	m = append(m, line[len(m[0]):])
+	if m[3] == "" && m[4] != "" {
+		return listItem{}, false
+	}
+	item := listItem{marker: m[2][len(m[2])-1]}
+	if c := m[2][0]; c >= '0' && c <= '9' {
+		item.ordered = true
+		item.start, _ = strconv.Atoi(m[2][:len(m[2])-1])
+	}
+	width := len(m[1]) + len(m[2])
+	switch {
+	case m[4] == "":
+		item.indent = width + 1
+	case len(m[3]) > 4:
+		// The content is indented code, which starts one space after
+		// the marker.
+		item.indent = width + 1
+		item.first = m[3][1:] + m[4]
+	default:
+		item.indent = width + len(m[3])
+		item.first = m[4]
+	}
+	return item, true
+}
+
+func isListItem(line string) bool {
+	_, ok := parseListItem(line)
+	return ok
+}
+
+// startsBlock reports whether line starts a block that ends a paragraph.
+func startsBlock(line string) bool {
+	if atxHeadingPattern.MatchString(line) || rulePattern.MatchString(line) ||
+		fencePattern.MatchString(line) || quotePattern.MatchString(line) {
+		return true
+	}
+	for _, h := range htmlBlockStarts {
+		if h.interrupts && h.start.MatchString(line) {
+			return true
+		}
+	}
+	// Only a list item with content, and an ordered one starting at 1, may
+	// interrupt a paragraph.
+	if item, ok := parseListItem(line); ok && !isBlank(item.first) && (!item.ordered || item.start == 1) {
+		return true
+	}
+	return false
+}
+
+// isTableStart reports whether lines[i] is a table header row followed by
+// its delimiter row.
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !delimiterRowPattern.MatchString(lines[i+1]) {
+		return false
+	}
+	return len(splitRow(lines[i])) == len(splitRow(lines[i+1]))
+}
+
+func (c *converter) parseBlocks(lines []string) []*block {
+	c.depth++
+	defer func() { c.depth-- }()
+	var blocks []*block
+	blank := false
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i]) {
+			blank = true
+			i++
+			continue
+		}
+		b, n := c.parseBlock(lines[i:])
+		i += n
+		if b == nil {
+			// Only link reference definitions.
+			continue
+		}
+		b.blankBefore = blank && len(blocks) > 0
+		blank = false
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// parseBlock parses the block starting at lines[0], which is not blank,
+// and returns it with the number of lines it spans.
+func (c *converter) parseBlock(lines []string) (*block, int) {
+	line := lines[0]
+	if indentation(line) >= 4 {
+		return parseIndentedCode(lines)
+	}
+	if m := fencePattern.FindStringSubmatch(line); m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`")) {
+		return parseFencedCode(lines, len(m[1]), m[2], m[3])
+	}
+	if m := atxHeadingPattern.FindStringSubmatch(line); m != nil {
+		return &block{kind: headingBlock, level: len(m[1]), text: strings.TrimSpace(m[2])}, 1
+	}
+	if rulePattern.MatchString(line) {
+		return &block{kind: ruleBlock}, 1
+	}
+	if c.depth <= maxNesting {
+		if quotePattern.MatchString(line) {
+			return c.parseQuote(lines)
+		}
+		if item, ok := parseListItem(line); ok {
+			return c.parseList(lines, item)
+		}
+	}
+	for _, h := range htmlBlockStarts {
+		if h.start.MatchString(line) {
+			return parseHTMLBlock(lines, h.end)
+		}
+	}
+	if isTableStart(lines, 0) {
+		return parseTable(lines)
+	}
+	return c.parseParagraph(lines)
+}
+
+func parseIndentedCode(lines []string) (*block, int) {
+	n := 0
+	var code []string
+	for n < len(lines) && (isBlank(lines[n]) || indentation(lines[n]) >= 4) {
+		code = append(code, stripIndent(lines[n], 4))
+		n++
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+	return &block{kind: codeBlock, text: strings.Join(code, "\n") + "\n"}, n
+}
+
+func parseFencedCode(lines []string, indent int, fence, info string) (*block, int) {
+	closing := regexp.MustCompile(`^ {0,3}` + regexp.QuoteMeta(fence) + regexp.QuoteMeta(fence[:1]) + `*[ \t]*$`)
+	var code []string
+	n := 1
+	for ; n < len(lines); n++ {
+		if closing.MatchString(lines[n]) {
+			n++
+			break
+		}
+		code = append(code, stripIndent(lines[n], indent))
+	}
+	text := strings.Join(code, "\n")
+	if len(code) > 0 {
+		text += "\n"
+	}
+	return &block{kind: codeBlock, text: text, info: unescape(strings.TrimSpace(info))}, n
+}
+
+func parseHTMLBlock(lines []string, end *regexp.Regexp) (*block, int) {
+	n := 0
+	for n < len(lines) {
+		if end == nil && isBlank(lines[n]) {
+			break
+		}
+		n++
+		if end != nil && end.MatchString(lines[n-1]) {
+			break
+		}
+	}
+	return &block{kind: htmlBlock, text: strings.Join(lines[:n], "\n")}, n
+}
+
+func (c *converter) parseQuote(lines []string) (*block, int) {
+	var content []string
+	n := 0
+	for ; n < len(lines); n++ {
+		line := lines[n]
+		if loc := quotePattern.FindStringIndex(line); loc != nil {
+			content = append(content, line[loc[1]:])
+			continue
+		}
+		// A lazy continuation line carries on a paragraph in the quote.
+		if isBlank(line) || len(content) == 0 || isBlank(content[len(content)-1]) || startsBlock(line) {
+			break
+		}
+		content = append(content, line)
+	}
+	return &block{kind: quoteBlock, children: c.parseBlocks(content)}, n
+}
+
+func (c *converter) parseList(lines []string, first listItem) (*block, int) {
+	list := &block{kind: listBlock, ordered: first.ordered, start: first.start, tight: true}
+	item := first
+	n := 0
+	for {
+		content := []string{item.first}
+		n++
+		// An item may start with at most one blank line.
+		if !(isBlank(item.first) && n < len(lines) && isBlank(lines[n])) {
+		collect:
+			for ; n < len(lines); n++ {
+				line := lines[n]
+				switch {
+				case isBlank(line):
+					content = append(content, "")
+				case indentation(line) >= item.indent:
+					content = append(content, line[item.indent:])
+				case !isBlank(content[len(content)-1]) && !startsBlock(line) && !isTableStart(lines, n) && !isListItem(line):
+					// A lazy continuation line.
+					content = append(content, strings.TrimLeft(line, " "))
+				default:
+					break collect
+				}
+			}
+		}
+		trailing := 0
+		for len(content) > 0 && isBlank(content[len(content)-1]) {
+			content = content[:len(content)-1]
+			trailing++
+		}
+		children := c.parseBlocks(content)
+		for _, child := range children {
+			if child.blankBefore {
+				list.tight = false
+			}
+		}
+		list.items = append(list.items, children)
+
+		if n >= len(lines) || rulePattern.MatchString(lines[n]) {
+			break
+		}
+		next, ok := parseListItem(lines[n])
+		if !ok || next.ordered != item.ordered || next.marker != item.marker {
+			break
+		}
+		if trailing > 0 {
+			list.tight = false
+		}
+		item = next
+	}
+	// Blank lines after the last item belong to the enclosing block.
+	for n > 0 && isBlank(lines[n-1]) {
+		n--
+	}
+	return list, n
+}
+
+func parseTable(lines []string) (*block, int) {
+	t := &block{kind: tableBlock, header: splitRow(lines[0])}
+	for _, cell := range splitRow(lines[1]) {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			t.align = append(t.align, "center")
+		case left:
+			t.align = append(t.align, "left")
+		case right:
+			t.align = append(t.align, "right")
+		default:
+			t.align = append(t.align, "")
+		}
+	}
+	n := 2
+	for ; n < len(lines) && !isBlank(lines[n]) && !startsBlock(lines[n]); n++ {
+		cells := splitRow(lines[n])
+		row := make([]string, len(t.header))
+		copy(row, cells)
+		t.rows = append(t.rows, row)
+	}
+	return t, n
+}
+
+// splitRow splits a table row into its trimmed cells at the pipes not
+// escaped with a backslash.
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func (c *converter) parseParagraph(lines []string) (*block, int) {
+	text := []string{strings.TrimLeft(lines[0], " ")}
+	n := 1
+	level := 0
+	for ; n < len(lines); n++ {
+		line := lines[n]
+		if m := setextPattern.FindStringSubmatch(line); m != nil {
+			level = 2
+			if m[1][0] == '=' {
+				level = 1
+			}
+			n++
+			break
+		}
+		if isBlank(line) || startsBlock(line) || isTableStart(lines, n) {
+			break
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+
+	// Link reference definitions open the paragraph.
+	for len(text) > 0 {
+		m := refDefPattern.FindStringSubmatch(text[0])
+		if m == nil {
+			break
+		}
+		label := normalizeLabel(m[1])
+		if _, ok := c.refs[label]; !ok && label != "" {
+			dest := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+			title := ""
+			if m[3] != "" {
+				title = unescape(m[3][1 : len(m[3])-1])
+			}
+			c.refs[label] = reference{dest: unescape(dest), title: title}
+		}
+		text = text[1:]
+	}
+	if len(text) == 0 {
+		if level > 0 {
+			// The underline is a paragraph of its own, or a rule.
+			return c.parseParagraph(lines[n-1:])
+		}
+		return nil, n
+	}
+
+	joined := strings.TrimRight(strings.Join(text, "\n"), " \t")
+	if level > 0 {
+		return &block{kind: headingBlock, level: level, text: joined}, n
+	}
+	return &block{kind: paragraphBlock, text: joined}, n
+}
+
+// normalizeLabel folds a link label for matching: case is ignored and runs
+// of whitespace count as one space.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func (c *converter) render(out *strings.Builder, blocks []*block, tight bool) {
+	for _, b := range blocks {
+		switch b.kind {
+		case paragraphBlock:
+			if tight {
+				out.WriteString(c.inline(b.text) + "\n")
+			} else {
+				out.WriteString("<p>" + c.inline(b.text) + "</p>\n")
+			}
+		case headingBlock:
+			content := c.inline(b.text)
+			text := plainText(content)
+			id := c.headingID(text)
+			c.headings = append(c.headings, Heading{Level: b.level, Text: text, ID: id})
+			tag := "h" + strconv.Itoa(b.level)
+			out.WriteString("<" + tag + ` id="` + escapeHTML(id) + `">` + content + "</" + tag + ">\n")
+		case codeBlock:
+			out.WriteString("<pre><code")
+			if lang, _, _ := strings.Cut(b.info, " "); lang != "" {
+				out.WriteString(` class="language-` + escapeHTML(lang) + `"`)
+			}
+			out.WriteString(">" + escapeHTML(b.text) + "</code></pre>\n")
+		case htmlBlock:
+			out.WriteString(b.text + "\n")
+		case ruleBlock:
+			out.WriteString("<hr />\n")
+		case quoteBlock:
+			out.WriteString("<blockquote>\n")
+			c.render(out, b.children, false)
+			out.WriteString("</blockquote>\n")
+		case listBlock:
+			c.renderList(out, b)
+		case tableBlock:
+			c.renderTable(out, b)
+		}
+	}
+}
+
+func (c *converter) renderList(out *strings.Builder, b *block) {
+	tag := "ul"
+	if b.ordered {
+		tag = "ol"
+	}
+	out.WriteString("<" + tag)
+	if b.ordered && b.start != 1 {
+		out.WriteString(` start="` + strconv.Itoa(b.start) + `"`)
+	}
+	out.WriteString(">\n")
+	for _, item := range b.items {
+		out.WriteString("<li>")
+		if len(item) > 0 && !(b.tight && item[0].kind == paragraphBlock) {
+			out.WriteString("\n")
+		}
+		var content strings.Builder
+		c.render(&content, item, b.tight)
+		text := content.String()
+		// In tight lists the last paragraph runs up to the closing tag.
+		if b.tight && len(item) > 0 && item[len(item)-1].kind == paragraphBlock {
+			text = strings.TrimSuffix(text, "\n")
+		}
+		out.WriteString(text + "</li>\n")
+	}
+	out.WriteString("</" + tag + ">\n")
+}
+
+func (c *converter) renderTable(out *strings.Builder, b *block) {
+	row := func(cells []string, tag string) {
+		out.WriteString("<tr>\n")
+		for i, cell := range cells {
+			out.WriteString("<" + tag)
+			if b.align[i] != "" {
+				out.WriteString(` align="` + b.align[i] + `"`)
+			}
+			out.WriteString(">" + c.inline(cell) + "</" + tag + ">\n")
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("<table>\n<thead>\n")
+	row(b.header, "th")
+	out.WriteString("</thead>\n")
+	if len(b.rows) > 0 {
+		out.WriteString("<tbody>\n")
+		for _, cells := range b.rows {
+			row(cells, "td")
+		}
+		out.WriteString("</tbody>\n")
+	}
+	out.WriteString("</table>\n")
+}
+
+// headingID derives a unique id from a heading's text: lower-case letters
+// and digits, with spaces turned into hyphens.
+func (c *converter) headingID(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+	n := c.ids[id]
+	c.ids[id] = n + 1
+	if n > 0 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// plainText returns the text of converted inline content.
+func plainText(content string) string {
+	return html.UnescapeString(tagPattern.ReplaceAllString(content, ""))
+}
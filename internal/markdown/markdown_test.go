@@ -0,0 +1,159 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "paragraph",
+			markdown: "Hello *world* and **bold** text with `code` and a [link](http://x.com \"T\").\n",
+			want:     "<p>Hello <em>world</em> and <strong>bold</strong> text with <code>code</code> and a <a href=\"http://x.com\" title=\"T\">link</a>.</p>\n",
+		},
+		{
+			name:     "lists",
+			markdown: "- a\n- b\n  - c\n\n1. x\n2. y\n",
+			want:     "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n<ol>\n<li>x</li>\n<li>y</li>\n</ol>\n",
+		},
+		{
+			name:     "loose list",
+			markdown: "- a\n\n- b\n",
+			want:     "<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>\n",
+		},
+		{
+			name:     "ordered list start",
+			markdown: "3) c\n4) d\n",
+			want:     "<ol start=\"3\">\n<li>c</li>\n<li>d</li>\n</ol>\n",
+		},
+		{
+			name:     "quote and code",
+			markdown: "> quote\nlazy\n\n```go\nfunc main() {}\n```\n\n    indented\n",
+			want:     "<blockquote>\n<p>quote\nlazy</p>\n</blockquote>\n<pre><code class=\"language-go\">func main() {}\n</code></pre>\n<pre><code>indented\n</code></pre>\n",
+		},
+		{
+			name:     "table",
+			markdown: "| Name | Amount |\n|:-----|-------:|\n| Tea | 1,000 |\n| Cake \\| pie | 2 |\n",
+			want:     "<table>\n<thead>\n<tr>\n<th align=\"left\">Name</th>\n<th align=\"right\">Amount</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">Tea</td>\n<td align=\"right\">1,000</td>\n</tr>\n<tr>\n<td align=\"left\">Cake | pie</td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "references",
+			markdown: "[ref]: /url \"Title\"\n\nSee [ref] and [text][ref] and ![img](a.png).\n",
+			want:     "<p>See <a href=\"/url\" title=\"Title\">ref</a> and <a href=\"/url\" title=\"Title\">text</a> and <img src=\"a.png\" alt=\"img\" />.</p>\n",
+		},
+		{
+			name:     "emphasis",
+			markdown: "***strong emph*** and *a **b** c* and _x_y_ and foo*bar*\n",
+			want:     "<p><em><strong>strong emph</strong></em> and <em>a <strong>b</strong> c</em> and <em>x_y</em> and foo<em>bar</em></p>\n",
+		},
+		{
+			name:     "line breaks",
+			markdown: "line one  \nline two\\\nthree\n",
+			want:     "<p>line one<br />\nline two<br />\nthree</p>\n",
+		},
+		{
+			name:     "html",
+			markdown: "<div>\n*raw*\n</div>\n\nA & B <b>x</b> &copy; <http://a.com>\n",
+			want:     "<div>\n*raw*\n</div>\n<p>A &amp; B <b>x</b> &copy; <a href=\"http://a.com\">http://a.com</a></p>\n",
+		},
+		{
+			name:     "nested links",
+			markdown: "[x]: /ref\n\n![a [b](/u) *c*](/i) [[x]](/v) **[x] _y_**\n",
+			want:     "<p><img src=\"/i\" alt=\"a b c\" /> <a href=\"/v\"><a href=\"/ref\">x</a></a> <strong><a href=\"/ref\">x</a> <em>y</em></strong></p>\n",
+		},
+		{
+			name:     "escaped",
+			markdown: "\\*not emphasis\\* and 1 < 2\n",
+			want:     "<p>*not emphasis* and 1 &lt; 2</p>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Convert(tt.markdown).HTML)
+		})
+	}
+}
+
+func TestConvert_Headings(t *testing.T) {
+	doc := Convert("Setext *title*\n===\n\n## Dup\n## Dup\n### فارسی عنوان\n")
+
+	assert.Equal(t, "<h1 id=\"setext-title\">Setext <em>title</em></h1>\n"+
+		"<h2 id=\"dup\">Dup</h2>\n"+
+		"<h2 id=\"dup-1\">Dup</h2>\n"+
+		"<h3 id=\"فارسی-عنوان\">فارسی عنوان</h3>\n", doc.HTML)
+	assert.Equal(t, []Heading{
+		{Level: 1, Text: "Setext title", ID: "setext-title"},
+		{Level: 2, Text: "Dup", ID: "dup"},
+		{Level: 2, Text: "Dup", ID: "dup-1"},
+		{Level: 3, Text: "فارسی عنوان", ID: "فارسی-عنوان"},
+	}, doc.Headings)
+}
+
+// pathologicalInputs are inlines that took time quadratic in their length
+// when every [, ( or delimiter run rescanned the rest of the text.
+func pathologicalInputs(n int) map[string]string {
+	return map[string]string{
+		"unclosed brackets":   strings.Repeat("![", n),
+		"nested links":        strings.Repeat("[", n) + "a" + strings.Repeat("](u)", n),
+		"nested labels":       "[a]: /u\n\n" + strings.Repeat("[", n) + "a" + strings.Repeat("]", n),
+		"reference labels":    strings.Repeat("[a][", n),
+		"unclosed parens":     strings.Repeat("[](a()", n),
+		"unclosed angle":      strings.Repeat("[](<", n),
+		"unclosed code spans": strings.Repeat("[ ``", n),
+		"emphasis":            strings.Repeat("*a_ ", n),
+		"hard breaks":         strings.Repeat("a  \n", n),
+	}
+}
+
+func TestConvert_PathologicalInput(t *testing.T) {
+	for name, markdown := range pathologicalInputs(50000) {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			Convert(markdown)
+			// Linear time is a few milliseconds; quadratic is minutes.
+			assert.Less(t, time.Since(start), 2*time.Second)
+		})
+	}
+}
+
+// nestedBlocks are lines of list and blockquote markers, each nesting the
+// rest of the line one level deeper.
+func nestedBlocks(n int) map[string]string {
+	return map[string]string{
+		"nested lists":    strings.Repeat("- ", n) + "a",
+		"ordered lists":   strings.Repeat("1. ", n) + "a",
+		"nested quotes":   strings.Repeat("> ", n) + "a",
+		"lists in quotes": strings.Repeat("> - ", n) + "a",
+	}
+}
+
+func TestConvert_DeeplyNestedBlocks(t *testing.T) {
+	for name, markdown := range nestedBlocks(50000) {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			html := Convert(markdown).HTML
+			assert.Less(t, time.Since(start), 2*time.Second)
+			// Markers past the nesting limit are kept as text.
+			assert.LessOrEqual(t, strings.Count(html, "<li>")+strings.Count(html, "<blockquote>"), maxNesting)
+			assert.True(t, strings.HasSuffix(strings.TrimSpace(plainText(html)), "a"))
+		})
+	}
+}
+
+func BenchmarkConvert_Pathological(b *testing.B) {
+	for name, markdown := range pathologicalInputs(10000) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Convert(markdown)
+			}
+		})
+	}
+}
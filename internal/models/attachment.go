@@ -0,0 +1,15 @@
+package models
+
+// AttachmentSourceData selects the request's JSON data as the attachment
+// content.
+const AttachmentSourceData = "data"
+
+type Attachment struct {
+	// Source is AttachmentSourceData or the name of a multipart file field.
+	Source       string `json:"source"`
+	Name         string `json:"name"`
+	MIMEType     string `json:"mime_type"`
+	Description  string `json:"description"`
+	Relationship string `json:"relationship"`
+	Data         []byte `json:"-"`
+}
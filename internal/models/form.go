@@ -0,0 +1,21 @@
+package models
+
+// FillRequest is an existing PDF form to fill with data.
+type FillRequest struct {
+	PDF  []byte                 `json:"-"`
+	Data map[string]interface{} `json:"data"`
+	// Flatten turns the filled fields into ordinary page content.
+	Flatten bool `json:"flatten"`
+}
+
+// FormField describes a field of a PDF form.
+type FormField struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Value     string   `json:"value,omitempty"`
+	Options   []string `json:"options,omitempty"`
+	ReadOnly  bool     `json:"read_only"`
+	Required  bool     `json:"required"`
+	Multiline bool     `json:"multiline,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+}
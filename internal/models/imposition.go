@@ -0,0 +1,17 @@
+package models
+
+// Imposition arranges generated pages on printing sheets: n-up, booklet or
+// label grids.
+type Imposition struct {
+	Layout        string  `json:"layout"`
+	PagesPerSheet int     `json:"pages_per_sheet"`
+	Columns       int     `json:"columns"`
+	Rows          int     `json:"rows"`
+	Sheet         string  `json:"sheet"`
+	Orientation   string  `json:"orientation"`
+	Margin        float64 `json:"margin"`
+	Gutter        float64 `json:"gutter"`
+	CropMarks     bool    `json:"crop_marks"`
+	Repeat        bool    `json:"repeat"`
+	RightToLeft   bool    `json:"right_to_left"`
+}
@@ -0,0 +1,52 @@
+package models
+
+// PDFInfo describes an existing PDF.
+type PDFInfo struct {
+	Version     string            `json:"version"`
+	PageCount   int               `json:"page_count"`
+	Pages       []PageInfo        `json:"pages"`
+	Fonts       []FontInfo        `json:"fonts"`
+	Metadata    map[string]string `json:"metadata"`
+	Encrypted   bool              `json:"encrypted"`
+	Tagged      bool              `json:"tagged"`
+	Attachments []AttachmentInfo  `json:"attachments"`
+}
+
+// PageInfo is the displayed size of a page in points.
+type PageInfo struct {
+	Number int     `json:"number"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Rotate int     `json:"rotate,omitempty"`
+	// Paper names a standard size such as A4.
+	Paper string `json:"paper,omitempty"`
+}
+
+// FontInfo describes a font used by a PDF.
+type FontInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Embedded bool   `json:"embedded"`
+	Subset   bool   `json:"subset"`
+}
+
+// AttachmentInfo describes a file embedded in a PDF.
+type AttachmentInfo struct {
+	Name         string `json:"name"`
+	MIMEType     string `json:"mime_type,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Relationship string `json:"relationship,omitempty"`
+	Size         int    `json:"size"`
+}
+
+// TextResult is the text extracted from a PDF.
+type TextResult struct {
+	PageCount int        `json:"page_count"`
+	Pages     []PageText `json:"pages"`
+}
+
+// PageText is the text of one page, line by line in reading order.
+type PageText struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
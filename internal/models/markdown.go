@@ -0,0 +1,21 @@
+package models
+
+// MarkdownRequest asks for a Markdown document to be converted to HTML
+// inside a layout and printed to PDF.
+type MarkdownRequest struct {
+	// Markdown is the document, optionally starting with YAML front
+	// matter whose values are passed to the layout.
+	Markdown string `json:"markdown"`
+	// Data is passed to the layout along with the front matter, taking
+	// precedence over it.
+	Data map[string]interface{} `json:"data,omitempty"`
+	// Layout is the ID of a registered template to place the converted
+	// HTML in. Empty uses the built-in layout.
+	Layout string `json:"layout,omitempty"`
+	// Stylesheet is CSS applied after the layout's default styles.
+	Stylesheet string `json:"stylesheet,omitempty"`
+
+	// PDF holds the options of the PDF pipeline, such as a watermark or
+	// PDF/A conformance. Its template and data are set from the Markdown.
+	PDF PDFRequest `json:"-"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type Optimization struct {
+	MaxImageDPI float64 `json:"max_image_dpi"`
+	JPEGQuality int     `json:"jpeg_quality"`
+}
+
+// OptimizationReport compares the document before and after optimization.
+type OptimizationReport struct {
+	OriginalSize  int `json:"original_size"`
+	OptimizedSize int `json:"optimized_size"`
+}
@@ -1,6 +1,38 @@
 package models
 
+import (
+	"html/template"
+	"pdf-service/internal/jsonschema"
+)
+
 type PDFRequest struct {
 	HTMLTemplate string                 `json:"html_template"`
 	Data         map[string]interface{} `json:"data"`
-}
\ No newline at end of file
+	Watermark    *Watermark             `json:"watermark,omitempty"`
+	PDFA         string                 `json:"pdfa,omitempty"`
+	Attachments  []Attachment           `json:"attachments,omitempty"`
+	Optimize     *Optimization          `json:"optimize,omitempty"`
+	Linearize    bool                   `json:"linearize,omitempty"`
+	Impose       *Imposition            `json:"impose,omitempty"`
+	Print        *PrintMarks            `json:"print,omitempty"`
+	// Strict makes keys missing from Data an error instead of rendering
+	// them as empty text.
+	Strict bool `json:"strict,omitempty"`
+	// AssetBaseURL, when rendering to HTML, is the absolute URL relative
+	// asset references are rewritten against, so the page can be opened
+	// on its own.
+	AssetBaseURL string `json:"asset_base_url,omitempty"`
+	// Outline builds the PDF's bookmarks from the HTML headings.
+	Outline bool `json:"outline,omitempty"`
+	// Sign signs the finished document, with a timestamp when the server
+	// has a timestamp authority configured.
+	Sign *Signature `json:"sign,omitempty"`
+
+	// Template is an already parsed template, used instead of HTMLTemplate
+	// for templates registered on the server.
+	Template *template.Template `json:"-"`
+	// Metadata are entries added to the document information dictionary.
+	Metadata map[string]string `json:"-"`
+	// Schema, when set, is checked against Data before rendering.
+	Schema *jsonschema.Schema `json:"-"`
+}
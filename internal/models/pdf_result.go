@@ -0,0 +1,32 @@
+package models
+
+// PDFResult is a generated document together with what is known about it.
+type PDFResult struct {
+	Content      []byte              `json:"-"`
+	Pages        []PageInfo          `json:"pages,omitempty"`
+	Conformance  *ConformanceReport  `json:"conformance,omitempty"`
+	Optimization *OptimizationReport `json:"optimization,omitempty"`
+	// UnmatchedFields are data keys that named no field of a filled form.
+	UnmatchedFields []string `json:"unmatched_fields,omitempty"`
+	// Template is the registered template version the document was
+	// rendered from.
+	Template *TemplateVersion `json:"template,omitempty"`
+	// DocumentID names the stored copy of a linearized document, which
+	// can be fetched in parts from /documents/{id}.
+	DocumentID string `json:"document_id,omitempty"`
+}
+
+// ConformanceReport records the outcome of a PDF/A conversion.
+type ConformanceReport struct {
+	Level      string   `json:"level"`
+	Compliant  bool     `json:"compliant"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// HTMLResult is a template rendered with its data, before conversion to
+// PDF.
+type HTMLResult struct {
+	HTML string `json:"-"`
+	// Template is the registered template version rendered.
+	Template *TemplateVersion `json:"template,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// PrintMarks prepares a document for commercial printing: the page grows by
+// the bleed on every side and gets trim and bleed boxes, with optional crop
+// and registration marks around it. Sizes are in points.
+type PrintMarks struct {
+	Bleed             float64 `json:"bleed"`
+	CropMarks         bool    `json:"crop_marks"`
+	RegistrationMarks bool    `json:"registration_marks"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// RasterRequest asks for PNG images of pages of an existing PDF.
+type RasterRequest struct {
+	PDF []byte `json:"-"`
+	// Pages is a page range such as "1-3"; only the first page when empty.
+	Pages string `json:"pages"`
+	// Width is the image width in pixels; the height follows the page.
+	Width int `json:"width"`
+}
+
+// PageImage is a PNG image of one page.
+type PageImage struct {
+	Number  int    `json:"number"`
+	Content []byte `json:"-"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// Signature asks for the document to be signed with the server's signing
+// certificate. The fields are shown by viewers with the signature.
+type Signature struct {
+	Name        string `json:"name"`
+	Reason      string `json:"reason"`
+	Location    string `json:"location"`
+	ContactInfo string `json:"contact_info"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// StoredDocument is a generated document kept to be fetched by ID.
+type StoredDocument struct {
+	ID      string
+	Content []byte
+	// ETag identifies the content, so that range requests can check with
+	// If-Range that they still address the same document.
+	ETag    string
+	Created time.Time
+}
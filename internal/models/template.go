@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// TemplateInfo describes a template registered on the server.
+type TemplateInfo struct {
+	ID string `json:"id"`
+	// LatestVersion is the version rendered unless another is pinned.
+	LatestVersion int `json:"latest_version"`
+	// Size is the length of the latest version's source in bytes.
+	Size      int       `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateVersion describes one immutable version of a template.
+type TemplateVersion struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+	Size    int    `json:"size"`
+	// SHA256 is the hex digest of the source.
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+	// RestoredFrom is the version a rollback copied.
+	RestoredFrom int `json:"restored_from,omitempty"`
+	// Partials maps the name of each partial to the version of it this
+	// version was parsed with.
+	Partials map[string]int `json:"partials,omitempty"`
+	// Pinned versions are kept however many versions are added after them.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// PartialInfo describes a partial or layout that registered templates can
+// include with {{template}} or extend with {{block}}.
+type PartialInfo struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Size      int       `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateFields describes the data a template reads and the functions it
+// calls, so callers know which JSON to send.
+type TemplateFields struct {
+	Fields []TemplateField `json:"fields"`
+	// Functions are the template functions and builtins called, sorted.
+	Functions []string `json:"functions"`
+}
+
+// TemplateField is a data path read by a template. Path separates keys with
+// periods and marks the elements of a ranged-over list with [], as in
+// items[].price.
+type TemplateField struct {
+	Path string `json:"path"`
+	// Type is "list" for values ranged over, "object" for values whose
+	// keys are read and "value" otherwise.
+	Type string `json:"type"`
+}
+
+// SchemaViolation is a part of the data that does not match a template's
+// JSON Schema. Pointer is its JSON pointer, "" for the whole document.
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// TemplateError locates an error in a template. Kind is "parse" for a
+// template that is malformed, or that html/template cannot escape, and
+// "execute" for one that fails while rendering the data sent.
+type TemplateError struct {
+	Kind     string `json:"kind"`
+	Template string `json:"template"`
+	// Line and Column are 1-based; Column is only known for execution
+	// errors and escaping errors.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Source is the template line the error is on.
+	Source string `json:"source,omitempty"`
+	// Field is the data path of the failing action as written in the
+	// template, such as .customer.name.
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// TemplateCacheStats counts the use of the cache of parsed uploaded
+// templates since the service started.
+type TemplateCacheStats struct {
+	Entries    int `json:"entries"`
+	MaxEntries int `json:"max_entries"`
+	// Bytes is the total size of the cached template sources.
+	Bytes     int64 `json:"bytes"`
+	MaxBytes  int64 `json:"max_bytes"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type Watermark struct {
+	Text       string  `json:"text"`
+	FontSize   float64 `json:"font_size"`
+	Color      string  `json:"color"`
+	Opacity    float64 `json:"opacity"`
+	Rotation   float64 `json:"rotation"`
+	Position   string  `json:"position"`
+	Pages      string  `json:"pages"`
+	ImageWidth float64 `json:"image_width"`
+	Image      []byte  `json:"-"`
+}
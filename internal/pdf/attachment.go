@@ -0,0 +1,193 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// afRelationships are the values of a file specification's AFRelationship
+// entry, keyed by their lower case spelling.
+var afRelationships = map[string]Name{
+	"source":      "Source",
+	"data":        "Data",
+	"alternative": "Alternative",
+	"supplement":  "Supplement",
+	"unspecified": "Unspecified",
+}
+
+// Attachment is a file embedded in the document.
+type Attachment struct {
+	// Name is the file name shown by viewers; it must be unique.
+	Name string
+	Data []byte
+	// MIMEType defaults to one derived from the name's extension.
+	MIMEType    string
+	Description string
+	// Relationship is Source, Data, Alternative, Supplement or
+	// Unspecified (the default), as used by PDF/A-3 associated files.
+	Relationship string
+	// ModTime defaults to the time of embedding.
+	ModTime time.Time
+}
+
+func (a *Attachment) normalize() error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAttachment)
+	}
+	if a.MIMEType == "" {
+		a.MIMEType = mime.TypeByExtension(path.Ext(a.Name))
+	}
+	if a.MIMEType == "" {
+		a.MIMEType = "application/octet-stream"
+	}
+	mediaType, _, err := mime.ParseMediaType(a.MIMEType)
+	if err != nil {
+		return fmt.Errorf("%w: %s: bad MIME type %q", ErrInvalidAttachment, a.Name, a.MIMEType)
+	}
+	a.MIMEType = mediaType
+	if a.Relationship == "" {
+		a.Relationship = "unspecified"
+	}
+	if _, ok := afRelationships[strings.ToLower(a.Relationship)]; !ok {
+		return fmt.Errorf("%w: %s: unknown relationship %q", ErrInvalidAttachment, a.Name, a.Relationship)
+	}
+	if a.ModTime.IsZero() {
+		a.ModTime = time.Now()
+	}
+	return nil
+}
+
+// AddAttachments embeds files in the document. Each is added to the
+// EmbeddedFiles name tree and, with its relationship, to the catalog's AF
+// array of associated files.
+func (d *Document) AddAttachments(attachments ...Attachment) error {
+	existing := map[string]Object{}
+	catalog := d.Catalog()
+	names := d.GetDict(catalog["Names"])
+	if names != nil {
+		d.walkNameTree(names["EmbeddedFiles"], func(key string, value Object) {
+			existing[key] = value
+		})
+	}
+
+	for i := range attachments {
+		if err := attachments[i].normalize(); err != nil {
+			return err
+		}
+		if _, dup := existing[attachments[i].Name]; dup {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidAttachment, attachments[i].Name)
+		}
+		existing[attachments[i].Name] = nil
+	}
+
+	af := d.GetArray(catalog["AF"])
+	for _, a := range attachments {
+		sum := md5.Sum(a.Data)
+		file := NewStream(Dict{
+			"Type":    Name("EmbeddedFile"),
+			"Subtype": Name(a.MIMEType),
+			"Params": Dict{
+				"Size":     len(a.Data),
+				"ModDate":  FormatDate(a.ModTime),
+				"CheckSum": String(sum[:]),
+			},
+		}, a.Data)
+		fileRef := d.Add(file)
+		spec := Dict{
+			"Type":           Name("Filespec"),
+			"F":              asciiFileName(a.Name),
+			"UF":             EncodeTextString(a.Name),
+			"EF":             Dict{"F": fileRef, "UF": fileRef},
+			"AFRelationship": afRelationships[strings.ToLower(a.Relationship)],
+		}
+		if a.Description != "" {
+			spec["Desc"] = EncodeTextString(a.Description)
+		}
+		ref := d.Add(spec)
+		existing[a.Name] = ref
+		af = append(af, ref)
+	}
+	catalog["AF"] = af
+
+	// Rebuild the tree as a single leaf; keys must be sorted by their
+	// encoded bytes.
+	type entry struct {
+		key   String
+		value Object
+	}
+	entries := make([]entry, 0, len(existing))
+	for k, v := range existing {
+		entries = append(entries, entry{EncodeTextString(k), v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+	pairs := make(Array, 0, 2*len(entries))
+	for _, e := range entries {
+		pairs = append(pairs, e.key, e.value)
+	}
+
+	if names == nil {
+		names = Dict{}
+		catalog["Names"] = names
+	}
+	names["EmbeddedFiles"] = d.Add(Dict{"Names": pairs})
+	return nil
+}
+
+// asciiFileName returns the portable form of name used for the F entry,
+// replacing characters outside printable ASCII.
+func asciiFileName(name string) String {
+	var b strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e {
+			b.WriteByte('_')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return String(b.String())
+}
+
+// Attachments returns the files embedded in the document's EmbeddedFiles
+// name tree.
+func (d *Document) Attachments() ([]Attachment, error) {
+	var out []Attachment
+	var firstErr error
+	for _, o := range d.embeddedFileSpecs() {
+		spec := d.GetDict(o)
+		a := Attachment{Name: DecodeTextString(d.GetString(spec["UF"]))}
+		if a.Name == "" {
+			a.Name = DecodeTextString(d.GetString(spec["F"]))
+		}
+		a.Description = DecodeTextString(d.GetString(spec["Desc"]))
+		a.Relationship = string(d.GetName(spec["AFRelationship"]))
+		ef := d.GetDict(spec["EF"])
+		file := d.GetStream(ef["UF"])
+		if file == nil {
+			file = d.GetStream(ef["F"])
+		}
+		if file == nil {
+			continue
+		}
+		a.MIMEType = string(d.GetName(file.Dict["Subtype"]))
+		if params := d.GetDict(file.Dict["Params"]); params != nil {
+			a.ModTime, _ = ParseDate(string(d.GetString(params["ModDate"])))
+		}
+		data, err := file.Decode()
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("attachment %s: %w", a.Name, err)
+		}
+		a.Data = data
+		out = append(out, a)
+	}
+	return out, firstErr
+}
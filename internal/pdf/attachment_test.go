@@ -0,0 +1,82 @@
+package pdf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAttachments_RoundTrip(t *testing.T) {
+	d := newTestDocument(t, 1)
+	modTime := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	err := d.AddAttachments(
+		Attachment{Name: "data.json", Data: []byte(`{"a":1}`), Description: "Source data", Relationship: "source", ModTime: modTime},
+		Attachment{Name: "فاکتور.xml", Data: []byte("<Invoice/>"), Relationship: "Alternative"},
+	)
+	require.NoError(t, err)
+
+	d = roundTrip(t, d)
+	attachments, err := d.Attachments()
+	require.NoError(t, err)
+	require.Len(t, attachments, 2)
+
+	// Keys are kept in sorted order, so the ASCII name comes first.
+	json := attachments[0]
+	assert.Equal(t, "data.json", json.Name)
+	assert.Equal(t, `{"a":1}`, string(json.Data))
+	assert.Equal(t, "application/json", json.MIMEType)
+	assert.Equal(t, "Source data", json.Description)
+	assert.Equal(t, "Source", json.Relationship)
+	assert.True(t, modTime.Equal(json.ModTime))
+
+	xml := attachments[1]
+	assert.Equal(t, "فاکتور.xml", xml.Name)
+	assert.Equal(t, "Alternative", xml.Relationship)
+	assert.Contains(t, xml.MIMEType, "xml")
+
+	assert.Len(t, d.GetArray(d.Catalog()["AF"]), 2)
+}
+
+func TestAddAttachments_KeepsExistingFiles(t *testing.T) {
+	d := newTestDocument(t, 1)
+	require.NoError(t, d.AddAttachments(Attachment{Name: "b.txt", Data: []byte("b")}))
+	require.NoError(t, d.AddAttachments(Attachment{Name: "a.txt", Data: []byte("a")}))
+
+	attachments, err := d.Attachments()
+	require.NoError(t, err)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "a.txt", attachments[0].Name)
+	assert.Equal(t, "b.txt", attachments[1].Name)
+	assert.Equal(t, "Unspecified", attachments[0].Relationship)
+}
+
+func TestAddAttachments_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		attachments []Attachment
+		want        string
+	}{
+		{"missing name", []Attachment{{Data: []byte("x")}}, "name is required"},
+		{"duplicate", []Attachment{{Name: "a.txt"}, {Name: "a.txt"}}, "duplicate name"},
+		{"relationship", []Attachment{{Name: "a.txt", Relationship: "parent"}}, "unknown relationship"},
+		{"mime type", []Attachment{{Name: "a.txt", MIMEType: "not a type"}}, "bad MIME type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestDocument(t, 1).AddAttachments(tt.attachments...)
+			assert.ErrorIs(t, err, ErrInvalidAttachment)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestAddAttachments_PDFA3(t *testing.T) {
+	d := newEmbeddedFontDocument(t)
+	require.NoError(t, d.AddAttachments(Attachment{Name: "data.json", Data: []byte("{}"), Relationship: "Source"}))
+
+	report, err := d.ConvertToPDFA(PDFALevel{Part: 3, Conformance: "B"})
+	require.NoError(t, err)
+	assert.True(t, report.Compliant(), "%v", report.Violations)
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import "strings"
+
+// presentationForms lists the Arabic Presentation Forms-B block from U+FE70
+// as runs of contextual forms of one base text.
+var presentationForms = []struct {
+	first rune
+	count int
+	base  string
+}{
+	{0xFE70, 1, "ً"}, {0xFE72, 1, "ٌ"}, {0xFE74, 1, "ٍ"},
+	{0xFE76, 2, "َ"}, {0xFE78, 2, "ُ"}, {0xFE7A, 2, "ِ"},
+	{0xFE7C, 2, "ّ"}, {0xFE7E, 2, "ْ"},
+	{0xFE80, 1, "ء"}, {0xFE81, 2, "آ"}, {0xFE83, 2, "أ"}, {0xFE85, 2, "ؤ"},
+	{0xFE87, 2, "إ"}, {0xFE89, 4, "ئ"}, {0xFE8D, 2, "ا"}, {0xFE8F, 4, "ب"},
+	{0xFE93, 2, "ة"}, {0xFE95, 4, "ت"}, {0xFE99, 4, "ث"}, {0xFE9D, 4, "ج"},
+	{0xFEA1, 4, "ح"}, {0xFEA5, 4, "خ"}, {0xFEA9, 2, "د"}, {0xFEAB, 2, "ذ"},
+	{0xFEAD, 2, "ر"}, {0xFEAF, 2, "ز"}, {0xFEB1, 4, "س"}, {0xFEB5, 4, "ش"},
+	{0xFEB9, 4, "ص"}, {0xFEBD, 4, "ض"}, {0xFEC1, 4, "ط"}, {0xFEC5, 4, "ظ"},
+	{0xFEC9, 4, "ع"}, {0xFECD, 4, "غ"}, {0xFED1, 4, "ف"}, {0xFED5, 4, "ق"},
+	{0xFED9, 4, "ك"}, {0xFEDD, 4, "ل"}, {0xFEE1, 4, "م"}, {0xFEE5, 4, "ن"},
+	{0xFEE9, 4, "ه"}, {0xFEED, 2, "و"}, {0xFEEF, 2, "ى"}, {0xFEF1, 4, "ي"},
+	{0xFEF5, 2, "لآ"}, {0xFEF7, 2, "لأ"}, {0xFEF9, 2, "لإ"}, {0xFEFB, 2, "لا"},
+	// Persian letters from Arabic Presentation Forms-A.
+	{0xFB56, 4, "پ"}, {0xFB7A, 4, "چ"}, {0xFB8A, 2, "ژ"}, {0xFB8E, 4, "ک"},
+	{0xFB92, 4, "گ"}, {0xFBFC, 4, "ی"},
+}
+
+var presentationBase = func() map[rune]string {
+	m := map[rune]string{}
+	for _, f := range presentationForms {
+		for i := 0; i < f.count; i++ {
+			m[f.first+rune(i)] = f.base
+		}
+	}
+	return m
+}()
+
+// normalizeArabic replaces contextual presentation forms, which some fonts
+// map their shaped glyphs to, with the letters they represent.
+func normalizeArabic(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= 0xFB50 && r <= 0xFEFF }) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if base, ok := presentationBase[r]; ok {
+			b.WriteString(base)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// direction classes used to restore logical order.
+const (
+	dirNeutral = iota
+	dirLTR
+	dirRTL
+)
+
+func runeDirection(r rune) int {
+	switch {
+	case r >= '0' && r <= '9', r >= 0x0660 && r <= 0x0669, r >= 0x06F0 && r <= 0x06F9:
+		// Digits keep their left-to-right order in either direction.
+		return dirLTR
+	case r >= 0x0590 && r <= 0x08FF, r >= 0xFB1D && r <= 0xFDFF, r >= 0xFE70 && r <= 0xFEFF:
+		return dirRTL
+	case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= 0xC0 && r < 0x0590:
+		return dirLTR
+	}
+	return dirNeutral
+}
+
+// clusterDirection returns the direction of the first strong character of
+// a cluster, the text of one glyph.
+func clusterDirection(s string) int {
+	for _, r := range s {
+		if d := runeDirection(r); d != dirNeutral {
+			return d
+		}
+	}
+	return dirNeutral
+}
+
+var mirrored = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<', '«': '»', '»': '«'}
+
+func mirror(s string) string {
+	return strings.Map(func(r rune) rune {
+		if m, ok := mirrored[r]; ok {
+			return m
+		}
+		return r
+	}, s)
+}
+
+// logicalOrder converts a line of clusters in visual, left-to-right order
+// into reading order. A line whose rightmost strong cluster is right-to-left
+// is read from the right, with left-to-right runs such as numbers and Latin
+// words kept in place; otherwise only right-to-left runs are reversed.
+func logicalOrder(clusters []string) []string {
+	dirs := make([]int, len(clusters))
+	base := dirNeutral
+	hasRTL := false
+	for i, c := range clusters {
+		dirs[i] = clusterDirection(c)
+		if dirs[i] != dirNeutral {
+			base = dirs[i]
+		}
+		hasRTL = hasRTL || dirs[i] == dirRTL
+	}
+	if !hasRTL {
+		return clusters
+	}
+
+	// Neutrals between two clusters of the same direction take it; the
+	// others take the line's direction.
+	resolved := make([]int, len(dirs))
+	for i := range dirs {
+		if dirs[i] != dirNeutral {
+			resolved[i] = dirs[i]
+			continue
+		}
+		prev, next := base, base
+		for j := i - 1; j >= 0; j-- {
+			if dirs[j] != dirNeutral {
+				prev = dirs[j]
+				break
+			}
+		}
+		for j := i + 1; j < len(dirs); j++ {
+			if dirs[j] != dirNeutral {
+				next = dirs[j]
+				break
+			}
+		}
+		if prev == next {
+			resolved[i] = prev
+		} else {
+			resolved[i] = base
+		}
+	}
+
+	out := make([]string, len(clusters))
+	copy(out, clusters)
+	reverse := func(from, to int) {
+		for i, j := from, to-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	if base == dirRTL {
+		reverse(0, len(out))
+		for i, j := 0, len(resolved)-1; i < j; i, j = i+1, j-1 {
+			resolved[i], resolved[j] = resolved[j], resolved[i]
+		}
+	}
+	for i := 0; i < len(out); {
+		j := i
+		for j < len(out) && resolved[j] == resolved[i] {
+			j++
+		}
+		if resolved[i] != base {
+			reverse(i, j)
+		}
+		if resolved[i] == dirRTL {
+			for k := i; k < j; k++ {
+				out[k] = mirror(out[k])
+			}
+		}
+		i = j
+	}
+	return out
+}
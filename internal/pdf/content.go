@@ -0,0 +1,144 @@
+package pdf
+
+import "math"
+
+// Operation is one operator of a content stream with its operands.
+type Operation struct {
+	Operator string
+	Operands []Object
+}
+
+// ParseContent splits a content stream into operations. An inline image is
+// returned as a single BI operation whose only operand is a *Stream holding
+// the image dictionary and data.
+func ParseContent(data []byte) ([]Operation, error) {
+	p := newParser(data)
+	var ops []Operation
+	var operands []Object
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return ops, nil
+		}
+		o, err := p.readObject()
+		if err != nil {
+			return ops, err
+		}
+		kw, ok := o.(keyword)
+		if !ok {
+			operands = append(operands, o)
+			continue
+		}
+		if kw == "BI" {
+			img, err := readInlineImage(p)
+			if err != nil {
+				return ops, err
+			}
+			operands = []Object{img}
+		}
+		ops = append(ops, Operation{Operator: string(kw), Operands: operands})
+		operands = nil
+	}
+}
+
+// readInlineImage reads the key-value pairs, the ID keyword and the data of
+// an inline image up to its closing EI.
+func readInlineImage(p *parser) (*Stream, error) {
+	dict := Dict{}
+	for {
+		o, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		if kw, ok := o.(keyword); ok && kw == "ID" {
+			break
+		}
+		key, ok := o.(Name)
+		if !ok {
+			return nil, p.errorf("inline image key is %T", o)
+		}
+		v, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = v
+	}
+	// A single whitespace byte separates ID from the data.
+	p.pos++
+	start := p.pos
+	for i := start; i+2 <= len(p.data); i++ {
+		if p.data[i] != 'E' || p.data[i+1] != 'I' {
+			continue
+		}
+		if i > start && !isWhitespace(p.data[i-1]) {
+			continue
+		}
+		if i+2 < len(p.data) && !isWhitespace(p.data[i+2]) {
+			continue
+		}
+		end := i
+		if end > start {
+			end--
+		}
+		p.pos = i + 2
+		return &Stream{Dict: dict, Data: p.data[start:end]}, nil
+	}
+	return nil, p.errorf("inline image without EI")
+}
+
+// Matrix is a PDF transformation matrix [a b c d e f].
+type Matrix [6]float64
+
+// Identity is the identity transformation.
+var Identity = Matrix{1, 0, 0, 1, 0, 0}
+
+// matrixFromArray reads a six number array, falling back to the identity.
+func matrixFromArray(a Array) Matrix {
+	if len(a) != 6 {
+		return Identity
+	}
+	var m Matrix
+	for i, o := range a {
+		v, ok := toFloat(o)
+		if !ok {
+			return Identity
+		}
+		m[i] = v
+	}
+	return m
+}
+
+// Multiply returns m × n, the transformation applying m first and then n.
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// Apply transforms the point (x, y).
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// Scale returns the lengths the unit vectors have after transformation.
+func (m Matrix) Scale() (float64, float64) {
+	return math.Hypot(m[0], m[1]), math.Hypot(m[2], m[3])
+}
+
+// operandFloats returns the operands as numbers, or false if any is not.
+func operandFloats(ops []Object) ([]float64, bool) {
+	out := make([]float64, len(ops))
+	for i, o := range ops {
+		v, ok := toFloat(o)
+		if !ok {
+			return nil, false
+		}
+		out[i] = v
+	}
+	return out, true
+}
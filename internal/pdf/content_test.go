@@ -0,0 +1,38 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContent(t *testing.T) {
+	ops, err := ParseContent([]byte("q 1 0 0 1 10 20 cm /Im1 Do BT /F1 12 Tf [(A) -20 (B)] TJ ET\nBI /W 2 /H 1 /CS /G /BPC 8 ID \x00\xff\nEI Q"))
+	require.NoError(t, err)
+
+	var operators []string
+	for _, op := range ops {
+		operators = append(operators, op.Operator)
+	}
+	assert.Equal(t, []string{"q", "cm", "Do", "BT", "Tf", "TJ", "ET", "BI", "Q"}, operators)
+	assert.Equal(t, []Object{1, 0, 0, 1, 10, 20}, ops[1].Operands)
+	assert.Equal(t, Array{String("A"), -20, String("B")}, ops[5].Operands[0])
+
+	img, ok := ops[7].Operands[0].(*Stream)
+	require.True(t, ok)
+	assert.Equal(t, 2, img.Dict["W"])
+	assert.Equal(t, []byte{0x00, 0xff}, img.Data)
+}
+
+func TestMatrix(t *testing.T) {
+	translate := Matrix{1, 0, 0, 1, 10, 20}
+	scale := Matrix{2, 0, 0, 3, 0, 0}
+
+	x, y := translate.Multiply(scale).Apply(1, 1)
+	assert.Equal(t, []float64{22, 63}, []float64{x, y})
+
+	w, h := Matrix{0, 2, -3, 0, 0, 0}.Scale()
+	assert.Equal(t, []float64{2, 3}, []float64{w, h})
+	assert.Equal(t, Identity, matrixFromArray(Array{1, 2}))
+}
@@ -0,0 +1,347 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+const maxRefDepth = 32
+
+// Document is an in-memory PDF. Objects are addressed by object number;
+// generation numbers are dropped and written as 0.
+type Document struct {
+	Version string
+	Trailer Dict
+	objects map[int]Object
+	nextNum int
+}
+
+// New returns an empty document with a catalog and an empty page tree.
+func New() *Document {
+	d := &Document{Version: "1.7", Trailer: Dict{}, objects: map[int]Object{}, nextNum: 1}
+	pages := d.Add(Dict{"Type": Name("Pages"), "Kids": Array{}, "Count": 0})
+	d.Trailer["Root"] = d.Add(Dict{"Type": Name("Catalog"), "Pages": pages})
+	return d
+}
+
+// Object returns the object with the given number, or nil.
+func (d *Document) Object(num int) Object {
+	return d.objects[num]
+}
+
+// ObjectNumbers returns the numbers of all objects in ascending order.
+func (d *Document) ObjectNumbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for n := range d.objects {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// Add stores o as a new indirect object and returns its reference.
+func (d *Document) Add(o Object) Ref {
+	if d.nextNum == 0 {
+		d.nextNum = 1
+	}
+	ref := Ref{Num: d.nextNum}
+	d.objects[ref.Num] = o
+	d.nextNum++
+	return ref
+}
+
+// Set replaces the indirect object ref points to.
+func (d *Document) Set(ref Ref, o Object) {
+	d.objects[ref.Num] = o
+	if ref.Num >= d.nextNum {
+		d.nextNum = ref.Num + 1
+	}
+}
+
+// Delete removes an indirect object. References to it become null.
+func (d *Document) Delete(ref Ref) {
+	delete(d.objects, ref.Num)
+}
+
+// Resolve follows references until it reaches a direct object.
+func (d *Document) Resolve(o Object) Object {
+	for i := 0; i < maxRefDepth; i++ {
+		ref, ok := o.(Ref)
+		if !ok {
+			return o
+		}
+		o = d.objects[ref.Num]
+	}
+	return nil
+}
+
+func (d *Document) GetDict(o Object) Dict {
+	switch v := d.Resolve(o).(type) {
+	case Dict:
+		return v
+	case *Stream:
+		return v.Dict
+	}
+	return nil
+}
+
+func (d *Document) GetArray(o Object) Array {
+	a, _ := d.Resolve(o).(Array)
+	return a
+}
+
+func (d *Document) GetStream(o Object) *Stream {
+	s, _ := d.Resolve(o).(*Stream)
+	return s
+}
+
+func (d *Document) GetName(o Object) Name {
+	n, _ := d.Resolve(o).(Name)
+	return n
+}
+
+func (d *Document) GetString(o Object) String {
+	s, _ := d.Resolve(o).(String)
+	return s
+}
+
+func (d *Document) GetInt(o Object) (int, bool) {
+	return toInt(d.Resolve(o))
+}
+
+func (d *Document) GetFloat(o Object) (float64, bool) {
+	return toFloat(d.Resolve(o))
+}
+
+func (d *Document) GetRect(o Object) (Rect, bool) {
+	a := d.GetArray(o)
+	for i, e := range a {
+		if _, isRef := e.(Ref); isRef {
+			a = append(Array(nil), a...)
+			a[i] = d.Resolve(e)
+		}
+	}
+	return rectFromArray(a)
+}
+
+// Catalog returns the document catalog dictionary.
+func (d *Document) Catalog() Dict {
+	return d.GetDict(d.Trailer["Root"])
+}
+
+// Info returns the document information dictionary, creating an empty one
+// when create is set and the document has none.
+func (d *Document) Info(create bool) Dict {
+	if info := d.GetDict(d.Trailer["Info"]); info != nil {
+		return info
+	}
+	if !create {
+		return nil
+	}
+	info := Dict{}
+	d.Trailer["Info"] = d.Add(info)
+	return info
+}
+
+// Encrypted reports whether the document uses the standard security
+// handler or any other encryption.
+func (d *Document) Encrypted() bool {
+	return d.Trailer["Encrypt"] != nil
+}
+
+// Page is a leaf of the page tree with its inheritable attributes resolved.
+type Page struct {
+	Ref       Ref
+	Dict      Dict
+	MediaBox  Rect
+	CropBox   Rect
+	Rotate    int
+	Resources Dict
+}
+
+// Pages walks the page tree and returns its leaves in document order.
+func (d *Document) Pages() ([]*Page, error) {
+	cat := d.Catalog()
+	if cat == nil {
+		return nil, fmt.Errorf("%w: no document catalog", ErrMalformed)
+	}
+	root, ok := cat["Pages"].(Ref)
+	if !ok {
+		return nil, fmt.Errorf("%w: page tree root is not an indirect object", ErrMalformed)
+	}
+
+	var pages []*Page
+	visited := map[int]bool{}
+	var walk func(ref Ref, inherited Dict, depth int) error
+	walk = func(ref Ref, inherited Dict, depth int) error {
+		if visited[ref.Num] || depth > maxNesting {
+			return fmt.Errorf("%w: page tree cycle at object %d", ErrMalformed, ref.Num)
+		}
+		visited[ref.Num] = true
+		node := d.GetDict(ref)
+		if node == nil {
+			return nil
+		}
+
+		attrs := Dict{}
+		for k, v := range inherited {
+			attrs[k] = v
+		}
+		for _, k := range []Name{"MediaBox", "CropBox", "Rotate", "Resources"} {
+			if v, ok := node[k]; ok {
+				attrs[k] = v
+			}
+		}
+
+		if kids, ok := d.Resolve(node["Kids"]).(Array); ok && d.GetName(node["Type"]) != "Page" {
+			for _, kid := range kids {
+				kidRef, ok := kid.(Ref)
+				if !ok {
+					continue
+				}
+				if err := walk(kidRef, attrs, depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		p := &Page{Ref: ref, Dict: node, Resources: d.GetDict(attrs["Resources"])}
+		p.MediaBox, ok = d.GetRect(attrs["MediaBox"])
+		if !ok {
+			p.MediaBox = Rect{0, 0, 612, 792}
+		}
+		p.CropBox, ok = d.GetRect(attrs["CropBox"])
+		if !ok {
+			p.CropBox = p.MediaBox
+		}
+		if r, ok := d.GetInt(attrs["Rotate"]); ok {
+			p.Rotate = ((r % 360) + 360) % 360
+		}
+		pages = append(pages, p)
+		return nil
+	}
+
+	if err := walk(root, Dict{}, 0); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// Content returns the page's decoded content, with multiple content streams
+// joined by whitespace.
+func (d *Document) Content(p *Page) ([]byte, error) {
+	var streams []*Stream
+	switch c := d.Resolve(p.Dict["Contents"]).(type) {
+	case *Stream:
+		streams = append(streams, c)
+	case Array:
+		for _, o := range c {
+			if s := d.GetStream(o); s != nil {
+				streams = append(streams, s)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, s := range streams {
+		data, err := s.Decode()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// WrapContent surrounds the page's existing content with before and after,
+// each written as its own stream so the original streams are untouched. The
+// original content is isolated in a q/Q pair so graphics state it leaves
+// behind does not leak into after.
+func (d *Document) WrapContent(p *Page, before, after []byte) {
+	var contents Array
+	switch c := p.Dict["Contents"].(type) {
+	case Ref:
+		if a, ok := d.Resolve(c).(Array); ok {
+			contents = append(contents, a...)
+		} else {
+			contents = Array{c}
+		}
+	case Array:
+		contents = append(contents, c...)
+	case *Stream:
+		contents = Array{d.Add(c)}
+	}
+
+	pre := append([]byte("q\n"), before...)
+	post := append([]byte("\nQ\n"), after...)
+	wrapped := Array{d.Add(NewStream(nil, pre))}
+	wrapped = append(wrapped, contents...)
+	wrapped = append(wrapped, d.Add(NewStream(nil, post)))
+	p.Dict["Contents"] = wrapped
+}
+
+// OwnResources gives the page a private, direct copy of its resource
+// dictionary so it can be extended without affecting pages that share or
+// inherit the original. The subdictionaries are resolved and copied too.
+func (d *Document) OwnResources(p *Page) Dict {
+	res := Dict{}
+	for k, v := range p.Resources {
+		if sub := d.GetDict(v); sub != nil && k != "ProcSet" {
+			if _, isStream := d.Resolve(v).(*Stream); !isStream {
+				c := Dict{}
+				for sk, sv := range sub {
+					c[sk] = sv
+				}
+				res[k] = c
+				continue
+			}
+		}
+		res[k] = v
+	}
+	p.Dict["Resources"] = res
+	p.Resources = res
+	return res
+}
+
+// AddResource registers o under a fresh name in the given resource category
+// (Font, XObject, ExtGState, ...) of a page-owned resource dictionary and
+// returns the name.
+func AddResource(res Dict, category Name, prefix string, o Object) Name {
+	sub, ok := res[category].(Dict)
+	if !ok {
+		sub = Dict{}
+		res[category] = sub
+	}
+	for i := 1; ; i++ {
+		name := Name(fmt.Sprintf("%s%d", prefix, i))
+		if _, taken := sub[name]; !taken {
+			sub[name] = o
+			return name
+		}
+	}
+}
+
+// AddPage appends a page to the root of the page tree.
+func (d *Document) AddPage(mediaBox Rect, resources Dict, content []byte) Ref {
+	cat := d.Catalog()
+	rootRef, _ := cat["Pages"].(Ref)
+	root := d.GetDict(rootRef)
+
+	if resources == nil {
+		resources = Dict{}
+	}
+	page := d.Add(Dict{
+		"Type":      Name("Page"),
+		"Parent":    rootRef,
+		"MediaBox":  mediaBox.Array(),
+		"Resources": resources,
+		"Contents":  d.Add(NewStream(nil, content)),
+	})
+	root["Kids"] = append(d.GetArray(root["Kids"]), page)
+	count, _ := d.GetInt(root["Count"])
+	root["Count"] = count + 1
+	return page
+}
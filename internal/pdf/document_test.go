@@ -0,0 +1,269 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var a4 = Rect{0, 0, 595.28, 841.89}
+
+// newTestDocument builds a document with n A4 pages, each showing its page
+// number in Helvetica.
+func newTestDocument(t testing.TB, n int) *Document {
+	t.Helper()
+	d := New()
+	font := d.Add(Dict{"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name("Helvetica")})
+	for i := 1; i <= n; i++ {
+		content := fmt.Sprintf("BT /F1 24 Tf 72 720 Td (Page %d) Tj ET", i)
+		d.AddPage(a4, Dict{"Font": Dict{"F1": font}}, []byte(content))
+	}
+	return d
+}
+
+func roundTrip(t *testing.T, d *Document) *Document {
+	t.Helper()
+	data, err := d.Bytes()
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestParse_HandWritten(t *testing.T) {
+	src := "%PDF-1.4\n" +
+		"1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] >>\nendobj\n" +
+		"3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Title (A \\(nested\\) string\\041) /Hex <48656C6C6F> /N#20ame 1.5 >>\nendobj\n" +
+		"4 0 obj\n<< /Length 5 0 R >>\nstream\nq Q\nendstream\nendobj\n" +
+		"5 0 obj\n3\nendobj\n"
+	offsets := []int{}
+	for i := 1; i <= 5; i++ {
+		offsets = append(offsets, bytes.Index([]byte(src), []byte(fmt.Sprintf("%d 0 obj", i))))
+	}
+	xref := len(src)
+	src += "xref\n0 6\n0000000000 65535 f \n"
+	for _, off := range offsets {
+		src += fmt.Sprintf("%010d 00000 n \n", off)
+	}
+	src += fmt.Sprintf("trailer\n<< /Size 6 /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	d, err := Parse([]byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, "1.4", d.Version)
+
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	assert.Equal(t, Rect{0, 0, 200, 100}, pages[0].MediaBox)
+	assert.Equal(t, String("A (nested) string!"), pages[0].Dict["Title"])
+	assert.Equal(t, String("Hello"), pages[0].Dict["Hex"])
+	assert.Equal(t, 1.5, pages[0].Dict["N ame"])
+
+	content, err := d.Content(pages[0])
+	require.NoError(t, err)
+	assert.Equal(t, "q Q\n", string(content))
+}
+
+func TestParse_ReconstructsBrokenXref(t *testing.T) {
+	data, err := newTestDocument(t, 2).Bytes()
+	require.NoError(t, err)
+
+	// Shift every object so that the recorded offsets are all wrong.
+	broken := bytes.Replace(data, []byte("%\xe2\xe3\xcf\xd3\n"), []byte("%\xe2\xe3\xcf\xd3\n% padding\n"), 1)
+
+	d, err := Parse(broken)
+	require.NoError(t, err)
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	assert.Len(t, pages, 2)
+}
+
+func TestParse_NotPDF(t *testing.T) {
+	_, err := Parse([]byte("<html></html>"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestParseObjectStream_InvalidHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		dict Dict
+		data string
+	}{
+		{"negative first", Dict{"N": 1, "First": -1}, "1 0 (a)"},
+		{"first beyond data", Dict{"N": 1, "First": 99}, "1 0 (a)"},
+		{"negative offset", Dict{"N": 1, "First": 4}, "1 -4 (a)"},
+		{"offset beyond data", Dict{"N": 1, "First": 4}, "1 9 (a)"},
+		{"huge count", Dict{"N": 1 << 40, "First": 4}, "1 0 (a)"},
+		{"garbage header", Dict{"N": 2, "First": 8}, "x y z w (a)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseObjectStream(&Stream{Dict: tt.dict, Data: []byte(tt.data)})
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
+
+func TestParse_InvalidXrefStreamWidths(t *testing.T) {
+	src := "%PDF-1.5\n1 0 obj\n<< /Type /XRef /W [1 -1 1] /Size 3 /Length 3 >>\nstream\nabc\nendstream\nendobj\n"
+	src += "startxref\n9\n%%EOF\n"
+	_, err := Parse([]byte(src))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestParse_StreamLengthOutOfRange(t *testing.T) {
+	// The declared length is ignored and the stream read up to endstream.
+	src := "%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Length 9223372036854775807 >>\nstream\nabc\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n"
+	_, err := Parse([]byte(src))
+	assert.NoError(t, err)
+
+	p := newParser([]byte("1 0 obj\n<< /Length -1 >>\nstream\nabc\nendstream\nendobj"))
+	_, _, err = p.readIndirect()
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func FuzzParse(f *testing.F) {
+	d := newTestDocument(f, 2)
+	plain, _ := d.Bytes()
+	compact, _ := d.BytesWith(WriteOptions{ObjectStreams: true})
+	f.Add(plain)
+	f.Add(compact)
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 99999999 /First 4 /Length 7 >>\nstream\n1 0 (a)\nendstream\nendobj\n"))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 1 /First -5 /Length 7 >>\nstream\n1 0 (a)\nendstream\nendobj\n"))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Type /XRef /W [1 -1 1] /Size 3 /Length 3 >>\nstream\nabc\nendstream\nendobj\nstartxref\n9\n%%EOF\n"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Length 9223372036854775807 >>\nstream\nabc\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Length -9223372036854775808 >>\nstream\nabc\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d, err := Parse(data)
+		if err != nil {
+			return
+		}
+		pages, err := d.Pages()
+		if err != nil {
+			return
+		}
+		for _, page := range pages {
+			d.Content(page)
+		}
+	})
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	d := newTestDocument(t, 3)
+	d.Info(true)["Title"] = String("Round (trip) \xfe")
+
+	parsed := roundTrip(t, d)
+	pages, err := parsed.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	assert.Equal(t, a4, pages[2].MediaBox)
+	assert.Equal(t, String("Round (trip) \xfe"), parsed.Info(false)["Title"])
+
+	content, err := parsed.Content(pages[2])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "(Page 3) Tj")
+
+	id, ok := parsed.Trailer["ID"].(Array)
+	require.True(t, ok)
+	assert.Len(t, id, 2)
+}
+
+func TestPages_InheritsAttributes(t *testing.T) {
+	d := New()
+	root := d.Catalog()["Pages"].(Ref)
+	d.GetDict(root)["MediaBox"] = Rect{0, 0, 300, 400}.Array()
+	d.GetDict(root)["Rotate"] = -90
+	d.AddPage(a4, nil, nil)
+	delete(d.GetDict(d.GetArray(d.GetDict(root)["Kids"])[0]), "MediaBox")
+
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	assert.Equal(t, Rect{0, 0, 300, 400}, pages[0].MediaBox)
+	assert.Equal(t, pages[0].MediaBox, pages[0].CropBox)
+	assert.Equal(t, 270, pages[0].Rotate)
+}
+
+func TestDecode_Filters(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream *Stream
+		want   string
+	}{
+		{"flate", NewStream(nil, []byte("hello flate")), "hello flate"},
+		{"ascii hex", &Stream{Dict: Dict{"Filter": Name("ASCIIHexDecode")}, Data: []byte("68 65 6C6C 6F>")}, "hello"},
+		{"ascii85", &Stream{Dict: Dict{"Filter": Name("ASCII85Decode")}, Data: []byte("BOu!rDZ~>")}, "hello"},
+		{"run length", &Stream{Dict: Dict{"Filter": Name("RunLengthDecode")}, Data: []byte{1, 'h', 'e', 255, 'l', 0, 'o', 128}}, "hello"},
+		{"png predictor", &Stream{
+			Dict: Dict{"Filter": Name("FlateDecode"), "DecodeParms": Dict{"Predictor": 12, "Columns": 2}},
+			Data: deflate([]byte{2, 1, 2, 2, 1, 1}),
+		}, "\x01\x02\x02\x03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.stream.Decode()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestDecode_InvalidParameters(t *testing.T) {
+	bomb := bytes.Repeat([]byte{0}, maxDecodedSize+1)
+	tests := []struct {
+		name   string
+		stream *Stream
+	}{
+		{"negative columns", &Stream{Dict: Dict{"Filter": Name("FlateDecode"), "DecodeParms": Dict{"Predictor": 12, "Columns": -1}}, Data: deflate([]byte{0, 1})}},
+		{"zero columns", &Stream{Dict: Dict{"Filter": Name("FlateDecode"), "DecodeParms": Dict{"Predictor": 2, "Columns": 0}}, Data: deflate([]byte{1, 2})}},
+		{"odd bits per component", &Stream{Dict: Dict{"Filter": Name("FlateDecode"), "DecodeParms": Dict{"Predictor": 12, "BitsPerComponent": 3}}, Data: deflate([]byte{0, 1})}},
+		{"decompression bomb", &Stream{Dict: Dict{"Filter": Name("FlateDecode")}, Data: deflate(bomb)}},
+		{"run length bomb", &Stream{Dict: Dict{"Filter": Name("RunLengthDecode")}, Data: bytes.Repeat([]byte{129, 0}, maxDecodedSize/128+2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.stream.Decode()
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
+
+func TestDecode_UnsupportedFilter(t *testing.T) {
+	s := &Stream{Dict: Dict{"Filter": Name("LZWDecode")}, Data: []byte("x")}
+	_, err := s.Decode()
+	assert.ErrorIs(t, err, ErrUnsupportedFilter)
+}
+
+func TestWrapContent_KeepsOriginalStreams(t *testing.T) {
+	d := newTestDocument(t, 1)
+	pages, _ := d.Pages()
+	original := pages[0].Dict["Contents"]
+
+	d.WrapContent(pages[0], []byte("0 g"), []byte("1 g"))
+
+	contents, ok := pages[0].Dict["Contents"].(Array)
+	require.True(t, ok)
+	require.Len(t, contents, 3)
+	assert.Equal(t, original, contents[1])
+
+	content, err := d.Content(pages[0])
+	require.NoError(t, err)
+	assert.Regexp(t, `(?s)^q\n0 g\n.*\(Page 1\) Tj ET\n\nQ\n1 g\n$`, string(content))
+}
+
+func TestOwnResources_DoesNotAffectSharedDictionary(t *testing.T) {
+	d := newTestDocument(t, 2)
+	pages, _ := d.Pages()
+
+	res := d.OwnResources(pages[0])
+	name := AddResource(res, "Font", "F", Name("X"))
+	assert.Equal(t, Name("F2"), name)
+
+	pages, _ = d.Pages()
+	assert.Contains(t, pages[0].Resources["Font"], Name("F2"))
+	assert.NotContains(t, pages[1].Resources["Font"], Name("F2"))
+}
@@ -0,0 +1,103 @@
+package pdf
+
+import "errors"
+
+var ErrNotEncodable = errors.New("text cannot be encoded in WinAnsiEncoding")
+
+// winAnsiHigh maps the 0x80-0x9F range of WinAnsiEncoding, where it differs
+// from ISO 8859-1, to Unicode. The other codes above 0x9F equal Latin-1.
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+var winAnsiReverse = func() map[rune]byte {
+	m := make(map[rune]byte, len(winAnsiHigh))
+	for b, r := range winAnsiHigh {
+		m[r] = b
+	}
+	return m
+}()
+
+// EncodeWinAnsi converts text to WinAnsiEncoding bytes for use with the
+// standard 14 fonts.
+func EncodeWinAnsi(text string) ([]byte, error) {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 0x20 && r <= 0x7e, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			b, ok := winAnsiReverse[r]
+			if !ok {
+				return nil, ErrNotEncodable
+			}
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// DecodeWinAnsi converts WinAnsiEncoding bytes to text.
+func DecodeWinAnsi(b []byte) string {
+	rs := make([]rune, 0, len(b))
+	for _, c := range b {
+		if r, ok := winAnsiHigh[c]; ok {
+			rs = append(rs, r)
+		} else {
+			rs = append(rs, rune(c))
+		}
+	}
+	return string(rs)
+}
+
+// helveticaWidths holds the glyph widths of Helvetica for the printable
+// ASCII range, in thousandths of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space - /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 - 9
+	278, 278, 584, 584, 584, 556, 1015, // : - @
+	667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A - M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N - Z
+	278, 278, 278, 469, 556, 333, // [ - `
+	556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a - m
+	556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n - z
+	334, 260, 334, 584, // { - ~
+}
+
+// helveticaBoldWidths holds the glyph widths of Helvetica-Bold for the
+// printable ASCII range, in thousandths of the font size.
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // space - /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 - 9
+	333, 333, 584, 584, 584, 611, 975, // : - @
+	722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, // A - M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N - Z
+	333, 278, 333, 584, 556, 333, // [ - `
+	556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, // a - m
+	611, 611, 611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, // n - z
+	389, 280, 389, 584, // { - ~
+}
+
+const (
+	helveticaBoldCapHeight    = 718
+	helveticaBoldDefaultWidth = 556
+)
+
+// textWidth returns the width of WinAnsi encoded text set in Helvetica-Bold
+// at the given size. Characters outside ASCII use an average width.
+func textWidth(encoded []byte, size float64) float64 {
+	total := 0
+	for _, c := range encoded {
+		if c >= 0x20 && c <= 0x7e {
+			total += helveticaBoldWidths[c-0x20]
+		} else {
+			total += helveticaBoldDefaultWidth
+		}
+	}
+	return float64(total) * size / 1000
+}
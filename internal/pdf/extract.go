@@ -0,0 +1,635 @@
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxCMapRange caps the codes expanded from one bfrange entry.
+const maxCMapRange = 1 << 16
+
+// codeRange is a codespace range of a CMap.
+type codeRange struct {
+	low, high []byte
+}
+
+func (r codeRange) contains(code []byte) bool {
+	for i := range code {
+		if code[i] < r.low[i] || code[i] > r.high[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cmap is the part of a CMap needed to decode text: the code lengths and,
+// for ToUnicode CMaps, the text of each code.
+type cmap struct {
+	ranges  []codeRange
+	unicode map[string]string
+}
+
+func parseCMap(data []byte) *cmap {
+	c := &cmap{unicode: map[string]string{}}
+	// Errors leave whatever was parsed before them.
+	ops, _ := ParseContent(data)
+	for _, op := range ops {
+		args := op.Operands
+		switch op.Operator {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(args); i += 2 {
+				low, ok1 := args[i].(String)
+				high, ok2 := args[i+1].(String)
+				if ok1 && ok2 && len(low) == len(high) && len(low) > 0 {
+					c.ranges = append(c.ranges, codeRange{low, high})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(args); i += 2 {
+				src, ok1 := args[i].(String)
+				dst, ok2 := args[i+1].(String)
+				if ok1 && ok2 {
+					c.unicode[string(src)] = decodeUTF16(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(args); i += 3 {
+				low, ok1 := args[i].(String)
+				high, ok2 := args[i+1].(String)
+				if !ok1 || !ok2 || len(low) != len(high) || len(low) > 4 {
+					continue
+				}
+				from, to := bytesToInt(low), bytesToInt(high)
+				if to < from || to-from >= maxCMapRange {
+					continue
+				}
+				for code := from; code <= to; code++ {
+					key := string(intToBytes(code, len(low)))
+					switch dst := args[i+2].(type) {
+					case String:
+						c.unicode[key] = decodeUTF16(incrementLast(dst, code-from))
+					case Array:
+						if k := code - from; k < len(dst) {
+							if s, ok := dst[k].(String); ok {
+								c.unicode[key] = decodeUTF16(s)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return c
+}
+
+func bytesToInt(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+	return n
+}
+
+func intToBytes(n, size int) []byte {
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
+
+// incrementLast adds n to the last UTF-16 code unit of s, as bfrange
+// destinations are incremented.
+func incrementLast(s String, n int) String {
+	out := append(String{}, s...)
+	if len(out) < 2 {
+		if len(out) == 1 {
+			out[0] += byte(n)
+		}
+		return out
+	}
+	v := int(out[len(out)-2])<<8 | int(out[len(out)-1])
+	v += n
+	out[len(out)-2], out[len(out)-1] = byte(v>>8), byte(v)
+	return out
+}
+
+func decodeUTF16(s String) string {
+	if len(s)%2 != 0 {
+		return string(s)
+	}
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// glyphNames maps the glyph names most used in Differences arrays to text;
+// single letters and uniXXXX names are handled by glyphText.
+var glyphNames = map[Name]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "parenleft": "(", "parenright": ")",
+	"asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-", "period": ".", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6",
+	"seven": "7", "eight": "8", "nine": "9", "colon": ":", "semicolon": ";", "less": "<",
+	"equal": "=", "greater": ">", "question": "?", "at": "@", "bracketleft": "[",
+	"backslash": "\\", "bracketright": "]", "asciicircum": "^", "underscore": "_", "grave": "`",
+	"braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~", "bullet": "•",
+	"endash": "–", "emdash": "—", "quoteleft": "‘", "quoteright": "’", "quotedblleft": "“",
+	"quotedblright": "”", "ellipsis": "…", "fi": "fi", "fl": "fl", "nbspace": " ",
+}
+
+func glyphText(name Name) string {
+	if s, ok := glyphNames[name]; ok {
+		return s
+	}
+	if len(name) == 1 {
+		return string(name)
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		hex := strings.TrimPrefix(string(name), prefix)
+		if hex == string(name) || len(hex) < 4 || len(hex) > 6 {
+			continue
+		}
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// textFont decodes the strings shown with one font.
+type textFont struct {
+	composite bool
+	codes     *cmap
+	toUnicode *cmap
+	// differences holds the simple font's Differences entries.
+	differences map[byte]string
+	// width returns a glyph's advance in text space units of a 1 point
+	// font.
+	width func(code []byte) float64
+}
+
+func (d *Document) textFont(font Dict) *textFont {
+	f := &textFont{}
+	if s := d.GetStream(font["ToUnicode"]); s != nil {
+		if data, err := s.Decode(); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+
+	if d.GetName(font["Subtype"]) == "Type0" {
+		f.composite = true
+		if s := d.GetStream(font["Encoding"]); s != nil {
+			if data, err := s.Decode(); err == nil {
+				f.codes = parseCMap(data)
+			}
+		}
+		descendant := d.GetDict(d.GetArray(font["DescendantFonts"]).first())
+		widths := d.cidWidths(descendant)
+		dw := 1000.0
+		if v, ok := d.GetFloat(descendant["DW"]); ok {
+			dw = v
+		}
+		f.width = func(code []byte) float64 {
+			if w, ok := widths[bytesToInt(code)]; ok {
+				return w / 1000
+			}
+			return dw / 1000
+		}
+		return f
+	}
+
+	if enc := d.GetDict(font["Encoding"]); enc != nil {
+		f.differences = map[byte]string{}
+		code := 0
+		for _, o := range d.GetArray(enc["Differences"]) {
+			switch v := d.Resolve(o).(type) {
+			case int:
+				code = v
+			case Name:
+				if code >= 0 && code < 256 {
+					f.differences[byte(code)] = glyphText(v)
+				}
+				code++
+			}
+		}
+	}
+	glyph := d.glyphWidths(font)
+	scale := 0.001
+	if d.GetName(font["Subtype"]) == "Type3" {
+		if m := d.GetArray(font["FontMatrix"]); len(m) == 6 {
+			scale, _ = toFloat(m[0])
+		}
+	}
+	f.width = func(code []byte) float64 { return glyph(code[0]) * scale }
+	return f
+}
+
+func (a Array) first() Object {
+	if len(a) == 0 {
+		return nil
+	}
+	return a[0]
+}
+
+// cidWidths reads the W array of a CIDFont.
+func (d *Document) cidWidths(font Dict) map[int]float64 {
+	widths := map[int]float64{}
+	w := d.GetArray(font["W"])
+	for i := 0; i < len(w); {
+		first, ok := d.GetInt(w[i])
+		if !ok || i+1 >= len(w) {
+			break
+		}
+		if list := d.GetArray(w[i+1]); list != nil {
+			for j, o := range list {
+				if v, ok := d.GetFloat(o); ok {
+					widths[first+j] = v
+				}
+			}
+			i += 2
+			continue
+		}
+		last, ok1 := d.GetInt(w[i+1])
+		if i+2 >= len(w) || !ok1 || last-first >= maxCMapRange {
+			break
+		}
+		if v, ok := d.GetFloat(w[i+2]); ok {
+			for c := first; c <= last; c++ {
+				widths[c] = v
+			}
+		}
+		i += 3
+	}
+	return widths
+}
+
+// split returns the next character code of s.
+func (f *textFont) split(s []byte) []byte {
+	if !f.composite {
+		return s[:1]
+	}
+	if f.codes != nil {
+		for _, r := range f.codes.ranges {
+			if len(s) >= len(r.low) && r.contains(s[:len(r.low)]) {
+				return s[:len(r.low)]
+			}
+		}
+	}
+	return s[:min(2, len(s))]
+}
+
+func (f *textFont) text(code []byte) string {
+	if f.toUnicode != nil {
+		if s, ok := f.toUnicode.unicode[string(code)]; ok {
+			return s
+		}
+	}
+	if f.composite {
+		return ""
+	}
+	if s, ok := f.differences[code[0]]; ok {
+		return s
+	}
+	// Other base encodings agree with WinAnsi on the printable ASCII range.
+	return DecodeWinAnsi(code)
+}
+
+// glyph is a piece of text placed on the page: usually the text of one
+// character code, or the replacement text of a marked-content span.
+type glyph struct {
+	text    string
+	x, y, w float64
+	size    float64
+}
+
+type textState struct {
+	font                                             *textFont
+	size, charSpace, wordSpace, scale, leading, rise float64
+}
+
+type graphicsState struct {
+	ctm  Matrix
+	text textState
+}
+
+// textExtractor interprets content streams, collecting the glyphs shown.
+type textExtractor struct {
+	d      *Document
+	fonts  map[Object]*textFont
+	glyphs []glyph
+}
+
+// ExtractText returns the text of every page in reading order.
+func (d *Document) ExtractText() ([]string, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(pages))
+	for i, p := range pages {
+		if texts[i], err = d.PageText(p); err != nil {
+			return nil, err
+		}
+	}
+	return texts, nil
+}
+
+// PageText returns the text of a page, line by line from the top. Glyphs
+// are ordered by position, so the order of drawing does not matter, and
+// right-to-left lines such as Persian text are returned in logical order.
+func (d *Document) PageText(p *Page) (string, error) {
+	content, err := d.Content(p)
+	if err != nil {
+		return "", err
+	}
+	x := &textExtractor{d: d, fonts: map[Object]*textFont{}}
+	state := graphicsState{ctm: Identity, text: textState{scale: 1}}
+	if err := x.run(content, p.Resources, state, 0); err != nil {
+		return "", err
+	}
+
+	// Turn positions into the page's displayed orientation.
+	for i := range x.glyphs {
+		g := &x.glyphs[i]
+		switch p.Rotate {
+		case 90:
+			g.x, g.y = g.y, -g.x
+		case 180:
+			g.x, g.y = -g.x, -g.y
+		case 270:
+			g.x, g.y = -g.y, g.x
+		}
+	}
+	return layoutText(x.glyphs), nil
+}
+
+// run interprets one content stream with the given resources.
+func (x *textExtractor) run(content []byte, res Dict, gs graphicsState, depth int) error {
+	ops, err := ParseContent(content)
+	if err != nil && len(ops) == 0 {
+		return err
+	}
+	d := x.d
+	var stack []graphicsState
+	var tm, tlm Matrix
+	type span struct {
+		actual bool
+		text   string
+		start  int
+	}
+	var spans []span
+
+	show := func(s String) {
+		ts := gs.text
+		if ts.font == nil {
+			return
+		}
+		for len(s) > 0 {
+			code := ts.font.split(s)
+			s = s[len(code):]
+			trm := Matrix{ts.size * ts.scale, 0, 0, ts.size, 0, ts.rise}.Multiply(tm).Multiply(gs.ctm)
+			gx, gy := trm.Apply(0, 0)
+			tx := ts.font.width(code)*ts.size + ts.charSpace
+			if len(code) == 1 && code[0] == ' ' {
+				tx += ts.wordSpace
+			}
+			tx *= ts.scale
+			m := tm.Multiply(gs.ctm)
+			_, size := trm.Scale()
+			x.glyphs = append(x.glyphs, glyph{
+				text: normalizeArabic(ts.font.text(code)),
+				x:    gx, y: gy,
+				w:    math.Hypot(tx*m[0], tx*m[1]),
+				size: size,
+			})
+			tm = Matrix{1, 0, 0, 1, tx, 0}.Multiply(tm)
+		}
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = Matrix{1, 0, 0, 1, tx, ty}.Multiply(tlm)
+		tm = tlm
+	}
+
+	for _, op := range ops {
+		args := op.Operands
+		nums, numeric := operandFloats(args)
+		switch op.Operator {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if numeric && len(nums) == 6 {
+				gs.ctm = Matrix(nums).Multiply(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = Identity, Identity
+		case "Tf":
+			if len(args) == 2 {
+				name, _ := args[0].(Name)
+				fontRef := d.GetDict(res["Font"])[name]
+				if font := d.GetDict(fontRef); font != nil {
+					key := fontRef
+					if _, ok := key.(Ref); !ok {
+						key = name
+					}
+					if x.fonts[key] == nil {
+						x.fonts[key] = d.textFont(font)
+					}
+					gs.text.font = x.fonts[key]
+				}
+				gs.text.size, _ = toFloat(args[1])
+			}
+		case "Tc", "Tw", "Tz", "TL", "Ts":
+			if numeric && len(nums) == 1 {
+				switch op.Operator {
+				case "Tc":
+					gs.text.charSpace = nums[0]
+				case "Tw":
+					gs.text.wordSpace = nums[0]
+				case "Tz":
+					gs.text.scale = nums[0] / 100
+				case "TL":
+					gs.text.leading = nums[0]
+				case "Ts":
+					gs.text.rise = nums[0]
+				}
+			}
+		case "Td", "TD":
+			if numeric && len(nums) == 2 {
+				if op.Operator == "TD" {
+					gs.text.leading = -nums[1]
+				}
+				nextLine(nums[0], nums[1])
+			}
+		case "Tm":
+			if numeric && len(nums) == 6 {
+				tlm = Matrix(nums)
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -gs.text.leading)
+		case "Tj", "'", "\"":
+			if op.Operator != "Tj" {
+				if op.Operator == "\"" && len(args) == 3 {
+					gs.text.wordSpace, _ = toFloat(args[0])
+					gs.text.charSpace, _ = toFloat(args[1])
+				}
+				nextLine(0, -gs.text.leading)
+			}
+			if len(args) > 0 {
+				if s, ok := args[len(args)-1].(String); ok {
+					show(s)
+				}
+			}
+		case "TJ":
+			if len(args) != 1 {
+				continue
+			}
+			arr, _ := args[0].(Array)
+			for _, o := range arr {
+				if s, ok := o.(String); ok {
+					show(s)
+				} else if n, ok := toFloat(o); ok {
+					tx := -n / 1000 * gs.text.size * gs.text.scale
+					tm = Matrix{1, 0, 0, 1, tx, 0}.Multiply(tm)
+				}
+			}
+		case "BMC":
+			spans = append(spans, span{})
+		case "BDC":
+			s := span{start: len(x.glyphs)}
+			if len(args) == 2 {
+				props := d.GetDict(args[1])
+				if name, ok := args[1].(Name); ok {
+					props = d.GetDict(d.GetDict(res["Properties"])[name])
+				}
+				if actual, ok := d.Resolve(props["ActualText"]).(String); ok {
+					s.actual, s.text = true, DecodeTextString(actual)
+				}
+			}
+			spans = append(spans, s)
+		case "EMC":
+			if len(spans) == 0 {
+				continue
+			}
+			s := spans[len(spans)-1]
+			spans = spans[:len(spans)-1]
+			if !s.actual || s.start >= len(x.glyphs) {
+				continue
+			}
+			// The span's text replaces its glyphs, placed where they
+			// start and as wide as they are together.
+			covered := x.glyphs[s.start:]
+			g := covered[0]
+			left, right := g.x, g.x+g.w
+			for _, c := range covered[1:] {
+				left, right = math.Min(left, c.x), math.Max(right, c.x+c.w)
+			}
+			g.text, g.x, g.w = s.text, left, right-left
+			x.glyphs = append(x.glyphs[:s.start], g)
+		case "Do":
+			if depth >= maxFormDepth || len(args) != 1 {
+				continue
+			}
+			name, _ := args[0].(Name)
+			form := d.GetStream(d.GetDict(res["XObject"])[name])
+			if form == nil || d.GetName(form.Dict["Subtype"]) != "Form" {
+				continue
+			}
+			data, err := form.Decode()
+			if err != nil {
+				continue
+			}
+			inner := gs
+			inner.ctm = matrixFromArray(d.GetArray(form.Dict["Matrix"])).Multiply(gs.ctm)
+			formRes := d.GetDict(form.Dict["Resources"])
+			if formRes == nil {
+				formRes = res
+			}
+			if err := x.run(data, formRes, inner, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// layoutText groups glyphs into lines from top to bottom, orders each line
+// from left to right, separates words by the gaps between glyphs and
+// restores the logical order of right-to-left text.
+func layoutText(glyphs []glyph) string {
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].y > glyphs[j].y })
+
+	var lines [][]glyph
+	var lineY, lineSize float64
+	for _, g := range glyphs {
+		if g.text == "" {
+			continue
+		}
+		size := math.Max(g.size, 1)
+		if len(lines) > 0 && math.Abs(g.y-lineY) <= math.Max(size, lineSize)/2 {
+			lines[len(lines)-1] = append(lines[len(lines)-1], g)
+			lineSize = math.Max(lineSize, size)
+			continue
+		}
+		lines = append(lines, []glyph{g})
+		lineY, lineSize = g.y, size
+	}
+
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].x < line[j].x })
+		var clusters []string
+		var prev *glyph
+		for i := range line {
+			g := &line[i]
+			if prev != nil {
+				// Skip glyphs drawn twice, as for simulated bold.
+				if g.text == prev.text && math.Abs(g.x-prev.x) < 0.1*g.size {
+					continue
+				}
+				gap := g.x - (prev.x + prev.w)
+				if gap > 0.2*math.Max(g.size, 1) && !strings.HasSuffix(prev.text, " ") && !strings.HasPrefix(g.text, " ") {
+					clusters = append(clusters, " ")
+				}
+			}
+			clusters = append(clusters, g.text)
+			prev = g
+		}
+		text := strings.Join(logicalOrder(clusters), "")
+		out = append(out, strings.TrimSpace(collapseSpaces(text)))
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// collapseSpaces replaces runs of spaces with one.
+func collapseSpaces(s string) string {
+	if !strings.Contains(s, "  ") {
+		return s
+	}
+	var b bytes.Buffer
+	space := false
+	for _, r := range s {
+		if r == ' ' {
+			if space {
+				continue
+			}
+			space = true
+		} else {
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
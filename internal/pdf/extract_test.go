@@ -0,0 +1,118 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unicodeFont adds a Type0 font like the subsets Chromium embeds: glyph
+// IDs as 2-byte codes and a ToUnicode CMap. It returns the font and an
+// encoder from the text of each glyph to the string showing it.
+func unicodeFont(d *Document, glyphs []string) (Ref, func(...string) string) {
+	cids := map[string]int{}
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n")
+	cmap.WriteString("1 begincodespacerange <0000> <FFFF> endcodespacerange\n")
+	fmt.Fprintf(&cmap, "%d beginbfchar\n", len(glyphs))
+	for i, g := range glyphs {
+		cids[g] = i + 1
+		fmt.Fprintf(&cmap, "<%04X> <", i+1)
+		for _, u := range utf16.Encode([]rune(g)) {
+			fmt.Fprintf(&cmap, "%04X", u)
+		}
+		cmap.WriteString(">\n")
+	}
+	cmap.WriteString("endbfchar endcmap CMapName currentdict /CMap defineresource pop end end")
+
+	font := d.Add(Dict{
+		"Type": Name("Font"), "Subtype": Name("Type0"), "BaseFont": Name("AAAAAA+Vazirmatn"),
+		"Encoding": Name("Identity-H"),
+		"DescendantFonts": Array{Dict{
+			"Type": Name("Font"), "Subtype": Name("CIDFontType2"), "BaseFont": Name("AAAAAA+Vazirmatn"),
+			"DW": 500,
+		}},
+		"ToUnicode": d.Add(NewStream(Dict{}, []byte(cmap.String()))),
+	})
+	encode := func(text ...string) string {
+		var b strings.Builder
+		b.WriteString("<")
+		for _, g := range text {
+			fmt.Fprintf(&b, "%04X", cids[g])
+		}
+		b.WriteString(">")
+		return b.String()
+	}
+	return font, encode
+}
+
+func TestExtractText(t *testing.T) {
+	d := newTestDocument(t, 2)
+	got, err := roundTrip(t, d).ExtractText()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Page 1", "Page 2"}, got)
+}
+
+func TestPageText_Layout(t *testing.T) {
+	d := New()
+	font := d.Add(Dict{"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name("Helvetica")})
+	// The second line is drawn first, words are positioned with TJ
+	// offsets and the heading is drawn twice for a bold effect.
+	content := "BT /F1 12 Tf 72 700 Td [(Second) -1000 (line)] TJ ET\n" +
+		"q 1 0 0 1 72 720 cm BT /F1 12 Tf (Title) Tj ET Q\n" +
+		"q 1 0 0 1 72.2 720 cm BT /F1 12 Tf (Title) Tj ET Q\n" +
+		"BT /F1 12 Tf 14 TL 72 686 Td (Third) Tj T* (Fourth) Tj ET"
+	d.AddPage(a4, Dict{"Font": Dict{"F1": font}}, []byte(content))
+
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	got, err := d.PageText(pages[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Title\nSecond line\nThird\nFourth", got)
+}
+
+func TestPageText_Persian(t *testing.T) {
+	d := New()
+	// Chromium draws shaped glyphs left to right in visual order; the
+	// initial form of seen maps to its presentation form.
+	font, enc := unicodeFont(d, []string{"1", "2", "3", " ", "ا", "ی", "ن", "د", "م", "ل", "ﺳ", "(", ")", "ف"})
+	visual := []string{"1", "2", "3", " ", "ا", "ی", "ن", "د", " ", "م", "ا", "ل", "ﺳ"}
+	content := fmt.Sprintf("BT /F1 10 Tf 300 720 Td %s Tj ET\n", enc(visual...)) +
+		fmt.Sprintf("BT /F1 10 Tf 300 700 Td %s Tj ET\n", enc("(", "ف", "ل", "ا", ")")) +
+		"/Span << /ActualText <FEFF0645062D0645062F> >> BDC\n" +
+		fmt.Sprintf("BT /F1 10 Tf 300 680 Td %s Tj ET\nEMC", enc("د", "م", "م"))
+	d.AddPage(a4, Dict{"Font": Dict{"F1": font}}, []byte(content))
+
+	got, err := roundTrip(t, d).ExtractText()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "سلام دنیا 123\n(الف)\nمحمد", got[0])
+}
+
+func TestPageText_FormXObject(t *testing.T) {
+	d := New()
+	font := d.Add(Dict{"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name("Helvetica")})
+	form := d.Add(NewStream(Dict{
+		"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": Array{0, 0, 200, 50},
+		"Resources": Dict{"Font": Dict{"F1": font}},
+	}, []byte("BT /F1 10 Tf 0 10 Td (Footer) Tj ET")))
+	d.AddPage(a4, Dict{"XObject": Dict{"X1": form}, "Font": Dict{"F1": font}},
+		[]byte("BT /F1 10 Tf 72 720 Td (Body) Tj ET q 1 0 0 1 72 40 cm /X1 Do Q"))
+
+	got, err := roundTrip(t, d).ExtractText()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Body\nFooter"}, got)
+}
+
+func TestLogicalOrder(t *testing.T) {
+	split := func(s string) []string { return strings.Split(s, "") }
+	assert.Equal(t, "abc def", strings.Join(logicalOrder(split("abc def")), ""))
+	// A Latin word inside a right-to-left line keeps its order.
+	assert.Equal(t, "سلام PDF خوب", strings.Join(logicalOrder(split("بوخ PDF مالس")), ""))
+	// A right-to-left word inside a left-to-right line is reversed.
+	assert.Equal(t, "Name: علی ok", strings.Join(logicalOrder(split("Name: یلع ok")), ""))
+}
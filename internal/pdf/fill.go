@@ -0,0 +1,687 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidFormValue = errors.New("invalid form value")
+
+// FieldButton is the type reported for push buttons, which hold no value.
+const FieldButton = "button"
+
+const (
+	flagPushButton = 1 << 16
+	flagEdit       = 1 << 18
+	annotHidden    = 2
+)
+
+// FieldInfo describes a field of a document's interactive form.
+type FieldInfo struct {
+	Name string
+	Type string
+	// Value is the current value; empty for an unchecked check box.
+	Value string
+	// Options are the choices of a select field, or the on states of a
+	// check box or radio group.
+	Options   []string
+	ReadOnly  bool
+	Required  bool
+	Multiline bool
+	MaxLength int
+}
+
+// formNode is a terminal field with its inheritable attributes resolved.
+type formNode struct {
+	name    string
+	dict    Dict
+	ft      Name
+	flags   int
+	da      string
+	q       int
+	widgets []Dict
+}
+
+func (n *formNode) kind() string {
+	switch n.ft {
+	case "Tx":
+		return FieldText
+	case "Ch":
+		return FieldSelect
+	case "Sig":
+		return FieldSignature
+	case "Btn":
+		switch {
+		case n.flags&flagPushButton != 0:
+			return FieldButton
+		case n.flags&flagRadio != 0:
+			return FieldRadio
+		}
+		return FieldCheckbox
+	}
+	return ""
+}
+
+// terminalFields returns the fields of the form that hold values, in the
+// order of the field tree.
+func (d *Document) terminalFields() []*formNode {
+	form := d.GetDict(d.Catalog()["AcroForm"])
+	if form == nil {
+		return nil
+	}
+	var out []*formNode
+	visited := map[int]bool{}
+	var walk func(o Object, parent formNode, depth int)
+	walk = func(o Object, parent formNode, depth int) {
+		if ref, ok := o.(Ref); ok {
+			if visited[ref.Num] {
+				return
+			}
+			visited[ref.Num] = true
+		}
+		dict := d.GetDict(o)
+		if dict == nil || depth > maxNesting {
+			return
+		}
+		node := parent
+		node.dict, node.widgets = dict, nil
+		if t := dict["T"]; t != nil {
+			part := DecodeTextString(d.GetString(t))
+			if node.name != "" {
+				part = node.name + "." + part
+			}
+			node.name = part
+		}
+		if ft := d.GetName(dict["FT"]); ft != "" {
+			node.ft = ft
+		}
+		if ff, ok := d.GetInt(dict["Ff"]); ok {
+			node.flags = ff
+		}
+		if da := d.GetString(dict["DA"]); da != nil {
+			node.da = string(da)
+		}
+		if q, ok := d.GetInt(dict["Q"]); ok {
+			node.q = q
+		}
+
+		kids := d.GetArray(dict["Kids"])
+		for _, kid := range kids {
+			if k := d.GetDict(kid); k != nil && k["T"] == nil {
+				node.widgets = append(node.widgets, k)
+			}
+		}
+		if len(kids) == 0 && d.GetName(dict["Subtype"]) == "Widget" {
+			node.widgets = []Dict{dict}
+		}
+		if node.ft != "" && (len(kids) == 0 || len(node.widgets) > 0) {
+			n := node
+			out = append(out, &n)
+		}
+		for _, kid := range kids {
+			if d.GetDict(kid)["T"] != nil {
+				walk(kid, node, depth+1)
+			}
+		}
+	}
+
+	root := formNode{da: string(d.GetString(form["DA"]))}
+	if q, ok := d.GetInt(form["Q"]); ok {
+		root.q = q
+	}
+	for _, f := range d.GetArray(form["Fields"]) {
+		walk(f, root, 0)
+	}
+	return out
+}
+
+// FormFields lists the fields of the document's interactive form.
+func (d *Document) FormFields() []FieldInfo {
+	var out []FieldInfo
+	for _, n := range d.terminalFields() {
+		info := FieldInfo{
+			Name:      n.name,
+			Type:      n.kind(),
+			ReadOnly:  n.flags&flagReadOnly != 0,
+			Required:  n.flags&flagRequired != 0,
+			Multiline: n.ft == "Tx" && n.flags&flagMultiline != 0,
+			Value:     d.fieldValue(n),
+		}
+		if info.Type == "" {
+			continue
+		}
+		info.MaxLength, _ = d.GetInt(n.dict["MaxLen"])
+		switch info.Type {
+		case FieldSelect:
+			for _, o := range d.GetArray(n.dict["Opt"]) {
+				export, _ := d.option(o)
+				info.Options = append(info.Options, export)
+			}
+		case FieldCheckbox, FieldRadio:
+			for _, s := range d.onStates(n) {
+				info.Options = append(info.Options, string(s))
+			}
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// fieldValue returns the value of n as text. Values inherited from a parent
+// field are ignored, as terminal fields of generated forms carry their own.
+func (d *Document) fieldValue(n *formNode) string {
+	switch v := d.Resolve(n.dict["V"]).(type) {
+	case Name:
+		if v == "Off" {
+			return ""
+		}
+		return string(v)
+	case String:
+		return DecodeTextString(v)
+	case Array:
+		values := make([]string, 0, len(v))
+		for _, o := range v {
+			values = append(values, DecodeTextString(d.GetString(o)))
+		}
+		return strings.Join(values, ", ")
+	}
+	return ""
+}
+
+// option returns the export value and the displayed text of an Opt entry.
+func (d *Document) option(o Object) (string, string) {
+	if pair := d.GetArray(o); len(pair) == 2 {
+		return DecodeTextString(d.GetString(pair[0])), DecodeTextString(d.GetString(pair[1]))
+	}
+	s := DecodeTextString(d.GetString(o))
+	return s, s
+}
+
+// onStates returns the distinct on states of a button field's widgets.
+func (d *Document) onStates(n *formNode) []Name {
+	var states []Name
+	seen := map[Name]bool{}
+	for _, w := range n.widgets {
+		for _, s := range widgetStates(d, w) {
+			if !seen[s] {
+				seen[s] = true
+				states = append(states, s)
+			}
+		}
+	}
+	return states
+}
+
+func widgetStates(d *Document, widget Dict) []Name {
+	var states []Name
+	for s := range d.GetDict(d.GetDict(widget["AP"])["N"]) {
+		if s != "Off" {
+			states = append(states, s)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	return states
+}
+
+// FillForm sets form fields by their fully qualified names and regenerates
+// the appearance of the text and select fields changed. Text the form's
+// fonts cannot show, such as Persian, is only set as the value, and the
+// form asks viewers to draw the field themselves. Check boxes accept true
+// or false as well as their on state, radio groups the state of one of
+// their buttons. It returns the names that match no field.
+func (d *Document) FillForm(values map[string]string) ([]string, error) {
+	if d.Encrypted() {
+		return nil, ErrEncrypted
+	}
+	nodes := map[string]*formNode{}
+	for _, n := range d.terminalFields() {
+		nodes[n.name] = n
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var unmatched []string
+	for _, name := range names {
+		n, ok := nodes[name]
+		if !ok {
+			unmatched = append(unmatched, name)
+			continue
+		}
+		value := values[name]
+		var err error
+		switch kind := n.kind(); kind {
+		case FieldText:
+			n.dict["V"] = EncodeTextString(value)
+			text := value
+			if n.flags&flagMultiline == 0 {
+				text = strings.ReplaceAll(text, "\n", " ")
+			}
+			if n.flags&flagPassword != 0 {
+				text = strings.Repeat("*", len([]rune(value)))
+			}
+			err = d.setTextAppearance(n, []string{text}, -1)
+		case FieldCheckbox, FieldRadio:
+			err = d.setToggle(n, value)
+		case FieldSelect:
+			err = d.setChoice(n, value)
+		default:
+			err = fmt.Errorf("%w: %s: %s fields cannot be filled", ErrInvalidFormValue, name, kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return unmatched, nil
+}
+
+func (d *Document) setToggle(n *formNode, value string) error {
+	states := d.onStates(n)
+	state := Name("")
+	for _, s := range states {
+		if string(s) == value {
+			state = s
+		}
+	}
+	if state == "" {
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "", "false", "no", "off", "0":
+			state = "Off"
+		case "true", "yes", "on", "1":
+			if n.kind() == FieldCheckbox || len(states) == 1 {
+				state = "Yes"
+				if len(states) > 0 {
+					state = states[0]
+				}
+			}
+		}
+	}
+	if state == "" {
+		options := make([]string, len(states))
+		for i, s := range states {
+			options[i] = string(s)
+		}
+		return fmt.Errorf("%w: %s: %q is not one of %s", ErrInvalidFormValue, n.name, value, strings.Join(options, ", "))
+	}
+
+	n.dict["V"] = state
+	for _, w := range n.widgets {
+		as := Name("Off")
+		for _, s := range widgetStates(d, w) {
+			if s == state {
+				as = s
+			}
+		}
+		w["AS"] = as
+	}
+	return nil
+}
+
+func (d *Document) setChoice(n *formNode, value string) error {
+	opts := d.GetArray(n.dict["Opt"])
+	selected := -1
+	label := value
+	for i, o := range opts {
+		export, display := d.option(o)
+		if value == export || value == display {
+			selected, label = i, display
+			value = export
+			break
+		}
+	}
+	if selected < 0 && value != "" && n.flags&flagEdit == 0 {
+		return fmt.Errorf("%w: %s: %q is not an option", ErrInvalidFormValue, n.name, value)
+	}
+	n.dict["V"] = EncodeTextString(value)
+	delete(n.dict, "I")
+
+	if n.flags&flagCombo != 0 {
+		return d.setTextAppearance(n, []string{label}, -1)
+	}
+	// A list box shows its options with the selected one highlighted.
+	lines := make([]string, len(opts))
+	for i, o := range opts {
+		_, lines[i] = d.option(o)
+	}
+	return d.setTextAppearance(n, lines, selected)
+}
+
+// setTextAppearance gives every widget of n an appearance showing lines,
+// with line highlight marked as selected. A single line of a field that
+// is not multiline is centred vertically; other text starts at the top.
+func (d *Document) setTextAppearance(n *formNode, lines []string, highlight int) error {
+	fontName, size, color := parseDA(n.da)
+	form := d.GetDict(d.Catalog()["AcroForm"])
+	fonts := d.GetDict(d.GetDict(form["DR"])["Font"])
+	font := fonts[fontName]
+	switch d.GetName(d.GetDict(font)["Subtype"]) {
+	case "Type1", "TrueType", "MMType1":
+	default:
+		// Composite and Type 3 fonts cannot be fed WinAnsi text.
+		fontName = "Helv"
+		font = d.GetDict(d.GetDict(d.acroForm()["DR"])["Font"])["Helv"]
+	}
+	width := d.glyphWidths(d.GetDict(font))
+
+	encoded := make([][]byte, 0, len(lines))
+	for _, line := range lines {
+		for _, part := range strings.Split(line, "\n") {
+			b, err := EncodeWinAnsi(strings.TrimRight(part, "\r"))
+			if err != nil {
+				d.needAppearances(n)
+				return nil
+			}
+			encoded = append(encoded, b)
+		}
+	}
+	multiline := n.flags&flagMultiline != 0 || highlight >= 0 || len(lines) > 1
+
+	for _, widget := range n.widgets {
+		rect, _ := d.GetRect(widget["Rect"])
+		w, h := rect.Width(), rect.Height()
+		const pad = 2
+		avail := w - 2*pad
+		measure := func(b []byte, size float64) float64 {
+			total := 0.0
+			for _, c := range b {
+				total += width(c)
+			}
+			return total * size / 1000
+		}
+
+		fs := size
+		if fs == 0 {
+			fs = math.Min(12, (h-2*pad)/1.15)
+			if !multiline && len(encoded) == 1 {
+				if tw := measure(encoded[0], fs); tw > avail {
+					fs *= avail / tw
+				}
+			}
+			fs = math.Max(fs, 4)
+		}
+		rows := encoded
+		if n.flags&flagMultiline != 0 {
+			rows = nil
+			for _, b := range encoded {
+				rows = append(rows, wrapLine(b, avail, func(b []byte) float64 { return measure(b, fs) })...)
+			}
+		}
+
+		var buf bytes.Buffer
+		mk := d.GetDict(widget["MK"])
+		if op := colorOp(d.GetArray(mk["BG"]), false); op != "" {
+			fmt.Fprintf(&buf, "%s 0 0 %s %s re f\n", op, formatReal(w), formatReal(h))
+		}
+		if op := colorOp(d.GetArray(mk["BC"]), true); op != "" {
+			fmt.Fprintf(&buf, "%s 1 w 0.5 0.5 %s %s re S\n", op, formatReal(w-1), formatReal(h-1))
+		}
+		lead := fs * 1.15
+		top := h - pad - fs
+		if highlight >= 0 && highlight < len(rows) {
+			y := top - float64(highlight)*lead
+			fmt.Fprintf(&buf, "0.6 0.75 0.88 rg 1 %s %s %s re f\n",
+				formatReal(y-fs*0.25), formatReal(w-2), formatReal(lead))
+		}
+		buf.WriteString("/Tx BMC\nq\n")
+		fmt.Fprintf(&buf, "1 1 %s %s re W n\nBT\n%s\n/%s %s Tf\n",
+			formatReal(w-2), formatReal(h-2), color, fontName, formatReal(fs))
+		for i, row := range rows {
+			y := top - float64(i)*lead
+			if !multiline {
+				y = (h - fs*0.7) / 2
+			}
+			x := pad + 0.0
+			switch n.q {
+			case 1:
+				x = (w - measure(row, fs)) / 2
+			case 2:
+				x = w - pad - measure(row, fs)
+			}
+			fmt.Fprintf(&buf, "1 0 0 1 %s %s Tm ", formatReal(x), formatReal(y))
+			writeString(&buf, String(row))
+			buf.WriteString(" Tj\n")
+		}
+		buf.WriteString("ET\nQ\nEMC\n")
+
+		ap := NewStream(Dict{
+			"Type": Name("XObject"), "Subtype": Name("Form"),
+			"BBox":      Rect{0, 0, w, h}.Array(),
+			"Resources": Dict{"Font": Dict{fontName: font}},
+		}, buf.Bytes())
+		widget["AP"] = Dict{"N": d.Add(ap)}
+	}
+	return nil
+}
+
+// parseDA returns the font, size and colour operator of a default
+// appearance string, defaulting to Helv and black.
+func parseDA(da string) (Name, float64, string) {
+	font, size, color := Name("Helv"), 0.0, "0 g"
+	ops, _ := ParseContent([]byte(da))
+	for _, op := range ops {
+		switch op.Operator {
+		case "Tf":
+			if len(op.Operands) == 2 {
+				if n, ok := op.Operands[0].(Name); ok {
+					font = n
+				}
+				size, _ = toFloat(op.Operands[1])
+			}
+		case "g", "rg", "k":
+			if vals, ok := operandFloats(op.Operands); ok {
+				parts := make([]string, len(vals))
+				for i, v := range vals {
+					parts[i] = formatReal(v)
+				}
+				color = strings.Join(parts, " ") + " " + op.Operator
+			}
+		}
+	}
+	return font, size, color
+}
+
+// colorOp returns the operator setting an MK colour array as fill colour,
+// or stroke colour when stroke is set.
+func colorOp(a Array, stroke bool) string {
+	vals, ok := operandFloats(a)
+	if !ok {
+		return ""
+	}
+	ops := map[int]string{1: "g", 3: "rg", 4: "k"}
+	op, ok := ops[len(vals)]
+	if !ok {
+		return ""
+	}
+	if stroke {
+		op = strings.ToUpper(op)
+	}
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = formatReal(v)
+	}
+	return strings.Join(parts, " ") + " " + op
+}
+
+// glyphWidths returns the width of a WinAnsi code in a simple font, in
+// thousandths of the font size. Standard fonts without a Widths array use
+// the Helvetica metrics, or fixed widths for Courier.
+func (d *Document) glyphWidths(font Dict) func(byte) float64 {
+	if widths := d.GetArray(font["Widths"]); len(widths) > 0 {
+		first, _ := d.GetInt(font["FirstChar"])
+		return func(c byte) float64 {
+			if i := int(c) - first; i >= 0 && i < len(widths) {
+				if w, ok := d.GetFloat(widths[i]); ok {
+					return w
+				}
+			}
+			return helveticaBoldDefaultWidth
+		}
+	}
+	base := string(d.GetName(font["BaseFont"]))
+	if strings.Contains(base, "Courier") {
+		return func(byte) float64 { return 600 }
+	}
+	table := &helveticaWidths
+	if strings.Contains(base, "Bold") {
+		table = &helveticaBoldWidths
+	}
+	return func(c byte) float64 {
+		if c >= 0x20 && c <= 0x7e {
+			return float64(table[c-0x20])
+		}
+		return helveticaBoldDefaultWidth
+	}
+}
+
+// wrapLine breaks text at spaces into lines no wider than avail; words
+// wider than a line are broken between characters.
+func wrapLine(text []byte, avail float64, measure func([]byte) float64) [][]byte {
+	var lines [][]byte
+	var line []byte
+	for _, word := range bytes.Split(text, []byte(" ")) {
+		candidate := word
+		if len(line) > 0 {
+			candidate = append(append(append([]byte{}, line...), ' '), word...)
+		}
+		if measure(candidate) <= avail {
+			line = candidate
+			continue
+		}
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+		for len(word) > 1 && measure(word) > avail {
+			cut := len(word) - 1
+			for cut > 1 && measure(word[:cut]) > avail {
+				cut--
+			}
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		line = word
+	}
+	return append(lines, line)
+}
+
+// needAppearances drops the appearances of n, which no longer show its
+// value, and has viewers generate them from the value instead.
+func (d *Document) needAppearances(n *formNode) {
+	for _, widget := range n.widgets {
+		delete(widget, "AP")
+	}
+	if form := d.GetDict(d.Catalog()["AcroForm"]); form != nil {
+		form["NeedAppearances"] = true
+	}
+}
+
+// FlattenForm draws the appearance of every visible widget into its page
+// and removes the interactive form, so field values become ordinary page
+// content. A field whose value has no appearance, left for viewers to
+// draw, cannot be flattened.
+func (d *Document) FlattenForm() error {
+	if d.Encrypted() {
+		return ErrEncrypted
+	}
+	if form := d.GetDict(d.Catalog()["AcroForm"]); form != nil && form["NeedAppearances"] == true {
+		for _, n := range d.terminalFields() {
+			if kind := n.kind(); (kind != FieldText && kind != FieldSelect) || len(d.GetString(n.dict["V"])) == 0 {
+				continue
+			}
+			for _, widget := range n.widgets {
+				if d.normalAppearance(widget) == nil {
+					return fmt.Errorf("%w: %s: text outside the Latin character set cannot be flattened without an embedded Unicode font", ErrInvalidFormValue, n.name)
+				}
+			}
+		}
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	for _, p := range pages {
+		annots := d.GetArray(p.Dict["Annots"])
+		if len(annots) == 0 {
+			continue
+		}
+		kept := make(Array, 0, len(annots))
+		var buf bytes.Buffer
+		var res Dict
+		for _, o := range annots {
+			annot := d.GetDict(o)
+			if d.GetName(annot["Subtype"]) != "Widget" {
+				kept = append(kept, o)
+				continue
+			}
+			if ref, ok := o.(Ref); ok {
+				d.Delete(ref)
+			}
+			flags, _ := d.GetInt(annot["F"])
+			ap := d.normalAppearance(annot)
+			stream := d.GetStream(ap)
+			if flags&annotHidden != 0 || stream == nil {
+				continue
+			}
+			rect, _ := d.GetRect(annot["Rect"])
+			bbox, _ := d.GetRect(stream.Dict["BBox"])
+			m := matrixFromArray(d.GetArray(stream.Dict["Matrix"]))
+			x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+			for _, c := range [][2]float64{{bbox.LLX, bbox.LLY}, {bbox.URX, bbox.LLY}, {bbox.LLX, bbox.URY}, {bbox.URX, bbox.URY}} {
+				x, y := m.Apply(c[0], c[1])
+				x0, y0, x1, y1 = math.Min(x0, x), math.Min(y0, y), math.Max(x1, x), math.Max(y1, y)
+			}
+			if x1-x0 <= 0 || y1-y0 <= 0 {
+				continue
+			}
+			ref, ok := ap.(Ref)
+			if !ok {
+				ref = d.Add(stream)
+			}
+			stream.Dict["Type"], stream.Dict["Subtype"] = Name("XObject"), Name("Form")
+			if res == nil {
+				res = d.OwnResources(p)
+			}
+			name := AddResource(res, "XObject", "Fm", ref)
+			sx, sy := rect.Width()/(x1-x0), rect.Height()/(y1-y0)
+			fmt.Fprintf(&buf, "q %s 0 0 %s %s %s cm /%s Do Q\n", formatReal(sx), formatReal(sy),
+				formatReal(rect.LLX-x0*sx), formatReal(rect.LLY-y0*sy), name)
+		}
+		if len(kept) == 0 {
+			delete(p.Dict, "Annots")
+		} else {
+			p.Dict["Annots"] = kept
+		}
+		if buf.Len() > 0 {
+			d.WrapContent(p, nil, buf.Bytes())
+		}
+	}
+
+	cat := d.Catalog()
+	if form := d.GetDict(cat["AcroForm"]); form != nil {
+		d.walkFields(form, func(_ string, ref Ref, _ Dict) { d.Delete(ref) })
+		if ref, ok := cat["AcroForm"].(Ref); ok {
+			d.Delete(ref)
+		}
+		delete(cat, "AcroForm")
+	}
+	return nil
+}
+
+// normalAppearance returns the normal appearance stream of an annotation,
+// selecting the current state for appearances that have several.
+func (d *Document) normalAppearance(annot Dict) Object {
+	n := d.GetDict(annot["AP"])["N"]
+	if d.GetStream(n) != nil {
+		return n
+	}
+	if states := d.GetDict(n); states != nil {
+		return states[d.GetName(annot["AS"])]
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFormDocument(t *testing.T) *Document {
+	t.Helper()
+	d := newTestDocument(t, 1)
+	require.NoError(t, d.AddFormFields([]FormField{
+		{Name: "customer.name", Type: FieldText, Rect: Rect{50, 700, 250, 720}, Required: true},
+		{Name: "notes", Type: FieldText, Rect: Rect{50, 600, 250, 680}, Multiline: true, MaxLength: 500},
+		{Name: "agree", Type: FieldCheckbox, Rect: Rect{50, 560, 62, 572}},
+		{Name: "plan", Type: FieldRadio, Rect: Rect{50, 540, 62, 552}, Export: "basic", Checked: true},
+		{Name: "plan", Type: FieldRadio, Rect: Rect{80, 540, 92, 552}, Export: "pro"},
+		{Name: "city", Type: FieldSelect, Rect: Rect{50, 500, 150, 520}, Options: []string{"thr", "isf"}, Labels: []string{"Tehran", "Isfahan"}},
+		{Name: "signature", Type: FieldSignature, Rect: Rect{50, 400, 250, 450}, ReadOnly: true},
+	}))
+	return roundTrip(t, d)
+}
+
+func TestFormFields(t *testing.T) {
+	d := newFormDocument(t)
+	fields := d.FormFields()
+	require.Len(t, fields, 6)
+
+	assert.Equal(t, FieldInfo{Name: "customer.name", Type: FieldText, Required: true}, fields[0])
+	assert.Equal(t, FieldInfo{Name: "notes", Type: FieldText, Multiline: true, MaxLength: 500}, fields[1])
+	assert.Equal(t, FieldInfo{Name: "agree", Type: FieldCheckbox, Options: []string{"Yes"}}, fields[2])
+	assert.Equal(t, FieldInfo{Name: "plan", Type: FieldRadio, Value: "basic", Options: []string{"basic", "pro"}}, fields[3])
+	assert.Equal(t, FieldInfo{Name: "city", Type: FieldSelect, Options: []string{"thr", "isf"}}, fields[4])
+	assert.Equal(t, FieldInfo{Name: "signature", Type: FieldSignature, ReadOnly: true}, fields[5])
+}
+
+func TestFillForm(t *testing.T) {
+	d := newFormDocument(t)
+	unmatched, err := d.FillForm(map[string]string{
+		"customer.name": "Ali Rezaei",
+		"notes":         "A long note that does not fit on a single line of the field",
+		"agree":         "true",
+		"plan":          "pro",
+		"city":          "Isfahan",
+		"unknown":       "x",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unknown"}, unmatched)
+
+	d = roundTrip(t, d)
+	values := map[string]string{}
+	for _, f := range d.FormFields() {
+		values[f.Name] = f.Value
+	}
+	assert.Equal(t, "Ali Rezaei", values["customer.name"])
+	assert.Equal(t, "Yes", values["agree"])
+	assert.Equal(t, "pro", values["plan"])
+	assert.Equal(t, "isf", values["city"])
+
+	fields := formFields(d)
+	widget := d.GetDict(d.GetArray(fields["customer.name"]["Kids"])[0])
+	ap := d.GetStream(d.GetDict(widget["AP"])["N"])
+	require.NotNil(t, ap)
+	data, err := ap.Decode()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "(Ali Rezaei) Tj")
+	assert.Contains(t, string(data), "/Helv 12 Tf")
+
+	notes := d.GetDict(d.GetArray(fields["notes"]["Kids"])[0])
+	data, err = d.GetStream(d.GetDict(notes["AP"])["N"]).Decode()
+	require.NoError(t, err)
+	ops, err := ParseContent(data)
+	require.NoError(t, err)
+	shown := 0
+	for _, op := range ops {
+		if op.Operator == "Tj" {
+			shown++
+		}
+	}
+	assert.Equal(t, 2, shown, "the note wraps onto two lines")
+
+	kids := d.GetArray(fields["plan"]["Kids"])
+	assert.Equal(t, Name("Off"), d.GetDict(kids[0])["AS"])
+	assert.Equal(t, Name("pro"), d.GetDict(kids[1])["AS"])
+}
+
+func TestFillForm_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   string
+	}{
+		{"radio state", map[string]string{"plan": "gold"}, `"gold" is not one of basic, pro`},
+		{"option", map[string]string{"city": "Shiraz"}, `"Shiraz" is not an option`},
+		{"signature", map[string]string{"signature": "x"}, "signature fields cannot be filled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFormDocument(t).FillForm(tt.values)
+			require.ErrorIs(t, err, ErrInvalidFormValue)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestFillForm_Persian(t *testing.T) {
+	d := newFormDocument(t)
+	_, err := d.FillForm(map[string]string{"customer.name": "علی رضایی", "notes": "Latin text"})
+	require.NoError(t, err)
+
+	d = roundTrip(t, d)
+	assert.Equal(t, "علی رضایی", d.FormFields()[0].Value)
+	form := d.GetDict(d.Catalog()["AcroForm"])
+	assert.Equal(t, true, form["NeedAppearances"], "viewers draw the field")
+	fields := formFields(d)
+	widget := d.GetDict(d.GetArray(fields["customer.name"]["Kids"])[0])
+	assert.Nil(t, widget["AP"], "the empty appearance is not kept")
+	notes := d.GetDict(d.GetArray(fields["notes"]["Kids"])[0])
+	assert.NotNil(t, notes["AP"])
+
+	// Flattening needs an appearance to draw.
+	err = d.FlattenForm()
+	require.ErrorIs(t, err, ErrInvalidFormValue)
+	assert.Contains(t, err.Error(), "customer.name")
+}
+
+func TestFlattenForm(t *testing.T) {
+	d := newFormDocument(t)
+	_, err := d.FillForm(map[string]string{"customer.name": "Ali Rezaei", "agree": "yes"})
+	require.NoError(t, err)
+	require.NoError(t, d.FlattenForm())
+
+	d = roundTrip(t, d)
+	assert.Nil(t, d.Catalog()["AcroForm"])
+	assert.Empty(t, d.FormFields())
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	assert.Nil(t, pages[0].Dict["Annots"])
+
+	content, err := d.Content(pages[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "q 1 0 0 1 50 700 cm /Fm1 Do Q")
+	xobjects := d.GetDict(pages[0].Resources["XObject"])
+	assert.Len(t, xobjects, 7)
+}
@@ -0,0 +1,337 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// maxDecodedSize bounds the decoded size of a single stream, so that a
+// small compressed stream cannot expand to exhaust memory.
+const maxDecodedSize = 256 << 20
+
+// imageFilters are left in place by Decode; their data is only meaningful
+// to an image decoder.
+var imageFilters = map[Name]bool{
+	"DCTDecode":      true,
+	"JPXDecode":      true,
+	"CCITTFaxDecode": true,
+	"JBIG2Decode":    true,
+}
+
+func (s *Stream) filters() ([]Name, []Dict) {
+	var names []Name
+	var parms []Dict
+	switch f := s.Dict["Filter"].(type) {
+	case Name:
+		names = []Name{f}
+	case Array:
+		for _, o := range f {
+			if n, ok := o.(Name); ok {
+				names = append(names, n)
+			}
+		}
+	}
+	switch p := s.Dict["DecodeParms"].(type) {
+	case Dict:
+		parms = []Dict{p}
+	case Array:
+		for _, o := range p {
+			d, _ := o.(Dict)
+			parms = append(parms, d)
+		}
+	}
+	for len(parms) < len(names) {
+		parms = append(parms, nil)
+	}
+	return names, parms
+}
+
+// Decode returns the stream data with all non-image filters applied. It
+// fails with ErrUnsupportedFilter if the chain contains a filter this
+// package cannot decode or an image filter followed by another filter.
+func (s *Stream) Decode() ([]byte, error) {
+	data := s.Data
+	names, parms := s.filters()
+	for i, name := range names {
+		if imageFilters[name] {
+			if i != len(names)-1 {
+				return nil, fmt.Errorf("%w: %s", ErrUnsupportedFilter, name)
+			}
+			return data, nil
+		}
+		var err error
+		data, err = applyFilter(name, parms[i], data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// ImageFilter returns the image filter left undecoded by Decode, if any.
+func (s *Stream) ImageFilter() Name {
+	names, _ := s.filters()
+	if len(names) > 0 && imageFilters[names[len(names)-1]] {
+		return names[len(names)-1]
+	}
+	return ""
+}
+
+func applyFilter(name Name, parms Dict, data []byte) ([]byte, error) {
+	switch name {
+	case "FlateDecode", "Fl":
+		out, err := inflate(data)
+		if err != nil {
+			return nil, err
+		}
+		return unpredict(out, parms)
+	case "ASCIIHexDecode", "AHx":
+		return decodeASCIIHex(data)
+	case "ASCII85Decode", "A85":
+		return decodeASCII85(data)
+	case "RunLengthDecode", "RL":
+		return decodeRunLength(data)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFilter, name)
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: flate: %v", ErrMalformed, err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if len(out) > maxDecodedSize {
+		return nil, fmt.Errorf("%w: flate: stream decodes to more than %d bytes", ErrMalformed, maxDecodedSize)
+	}
+	// Truncated streams are common; keep whatever could be inflated.
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("%w: flate: %v", ErrMalformed, err)
+	}
+	return out, nil
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func unpredict(data []byte, parms Dict) ([]byte, error) {
+	if parms == nil {
+		return data, nil
+	}
+	predictor, _ := toInt(parms["Predictor"])
+	if predictor <= 1 {
+		return data, nil
+	}
+	colors := intOr(parms["Colors"], 1)
+	bpc := intOr(parms["BitsPerComponent"], 8)
+	columns := intOr(parms["Columns"], 1)
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("%w: predictor with %d bits per component", ErrMalformed, bpc)
+	}
+	if colors < 1 || colors > 32 || columns < 1 || columns > maxDecodedSize {
+		return nil, fmt.Errorf("%w: predictor with %d colors and %d columns", ErrMalformed, colors, columns)
+	}
+	bpp := (colors*bpc + 7) / 8
+	rowLen := (colors*bpc*columns + 7) / 8
+
+	if predictor == 2 {
+		if bpc != 8 {
+			return nil, fmt.Errorf("%w: TIFF predictor with %d bits per component", ErrUnsupportedFilter, bpc)
+		}
+		out := append([]byte(nil), data...)
+		for row := 0; row+rowLen <= len(out); row += rowLen {
+			for i := bpp; i < rowLen; i++ {
+				out[row+i] += out[row+i-bpp]
+			}
+		}
+		return out, nil
+	}
+
+	// PNG predictors: every row starts with its own filter type byte.
+	out := make([]byte, 0, len(data))
+	if 1+rowLen > len(data) {
+		return out, nil
+	}
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += 1 + rowLen {
+		ft := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+		for i := range row {
+			var left, up, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up = prev[i]
+			switch ft {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func intOr(o Object, def int) int {
+	if v, ok := toInt(o); ok {
+		return v
+	}
+	return def
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var out []byte
+	var hi byte
+	half := false
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		v, ok := unhex(c)
+		if !ok {
+			if isWhitespace(c) {
+				continue
+			}
+			return nil, fmt.Errorf("%w: invalid ASCIIHex data", ErrMalformed)
+		}
+		if half {
+			out = append(out, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		out = append(out, hi<<4)
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	var out []byte
+	var group [5]byte
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if isWhitespace(c) {
+			continue
+		}
+		if c == '~' {
+			break
+		}
+		if c == 'z' && n == 0 {
+			out = append(out, 0, 0, 0, 0)
+			continue
+		}
+		if c < '!' || c > 'u' {
+			return nil, fmt.Errorf("%w: invalid ASCII85 data", ErrMalformed)
+		}
+		group[n] = c - '!'
+		n++
+		if n == 5 {
+			v := uint32(0)
+			for _, g := range group {
+				v = v*85 + uint32(g)
+			}
+			out = append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+			n = 0
+		}
+	}
+	if n > 1 {
+		for i := n; i < 5; i++ {
+			group[i] = 84
+		}
+		v := uint32(0)
+		for _, g := range group {
+			v = v*85 + uint32(g)
+		}
+		b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, b[:n-1]...)
+	}
+	return out, nil
+}
+
+func decodeRunLength(data []byte) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(data); {
+		if len(out) > maxDecodedSize {
+			return nil, fmt.Errorf("%w: run length: stream decodes to more than %d bytes", ErrMalformed, maxDecodedSize)
+		}
+		l := int(data[i])
+		i++
+		switch {
+		case l == 128:
+			return out, nil
+		case l < 128:
+			end := i + l + 1
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		default:
+			if i < len(data) {
+				for j := 0; j < 257-l; j++ {
+					out = append(out, data[i])
+				}
+			}
+			i++
+		}
+	}
+	return out, nil
+}
+
+// NewStream returns a Flate compressed stream holding data. Entries of dict
+// other than the filter and length are kept.
+func NewStream(dict Dict, data []byte) *Stream {
+	if dict == nil {
+		dict = Dict{}
+	}
+	delete(dict, "DecodeParms")
+	dict["Filter"] = Name("FlateDecode")
+	return &Stream{Dict: dict, Data: deflate(data)}
+}
+
+// SetData replaces the stream content with data, Flate compressed.
+func (s *Stream) SetData(data []byte) {
+	delete(s.Dict, "DecodeParms")
+	s.Dict["Filter"] = Name("FlateDecode")
+	s.Data = deflate(data)
+}
@@ -0,0 +1,478 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+var ErrInvalidFormField = errors.New("invalid form field")
+
+// Field types accepted in FormField.Type.
+const (
+	FieldText      = "text"
+	FieldCheckbox  = "checkbox"
+	FieldRadio     = "radio"
+	FieldSelect    = "select"
+	FieldSignature = "signature"
+)
+
+// Field flags (ISO 32000-1, Tables 221, 226, 228 and 230).
+const (
+	flagReadOnly    = 1 << 0
+	flagRequired    = 1 << 1
+	flagMultiline   = 1 << 12
+	flagPassword    = 1 << 13
+	flagNoToggleOff = 1 << 14
+	flagRadio       = 1 << 15
+	flagCombo       = 1 << 17
+	flagMultiSelect = 1 << 21
+)
+
+// annotPrint is the annotation flag that makes widgets appear when printed.
+const annotPrint = 4
+
+// FormField describes one widget of an interactive form field. Widgets
+// sharing a name form one field; for radio buttons they are the choices of
+// one group.
+type FormField struct {
+	// Name is the fully qualified field name; periods separate the names
+	// of parent fields.
+	Name string
+	Type string
+	// Page is the zero-based index of the page the widget is placed on.
+	Page int
+	Rect Rect
+	// Value is the text or selected option. For check boxes and radio
+	// buttons, Checked selects the widget and Export names its on state.
+	Value   string
+	Export  string
+	Checked bool
+	// Options are the choices of a select field; Labels, when set, are
+	// shown instead of the corresponding option.
+	Options   []string
+	Labels    []string
+	ReadOnly  bool
+	Required  bool
+	Multiline bool
+	Password  bool
+	// ListBox shows a select field as a list instead of a drop-down;
+	// MultiSelect also allows several options to be chosen.
+	ListBox     bool
+	MultiSelect bool
+	MaxLength   int
+	// FontSize is used for text entered in a viewer; zero sizes the text
+	// to fit the widget.
+	FontSize float64
+}
+
+// formFonts are the standard fonts added to the form's default resources.
+var formFonts = map[Name]string{"Helv": "Helvetica", "ZaDb": "ZapfDingbats"}
+
+// AddFormFields adds interactive form fields to the document. Text and
+// select widgets get a transparent appearance, so whatever the page
+// already shows at their position stays visible until the value is edited;
+// check boxes and radio buttons get on and off appearances.
+func (d *Document) AddFormFields(fields []FormField) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+
+	// Group widgets by field name, keeping the order of first appearance.
+	var names []string
+	groups := map[string][]FormField{}
+	for _, f := range fields {
+		f.Name = strings.TrimSpace(f.Name)
+		if err := f.validate(len(pages)); err != nil {
+			return err
+		}
+		if prev, ok := groups[f.Name]; ok && prev[0].Type != f.Type {
+			return fmt.Errorf("%w: %q is both %s and %s", ErrInvalidFormField, f.Name, prev[0].Type, f.Type)
+		}
+		if _, ok := groups[f.Name]; !ok {
+			names = append(names, f.Name)
+		}
+		groups[f.Name] = append(groups[f.Name], f)
+	}
+
+	form := d.acroForm()
+	zapf := d.GetDict(d.GetDict(form["DR"])["Font"])["ZaDb"]
+	tree := &fieldTree{d: d, form: form, nodes: map[string]Ref{}}
+	for _, name := range names {
+		widgets := groups[name]
+		field, ref, err := tree.terminal(name)
+		if err != nil {
+			return err
+		}
+		d.fillField(field, widgets)
+		for i, w := range widgets {
+			widget := d.widget(w, i, field, zapf)
+			widget["Parent"] = ref
+			widget["P"] = pages[w.Page].Ref
+			wref := d.Add(widget)
+			field["Kids"] = append(d.GetArray(field["Kids"]), wref)
+			pages[w.Page].Dict["Annots"] = append(d.GetArray(pages[w.Page].Dict["Annots"]), wref)
+		}
+	}
+	return nil
+}
+
+func (f *FormField) validate(pageCount int) error {
+	if f.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFormField)
+	}
+	for _, part := range strings.Split(f.Name, ".") {
+		if part == "" {
+			return fmt.Errorf("%w: %q has an empty name part", ErrInvalidFormField, f.Name)
+		}
+	}
+	switch f.Type {
+	case FieldText, FieldCheckbox, FieldRadio, FieldSelect, FieldSignature:
+	default:
+		return fmt.Errorf("%w: %s: unknown type %q", ErrInvalidFormField, f.Name, f.Type)
+	}
+	if f.Page < 0 || f.Page >= pageCount {
+		return fmt.Errorf("%w: %s: page %d out of range", ErrInvalidFormField, f.Name, f.Page+1)
+	}
+	if f.Rect.Width() <= 0 || f.Rect.Height() <= 0 {
+		return fmt.Errorf("%w: %s: empty rectangle", ErrInvalidFormField, f.Name)
+	}
+	if len(f.Labels) > 0 && len(f.Labels) != len(f.Options) {
+		return fmt.Errorf("%w: %s: labels do not match options", ErrInvalidFormField, f.Name)
+	}
+	return nil
+}
+
+// acroForm returns the interactive form dictionary, creating it and adding
+// the standard fonts used by field appearances.
+func (d *Document) acroForm() Dict {
+	cat := d.Catalog()
+	form := d.GetDict(cat["AcroForm"])
+	if form == nil {
+		form = Dict{"Fields": Array{}}
+		cat["AcroForm"] = d.Add(form)
+	}
+	if form["DA"] == nil {
+		form["DA"] = String("/Helv 0 Tf 0 g")
+	}
+	dr := d.GetDict(form["DR"])
+	if dr == nil {
+		dr = Dict{}
+		form["DR"] = dr
+	}
+	fonts := d.GetDict(dr["Font"])
+	if fonts == nil {
+		fonts = Dict{}
+		dr["Font"] = fonts
+	}
+	for name, base := range formFonts {
+		if fonts[name] == nil {
+			font := Dict{"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name(base)}
+			if base == "Helvetica" {
+				font["Encoding"] = Name("WinAnsiEncoding")
+			}
+			fonts[name] = d.Add(font)
+		}
+	}
+	return form
+}
+
+// fieldTree finds or creates the fields along a qualified name.
+type fieldTree struct {
+	d     *Document
+	form  Dict
+	nodes map[string]Ref
+}
+
+func (t *fieldTree) terminal(name string) (Dict, Ref, error) {
+	d := t.d
+	if len(t.nodes) == 0 {
+		d.walkFields(t.form, func(qualified string, ref Ref, _ Dict) {
+			t.nodes[qualified] = ref
+		})
+	}
+	if _, exists := t.nodes[name]; exists {
+		return nil, Ref{}, fmt.Errorf("%w: %q already exists", ErrInvalidFormField, name)
+	}
+
+	parts := strings.Split(name, ".")
+	var parent Ref
+	hasParent := false
+	for i := range parts[:len(parts)-1] {
+		path := strings.Join(parts[:i+1], ".")
+		ref, ok := t.nodes[path]
+		if !ok {
+			node := Dict{"T": EncodeTextString(parts[i]), "Kids": Array{}}
+			ref = t.attach(node, parent, hasParent)
+			t.nodes[path] = ref
+		} else if d.GetDict(ref)["FT"] != nil {
+			return nil, Ref{}, fmt.Errorf("%w: %q is a field and cannot have children", ErrInvalidFormField, path)
+		}
+		parent, hasParent = ref, true
+	}
+
+	field := Dict{"T": EncodeTextString(parts[len(parts)-1])}
+	ref := t.attach(field, parent, hasParent)
+	t.nodes[name] = ref
+	return field, ref, nil
+}
+
+func (t *fieldTree) attach(node Dict, parent Ref, hasParent bool) Ref {
+	ref := t.d.Add(node)
+	if hasParent {
+		node["Parent"] = parent
+		p := t.d.GetDict(parent)
+		p["Kids"] = append(t.d.GetArray(p["Kids"]), ref)
+	} else {
+		t.form["Fields"] = append(t.d.GetArray(t.form["Fields"]), ref)
+	}
+	return ref
+}
+
+// walkFields calls fn for every field of the form with its fully qualified
+// name. Widget annotations without a name of their own are skipped.
+func (d *Document) walkFields(form Dict, fn func(name string, ref Ref, field Dict)) {
+	visited := map[int]bool{}
+	var walk func(o Object, prefix string, depth int)
+	walk = func(o Object, prefix string, depth int) {
+		ref, ok := o.(Ref)
+		if !ok || visited[ref.Num] || depth > maxNesting {
+			return
+		}
+		visited[ref.Num] = true
+		field := d.GetDict(ref)
+		if field == nil || field["T"] == nil {
+			return
+		}
+		name := DecodeTextString(d.GetString(field["T"]))
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fn(name, ref, field)
+		for _, kid := range d.GetArray(field["Kids"]) {
+			walk(kid, name, depth+1)
+		}
+	}
+	for _, f := range d.GetArray(form["Fields"]) {
+		walk(f, "", 0)
+	}
+}
+
+// fillField sets the field type, flags and value from its widgets.
+func (d *Document) fillField(field Dict, widgets []FormField) {
+	first := widgets[0]
+	flags := 0
+	if first.ReadOnly {
+		flags |= flagReadOnly
+	}
+	if first.Required {
+		flags |= flagRequired
+	}
+
+	switch first.Type {
+	case FieldText:
+		field["FT"] = Name("Tx")
+		if first.Multiline {
+			flags |= flagMultiline
+		}
+		if first.Password {
+			flags |= flagPassword
+		}
+		if first.MaxLength > 0 {
+			field["MaxLen"] = first.MaxLength
+		}
+		field["V"] = EncodeTextString(first.Value)
+		field["DV"] = EncodeTextString(first.Value)
+		field["DA"] = fieldDA("Helv", first.FontSize)
+	case FieldCheckbox, FieldRadio:
+		field["FT"] = Name("Btn")
+		if first.Type == FieldRadio {
+			flags |= flagRadio | flagNoToggleOff
+		}
+		value := Name("Off")
+		for i, w := range widgets {
+			if w.Checked {
+				value = exportName(w, i)
+				break
+			}
+		}
+		field["V"] = value
+		field["DV"] = value
+		field["DA"] = fieldDA("ZaDb", 0)
+	case FieldSelect:
+		field["FT"] = Name("Ch")
+		if !first.ListBox {
+			flags |= flagCombo
+		} else if first.MultiSelect {
+			flags |= flagMultiSelect
+		}
+		opts := make(Array, len(first.Options))
+		for i, o := range first.Options {
+			if len(first.Labels) > 0 && first.Labels[i] != o {
+				opts[i] = Array{EncodeTextString(o), EncodeTextString(first.Labels[i])}
+			} else {
+				opts[i] = EncodeTextString(o)
+			}
+		}
+		field["Opt"] = opts
+		if first.Value != "" {
+			field["V"] = EncodeTextString(first.Value)
+			field["DV"] = EncodeTextString(first.Value)
+		}
+		field["DA"] = fieldDA("Helv", first.FontSize)
+	case FieldSignature:
+		field["FT"] = Name("Sig")
+	}
+	if flags != 0 {
+		field["Ff"] = flags
+	}
+}
+
+func fieldDA(font string, size float64) String {
+	return String(fmt.Sprintf("/%s %s Tf 0 g", font, formatReal(size)))
+}
+
+// exportName returns the on state of a check box or radio button widget.
+func exportName(w FormField, index int) Name {
+	export := strings.TrimSpace(w.Export)
+	switch {
+	case export == "" && w.Type == FieldRadio:
+		return Name(fmt.Sprintf("Choice%d", index+1))
+	case export == "", export == "Off":
+		return "Yes"
+	}
+	return Name(export)
+}
+
+// widget builds the widget annotation for w, the index-th widget of field;
+// zapf is the ZapfDingbats font drawing check marks.
+func (d *Document) widget(w FormField, index int, field Dict, zapf Object) Dict {
+	width, height := w.Rect.Width(), w.Rect.Height()
+	bbox := Rect{0, 0, width, height}.Array()
+	widget := Dict{
+		"Type":    Name("Annot"),
+		"Subtype": Name("Widget"),
+		"Rect":    w.Rect.Array(),
+		"F":       annotPrint,
+		"MK":      Dict{"BG": Array{1}, "BC": Array{0.5}},
+	}
+
+	switch w.Type {
+	case FieldCheckbox, FieldRadio:
+		on := exportName(w, index)
+		state := Name("Off")
+		if field["V"] == on {
+			state = on
+		}
+		widget["AS"] = state
+		glyph := "4" // check mark
+		if w.Type == FieldRadio {
+			glyph = "l" // filled circle
+		}
+		widget["MK"].(Dict)["CA"] = String(glyph)
+		fonts := Dict{"Font": Dict{"ZaDb": zapf}}
+		appearance := func(checked bool) Ref {
+			return d.Add(NewStream(Dict{
+				"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": bbox, "Resources": fonts,
+			}, toggleAppearance(w.Type, width, height, checked, glyph)))
+		}
+		widget["AP"] = Dict{"N": Dict{on: appearance(true), "Off": appearance(false)}}
+	default:
+		// The page content underneath shows the initial value.
+		widget["AP"] = Dict{"N": d.Add(NewStream(Dict{
+			"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": bbox,
+		}, nil))}
+	}
+	return widget
+}
+
+// writeCircle adds a circle path, approximated by four Bézier arcs, and
+// paints it with op.
+func writeCircle(w io.Writer, cx, cy, radius float64, op string) {
+	k := radius * 0.5523
+	fmt.Fprintf(w, "%s %s m\n", formatReal(cx+radius), formatReal(cy))
+	fmt.Fprintf(w, "%s %s %s %s %s %s c\n", formatReal(cx+radius), formatReal(cy+k), formatReal(cx+k), formatReal(cy+radius), formatReal(cx), formatReal(cy+radius))
+	fmt.Fprintf(w, "%s %s %s %s %s %s c\n", formatReal(cx-k), formatReal(cy+radius), formatReal(cx-radius), formatReal(cy+k), formatReal(cx-radius), formatReal(cy))
+	fmt.Fprintf(w, "%s %s %s %s %s %s c\n", formatReal(cx-radius), formatReal(cy-k), formatReal(cx-k), formatReal(cy-radius), formatReal(cx), formatReal(cy-radius))
+	fmt.Fprintf(w, "%s %s %s %s %s %s c\n", formatReal(cx+k), formatReal(cy-radius), formatReal(cx+radius), formatReal(cy-k), formatReal(cx+radius), formatReal(cy))
+	fmt.Fprintln(w, op)
+}
+
+// toggleAppearance draws a check box or radio button, with its mark when
+// checked, on an opaque background that hides what the page shows beneath.
+func toggleAppearance(kind string, w, h float64, checked bool, glyph string) []byte {
+	var b strings.Builder
+	b.WriteString("q\n")
+	if kind == FieldRadio {
+		r := math.Min(w, h) / 2
+		circle := func(radius float64, op string) { writeCircle(&b, w/2, h/2, radius, op) }
+		b.WriteString("1 g 0.5 G 1 w\n")
+		circle(r-0.5, "B")
+		if checked {
+			b.WriteString("0 g\n")
+			circle(r/2.2, "f")
+		}
+	} else {
+		fmt.Fprintf(&b, "1 g 0 0 %s %s re f\n", formatReal(w), formatReal(h))
+		fmt.Fprintf(&b, "0.5 G 1 w 0.5 0.5 %s %s re S\n", formatReal(w-1), formatReal(h-1))
+		if checked {
+			size := math.Min(w, h) * 0.8
+			// ZapfDingbats' check mark is 0.846 em wide and about 0.7 em tall.
+			x := (w - size*0.846) / 2
+			y := (h - size*0.7) / 2
+			fmt.Fprintf(&b, "BT 0 g /ZaDb %s Tf %s %s Td (%s) Tj ET\n", formatReal(size), formatReal(x), formatReal(y), glyph)
+		}
+	}
+	b.WriteString("Q\n")
+	return []byte(b.String())
+}
+
+// Link is a link annotation with a URI action.
+type Link struct {
+	Page int
+	Rect Rect
+	URI  string
+}
+
+// TakeLinks removes the link annotations whose URI starts with prefix and
+// returns them in page order. Producers such as Chromium emit a link for
+// every fragment of an anchor, so callers may see several per anchor.
+func (d *Document) TakeLinks(prefix string) ([]Link, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	var links []Link
+	for i, p := range pages {
+		annots := d.GetArray(p.Dict["Annots"])
+		if len(annots) == 0 {
+			continue
+		}
+		kept := make(Array, 0, len(annots))
+		for _, o := range annots {
+			annot := d.GetDict(o)
+			uri := DecodeTextString(d.GetString(d.GetDict(annot["A"])["URI"]))
+			if d.GetName(annot["Subtype"]) != "Link" || !strings.HasPrefix(uri, prefix) {
+				kept = append(kept, o)
+				continue
+			}
+			rect, _ := d.GetRect(annot["Rect"])
+			links = append(links, Link{Page: i, Rect: rect, URI: uri})
+			if ref, ok := o.(Ref); ok {
+				d.Delete(ref)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.Dict, "Annots")
+		} else {
+			p.Dict["Annots"] = kept
+		}
+	}
+	return links, nil
+}
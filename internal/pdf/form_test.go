@@ -0,0 +1,145 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func formFields(d *Document) map[string]Dict {
+	fields := map[string]Dict{}
+	d.walkFields(d.GetDict(d.Catalog()["AcroForm"]), func(name string, _ Ref, field Dict) {
+		fields[name] = field
+	})
+	return fields
+}
+
+func fieldInt(d *Document, o Object) int {
+	v, _ := d.GetInt(o)
+	return v
+}
+
+func TestAddFormFields_RoundTrip(t *testing.T) {
+	d := newTestDocument(t, 2)
+	err := d.AddFormFields([]FormField{
+		{Name: "customer.name", Type: FieldText, Page: 0, Rect: Rect{50, 700, 250, 720}, Value: "Ali Rezaei", ReadOnly: true, FontSize: 12},
+		{Name: "customer.notes", Type: FieldText, Page: 0, Rect: Rect{50, 600, 250, 680}, Multiline: true, MaxLength: 200},
+		{Name: "agree", Type: FieldCheckbox, Page: 1, Rect: Rect{50, 700, 62, 712}, Checked: true},
+		{Name: "plan", Type: FieldRadio, Page: 1, Rect: Rect{50, 650, 62, 662}, Export: "basic"},
+		{Name: "plan", Type: FieldRadio, Page: 1, Rect: Rect{80, 650, 92, 662}, Export: "pro", Checked: true},
+		{Name: "city", Type: FieldSelect, Page: 1, Rect: Rect{50, 600, 150, 620}, Options: []string{"thr", "isf"}, Labels: []string{"تهران", "isf"}, Value: "isf"},
+		{Name: "signature", Type: FieldSignature, Page: 1, Rect: Rect{50, 500, 250, 550}},
+	})
+	require.NoError(t, err)
+
+	d = roundTrip(t, d)
+	fields := formFields(d)
+	require.Contains(t, fields, "customer")
+	assert.Nil(t, fields["customer"]["FT"])
+
+	name := fields["customer.name"]
+	assert.Equal(t, Name("Tx"), name["FT"])
+	assert.Equal(t, "Ali Rezaei", DecodeTextString(d.GetString(name["V"])))
+	assert.Equal(t, flagReadOnly, fieldInt(d, name["Ff"]))
+	assert.Equal(t, "/Helv 12 Tf 0 g", string(d.GetString(name["DA"])))
+
+	notes := fields["customer.notes"]
+	assert.Equal(t, flagMultiline, fieldInt(d, notes["Ff"]))
+	assert.Equal(t, 200, fieldInt(d, notes["MaxLen"]))
+
+	agree := fields["agree"]
+	assert.Equal(t, Name("Btn"), agree["FT"])
+	assert.Equal(t, Name("Yes"), agree["V"])
+	widget := d.GetDict(d.GetArray(agree["Kids"])[0])
+	assert.Equal(t, Name("Yes"), widget["AS"])
+	assert.Len(t, d.GetDict(d.GetDict(widget["AP"])["N"]), 2)
+
+	plan := fields["plan"]
+	assert.Equal(t, flagRadio|flagNoToggleOff, fieldInt(d, plan["Ff"]))
+	assert.Equal(t, Name("pro"), plan["V"])
+	kids := d.GetArray(plan["Kids"])
+	require.Len(t, kids, 2)
+	assert.Equal(t, Name("Off"), d.GetDict(kids[0])["AS"])
+	assert.Equal(t, Name("pro"), d.GetDict(kids[1])["AS"])
+
+	city := fields["city"]
+	assert.Equal(t, Name("Ch"), city["FT"])
+	assert.Equal(t, flagCombo, fieldInt(d, city["Ff"]))
+	opts := d.GetArray(city["Opt"])
+	require.Len(t, opts, 2)
+	assert.Equal(t, "تهران", DecodeTextString(d.GetString(d.GetArray(opts[0])[1])))
+	assert.Equal(t, "isf", DecodeTextString(d.GetString(opts[1])))
+
+	assert.Equal(t, Name("Sig"), fields["signature"]["FT"])
+
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	assert.Len(t, d.GetArray(pages[0].Dict["Annots"]), 2)
+	assert.Len(t, d.GetArray(pages[1].Dict["Annots"]), 5)
+	form := d.GetDict(d.Catalog()["AcroForm"])
+	assert.Len(t, d.GetArray(form["Fields"]), 5)
+	assert.NotNil(t, d.GetDict(d.GetDict(form["DR"])["Font"])["Helv"])
+}
+
+func TestAddFormFields_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []FormField
+		want   string
+	}{
+		{"no name", []FormField{{Type: FieldText, Rect: Rect{0, 0, 10, 10}}}, "name is required"},
+		{"empty part", []FormField{{Name: "a..b", Type: FieldText, Rect: Rect{0, 0, 10, 10}}}, "empty name part"},
+		{"unknown type", []FormField{{Name: "a", Type: "file", Rect: Rect{0, 0, 10, 10}}}, "unknown type"},
+		{"page", []FormField{{Name: "a", Type: FieldText, Page: 3, Rect: Rect{0, 0, 10, 10}}}, "page 4 out of range"},
+		{"empty rect", []FormField{{Name: "a", Type: FieldText}}, "empty rectangle"},
+		{"type clash", []FormField{
+			{Name: "a", Type: FieldText, Rect: Rect{0, 0, 10, 10}},
+			{Name: "a", Type: FieldCheckbox, Rect: Rect{0, 0, 10, 10}},
+		}, "both text and checkbox"},
+		{"field with children", []FormField{
+			{Name: "a", Type: FieldText, Rect: Rect{0, 0, 10, 10}},
+			{Name: "a.b", Type: FieldText, Rect: Rect{0, 0, 10, 10}},
+		}, "cannot have children"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestDocument(t, 1).AddFormFields(tt.fields)
+			require.ErrorIs(t, err, ErrInvalidFormField)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestAddFormFields_RejectsExistingName(t *testing.T) {
+	d := newTestDocument(t, 1)
+	field := FormField{Name: "a", Type: FieldText, Rect: Rect{0, 0, 10, 10}}
+	require.NoError(t, d.AddFormFields([]FormField{field}))
+	err := d.AddFormFields([]FormField{field})
+	require.ErrorIs(t, err, ErrInvalidFormField)
+	assert.Contains(t, err.Error(), "already exists")
+}
+
+func TestTakeLinks(t *testing.T) {
+	d := newTestDocument(t, 2)
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	link := func(uri string, rect Rect) Ref {
+		return d.Add(Dict{
+			"Type": Name("Annot"), "Subtype": Name("Link"), "Rect": rect.Array(),
+			"A": Dict{"S": Name("URI"), "URI": String(uri)},
+		})
+	}
+	other := link("https://example.com/", Rect{0, 0, 5, 5})
+	pages[0].Dict["Annots"] = Array{other, link("https://field.invalid/#a", Rect{1, 2, 3, 4})}
+	pages[1].Dict["Annots"] = Array{link("https://field.invalid/#b", Rect{5, 6, 7, 8})}
+
+	links, err := d.TakeLinks("https://field.invalid/")
+	require.NoError(t, err)
+	assert.Equal(t, []Link{
+		{Page: 0, Rect: Rect{1, 2, 3, 4}, URI: "https://field.invalid/#a"},
+		{Page: 1, Rect: Rect{5, 6, 7, 8}, URI: "https://field.invalid/#b"},
+	}, links)
+	assert.Equal(t, Array{other}, pages[0].Dict["Annots"])
+	assert.Nil(t, pages[1].Dict["Annots"])
+}
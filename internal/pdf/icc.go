@@ -0,0 +1,115 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+const srgbDescription = "sRGB IEC61966-2.1"
+
+// sRGBProfile returns an ICC version 2 display profile for sRGB, built from
+// the IEC 61966-2-1 primaries adapted to D50 and a sampled transfer curve.
+func sRGBProfile() []byte {
+	type tag struct {
+		sig  string
+		data []byte
+	}
+
+	xyz := func(x, y, z float64) []byte {
+		var b bytes.Buffer
+		b.WriteString("XYZ ")
+		b.Write(make([]byte, 4))
+		for _, v := range []float64{x, y, z} {
+			binary.Write(&b, binary.BigEndian, s15Fixed16(v))
+		}
+		return b.Bytes()
+	}
+
+	var curve bytes.Buffer
+	curve.WriteString("curv")
+	curve.Write(make([]byte, 4))
+	const samples = 1024
+	binary.Write(&curve, binary.BigEndian, uint32(samples))
+	for i := 0; i < samples; i++ {
+		v := float64(i) / (samples - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.Write(&curve, binary.BigEndian, uint16(math.Round(v*65535)))
+	}
+
+	var desc bytes.Buffer
+	desc.WriteString("desc")
+	desc.Write(make([]byte, 4))
+	binary.Write(&desc, binary.BigEndian, uint32(len(srgbDescription)+1))
+	desc.WriteString(srgbDescription)
+	desc.WriteByte(0)
+	// Empty Unicode and ScriptCode descriptions.
+	desc.Write(make([]byte, 4+4+2+1+67))
+
+	var cprt bytes.Buffer
+	cprt.WriteString("text")
+	cprt.Write(make([]byte, 4))
+	cprt.WriteString("No copyright, use freely")
+	cprt.WriteByte(0)
+
+	trc := curve.Bytes()
+	tags := []tag{
+		{"desc", desc.Bytes()},
+		{"cprt", cprt.Bytes()},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4360747, 0.2225045, 0.0139322)},
+		{"gXYZ", xyz(0.3850649, 0.7168786, 0.0971045)},
+		{"bXYZ", xyz(0.1430804, 0.0606169, 0.7141733)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	// The three TRC tags share one copy of the curve data.
+	offset := 128 + 4 + 12*len(tags)
+	var table, body bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	shared := map[string]uint32{}
+	for _, t := range tags {
+		key := string(t.data)
+		off, ok := shared[key]
+		if !ok {
+			off = uint32(offset + body.Len())
+			shared[key] = off
+			body.Write(t.data)
+			for body.Len()%4 != 0 {
+				body.WriteByte(0)
+			}
+		}
+		table.WriteString(t.sig)
+		binary.Write(&table, binary.BigEndian, off)
+		binary.Write(&table, binary.BigEndian, uint32(len(t.data)))
+	}
+
+	size := uint32(offset + body.Len())
+	var header bytes.Buffer
+	binary.Write(&header, binary.BigEndian, size)
+	header.Write(make([]byte, 4))                               // preferred CMM
+	binary.Write(&header, binary.BigEndian, uint32(0x02100000)) // version 2.1
+	header.WriteString("mntrRGB XYZ ")                          // class, colour space, PCS
+	for _, v := range []uint16{2026, 1, 1, 0, 0, 0} {           // creation date
+		binary.Write(&header, binary.BigEndian, v)
+	}
+	header.WriteString("acsp")
+	header.Write(make([]byte, 4+4+4+4+8+4)) // platform, flags, device, attributes, intent
+	for _, v := range []float64{0.9642, 1.0, 0.8249} {
+		binary.Write(&header, binary.BigEndian, s15Fixed16(v))
+	}
+	header.Write(make([]byte, 4+16+28)) // creator, profile ID, reserved
+
+	out := append(header.Bytes(), table.Bytes()...)
+	return append(out, body.Bytes()...)
+}
+
+func s15Fixed16(v float64) int32 {
+	return int32(math.Round(v * 65536))
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// AddImage embeds a JPEG, PNG or GIF image as an image XObject and returns
+// its reference and pixel size. JPEGs are embedded as-is; other formats are
+// stored as Flate compressed RGB with an alpha soft mask when needed.
+func (d *Document) AddImage(data []byte) (Ref, int, int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Ref{}, 0, 0, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	if format == "jpeg" {
+		if cs, ok := jpegColorSpace(data); ok {
+			s := &Stream{Dict: Dict{
+				"Type": Name("XObject"), "Subtype": Name("Image"),
+				"Width": cfg.Width, "Height": cfg.Height,
+				"ColorSpace": cs, "BitsPerComponent": 8,
+				"Filter": Name("DCTDecode"),
+			}, Data: data}
+			return d.Add(s), cfg.Width, cfg.Height, nil
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Ref{}, 0, 0, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	ref := d.AddRGBImage(img)
+	return ref, img.Bounds().Dx(), img.Bounds().Dy(), nil
+}
+
+// AddRGBImage embeds an arbitrary decoded image as Flate compressed RGB.
+func (d *Document) AddRGBImage(img image.Image) Ref {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			// Undo alpha premultiplication so the soft mask applies once.
+			if a > 0 && a < 0xffff {
+				r, g, bl = r*0xffff/a, g*0xffff/a, bl*0xffff/a
+			}
+			rgb = append(rgb, byte(r>>8), byte(g>>8), byte(bl>>8))
+			alpha = append(alpha, byte(a>>8))
+			if a != 0xffff {
+				opaque = false
+			}
+		}
+	}
+
+	dict := Dict{
+		"Type": Name("XObject"), "Subtype": Name("Image"),
+		"Width": b.Dx(), "Height": b.Dy(),
+		"ColorSpace": Name("DeviceRGB"), "BitsPerComponent": 8,
+	}
+	if !opaque {
+		dict["SMask"] = d.Add(NewStream(Dict{
+			"Type": Name("XObject"), "Subtype": Name("Image"),
+			"Width": b.Dx(), "Height": b.Dy(),
+			"ColorSpace": Name("DeviceGray"), "BitsPerComponent": 8,
+		}, alpha))
+	}
+	return d.Add(NewStream(dict, rgb))
+}
+
+// jpegColorSpace picks the PDF colour space for a baseline JPEG. CMYK JPEGs
+// are rejected so the caller re-encodes them, because Adobe's inverted CMYK
+// convention cannot be detected reliably from the config alone.
+func jpegColorSpace(data []byte) (Name, bool) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", false
+	}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		return "DeviceGray", true
+	case color.YCbCrModel:
+		return "DeviceRGB", true
+	}
+	return "", false
+}
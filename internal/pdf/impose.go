@@ -0,0 +1,390 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrInvalidImposition = errors.New("invalid imposition")
+
+const (
+	ImposeNUp     = "n-up"
+	ImposeBooklet = "booklet"
+	ImposeLabels  = "labels"
+
+	DefaultImpositionSheet = "A4"
+
+	// cropMarkOffset separates crop marks from the corner they mark, and
+	// cropMarkLength is their length, both in points.
+	cropMarkOffset = 3
+	cropMarkLength = 12
+	cropMarkWidth  = 0.25
+)
+
+// nUpGrids are the grids used for each number of pages per sheet, with at
+// least as many columns as rows; they may be turned to suit the sheet.
+var nUpGrids = map[int][2]int{2: {2, 1}, 4: {2, 2}, 6: {3, 2}, 8: {4, 2}, 9: {3, 3}, 16: {4, 4}}
+
+// Imposition arranges the pages of a document on larger printing sheets.
+type Imposition struct {
+	// Layout is n-up, booklet or labels. N-up places consecutive pages on
+	// a grid; booklet places pairs of pages side by side in saddle-stitch
+	// order, for sheets printed on both sides, folded and stapled; labels
+	// places pages on a grid of Columns × Rows cells.
+	Layout string
+	// PagesPerSheet is 2, 4, 6, 8, 9 or 16 for n-up.
+	PagesPerSheet int
+	Columns, Rows int
+	// Sheet names the paper size, as A4 or Letter.
+	Sheet string
+	// Orientation is portrait or landscape; when empty, the one showing
+	// the pages largest is used.
+	Orientation string
+	// Margin around the sheet and Gutter between cells, in points.
+	Margin, Gutter float64
+	// CropMarks draws marks at the corners of every page, outside it; the
+	// margin is enlarged to make room for them.
+	CropMarks bool
+	// Repeat fills every cell of a label sheet with the same page, giving
+	// one sheet per page.
+	Repeat bool
+	// RightToLeft fills grids from the right and binds booklets on the
+	// right, for documents read right to left.
+	RightToLeft bool
+}
+
+func (imp *Imposition) normalize() error {
+	imp.Layout = strings.ToLower(imp.Layout)
+	if imp.Sheet == "" {
+		imp.Sheet = DefaultImpositionSheet
+	}
+	switch imp.Layout {
+	case ImposeNUp:
+		grid, ok := nUpGrids[imp.PagesPerSheet]
+		if !ok {
+			return fmt.Errorf("%w: pages per sheet must be 2, 4, 6, 8, 9 or 16", ErrInvalidImposition)
+		}
+		imp.Columns, imp.Rows = grid[0], grid[1]
+	case ImposeBooklet:
+		imp.Columns, imp.Rows = 2, 1
+	case ImposeLabels:
+		if imp.Columns < 1 || imp.Rows < 1 || imp.Columns*imp.Rows > 100 {
+			return fmt.Errorf("%w: labels need between 1 and 100 cells of columns × rows", ErrInvalidImposition)
+		}
+	default:
+		return fmt.Errorf("%w: unknown layout %q", ErrInvalidImposition, imp.Layout)
+	}
+	imp.Orientation = strings.ToLower(imp.Orientation)
+	if imp.Orientation != "" && imp.Orientation != "portrait" && imp.Orientation != "landscape" {
+		return fmt.Errorf("%w: orientation must be portrait or landscape", ErrInvalidImposition)
+	}
+	if imp.Margin < 0 || imp.Gutter < 0 {
+		return fmt.Errorf("%w: margin and gutter must not be negative", ErrInvalidImposition)
+	}
+	if imp.CropMarks {
+		imp.Margin = math.Max(imp.Margin, cropMarkOffset+cropMarkLength)
+	}
+	return nil
+}
+
+// paperSize returns the portrait size of a named paper size.
+func paperSize(name string) (float64, float64, bool) {
+	for _, p := range paperSizes {
+		if strings.EqualFold(p.name, name) {
+			return p.width, p.height, true
+		}
+	}
+	return 0, 0, false
+}
+
+// sheetLayout is the sheet size and grid chosen for an imposition.
+type sheetLayout struct {
+	width, height float64
+	columns, rows int
+}
+
+func (l sheetLayout) cell(imp Imposition, col, row int) Rect {
+	w := (l.width - 2*imp.Margin - float64(l.columns-1)*imp.Gutter) / float64(l.columns)
+	h := (l.height - 2*imp.Margin - float64(l.rows-1)*imp.Gutter) / float64(l.rows)
+	x := imp.Margin + float64(col)*(w+imp.Gutter)
+	// Rows are counted from the top of the sheet.
+	y := l.height - imp.Margin - float64(row+1)*h - float64(row)*imp.Gutter
+	return Rect{x, y, x + w, y + h}
+}
+
+// chooseLayout picks the sheet orientation, and for n-up which way to turn
+// the grid, that shows a page of the given size largest.
+func chooseLayout(imp Imposition, pageW, pageH float64) (sheetLayout, error) {
+	sw, sh, ok := paperSize(imp.Sheet)
+	if !ok {
+		return sheetLayout{}, fmt.Errorf("%w: unknown sheet size %q", ErrInvalidImposition, imp.Sheet)
+	}
+	var candidates []sheetLayout
+	for _, landscape := range []bool{true, false} {
+		if imp.Orientation == "portrait" && landscape || imp.Orientation == "landscape" && !landscape {
+			continue
+		}
+		w, h := sw, sh
+		if landscape {
+			w, h = sh, sw
+		}
+		candidates = append(candidates, sheetLayout{w, h, imp.Columns, imp.Rows})
+		if imp.Layout == ImposeNUp && imp.Columns != imp.Rows {
+			candidates = append(candidates, sheetLayout{w, h, imp.Rows, imp.Columns})
+		}
+	}
+
+	best, bestScale := sheetLayout{}, 0.0
+	for _, c := range candidates {
+		cell := c.cell(imp, 0, 0)
+		if cell.Width() <= 0 || cell.Height() <= 0 {
+			continue
+		}
+		if s := math.Min(cell.Width()/pageW, cell.Height()/pageH); s > bestScale {
+			best, bestScale = c, s
+		}
+	}
+	if bestScale == 0 {
+		return sheetLayout{}, fmt.Errorf("%w: margins and gutters leave no room for pages", ErrInvalidImposition)
+	}
+	return best, nil
+}
+
+// pageXObject turns a page into a form XObject drawn at its displayed size,
+// with the origin at its lower left corner whatever its rotation.
+func (d *Document) pageXObject(p *Page) (Ref, error) {
+	content, err := d.Content(p)
+	if err != nil {
+		return Ref{}, err
+	}
+	c := p.CropBox
+	var m Array
+	switch p.Rotate {
+	case 90:
+		m = Array{0, -1, 1, 0, -c.LLY, c.URX}
+	case 180:
+		m = Array{-1, 0, 0, -1, c.URX, c.URY}
+	case 270:
+		m = Array{0, 1, -1, 0, c.URY, -c.LLX}
+	default:
+		m = Array{1, 0, 0, 1, -c.LLX, -c.LLY}
+	}
+	dict := Dict{"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": c.Array(), "Matrix": m}
+	if p.Resources != nil {
+		dict["Resources"] = p.Resources
+	}
+	return d.Add(NewStream(dict, content)), nil
+}
+
+// placement is a page, by index, drawn in a cell of a sheet; -1 leaves
+// the cell blank.
+type placement struct {
+	page int
+	cell Rect
+	// align is -1 to push the page against the left of its cell, 1 against
+	// the right and 0 to centre it.
+	align int
+}
+
+// Impose replaces the pages of the document with sheets carrying several
+// pages each. Interactive form fields are flattened first; links,
+// bookmarks and the structure tree, which refer to the original pages, are
+// dropped.
+func (d *Document) Impose(imp Imposition) error {
+	if err := imp.normalize(); err != nil {
+		return err
+	}
+	if d.Encrypted() {
+		return ErrEncrypted
+	}
+	if d.Catalog()["AcroForm"] != nil {
+		if err := d.FlattenForm(); err != nil {
+			return err
+		}
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("%w: document has no pages", ErrInvalidImposition)
+	}
+	pageW, pageH := pageMax(pages)
+	layout, err := chooseLayout(imp, pageW, pageH)
+	if err != nil {
+		return err
+	}
+	sheets := imposeSheets(imp, layout, len(pages))
+
+	forms := make([]Ref, len(pages))
+	sizes := make([][2]float64, len(pages))
+	for i, p := range pages {
+		if forms[i], err = d.pageXObject(p); err != nil {
+			return err
+		}
+		sizes[i][0], sizes[i][1] = p.Size()
+	}
+
+	d.clearPages()
+	for _, sheet := range sheets {
+		res := Dict{}
+		var buf bytes.Buffer
+		var drawn []Rect
+		for _, pl := range sheet {
+			if pl.page < 0 {
+				continue
+			}
+			w, h := sizes[pl.page][0], sizes[pl.page][1]
+			s := math.Min(pl.cell.Width()/w, pl.cell.Height()/h)
+			x := pl.cell.LLX + (pl.cell.Width()-w*s)/2
+			switch pl.align {
+			case -1:
+				x = pl.cell.LLX
+			case 1:
+				x = pl.cell.URX - w*s
+			}
+			y := pl.cell.LLY + (pl.cell.Height()-h*s)/2
+			name := AddResource(res, "XObject", "P", forms[pl.page])
+			fmt.Fprintf(&buf, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+				formatReal(s), formatReal(s), formatReal(x), formatReal(y), name)
+			drawn = append(drawn, Rect{x, y, x + w*s, y + h*s})
+		}
+		if imp.CropMarks {
+			writeCropMarks(&buf, drawn, drawn, cropMarkOffset, "0 G")
+		}
+		d.AddPage(Rect{URX: layout.width, URY: layout.height}, res, buf.Bytes())
+	}
+	d.removeUnreachable()
+	return nil
+}
+
+// pageMax returns the largest displayed width and height among pages.
+func pageMax(pages []*Page) (float64, float64) {
+	var w, h float64
+	for _, p := range pages {
+		pw, ph := p.Size()
+		w, h = math.Max(w, pw), math.Max(h, ph)
+	}
+	return w, h
+}
+
+// imposeSheets assigns page indexes, or -1 for blanks, to the cells of
+// each sheet.
+func imposeSheets(imp Imposition, l sheetLayout, n int) [][]placement {
+	cells := make([]Rect, 0, l.columns*l.rows)
+	for row := 0; row < l.rows; row++ {
+		for col := 0; col < l.columns; col++ {
+			c := col
+			if imp.RightToLeft {
+				c = l.columns - 1 - col
+			}
+			cells = append(cells, l.cell(imp, c, row))
+		}
+	}
+
+	var sheets [][]placement
+	switch {
+	case imp.Layout == ImposeBooklet:
+		// Sheet i carries, outside, the last and first pages not yet
+		// placed and, inside, the two pages following and preceding
+		// them; folded, the sheets nest into page order.
+		total := (n + 3) / 4 * 4
+		blank := func(i int) int {
+			if i >= n {
+				return -1
+			}
+			return i
+		}
+		// Pages meet at the fold in the middle of the sheet; cells come
+		// in reading order, so the first is on the right for right to
+		// left documents.
+		toFold := [2]int{1, -1}
+		if imp.RightToLeft {
+			toFold = [2]int{-1, 1}
+		}
+		spread := func(a, b int) []placement {
+			return []placement{
+				{page: blank(a), cell: cells[0], align: toFold[0]},
+				{page: blank(b), cell: cells[1], align: toFold[1]},
+			}
+		}
+		for i := 0; i < total/4; i++ {
+			sheets = append(sheets, spread(total-1-2*i, 2*i), spread(2*i+1, total-2-2*i))
+		}
+	case imp.Repeat:
+		for page := 0; page < n; page++ {
+			sheet := make([]placement, len(cells))
+			for i, c := range cells {
+				sheet[i] = placement{page: page, cell: c}
+			}
+			sheets = append(sheets, sheet)
+		}
+	default:
+		for first := 0; first < n; first += len(cells) {
+			var sheet []placement
+			for i, c := range cells {
+				if first+i < n {
+					sheet = append(sheet, placement{page: first + i, cell: c})
+				}
+			}
+			sheets = append(sheets, sheet)
+		}
+	}
+	return sheets
+}
+
+// clearPages empties the page tree and removes what refers to its pages,
+// ahead of adding new ones.
+func (d *Document) clearPages() {
+	cat := d.Catalog()
+	root := d.GetDict(cat["Pages"])
+	root["Kids"] = Array{}
+	root["Count"] = 0
+	for _, k := range []Name{"MediaBox", "CropBox", "Rotate", "Resources"} {
+		delete(root, k)
+	}
+	for _, k := range []Name{"Outlines", "StructTreeRoot", "MarkInfo", "PageLabels", "OpenAction", "Dests"} {
+		delete(cat, k)
+	}
+	if names := d.GetDict(cat["Names"]); names != nil {
+		delete(names, "Dests")
+	}
+}
+
+// writeCropMarks draws marks at the corners of each of the trimmed
+// rectangles, starting offset points from the corner, in the stroke color
+// set by color. Marks that would fall on any of the drawn rectangles are
+// left out.
+func writeCropMarks(buf *bytes.Buffer, trims, drawn []Rect, offset float64, color string) {
+	buf.WriteString("q " + formatReal(cropMarkWidth) + " w " + color + "\n")
+	line := func(x0, y0, x1, y1 float64) {
+		for _, r := range drawn {
+			if math.Max(x0, x1) >= r.LLX && math.Min(x0, x1) <= r.URX &&
+				math.Max(y0, y1) >= r.LLY && math.Min(y0, y1) <= r.URY {
+				return
+			}
+		}
+		fmt.Fprintf(buf, "%s %s m %s %s l S\n", formatReal(x0), formatReal(y0), formatReal(x1), formatReal(y1))
+	}
+	near, far := offset, offset+cropMarkLength
+	for _, r := range trims {
+		for _, x := range []float64{r.LLX, r.URX} {
+			for _, y := range []float64{r.LLY, r.URY} {
+				// Marks point away from the rectangle along both edges.
+				dx, dy := 1.0, 1.0
+				if x == r.LLX {
+					dx = -1
+				}
+				if y == r.LLY {
+					dy = -1
+				}
+				line(x+dx*near, y, x+dx*far, y)
+				line(x, y+dy*near, x, y+dy*far)
+			}
+		}
+	}
+	buf.WriteString("Q\n")
+}
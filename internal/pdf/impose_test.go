@@ -0,0 +1,89 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func imposedText(t *testing.T, d *Document, imp Imposition) ([]string, []*Page) {
+	t.Helper()
+	require.NoError(t, d.Impose(imp))
+	parsed := roundTrip(t, d)
+	pages, err := parsed.Pages()
+	require.NoError(t, err)
+	text, err := parsed.ExtractText()
+	require.NoError(t, err)
+	return text, pages
+}
+
+func TestImpose_NUp(t *testing.T) {
+	text, pages := imposedText(t, newTestDocument(t, 5), Imposition{Layout: ImposeNUp, PagesPerSheet: 4})
+	assert.Equal(t, []string{"Page 1 Page 2\nPage 3 Page 4", "Page 5"}, text)
+	require.Len(t, pages, 2)
+	assert.Equal(t, "A4", pages[0].Info().Paper)
+	assert.Equal(t, 0, pages[0].Rotate)
+	w, h := pages[0].Size()
+	assert.Less(t, w, h)
+
+	text, pages = imposedText(t, newTestDocument(t, 2), Imposition{Layout: ImposeNUp, PagesPerSheet: 2, RightToLeft: true})
+	assert.Equal(t, []string{"Page 2 Page 1"}, text)
+	w, h = pages[0].Size()
+	assert.Greater(t, w, h, "two portrait pages sit side by side on a landscape sheet")
+}
+
+func TestImpose_Booklet(t *testing.T) {
+	text, pages := imposedText(t, newTestDocument(t, 5), Imposition{Layout: ImposeBooklet})
+	// Eight pages in saddle-stitch order, the last three blank: 8|1, 2|7,
+	// 6|3 and 4|5.
+	assert.Equal(t, []string{"Page 1", "Page 2", "Page 3", "Page 4 Page 5"}, text)
+	require.Len(t, pages, 4)
+
+	text, _ = imposedText(t, newTestDocument(t, 4), Imposition{Layout: ImposeBooklet})
+	assert.Equal(t, []string{"Page 4 Page 1", "Page 2 Page 3"}, text)
+
+	text, _ = imposedText(t, newTestDocument(t, 4), Imposition{Layout: ImposeBooklet, RightToLeft: true})
+	assert.Equal(t, []string{"Page 1 Page 4", "Page 3 Page 2"}, text)
+}
+
+func TestImpose_Labels(t *testing.T) {
+	d := newTestDocument(t, 2)
+	require.NoError(t, d.Impose(Imposition{Layout: ImposeLabels, Columns: 2, Rows: 3, Gutter: 10, CropMarks: true, Repeat: true}))
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	content, err := d.Content(pages[1])
+	require.NoError(t, err)
+	assert.Equal(t, 6, strings.Count(string(content), " Do "))
+	assert.Contains(t, string(content), "0.25 w")
+
+	text, err := d.PageText(pages[1])
+	require.NoError(t, err)
+	assert.Equal(t, "Page 2 Page 2\nPage 2 Page 2\nPage 2 Page 2", text)
+}
+
+func TestImpose_RotatedPage(t *testing.T) {
+	d := newTestDocument(t, 2)
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	pages[1].Dict["Rotate"] = 90
+
+	text, _ := imposedText(t, d, Imposition{Layout: ImposeNUp, PagesPerSheet: 2})
+	require.Len(t, text, 1)
+	assert.Contains(t, text[0], "Page 1")
+}
+
+func TestImpose_Invalid(t *testing.T) {
+	for _, imp := range []Imposition{
+		{Layout: "poster"},
+		{Layout: ImposeNUp, PagesPerSheet: 3},
+		{Layout: ImposeLabels},
+		{Layout: ImposeNUp, PagesPerSheet: 2, Sheet: "B9"},
+		{Layout: ImposeNUp, PagesPerSheet: 2, Orientation: "diagonal"},
+		{Layout: ImposeNUp, PagesPerSheet: 2, Margin: 400},
+	} {
+		assert.ErrorIs(t, newTestDocument(t, 1).Impose(imp), ErrInvalidImposition, "%+v", imp)
+	}
+}
@@ -0,0 +1,118 @@
+package pdf
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// paperSizes are standard paper sizes in portrait orientation, in points.
+var paperSizes = []struct {
+	name          string
+	width, height float64
+}{
+	{"A3", 841.89, 1190.55},
+	{"A4", 595.28, 841.89},
+	{"A5", 419.53, 595.28},
+	{"Letter", 612, 792},
+	{"Legal", 612, 1008},
+}
+
+// paperTolerance absorbs rounding in sizes given in inches or pixels, such
+// as Chromium's 8.27 × 11.69 inch A4.
+const paperTolerance = 1.5
+
+// PaperSize names the standard paper size of a width × height page in
+// either orientation, or returns "" for other sizes.
+func PaperSize(width, height float64) string {
+	short, long := math.Min(width, height), math.Max(width, height)
+	for _, p := range paperSizes {
+		if math.Abs(short-p.width) <= paperTolerance && math.Abs(long-p.height) <= paperTolerance {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// Size returns the width and height of the page as displayed: its crop
+// box, turned by its rotation.
+func (p *Page) Size() (float64, float64) {
+	w, h := p.CropBox.Width(), p.CropBox.Height()
+	if p.Rotate == 90 || p.Rotate == 270 {
+		w, h = h, w
+	}
+	return w, h
+}
+
+// PageInfo is the displayed size of a page in points.
+type PageInfo struct {
+	Width  float64
+	Height float64
+	Rotate int
+	// Paper is the standard paper size, if any.
+	Paper string
+}
+
+// Info returns the page's displayed size and paper.
+func (p *Page) Info() PageInfo {
+	w, h := p.Size()
+	return PageInfo{Width: w, Height: h, Rotate: p.Rotate, Paper: PaperSize(w, h)}
+}
+
+// Inspection summarises a document.
+type Inspection struct {
+	Version string
+	Pages   []PageInfo
+	Fonts   []Font
+	// Metadata holds the document information dictionary, with dates in
+	// RFC 3339 format.
+	Metadata    map[string]string
+	Encrypted   bool
+	Tagged      bool
+	Attachments []Attachment
+}
+
+// Inspect describes the document's pages, fonts, metadata, attachments
+// and structure. Metadata and attachments of encrypted documents are not
+// readable and left out.
+func (d *Document) Inspect() (*Inspection, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	info := &Inspection{Version: d.Version, Encrypted: d.Encrypted(), Metadata: map[string]string{}}
+	for _, p := range pages {
+		info.Pages = append(info.Pages, p.Info())
+	}
+
+	if info.Fonts, err = d.Fonts(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(info.Fonts, func(i, j int) bool { return info.Fonts[i].BaseFont < info.Fonts[j].BaseFont })
+
+	cat := d.Catalog()
+	markInfo := d.GetDict(cat["MarkInfo"])
+	marked, _ := d.Resolve(markInfo["Marked"]).(bool)
+	info.Tagged = marked && cat["StructTreeRoot"] != nil
+
+	if info.Encrypted {
+		return info, nil
+	}
+	for k, v := range d.Info(false) {
+		s, ok := d.Resolve(v).(String)
+		if !ok {
+			continue
+		}
+		value := DecodeTextString(s)
+		if k == "CreationDate" || k == "ModDate" {
+			if t, err := ParseDate(string(s)); err == nil {
+				value = t.Format(time.RFC3339)
+			}
+		}
+		info.Metadata[string(k)] = value
+	}
+	// A damaged attachment is listed without its data rather than failing
+	// the whole inspection.
+	info.Attachments, _ = d.Attachments()
+	return info, nil
+}
@@ -0,0 +1,59 @@
+package pdf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaperSize(t *testing.T) {
+	assert.Equal(t, "A4", PaperSize(595.28, 841.89))
+	assert.Equal(t, "A4", PaperSize(8.27*72, 11.69*72))
+	assert.Equal(t, "A4", PaperSize(841.89, 595.28))
+	assert.Equal(t, "Letter", PaperSize(612, 792))
+	assert.Equal(t, "", PaperSize(300, 300))
+}
+
+func TestInspect(t *testing.T) {
+	d := newTestDocument(t, 2)
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	pages[1].Dict["Rotate"] = 90
+	info := d.Info(true)
+	info["Title"] = EncodeTextString("گزارش")
+	info["CreationDate"] = FormatDate(time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC))
+	require.NoError(t, d.AddAttachments(Attachment{Name: "data.json", Data: []byte("{}")}))
+	cat := d.Catalog()
+	cat["MarkInfo"] = Dict{"Marked": true}
+	cat["StructTreeRoot"] = d.Add(Dict{"Type": Name("StructTreeRoot")})
+
+	got, err := roundTrip(t, d).Inspect()
+	require.NoError(t, err)
+	assert.Equal(t, []PageInfo{
+		{Width: 595.28, Height: 841.89, Paper: "A4"},
+		{Width: 841.89, Height: 595.28, Rotate: 90, Paper: "A4"},
+	}, got.Pages)
+	require.Len(t, got.Fonts, 1)
+	assert.Equal(t, "Helvetica", got.Fonts[0].BaseFont)
+	assert.False(t, got.Fonts[0].Embedded)
+	assert.Equal(t, "گزارش", got.Metadata["Title"])
+	assert.Equal(t, "2026-05-01T08:30:00Z", got.Metadata["CreationDate"])
+	assert.True(t, got.Tagged)
+	assert.False(t, got.Encrypted)
+	require.Len(t, got.Attachments, 1)
+	assert.Equal(t, "data.json", got.Attachments[0].Name)
+}
+
+func TestInspect_Encrypted(t *testing.T) {
+	d := newTestDocument(t, 1)
+	d.Info(true)["Title"] = String("encrypted bytes")
+	d.Trailer["Encrypt"] = d.Add(Dict{"Filter": Name("Standard")})
+
+	got, err := d.Inspect()
+	require.NoError(t, err)
+	assert.True(t, got.Encrypted)
+	assert.Len(t, got.Pages, 1)
+	assert.Empty(t, got.Metadata)
+}
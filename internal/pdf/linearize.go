@@ -0,0 +1,522 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// linearLayout assigns every object of a document to a part of a
+// linearized file (ISO 32000-1, Annex F) and gives it its new number.
+type linearLayout struct {
+	pages []*Page
+	// first lists the first page section, starting with the page object.
+	first []int
+	// pageObjects lists, for each later page, its page object followed by
+	// the objects only that page uses.
+	pageObjects [][]int
+	// shared lists objects used by several later pages but not the first.
+	shared []int
+	// other lists the remaining objects, such as the page tree and the
+	// document information dictionary.
+	other []int
+	// pageShared lists, for each page, the shared objects it uses.
+	pageShared [][]int
+	catalog    int
+	renumber   map[int]int
+}
+
+// pageReach returns the objects reachable from a page without passing
+// through other pages, the page tree or the catalog.
+func (d *Document) pageReach(page Ref) []int {
+	var order []int
+	seen := map[int]bool{}
+	var walk func(o Object)
+	walk = func(o Object) {
+		switch v := o.(type) {
+		case Ref:
+			if seen[v.Num] {
+				return
+			}
+			target := d.objects[v.Num]
+			if dict, ok := target.(Dict); ok && v != page {
+				switch dict["Type"] {
+				case Name("Page"), Name("Pages"), Name("Catalog"):
+					return
+				}
+			}
+			seen[v.Num] = true
+			order = append(order, v.Num)
+			walk(target)
+		case Array:
+			for _, e := range v {
+				walk(e)
+			}
+		case Dict:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				// Parents lead to form field hierarchies spanning pages.
+				if k != "Parent" && k != "P" {
+					keys = append(keys, string(k))
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[Name(k)])
+			}
+		case *Stream:
+			walk(v.Dict)
+		}
+	}
+	walk(page)
+	return order
+}
+
+func (d *Document) linearLayout() (*linearLayout, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages to linearize", ErrMalformed)
+	}
+	catalog, ok := d.Trailer["Root"].(Ref)
+	if !ok {
+		return nil, fmt.Errorf("%w: catalog is not an indirect object", ErrMalformed)
+	}
+
+	l := &linearLayout{pages: pages, catalog: catalog.Num, renumber: map[int]int{}}
+	reach := make([][]int, len(pages))
+	owners := map[int][]int{}
+	for i, p := range pages {
+		reach[i] = d.pageReach(p.Ref)
+		for _, num := range reach[i] {
+			owners[num] = append(owners[num], i)
+		}
+	}
+
+	placed := map[int]bool{catalog.Num: true}
+	l.first = reach[0]
+	for _, num := range l.first {
+		placed[num] = true
+	}
+	l.pageObjects = make([][]int, len(pages)-1)
+	for i := 1; i < len(pages); i++ {
+		for _, num := range reach[i] {
+			if len(owners[num]) == 1 {
+				l.pageObjects[i-1] = append(l.pageObjects[i-1], num)
+				placed[num] = true
+			}
+		}
+	}
+	for i := 1; i < len(pages); i++ {
+		for _, num := range reach[i] {
+			if !placed[num] {
+				l.shared = append(l.shared, num)
+				placed[num] = true
+			}
+		}
+	}
+	for _, num := range d.ObjectNumbers() {
+		if !placed[num] {
+			l.other = append(l.other, num)
+		}
+	}
+
+	// Later pages, shared and other objects are numbered from 1 in file
+	// order; the first page block follows the linearization dictionary,
+	// catalog and hint stream.
+	next := 1
+	for _, objs := range l.pageObjects {
+		for _, num := range objs {
+			l.renumber[num] = next
+			next++
+		}
+	}
+	for _, num := range append(append([]int(nil), l.shared...), l.other...) {
+		l.renumber[num] = next
+		next++
+	}
+	l.renumber[catalog.Num] = next + 1
+	for i, num := range l.first {
+		l.renumber[num] = next + 3 + i
+	}
+
+	// Shared object identifiers index the shared object hint table, which
+	// lists the first page section and then the shared objects section.
+	ids := map[int]int{}
+	for i, num := range l.first {
+		ids[num] = i
+	}
+	for i, num := range l.shared {
+		ids[num] = len(l.first) + i
+	}
+	l.pageShared = make([][]int, len(pages))
+	for i := 1; i < len(pages); i++ {
+		for _, num := range reach[i] {
+			if len(owners[num]) > 1 {
+				l.pageShared[i] = append(l.pageShared[i], ids[num])
+			}
+		}
+		sort.Ints(l.pageShared[i])
+	}
+	return l, nil
+}
+
+// renumbered returns a copy of o with references mapped to new numbers.
+func renumbered(o Object, mapping map[int]int) Object {
+	switch v := o.(type) {
+	case Ref:
+		if num, ok := mapping[v.Num]; ok {
+			return Ref{Num: num}
+		}
+		// A dangling reference resolves to null either way.
+		return nil
+	case Array:
+		c := make(Array, len(v))
+		for i, e := range v {
+			c[i] = renumbered(e, mapping)
+		}
+		return c
+	case Dict:
+		c := make(Dict, len(v))
+		for k, e := range v {
+			c[k] = renumbered(e, mapping)
+		}
+		return c
+	case *Stream:
+		return &Stream{Dict: renumbered(v.Dict, mapping).(Dict), Data: v.Data}
+	}
+	return o
+}
+
+// bitWriter packs the hint tables' variable width fields.
+type bitWriter struct {
+	buf   bytes.Buffer
+	cur   byte
+	nbits int
+}
+
+func (w *bitWriter) write(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i)&1)
+		w.nbits++
+		if w.nbits == 8 {
+			w.buf.WriteByte(w.cur)
+			w.cur, w.nbits = 0, 0
+		}
+	}
+}
+
+func (w *bitWriter) align() {
+	if w.nbits > 0 {
+		w.write(0, 8-w.nbits)
+	}
+}
+
+// bitsFor returns the number of bits needed to represent v.
+func bitsFor(v int) int {
+	return bits.Len(uint(v))
+}
+
+// minMax returns the least value of vs and the bits needed for the largest
+// difference from it.
+func minMax(vs []int) (int, int) {
+	if len(vs) == 0 {
+		return 0, 0
+	}
+	lo, hi := vs[0], vs[0]
+	for _, v := range vs {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return lo, bitsFor(hi - lo)
+}
+
+// hintTables holds the values the page offset and shared object hint tables
+// are built from. Offsets are as if the hint stream were absent.
+type hintTables struct {
+	firstPageOffset int
+	pageObjects     []int
+	pageLengths     []int
+	pageShared      [][]int
+	firstSharedNum  int
+	firstSharedOff  int
+	sharedFirstPage int
+	groupLengths    []int
+}
+
+// encode returns the hint stream data and the offset of the shared object
+// hint table within it.
+func (h *hintTables) encode() ([]byte, int) {
+	w := &bitWriter{}
+
+	leastObjects, objectBits := minMax(h.pageObjects)
+	leastLength, lengthBits := minMax(h.pageLengths)
+	maxShared, maxID := 0, 0
+	for _, ids := range h.pageShared {
+		maxShared = max(maxShared, len(ids))
+		for _, id := range ids {
+			maxID = max(maxID, id)
+		}
+	}
+	sharedBits, idBits := bitsFor(maxShared), bitsFor(maxID)
+
+	// Page offset hint table header (Table F.3).
+	w.write(uint64(leastObjects), 32)
+	w.write(uint64(h.firstPageOffset), 32)
+	w.write(uint64(objectBits), 16)
+	w.write(uint64(leastLength), 32)
+	w.write(uint64(lengthBits), 16)
+	w.write(0, 32) // least content stream offset
+	w.write(0, 16)
+	w.write(0, 32) // least content stream length
+	w.write(0, 16)
+	w.write(uint64(sharedBits), 16)
+	w.write(uint64(idBits), 16)
+	w.write(0, 16) // fractional position numerator bits
+	w.write(1, 16) // fractional position denominator
+
+	// Page entries (Table F.4), grouped by item.
+	for _, n := range h.pageObjects {
+		w.write(uint64(n-leastObjects), objectBits)
+	}
+	w.align()
+	for _, n := range h.pageLengths {
+		w.write(uint64(n-leastLength), lengthBits)
+	}
+	w.align()
+	for _, ids := range h.pageShared {
+		w.write(uint64(len(ids)), sharedBits)
+	}
+	w.align()
+	for _, ids := range h.pageShared {
+		for _, id := range ids {
+			w.write(uint64(id), idBits)
+		}
+	}
+	w.align()
+
+	sharedOffset := w.buf.Len()
+	leastGroup, groupBits := minMax(h.groupLengths)
+
+	// Shared object hint table header (Table F.5).
+	w.write(uint64(h.firstSharedNum), 32)
+	w.write(uint64(h.firstSharedOff), 32)
+	w.write(uint64(h.sharedFirstPage), 32)
+	w.write(uint64(len(h.groupLengths)), 32)
+	w.write(0, 16) // every group holds a single object
+	w.write(uint64(leastGroup), 32)
+	w.write(uint64(groupBits), 16)
+
+	// Shared object entries (Table F.6).
+	for _, n := range h.groupLengths {
+		w.write(uint64(n-leastGroup), groupBits)
+	}
+	w.align()
+	for range h.groupLengths {
+		w.write(0, 1) // no MD5 signature
+	}
+	w.align()
+	return w.buf.Bytes(), sharedOffset
+}
+
+// padTo pads s with spaces to n bytes.
+func padTo(s string, n int) string {
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+// writeLinearized writes the document so that viewers can display the
+// first page before the rest of the file has arrived.
+func (d *Document) writeLinearized(w io.Writer) error {
+	l, err := d.linearLayout()
+	if err != nil {
+		return err
+	}
+
+	// Inherited page attributes are copied into each page so that the
+	// first page section is self-contained.
+	objects := map[int]Object{}
+	for num, o := range d.objects {
+		objects[num] = o
+	}
+	for _, p := range l.pages {
+		page := Clone(p.Dict).(Dict)
+		if _, ok := page["MediaBox"]; !ok {
+			page["MediaBox"] = p.MediaBox.Array()
+		}
+		if _, ok := page["Resources"]; !ok && p.Resources != nil {
+			page["Resources"] = p.Resources
+		}
+		if _, ok := page["Rotate"]; !ok && p.Rotate != 0 {
+			page["Rotate"] = p.Rotate
+		}
+		objects[p.Ref.Num] = page
+	}
+
+	body := map[int][]byte{}
+	serialize := func(old int) []byte {
+		var buf bytes.Buffer
+		writeIndirect(&buf, l.renumber[old], renumbered(objects[old], l.renumber))
+		return buf.Bytes()
+	}
+	for old := range l.renumber {
+		body[old] = serialize(old)
+	}
+
+	size := len(l.renumber) + 3 // object 0, the linearization dict and hint stream
+	linNum := l.renumber[l.catalog] - 1
+	hintNum := l.renumber[l.catalog] + 1
+	firstCount := size - linNum
+	header := "%PDF-" + d.Version + "\n%\xe2\xe3\xcf\xd3\n"
+
+	if _, ok := d.Trailer["ID"].(Array); !ok {
+		d.trailerDict(size, []byte(header+string(bytes.Join(mapValues(body), nil))))
+	}
+	trailer := func(prev int) string {
+		t := Dict{"Size": size, "Prev": prev, "Root": Ref{Num: l.renumber[l.catalog]}, "ID": d.Trailer["ID"]}
+		if info, ok := d.Trailer["Info"].(Ref); ok {
+			t["Info"] = Ref{Num: l.renumber[info.Num]}
+		}
+		return string(Serialize(t))
+	}
+	linDict := func(length, hintOff, hintLen, endFirst, mainXref int) string {
+		return fmt.Sprintf("<< /Linearized 1 /L %d /H [ %d %d ] /O %d /E %d /N %d /T %d >>",
+			length, hintOff, hintLen, l.renumber[l.first[0]], endFirst, len(l.pages), mainXref)
+	}
+	const wide = 9999999999
+	linObjLen := len(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", linNum, linDict(wide, wide, wide, wide, wide)))
+	trailerLen := len(trailer(wide))
+
+	var mainBody []int
+	for _, objs := range l.pageObjects {
+		mainBody = append(mainBody, objs...)
+	}
+	mainBody = append(append(mainBody, l.shared...), l.other...)
+
+	firstXrefOff := len(header) + linObjLen
+	firstXrefLen := len("xref\n"+strconv.Itoa(linNum)+" "+strconv.Itoa(firstCount)+"\n") + 20*firstCount
+	trailerBlockLen := len("trailer\n") + trailerLen + len("\nstartxref\n0\n%%EOF\n")
+	catalogOff := firstXrefOff + firstXrefLen + trailerBlockLen
+	hintOff := catalogOff + len(body[l.catalog])
+
+	offsets := map[int]int{}
+	var hint []byte
+	hintLen := 0
+	for {
+		off := hintOff + hintLen
+		for _, num := range l.first {
+			offsets[num] = off
+			off += len(body[num])
+		}
+		endFirst := off
+		for _, num := range mainBody {
+			offsets[num] = off
+			off += len(body[num])
+		}
+
+		h := &hintTables{
+			firstPageOffset: offsets[l.first[0]] - hintLen,
+			pageShared:      l.pageShared,
+			sharedFirstPage: len(l.first),
+		}
+		h.pageObjects = append(h.pageObjects, len(l.first))
+		h.pageLengths = append(h.pageLengths, endFirst-offsets[l.first[0]])
+		for _, objs := range l.pageObjects {
+			n := 0
+			for _, num := range objs {
+				n += len(body[num])
+			}
+			h.pageObjects = append(h.pageObjects, len(objs))
+			h.pageLengths = append(h.pageLengths, n)
+		}
+		for _, num := range l.first {
+			h.groupLengths = append(h.groupLengths, len(body[num]))
+		}
+		for _, num := range l.shared {
+			h.groupLengths = append(h.groupLengths, len(body[num]))
+		}
+		if len(l.shared) > 0 {
+			h.firstSharedNum = l.renumber[l.shared[0]]
+			h.firstSharedOff = offsets[l.shared[0]] - hintLen
+		}
+
+		data, sharedOffset := h.encode()
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "%d 0 obj\n", hintNum)
+		writeObject(&buf, &Stream{Dict: Dict{"S": sharedOffset}, Data: data})
+		buf.WriteString("\nendobj\n")
+		if buf.Len() <= hintLen {
+			// Whitespace between objects keeps the layout computed for the
+			// reserved length.
+			hint = append(buf.Bytes(), strings.Repeat("\n", hintLen-buf.Len())...)
+			break
+		}
+		hintLen = buf.Len()
+	}
+
+	endFirst := hintOff + hintLen
+	for _, num := range l.first {
+		endFirst += len(body[num])
+	}
+	mainXrefOff := endFirst
+	for _, num := range mainBody {
+		mainXrefOff += len(body[num])
+	}
+	mainXrefHead := "xref\n0 " + strconv.Itoa(linNum)
+	mainXref := mainXrefHead + "\n" + xrefLine(0, 65535, 'f')
+	for _, num := range mainBody {
+		mainXref += xrefLine(offsets[num], 0, 'n')
+	}
+	mainXref += "trailer\n<< /Size " + strconv.Itoa(linNum) + " >>\nstartxref\n" + strconv.Itoa(firstXrefOff) + "\n%%EOF\n"
+	length := mainXrefOff + len(mainXref)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	linDictStr := linDict(length, hintOff, hintLen, endFirst, mainXrefOff+len(mainXrefHead))
+	buf.WriteString(padTo(fmt.Sprintf("%d 0 obj\n%s", linNum, linDictStr), linObjLen-len("\nendobj\n")) + "\nendobj\n")
+	buf.WriteString("xref\n" + strconv.Itoa(linNum) + " " + strconv.Itoa(firstCount) + "\n")
+	buf.WriteString(xrefLine(len(header), 0, 'n'))
+	buf.WriteString(xrefLine(catalogOff, 0, 'n'))
+	buf.WriteString(xrefLine(hintOff, 0, 'n'))
+	for _, num := range l.first {
+		buf.WriteString(xrefLine(offsets[num], 0, 'n'))
+	}
+	buf.WriteString("trailer\n" + padTo(trailer(mainXrefOff), trailerLen) + "\nstartxref\n0\n%%EOF\n")
+	buf.Write(body[l.catalog])
+	buf.Write(hint)
+	for _, num := range l.first {
+		buf.Write(body[num])
+	}
+	for _, num := range mainBody {
+		buf.Write(body[num])
+	}
+	buf.WriteString(mainXref)
+
+	if buf.Len() != length {
+		return fmt.Errorf("pdf: linearized length %d, expected %d", buf.Len(), length)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func xrefLine(offset, gen int, kind byte) string {
+	return fmt.Sprintf("%010d %05d %c\r\n", offset, gen, kind)
+}
+
+// mapValues returns the values of m ordered by key.
+func mapValues(m map[int][]byte) [][]byte {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	out := make([][]byte, len(keys))
+	for i, k := range keys {
+		out[i] = m[k]
+	}
+	return out
+}
@@ -0,0 +1,179 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSharedFontDocument builds n pages that share one font, each with its
+// own content.
+func newSharedFontDocument(t *testing.T, n int) *Document {
+	t.Helper()
+	d := New()
+	font := d.Add(Dict{"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name("Helvetica")})
+	for i := 1; i <= n; i++ {
+		content := fmt.Sprintf("BT /F1 24 Tf 72 720 Td (Page %d) Tj ET", i)
+		d.AddPage(a4, Dict{"Font": Dict{"F1": font}}, []byte(content))
+	}
+	d.Info(true)["Title"] = String("Statement")
+	return d
+}
+
+func readObjectAt(t *testing.T, data []byte, offset int) (Ref, Object) {
+	t.Helper()
+	p := newParser(data)
+	p.pos = offset
+	ref, o, err := p.readIndirect()
+	require.NoError(t, err)
+	return ref, o
+}
+
+func TestWrite_Linearized(t *testing.T) {
+	d := newSharedFontDocument(t, 3)
+	data, err := d.BytesWith(WriteOptions{Linearize: true})
+	require.NoError(t, err)
+
+	// The linearization dictionary is the first object.
+	headerLen := bytes.IndexByte(data[9:], '\n') + 10
+	_, o := readObjectAt(t, data, headerLen)
+	lin, ok := o.(Dict)
+	require.True(t, ok)
+	assert.Equal(t, 1, lin["Linearized"])
+	assert.Equal(t, len(data), lin["L"])
+	assert.Equal(t, 3, lin["N"])
+
+	// T points just before the first entry of the main cross-reference table.
+	mainXref := lin["T"].(int)
+	assert.Equal(t, "\n0000000000 65535 f", string(data[mainXref:mainXref+19]))
+
+	// The last startxref leads to the first page cross-reference table.
+	tail := data[bytes.LastIndex(data, []byte("startxref"))+10:]
+	firstXref, err := strconv.Atoi(string(bytes.Fields(tail)[0]))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data[firstXref:], []byte("xref\n")))
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	pages, err := parsed.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	assert.Equal(t, lin["O"], pages[0].Ref.Num)
+	content, err := parsed.Content(pages[2])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "(Page 3) Tj")
+	assert.Equal(t, String("Statement"), parsed.Info(false)["Title"])
+
+	// The first page and everything it uses end before E.
+	firstPage := bytes.Index(data, []byte(strconv.Itoa(pages[0].Ref.Num)+" 0 obj"))
+	assert.Less(t, firstPage, lin["E"].(int))
+	firstContent := pages[0].Dict["Contents"].(Ref)
+	assert.Less(t, bytes.Index(data, []byte(strconv.Itoa(firstContent.Num)+" 0 obj")), lin["E"].(int))
+
+	// The page offset hint table locates the first page as if the hint
+	// stream were absent.
+	h := lin["H"].(Array)
+	hintOffset, hintLength := h[0].(int), h[1].(int)
+	_, o = readObjectAt(t, data, hintOffset)
+	hint, ok := o.(*Stream)
+	require.True(t, ok)
+	assert.NotNil(t, hint.Dict["S"])
+	location := int(binary.BigEndian.Uint32(hint.Data[4:8]))
+	assert.Equal(t, firstPage, location+hintLength)
+
+	// Later pages follow one another from E, with the lengths the table
+	// records for each page.
+	r := &bitReader{data: hint.Data}
+	leastObjects := r.read(32)
+	r.read(32)
+	objectBits := r.read(16)
+	leastLength := r.read(32)
+	lengthBits := r.read(16)
+	r.skip(32 + 16 + 32 + 16 + 16 + 16 + 16 + 16)
+	counts := make([]int, 3)
+	for i := range counts {
+		counts[i] = leastObjects + r.read(objectBits)
+	}
+	r.align()
+	offset := lin["E"].(int)
+	r.read(lengthBits) // first page
+	for i := 1; i < 3; i++ {
+		ref, _ := readObjectAt(t, data, offset)
+		assert.Equal(t, pages[i].Ref.Num, ref.Num)
+		offset += leastLength + r.read(lengthBits)
+	}
+	assert.Equal(t, []int{3, 2, 2}, counts)
+
+	// Reading and writing again drops the stale linearization data.
+	rewritten, err := parsed.Bytes()
+	require.NoError(t, err)
+	assert.NotContains(t, string(rewritten), "/Linearized")
+}
+
+func TestWrite_LinearizedSinglePage(t *testing.T) {
+	data, err := newTestDocument(t, 1).BytesWith(WriteOptions{Linearize: true, ObjectStreams: true})
+	require.NoError(t, err)
+	assert.Contains(t, string(data[:200]), "/Linearized 1")
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	pages, err := parsed.Pages()
+	require.NoError(t, err)
+	assert.Len(t, pages, 1)
+}
+
+func TestLinearLayout_SharedObjects(t *testing.T) {
+	d := newSharedFontDocument(t, 3)
+	pages, _ := d.Pages()
+	logo := d.Add(NewStream(Dict{"Type": Name("XObject"), "Subtype": Name("Form")}, []byte("0 g")))
+	for _, p := range pages[1:] {
+		p.Dict["Resources"].(Dict)["XObject"] = Dict{"Logo": logo}
+	}
+
+	l, err := d.linearLayout()
+	require.NoError(t, err)
+	assert.Equal(t, pages[0].Ref.Num, l.first[0])
+	assert.Equal(t, []int{logo.Num}, l.shared)
+	// The font is in the first page section; the logo is the first entry
+	// after it in the shared object hint table.
+	assert.Equal(t, []int{l.pageShared[1][0], len(l.first)}, l.pageShared[1])
+	assert.Equal(t, l.pageShared[1], l.pageShared[2])
+	assert.Empty(t, l.pageShared[0])
+}
+
+func TestBitWriter(t *testing.T) {
+	w := &bitWriter{}
+	w.write(1, 1)
+	w.write(0, 2)
+	w.write(5, 3)
+	w.align()
+	w.write(0xabc, 12)
+	w.align()
+	assert.Equal(t, []byte{0x94, 0xab, 0xc0}, w.buf.Bytes())
+	assert.Equal(t, 0, bitsFor(0))
+	assert.Equal(t, 3, bitsFor(4))
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit := int(r.data[r.pos/8]>>(7-uint(r.pos%8))) & 1
+		v = v<<1 | bit
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) skip(n int) { r.pos += n }
+
+func (r *bitReader) align() { r.pos = (r.pos + 7) / 8 * 8 }
@@ -0,0 +1,127 @@
+// Package pdf reads, modifies and writes PDF files. It implements the subset
+// of ISO 32000 needed to post-process documents produced by Chromium:
+// classic and stream cross-reference sections, object streams, the common
+// stream filters and a full-rewrite writer.
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrMalformed         = errors.New("malformed PDF")
+	ErrUnsupportedFilter = errors.New("unsupported stream filter")
+	ErrEncrypted         = errors.New("encrypted PDF")
+)
+
+// Object is any PDF object: nil (null), bool, int, float64, Name, String,
+// Array, Dict, *Stream or Ref.
+type Object interface{}
+
+type Name string
+
+type String []byte
+
+type Array []Object
+
+type Dict map[Name]Object
+
+type Ref struct {
+	Num int
+	Gen int
+}
+
+func (r Ref) String() string {
+	return fmt.Sprintf("%d %d R", r.Num, r.Gen)
+}
+
+// Stream holds a stream dictionary and its raw, still encoded, data.
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// keyword is a bare token such as obj, R or a content stream operator.
+type keyword string
+
+// Rect is a PDF rectangle normalised so that LLX <= URX and LLY <= URY.
+type Rect struct {
+	LLX, LLY, URX, URY float64
+}
+
+func (r Rect) Width() float64  { return r.URX - r.LLX }
+func (r Rect) Height() float64 { return r.URY - r.LLY }
+
+func (r Rect) Array() Array {
+	return Array{r.LLX, r.LLY, r.URX, r.URY}
+}
+
+func (r Rect) IsZero() bool {
+	return r == Rect{}
+}
+
+func rectFromArray(a Array) (Rect, bool) {
+	if len(a) != 4 {
+		return Rect{}, false
+	}
+	var v [4]float64
+	for i, o := range a {
+		f, ok := toFloat(o)
+		if !ok {
+			return Rect{}, false
+		}
+		v[i] = f
+	}
+	return Rect{
+		LLX: math.Min(v[0], v[2]), LLY: math.Min(v[1], v[3]),
+		URX: math.Max(v[0], v[2]), URY: math.Max(v[1], v[3]),
+	}, true
+}
+
+func toFloat(o Object) (float64, bool) {
+	switch v := o.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func toInt(o Object) (int, bool) {
+	switch v := o.(type) {
+	case int:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+// Clone returns a deep copy of a direct object. References are copied as
+// references; the objects they point to are not.
+func Clone(o Object) Object {
+	switch v := o.(type) {
+	case String:
+		return append(String(nil), v...)
+	case Array:
+		c := make(Array, len(v))
+		for i, e := range v {
+			c[i] = Clone(e)
+		}
+		return c
+	case Dict:
+		c := make(Dict, len(v))
+		for k, e := range v {
+			c[k] = Clone(e)
+		}
+		return c
+	case *Stream:
+		return &Stream{Dict: Clone(v.Dict).(Dict), Data: append([]byte(nil), v.Data...)}
+	}
+	return o
+}
@@ -0,0 +1,502 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+)
+
+var ErrInvalidOptimization = errors.New("invalid optimization options")
+
+const (
+	DefaultMaxImageDPI = 150
+	DefaultJPEGQuality = 85
+
+	// maxFormDepth bounds the recursion into nested form XObjects when
+	// measuring how large images are drawn.
+	maxFormDepth = 16
+)
+
+// OptimizeOptions control the size optimization pass.
+type OptimizeOptions struct {
+	// MaxImageDPI is the highest resolution kept for raster images, based
+	// on the largest size at which each image is drawn.
+	MaxImageDPI float64
+	// JPEGQuality is used when re-encoding downsampled JPEG images.
+	JPEGQuality int
+}
+
+// OptimizeStats counts what an optimization pass changed.
+type OptimizeStats struct {
+	ImagesDownsampled   int
+	DuplicatesRemoved   int
+	UnusedRemoved       int
+	StreamsRecompressed int
+}
+
+func (o *OptimizeOptions) normalize() error {
+	if o.MaxImageDPI == 0 {
+		o.MaxImageDPI = DefaultMaxImageDPI
+	}
+	if o.JPEGQuality == 0 {
+		o.JPEGQuality = DefaultJPEGQuality
+	}
+	if o.MaxImageDPI < 0 {
+		return fmt.Errorf("%w: max image DPI must be positive", ErrInvalidOptimization)
+	}
+	if o.JPEGQuality < 1 || o.JPEGQuality > 100 {
+		return fmt.Errorf("%w: JPEG quality must be between 1 and 100", ErrInvalidOptimization)
+	}
+	return nil
+}
+
+// Optimize reduces the document's size: it downsamples raster images drawn
+// above opts.MaxImageDPI, merges identical objects such as fonts embedded
+// once per use, drops unreachable objects, recompresses streams with the
+// best Flate level and renumbers the remaining objects. Write the result
+// with WriteOptions.ObjectStreams to also compress the file structure.
+func (d *Document) Optimize(opts OptimizeOptions) (*OptimizeStats, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	stats := &OptimizeStats{}
+	if d.Encrypted() {
+		// Stream data and strings cannot be rewritten without the key.
+		return stats, nil
+	}
+
+	var err error
+	if stats.ImagesDownsampled, err = d.downsampleImages(opts); err != nil {
+		return nil, err
+	}
+	stats.DuplicatesRemoved = d.deduplicate()
+	stats.UnusedRemoved = d.removeUnreachable()
+	stats.StreamsRecompressed = d.recompressStreams()
+	d.renumber()
+	return stats, nil
+}
+
+// dedupable reports whether o may be merged with an identical object.
+// Objects whose identity matters, such as pages, annotations, form fields
+// and tree nodes pointing to a parent, are kept.
+func dedupable(o Object) bool {
+	var dict Dict
+	switch v := o.(type) {
+	case Dict:
+		dict = v
+	case *Stream:
+		dict = v.Dict
+	default:
+		return true
+	}
+	switch dict["Type"] {
+	case Name("Catalog"), Name("Pages"), Name("Page"), Name("Annot"):
+		return false
+	}
+	for _, k := range []Name{"Parent", "P", "Rect", "FT", "T"} {
+		if _, ok := dict[k]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// deduplicate merges identical objects, repeating until no more merge
+// because merging children can make their parents identical.
+func (d *Document) deduplicate() int {
+	removed := 0
+	for {
+		canonical := map[string]int{}
+		replace := map[int]int{}
+		for _, num := range d.ObjectNumbers() {
+			o := d.objects[num]
+			if !dedupable(o) {
+				continue
+			}
+			key := string(Serialize(o))
+			if first, ok := canonical[key]; ok {
+				replace[num] = first
+			} else {
+				canonical[key] = num
+			}
+		}
+		if len(replace) == 0 {
+			return removed
+		}
+		for num := range replace {
+			delete(d.objects, num)
+		}
+		d.rewriteRefs(replace)
+		removed += len(replace)
+	}
+}
+
+// rewriteRefs replaces references to the keys of mapping with references
+// to its values throughout the document. Direct arrays and dictionaries are
+// copied, so one shared by several objects is rewritten only once.
+func (d *Document) rewriteRefs(mapping map[int]int) {
+	var rewrite func(o Object) Object
+	rewrite = func(o Object) Object {
+		switch v := o.(type) {
+		case Ref:
+			if num, ok := mapping[v.Num]; ok {
+				return Ref{Num: num}
+			}
+		case Array:
+			c := make(Array, len(v))
+			for i, e := range v {
+				c[i] = rewrite(e)
+			}
+			return c
+		case Dict:
+			c := make(Dict, len(v))
+			for k, e := range v {
+				c[k] = rewrite(e)
+			}
+			return c
+		case *Stream:
+			v.Dict = rewrite(v.Dict).(Dict)
+		}
+		return o
+	}
+	for num, o := range d.objects {
+		d.objects[num] = rewrite(o)
+	}
+	d.Trailer = rewrite(d.Trailer).(Dict)
+}
+
+// removeUnreachable deletes objects that cannot be reached from the
+// trailer.
+func (d *Document) removeUnreachable() int {
+	reached := map[int]bool{}
+	var mark func(o Object)
+	mark = func(o Object) {
+		switch v := o.(type) {
+		case Ref:
+			if reached[v.Num] {
+				return
+			}
+			reached[v.Num] = true
+			mark(d.objects[v.Num])
+		case Array:
+			for _, e := range v {
+				mark(e)
+			}
+		case Dict:
+			for _, e := range v {
+				mark(e)
+			}
+		case *Stream:
+			mark(v.Dict)
+		}
+	}
+	mark(d.Trailer)
+
+	removed := 0
+	for num := range d.objects {
+		if !reached[num] {
+			delete(d.objects, num)
+			removed++
+		}
+	}
+	return removed
+}
+
+// recompressStreams re-encodes every non-image stream with Flate at the
+// best compression level, keeping the result when it is smaller. XMP
+// metadata stays uncompressed so that it remains readable by tools that
+// scan files for it.
+func (d *Document) recompressStreams() int {
+	n := 0
+	for _, o := range d.objects {
+		s, ok := o.(*Stream)
+		if !ok || s.ImageFilter() != "" || s.Dict["Type"] == Name("Metadata") {
+			continue
+		}
+		data, err := s.Decode()
+		if err != nil {
+			continue
+		}
+		packed := deflate(data)
+		if len(packed) >= len(s.Data) {
+			continue
+		}
+		delete(s.Dict, "DecodeParms")
+		delete(s.Dict, "DL")
+		s.Dict["Filter"] = Name("FlateDecode")
+		s.Data = packed
+		n++
+	}
+	return n
+}
+
+// renumber assigns consecutive object numbers starting at 1.
+func (d *Document) renumber() {
+	mapping := map[int]int{}
+	objects := make(map[int]Object, len(d.objects))
+	for i, num := range d.ObjectNumbers() {
+		mapping[num] = i + 1
+		objects[i+1] = d.objects[num]
+	}
+	d.objects = objects
+	d.nextNum = len(objects) + 1
+	d.rewriteRefs(mapping)
+}
+
+// drawnSize is the largest size, in points, at which an image is drawn.
+type drawnSize struct {
+	width, height float64
+}
+
+// imageUses walks the content of every page, including nested forms, and
+// records the largest drawn size of each image XObject.
+func (d *Document) imageUses() (map[int]*drawnSize, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	uses := map[int]*drawnSize{}
+	var walk func(content []byte, res Dict, ctm Matrix, depth int)
+	walk = func(content []byte, res Dict, ctm Matrix, depth int) {
+		ops, _ := ParseContent(content)
+		var stack []Matrix
+		for _, op := range ops {
+			switch op.Operator {
+			case "q":
+				stack = append(stack, ctm)
+			case "Q":
+				if len(stack) > 0 {
+					ctm = stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			case "cm":
+				if v, ok := operandFloats(op.Operands); ok && len(v) == 6 {
+					ctm = Matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.Multiply(ctm)
+				}
+			case "Do":
+				if len(op.Operands) != 1 {
+					continue
+				}
+				name, _ := op.Operands[0].(Name)
+				o := d.GetDict(res["XObject"])[name]
+				s := d.GetStream(o)
+				if s == nil {
+					continue
+				}
+				switch d.GetName(s.Dict["Subtype"]) {
+				case "Image":
+					ref, ok := o.(Ref)
+					if !ok {
+						continue
+					}
+					w, h := ctm.Scale()
+					use := uses[ref.Num]
+					if use == nil {
+						use = &drawnSize{}
+						uses[ref.Num] = use
+					}
+					use.width = math.Max(use.width, w)
+					use.height = math.Max(use.height, h)
+				case "Form":
+					if depth >= maxFormDepth {
+						continue
+					}
+					data, err := s.Decode()
+					if err != nil {
+						continue
+					}
+					formRes := d.GetDict(s.Dict["Resources"])
+					if formRes == nil {
+						formRes = res
+					}
+					m := matrixFromArray(d.GetArray(s.Dict["Matrix"]))
+					walk(data, formRes, m.Multiply(ctm), depth+1)
+				}
+			}
+		}
+	}
+	for _, p := range pages {
+		content, err := d.Content(p)
+		if err != nil {
+			continue
+		}
+		walk(content, p.Resources, Identity, 0)
+	}
+	return uses, nil
+}
+
+func (d *Document) downsampleImages(opts OptimizeOptions) (int, error) {
+	uses, err := d.imageUses()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for num, use := range uses {
+		s, ok := d.objects[num].(*Stream)
+		if !ok {
+			continue
+		}
+		w, _ := d.GetInt(s.Dict["Width"])
+		h, _ := d.GetInt(s.Dict["Height"])
+		if w <= 0 || h <= 0 || use.width <= 0 || use.height <= 0 {
+			continue
+		}
+		// Each axis keeps enough pixels for the largest drawn size.
+		nw := min(w, int(math.Ceil(use.width/72*opts.MaxImageDPI)))
+		nh := min(h, int(math.Ceil(use.height/72*opts.MaxImageDPI)))
+		if nw == w && nh == h {
+			continue
+		}
+		if d.downsampleImage(s, w, h, nw, nh, opts.JPEGQuality) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// imageComponents returns the number of colour components of an image
+// colour space this pass can resample, or 0.
+func (d *Document) imageComponents(cs Object) int {
+	switch d.GetName(cs) {
+	case "DeviceGray", "G":
+		return 1
+	case "DeviceRGB", "RGB":
+		return 3
+	case "DeviceCMYK", "CMYK":
+		return 4
+	}
+	if a := d.GetArray(cs); len(a) == 2 && d.GetName(a[0]) == "ICCBased" {
+		if icc := d.GetStream(a[1]); icc != nil {
+			n, _ := d.GetInt(icc.Dict["N"])
+			return n
+		}
+	}
+	return 0
+}
+
+// downsampleImage resamples an 8-bit image XObject to nw × nh pixels. Only
+// plain and JPEG encoded images in device or ICC based colour spaces are
+// handled; the result is kept only if it is smaller.
+func (d *Document) downsampleImage(s *Stream, w, h, nw, nh, quality int) bool {
+	if bpc, _ := d.GetInt(s.Dict["BitsPerComponent"]); bpc != 8 {
+		return false
+	}
+	if s.Dict["ImageMask"] == true || s.Dict["Decode"] != nil {
+		return false
+	}
+	comps := d.imageComponents(s.Dict["ColorSpace"])
+	if comps == 0 {
+		return false
+	}
+
+	filter := s.ImageFilter()
+	var pixels []byte
+	switch filter {
+	case "":
+		data, err := s.Decode()
+		if err != nil || len(data) < w*h*comps {
+			return false
+		}
+		pixels = data[:w*h*comps]
+	case "DCTDecode":
+		if comps == 4 {
+			return false
+		}
+		img, err := jpeg.Decode(bytes.NewReader(s.Data))
+		if err != nil || img.Bounds().Dx() != w || img.Bounds().Dy() != h {
+			return false
+		}
+		pixels = imagePixels(img, comps)
+	default:
+		return false
+	}
+
+	resampled := resample(pixels, w, h, nw, nh, comps)
+	var data []byte
+	var filterName Name
+	if filter == "DCTDecode" {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, pixelImage(resampled, nw, nh, comps), &jpeg.Options{Quality: quality}); err != nil {
+			return false
+		}
+		data, filterName = buf.Bytes(), "DCTDecode"
+	} else {
+		data, filterName = deflate(resampled), "FlateDecode"
+	}
+	if len(data) >= len(s.Data) {
+		return false
+	}
+
+	delete(s.Dict, "DecodeParms")
+	s.Dict["Filter"] = filterName
+	s.Dict["Width"] = nw
+	s.Dict["Height"] = nh
+	s.Data = data
+	return true
+}
+
+// imagePixels returns the samples of img as interleaved 8-bit gray or RGB.
+func imagePixels(img image.Image, comps int) []byte {
+	b := img.Bounds()
+	out := make([]byte, 0, b.Dx()*b.Dy()*comps)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if comps == 1 {
+				out = append(out, byte(r>>8))
+			} else {
+				out = append(out, byte(r>>8), byte(g>>8), byte(bl>>8))
+			}
+		}
+	}
+	return out
+}
+
+// pixelImage wraps interleaved gray or RGB samples as an image.
+func pixelImage(pixels []byte, w, h, comps int) image.Image {
+	if comps == 1 {
+		return &image.Gray{Pix: pixels, Stride: w, Rect: image.Rect(0, 0, w, h)}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		copy(img.Pix[i*4:], pixels[i*3:i*3+3])
+		img.Pix[i*4+3] = 0xff
+	}
+	return img
+}
+
+// resample scales interleaved samples by averaging the source pixels that
+// fall into each destination pixel.
+func resample(src []byte, w, h, nw, nh, comps int) []byte {
+	out := make([]byte, 0, nw*nh*comps)
+	sums := make([]int, comps)
+	for y := 0; y < nh; y++ {
+		y0, y1 := y*h/nh, (y+1)*h/nh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < nw; x++ {
+			x0, x1 := x*w/nw, (x+1)*w/nw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			for c := range sums {
+				sums[c] = 0
+			}
+			for sy := y0; sy < y1; sy++ {
+				row := src[(sy*w+x0)*comps : (sy*w+x1)*comps]
+				for i, v := range row {
+					sums[i%comps] += int(v)
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			for _, sum := range sums {
+				out = append(out, byte((sum+count/2)/count))
+			}
+		}
+	}
+	return out
+}
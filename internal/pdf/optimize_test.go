@@ -0,0 +1,212 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noise returns an image whose pixels vary enough to defeat compression.
+func noise(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = byte(seed >> 24)
+		if i%4 == 3 {
+			img.Pix[i] = 0xff
+		}
+	}
+	return img
+}
+
+// addImagePage adds a page drawing image ref at size × size points.
+func addImagePage(d *Document, ref Ref, size int) {
+	content := []byte("q " + formatReal(float64(size)) + " 0 0 " + formatReal(float64(size)) + " 72 72 cm /Im1 Do Q")
+	d.AddPage(a4, Dict{"XObject": Dict{"Im1": ref}}, content)
+}
+
+func TestOptimize_DeduplicatesFonts(t *testing.T) {
+	d := New()
+	for i := 0; i < 3; i++ {
+		font := d.Add(Dict{
+			"Type": Name("Font"), "Subtype": Name("TrueType"), "BaseFont": Name("Vazir"),
+			"FontDescriptor": d.Add(Dict{
+				"Type": Name("FontDescriptor"), "FontName": Name("Vazir"),
+				"FontFile2": d.Add(NewStream(nil, bytes.Repeat([]byte("glyf"), 1000))),
+			}),
+		})
+		d.AddPage(a4, Dict{"Font": Dict{"F1": font}}, []byte("BT /F1 12 Tf (x) Tj ET"))
+	}
+	d.Add(Dict{"Unused": true})
+
+	stats, err := d.Optimize(OptimizeOptions{})
+	require.NoError(t, err)
+	// Three fonts with their descriptors and files, and the page contents.
+	assert.Equal(t, 8, stats.DuplicatesRemoved)
+	assert.Equal(t, 1, stats.UnusedRemoved)
+
+	d = roundTrip(t, d)
+	pages, err := d.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	fonts, err := d.Fonts()
+	require.NoError(t, err)
+	assert.Len(t, fonts, 1)
+	assert.True(t, fonts[0].Embedded)
+
+	// Objects are renumbered without gaps.
+	nums := d.ObjectNumbers()
+	assert.Equal(t, len(nums), nums[len(nums)-1])
+}
+
+func TestOptimize_KeepsIdenticalPages(t *testing.T) {
+	d := New()
+	d.AddPage(a4, nil, []byte("0 g"))
+	d.AddPage(a4, nil, []byte("0 g"))
+
+	_, err := d.Optimize(OptimizeOptions{})
+	require.NoError(t, err)
+	pages, err := roundTrip(t, d).Pages()
+	require.NoError(t, err)
+	assert.Len(t, pages, 2)
+}
+
+func TestOptimize_RecompressesStreams(t *testing.T) {
+	d := New()
+	content := bytes.Repeat([]byte("0 0 m 100 100 l S\n"), 200)
+	page := d.AddPage(a4, nil, nil)
+	contentRef := d.Add(&Stream{Dict: Dict{}, Data: content})
+	d.GetDict(page)["Contents"] = contentRef
+
+	stats, err := d.Optimize(OptimizeOptions{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, stats.StreamsRecompressed, 1)
+
+	pages, _ := d.Pages()
+	got, err := d.Content(pages[0])
+	require.NoError(t, err)
+	assert.Equal(t, append(content, '\n'), got)
+	s := d.GetStream(pages[0].Dict["Contents"])
+	assert.Equal(t, Name("FlateDecode"), s.Dict["Filter"])
+	assert.Less(t, len(s.Data), len(content))
+}
+
+func TestOptimize_DownsamplesImages(t *testing.T) {
+	d := New()
+	ref := d.AddRGBImage(noise(600, 400))
+	// 600 pixels across 100 points is 432 DPI.
+	addImagePage(d, ref, 100)
+
+	stats, err := d.Optimize(OptimizeOptions{MaxImageDPI: 144})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.ImagesDownsampled)
+
+	d = roundTrip(t, d)
+	pages, _ := d.Pages()
+	img := d.GetStream(d.GetDict(pages[0].Resources["XObject"])["Im1"])
+	require.NotNil(t, img)
+	assert.Equal(t, 200, img.Dict["Width"])
+	assert.Equal(t, 200, img.Dict["Height"])
+	data, err := img.Decode()
+	require.NoError(t, err)
+	assert.Len(t, data, 200*200*3)
+}
+
+func TestOptimize_DownsamplesJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, noise(800, 800), &jpeg.Options{Quality: 95}))
+
+	d := New()
+	ref, _, _, err := d.AddImage(buf.Bytes())
+	require.NoError(t, err)
+	addImagePage(d, ref, 144)
+
+	stats, err := d.Optimize(OptimizeOptions{MaxImageDPI: 100, JPEGQuality: 70})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.ImagesDownsampled)
+
+	pages, _ := d.Pages()
+	img := d.GetStream(d.GetDict(pages[0].Resources["XObject"])["Im1"])
+	assert.Equal(t, Name("DCTDecode"), img.Dict["Filter"])
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(200, 200), decoded.Bounds().Size())
+	assert.Less(t, len(img.Data), buf.Len())
+}
+
+func TestOptimize_KeepsImagesDrawnLarge(t *testing.T) {
+	d := New()
+	ref := d.AddRGBImage(noise(100, 100))
+	addImagePage(d, ref, 10)
+	// The same image drawn at 100 points on another page needs 150 DPI.
+	addImagePage(d, ref, 72*100/150)
+
+	stats, err := d.Optimize(OptimizeOptions{MaxImageDPI: 150})
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.ImagesDownsampled)
+}
+
+func TestOptimize_ImageInsideForm(t *testing.T) {
+	d := New()
+	img := d.AddRGBImage(noise(400, 400))
+	form := d.Add(NewStream(Dict{
+		"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": Array{0, 0, 1, 1},
+		"Matrix":    Array{0.5, 0, 0, 0.5, 0, 0},
+		"Resources": Dict{"XObject": Dict{"Im1": img}},
+	}, []byte("/Im1 Do")))
+	d.AddPage(a4, Dict{"XObject": Dict{"Fm1": form}}, []byte("q 200 0 0 200 0 0 cm /Fm1 Do Q"))
+
+	// The image is drawn at 100 points, so 72 DPI keeps 100 pixels.
+	_, err := d.Optimize(OptimizeOptions{MaxImageDPI: 72})
+	require.NoError(t, err)
+	pages, _ := d.Pages()
+	f := d.GetStream(d.GetDict(pages[0].Resources["XObject"])["Fm1"])
+	im := d.GetStream(d.GetDict(d.GetDict(f.Dict["Resources"])["XObject"])["Im1"])
+	assert.Equal(t, 100, im.Dict["Width"])
+}
+
+func TestOptimize_InvalidOptions(t *testing.T) {
+	_, err := New().Optimize(OptimizeOptions{JPEGQuality: 101})
+	assert.ErrorIs(t, err, ErrInvalidOptimization)
+	_, err = New().Optimize(OptimizeOptions{MaxImageDPI: -1})
+	assert.ErrorIs(t, err, ErrInvalidOptimization)
+}
+
+func TestWrite_ObjectStreams(t *testing.T) {
+	d := newTestDocument(t, 150)
+	d.Version = "1.4"
+	d.Info(true)["Title"] = String("Compressed")
+
+	classic, err := d.Bytes()
+	require.NoError(t, err)
+	compressed, err := d.BytesWith(WriteOptions{ObjectStreams: true})
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), len(classic))
+	assert.True(t, bytes.HasPrefix(compressed, []byte("%PDF-1.5")))
+	assert.NotContains(t, string(compressed), "\nxref\n")
+
+	parsed, err := Parse(compressed)
+	require.NoError(t, err)
+	pages, err := parsed.Pages()
+	require.NoError(t, err)
+	require.Len(t, pages, 150)
+	content, err := parsed.Content(pages[149])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "(Page 150) Tj")
+	assert.Equal(t, String("Compressed"), parsed.Info(false)["Title"])
+}
+
+func TestResample_Averages(t *testing.T) {
+	src := []byte{0, 100, 200, 100}
+	assert.Equal(t, []byte{100}, resample(src, 2, 2, 1, 1, 1))
+
+	gray := &image.Gray{Pix: []byte{10, 20}, Stride: 2, Rect: image.Rect(0, 0, 2, 1)}
+	assert.Equal(t, []byte{10, 20}, imagePixels(gray, 1))
+	assert.Equal(t, color.RGBA{1, 2, 3, 255}, pixelImage([]byte{1, 2, 3}, 1, 1, 3).At(0, 0))
+}
@@ -0,0 +1,79 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPageRange = errors.New("invalid page range")
+
+// ParsePageRange expands a page range such as "1,3-5,8-" into sorted,
+// de-duplicated 1-based page numbers. An empty spec or "all" selects every
+// page; "last" may be used in place of the final page number.
+func ParsePageRange(spec string, pageCount int) ([]int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "all") {
+		pages := make([]int, pageCount)
+		for i := range pages {
+			pages[i] = i + 1
+		}
+		return pages, nil
+	}
+
+	parseNum := func(s string) (int, error) {
+		s = strings.TrimSpace(s)
+		if strings.EqualFold(s, "last") {
+			return pageCount, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > pageCount {
+			return 0, fmt.Errorf("%w: page %q is outside 1-%d", ErrInvalidPageRange, s, pageCount)
+		}
+		return n, nil
+	}
+
+	seen := map[int]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			from, to = part[:i], part[i+1:]
+			if strings.TrimSpace(from) == "" {
+				from = "1"
+			}
+			if strings.TrimSpace(to) == "" {
+				to = "last"
+			}
+		}
+		start, err := parseNum(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseNum(to)
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("%w: %q runs backwards", ErrInvalidPageRange, part)
+		}
+		for n := start; n <= end; n++ {
+			seen[n] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("%w: %q selects no pages", ErrInvalidPageRange, spec)
+	}
+
+	pages := make([]int, 0, len(seen))
+	for n := range seen {
+		pages = append(pages, n)
+	}
+	sort.Ints(pages)
+	return pages, nil
+}
@@ -0,0 +1,35 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePageRange(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{"", []int{1, 2, 3, 4, 5}},
+		{"all", []int{1, 2, 3, 4, 5}},
+		{"2", []int{2}},
+		{"1,3-4", []int{1, 3, 4}},
+		{"4-", []int{4, 5}},
+		{"-2", []int{1, 2}},
+		{"last", []int{5}},
+		{"3-last, 1, 3", []int{1, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePageRange(tt.spec, 5)
+		assert.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, got, tt.spec)
+	}
+}
+
+func TestParsePageRange_Invalid(t *testing.T) {
+	for _, spec := range []string{"0", "6", "3-2", "a", ",", "1-x"} {
+		_, err := ParsePageRange(spec, 5)
+		assert.ErrorIs(t, err, ErrInvalidPageRange, spec)
+	}
+}
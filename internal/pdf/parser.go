@@ -0,0 +1,458 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+const maxNesting = 256
+
+// parser tokenizes PDF syntax from a byte slice. It is used both for the
+// file structure and for content streams, where operators come back as
+// keywords.
+type parser struct {
+	data []byte
+	pos  int
+	// resolveLength resolves an indirect stream /Length. It may be nil.
+	resolveLength func(Ref) (int, bool)
+}
+
+func newParser(data []byte) *parser {
+	return &parser{data: data}
+}
+
+func isWhitespace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isRegular(c byte) bool {
+	return !isWhitespace(c) && !isDelimiter(c)
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: offset %d: %s", ErrMalformed, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if isWhitespace(c) {
+			p.pos++
+		} else if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+func (p *parser) readRegular() []byte {
+	start := p.pos
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+// peekKeyword reports whether the next token is the keyword kw without
+// consuming it.
+func (p *parser) peekKeyword(kw string) bool {
+	save := p.pos
+	p.skipSpace()
+	tok := p.readRegular()
+	p.pos = save
+	return string(tok) == kw
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	p.skipSpace()
+	if tok := p.readRegular(); string(tok) != kw {
+		return p.errorf("expected %q, found %q", kw, tok)
+	}
+	return nil
+}
+
+// readObject reads the next object. References of the form "n g R" are
+// recognised; any other bare token is returned as a keyword.
+func (p *parser) readObject() (Object, error) {
+	return p.readNested(0)
+}
+
+func (p *parser) readNested(depth int) (Object, error) {
+	if depth > maxNesting {
+		return nil, p.errorf("objects nested too deeply")
+	}
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unexpected end of data")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		p.pos++
+		return p.readName()
+	case c == '(':
+		p.pos++
+		return p.readLiteralString()
+	case c == '<':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '<' {
+			p.pos += 2
+			return p.readDict(depth)
+		}
+		p.pos++
+		return p.readHexString()
+	case c == '[':
+		p.pos++
+		return p.readArray(depth)
+	case c == ']' || c == '>' || c == ')':
+		p.pos++
+		return keyword(c), nil
+	case c == '{' || c == '}':
+		p.pos++
+		return keyword(c), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.readNumberOrRef()
+	}
+
+	tok := p.readRegular()
+	switch string(tok) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return keyword(tok), nil
+}
+
+func (p *parser) readName() (Object, error) {
+	tok := p.readRegular()
+	if bytes.IndexByte(tok, '#') < 0 {
+		return Name(tok), nil
+	}
+	var b []byte
+	for i := 0; i < len(tok); i++ {
+		if tok[i] == '#' && i+2 < len(tok) {
+			if v, err := strconv.ParseUint(string(tok[i+1:i+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, tok[i])
+	}
+	return Name(b), nil
+}
+
+func (p *parser) readLiteralString() (Object, error) {
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(b), nil
+			}
+		case '\\':
+			if p.eof() {
+				return nil, p.errorf("unterminated string")
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case '\r':
+				if !p.eof() && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && !p.eof() && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					b = append(b, byte(v))
+				} else {
+					b = append(b, e)
+				}
+			}
+			continue
+		}
+		b = append(b, c)
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func (p *parser) readHexString() (Object, error) {
+	var b []byte
+	var hi byte
+	half := false
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			if half {
+				b = append(b, hi<<4)
+			}
+			return String(b), nil
+		}
+		v, ok := unhex(c)
+		if !ok {
+			if isWhitespace(c) {
+				continue
+			}
+			return nil, p.errorf("invalid hex string character %q", c)
+		}
+		if half {
+			b = append(b, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	return nil, p.errorf("unterminated hex string")
+}
+
+func (p *parser) readArray(depth int) (Object, error) {
+	a := Array{}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		o, err := p.readNested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, o)
+	}
+}
+
+func (p *parser) readDict(depth int) (Object, error) {
+	d := Dict{}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated dictionary")
+		}
+		if p.data[p.pos] == '>' {
+			if p.pos+1 < len(p.data) && p.data[p.pos+1] == '>' {
+				p.pos += 2
+				return d, nil
+			}
+			return nil, p.errorf("unexpected '>' in dictionary")
+		}
+		k, err := p.readNested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(Name)
+		if !ok {
+			return nil, p.errorf("dictionary key is %T, not a name", k)
+		}
+		v, err := p.readNested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if kw, ok := v.(keyword); ok && kw == ">" {
+			return nil, p.errorf("missing value for key /%s", key)
+		}
+		// A null value is equivalent to the key being absent.
+		if v != nil {
+			d[key] = v
+		}
+	}
+}
+
+func (p *parser) readNumber() (Object, error) {
+	tok := p.readRegular()
+	if n, err := strconv.Atoi(string(tok)); err == nil {
+		return n, nil
+	}
+	s := string(tok)
+	// Tolerate the "--5" and "5-" forms some producers emit.
+	for len(s) > 1 && s[0] == '-' && s[1] == '-' {
+		s = s[1:]
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return 0, nil
+}
+
+func (p *parser) readNumberOrRef() (Object, error) {
+	n, err := p.readNumber()
+	if err != nil {
+		return nil, err
+	}
+	num, ok := n.(int)
+	if !ok || num < 0 {
+		return n, nil
+	}
+
+	save := p.pos
+	p.skipSpace()
+	if p.eof() || p.data[p.pos] < '0' || p.data[p.pos] > '9' {
+		p.pos = save
+		return n, nil
+	}
+	genTok := p.readRegular()
+	gen, err := strconv.Atoi(string(genTok))
+	if err != nil {
+		p.pos = save
+		return n, nil
+	}
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == 'R' && (p.pos+1 == len(p.data) || !isRegular(p.data[p.pos+1])) {
+		p.pos++
+		return Ref{Num: num, Gen: gen}, nil
+	}
+	p.pos = save
+	return n, nil
+}
+
+// readIndirect reads "n g obj ... endobj" at the current position, including
+// a trailing stream if present.
+func (p *parser) readIndirect() (Ref, Object, error) {
+	p.skipSpace()
+	numObj, err := p.readNumber()
+	if err != nil {
+		return Ref{}, nil, err
+	}
+	p.skipSpace()
+	genObj, err := p.readNumber()
+	if err != nil {
+		return Ref{}, nil, err
+	}
+	num, ok1 := numObj.(int)
+	gen, ok2 := genObj.(int)
+	if !ok1 || !ok2 {
+		return Ref{}, nil, p.errorf("invalid object header")
+	}
+	if err := p.expectKeyword("obj"); err != nil {
+		return Ref{}, nil, err
+	}
+	ref := Ref{Num: num, Gen: gen}
+
+	o, err := p.readObject()
+	if err != nil {
+		return ref, nil, err
+	}
+	if kw, ok := o.(keyword); ok && kw == "endobj" {
+		return ref, nil, nil
+	}
+
+	dict, isDict := o.(Dict)
+	if isDict && p.peekKeyword("stream") {
+		s, err := p.readStreamBody(dict)
+		if err != nil {
+			return ref, nil, err
+		}
+		return ref, s, nil
+	}
+	return ref, o, nil
+}
+
+func (p *parser) readStreamBody(dict Dict) (*Stream, error) {
+	p.skipSpace()
+	p.pos += len("stream")
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	length, known := 0, false
+	switch l := dict["Length"].(type) {
+	case int:
+		length, known = l, true
+	case Ref:
+		if p.resolveLength != nil {
+			length, known = p.resolveLength(l)
+		}
+	}
+
+	if known && length < 0 {
+		return nil, p.errorf("negative stream length %d", length)
+	}
+	// Compared this way round, a huge length cannot overflow.
+	if known && length <= len(p.data)-start {
+		end := start + length
+		q := &parser{data: p.data, pos: end}
+		q.skipSpace()
+		if bytes.HasPrefix(p.data[q.pos:], []byte("endstream")) {
+			p.pos = q.pos + len("endstream")
+			return &Stream{Dict: dict, Data: p.data[start:end]}, nil
+		}
+	}
+
+	// The declared length is missing or wrong: fall back to searching for
+	// the end marker and trimming the EOL that precedes it.
+	idx := bytes.Index(p.data[start:], []byte("endstream"))
+	if idx < 0 {
+		return nil, p.errorf("stream without endstream")
+	}
+	end := start + idx
+	if end > start && p.data[end-1] == '\n' {
+		end--
+	}
+	if end > start && p.data[end-1] == '\r' {
+		end--
+	}
+	p.pos = start + idx + len("endstream")
+	return &Stream{Dict: dict, Data: p.data[start:end]}, nil
+}
@@ -0,0 +1,469 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPDFALevel = errors.New("invalid PDF/A level")
+
+// PDFALevel identifies a PDF/A part and conformance level, such as 2B.
+type PDFALevel struct {
+	Part        int
+	Conformance string
+}
+
+func (l PDFALevel) String() string {
+	return fmt.Sprintf("PDF/A-%d%s", l.Part, strings.ToLower(l.Conformance))
+}
+
+// ParsePDFALevel accepts "2b", "3u", "PDF/A-2b" and similar spellings.
+// Parts 2 and 3 with conformance levels B and U are supported.
+func ParsePDFALevel(s string) (PDFALevel, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimPrefix(v, "PDF/A-")
+	v = strings.TrimPrefix(v, "PDFA-")
+	if len(v) != 2 || (v[0] != '2' && v[0] != '3') || (v[1] != 'B' && v[1] != 'U') {
+		return PDFALevel{}, fmt.Errorf("%w: %q (supported: 2b, 2u, 3b, 3u)", ErrInvalidPDFALevel, s)
+	}
+	return PDFALevel{Part: int(v[0] - '0'), Conformance: string(v[1])}, nil
+}
+
+// ConformanceReport is the outcome of a PDF/A check.
+type ConformanceReport struct {
+	Level      PDFALevel
+	Violations []string
+}
+
+func (r *ConformanceReport) Compliant() bool {
+	return len(r.Violations) == 0
+}
+
+func (r *ConformanceReport) addf(format string, args ...interface{}) {
+	r.Violations = append(r.Violations, fmt.Sprintf(format, args...))
+}
+
+// forbiddenActions may not appear anywhere in a PDF/A-2 or PDF/A-3 file.
+var forbiddenActions = map[Name]bool{
+	"Launch": true, "Sound": true, "Movie": true, "ResetForm": true,
+	"ImportData": true, "Hide": true, "SetOCGState": true, "Rendition": true,
+	"Trans": true, "GoTo3DView": true, "JavaScript": true,
+}
+
+var allowedNamedActions = map[Name]bool{
+	"NextPage": true, "PrevPage": true, "FirstPage": true, "LastPage": true,
+}
+
+var forbiddenAnnotations = map[Name]bool{
+	"Sound": true, "Movie": true, "Screen": true, "3D": true, "RichMedia": true,
+}
+
+// ConvertToPDFA rewrites the document for PDF/A archival: it embeds an sRGB
+// output intent, writes matching XMP identification and document metadata,
+// and strips features the standard forbids (JavaScript and other
+// non-conforming actions, multimedia annotations, PostScript XObjects,
+// transfer functions and interpolation). Problems it cannot repair, such as
+// fonts that are not embedded or encryption it cannot remove, are returned
+// in the report, which also reflects a final conformance check.
+func (d *Document) ConvertToPDFA(level PDFALevel) (*ConformanceReport, error) {
+	if _, err := d.Pages(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if d.Version < "1.7" {
+		d.Version = "1.7"
+	}
+	cat := d.Catalog()
+	delete(cat, "Version")
+
+	d.removeForbiddenFeatures()
+
+	cat["OutputIntents"] = Array{d.Add(Dict{
+		"Type":                      Name("OutputIntent"),
+		"S":                         Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": String(srgbDescription),
+		"Info":                      String(srgbDescription),
+		"RegistryName":              String("http://www.color.org"),
+		"DestOutputProfile":         d.Add(NewStream(Dict{"N": 3}, sRGBProfile())),
+	})}
+
+	info := d.Info(true)
+	if _, ok := info["CreationDate"]; !ok {
+		info["CreationDate"] = FormatDate(now)
+	}
+	info["ModDate"] = FormatDate(now)
+	delete(info, "Trapped")
+	cat["Metadata"] = d.Add(&Stream{
+		Dict: Dict{"Type": Name("Metadata"), "Subtype": Name("XML")},
+		Data: d.xmpMetadata(level, now),
+	})
+
+	return d.CheckPDFA(level)
+}
+
+func (d *Document) removeForbiddenFeatures() {
+	cat := d.Catalog()
+	if names := d.GetDict(cat["Names"]); names != nil {
+		delete(names, "JavaScript")
+	}
+	delete(cat, "AA")
+	delete(cat, "NeedsRendering")
+	if !d.allowedAction(cat["OpenAction"]) {
+		delete(cat, "OpenAction")
+	}
+	if form := d.GetDict(cat["AcroForm"]); form != nil {
+		delete(form, "NeedAppearances")
+		delete(form, "XFA")
+	}
+
+	for _, num := range d.ObjectNumbers() {
+		switch o := d.objects[num].(type) {
+		case Dict:
+			d.sanitizeDict(o)
+		case *Stream:
+			d.sanitizeDict(o.Dict)
+			for _, k := range []Name{"F", "FFilter", "FDecodeParms"} {
+				delete(o.Dict, k)
+			}
+			if d.GetName(o.Dict["Type"]) == "XObject" || o.Dict["Subtype"] != nil {
+				switch d.GetName(o.Dict["Subtype"]) {
+				case "Image":
+					delete(o.Dict, "Interpolate")
+					delete(o.Dict, "Alternates")
+					delete(o.Dict, "OPI")
+				case "Form":
+					delete(o.Dict, "OPI")
+					delete(o.Dict, "Ref")
+					if d.GetName(o.Dict["Subtype2"]) == "PS" {
+						d.objects[num] = NewStream(Dict{"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": o.Dict["BBox"]}, nil)
+					}
+				case "PS":
+					d.objects[num] = NewStream(Dict{"Type": Name("XObject"), "Subtype": Name("Form"), "BBox": Array{0, 0, 0, 0}}, nil)
+				}
+			}
+		}
+	}
+
+	pages, _ := d.Pages()
+	for _, p := range pages {
+		delete(p.Dict, "AA")
+		annots := d.GetArray(p.Dict["Annots"])
+		if annots == nil {
+			continue
+		}
+		kept := Array{}
+		for _, a := range annots {
+			annot := d.GetDict(a)
+			if annot == nil || forbiddenAnnotations[d.GetName(annot["Subtype"])] {
+				continue
+			}
+			// Annotations must print and may not be hidden.
+			flags, _ := d.GetInt(annot["F"])
+			flags = (flags | 4) &^ (1 | 2 | 32)
+			annot["F"] = flags
+			kept = append(kept, a)
+		}
+		p.Dict["Annots"] = kept
+	}
+}
+
+// sanitizeDict removes forbidden actions and additional-actions entries from
+// annotation, field and ExtGState dictionaries.
+func (d *Document) sanitizeDict(dict Dict) {
+	if _, ok := dict["A"]; ok && !d.allowedAction(dict["A"]) {
+		delete(dict, "A")
+	}
+	if d.GetName(dict["Type"]) == "Annot" || dict["FT"] != nil {
+		delete(dict, "AA")
+	}
+	if d.GetName(dict["Type"]) == "ExtGState" || dict["TR"] != nil {
+		delete(dict, "TR")
+		if _, ok := dict["TR2"]; ok {
+			dict["TR2"] = Name("Default")
+		}
+		delete(dict, "HTP")
+	}
+}
+
+func (d *Document) allowedAction(o Object) bool {
+	action := d.GetDict(o)
+	if action == nil {
+		// Destinations given as arrays are not actions.
+		return true
+	}
+	s := d.GetName(action["S"])
+	if forbiddenActions[s] {
+		return false
+	}
+	if s == "Named" && !allowedNamedActions[d.GetName(action["N"])] {
+		return false
+	}
+	return true
+}
+
+// CheckPDFA reports the PDF/A requirements the document does not meet. It
+// covers the requirements this package can verify structurally; it is not
+// a full validator.
+func (d *Document) CheckPDFA(level PDFALevel) (*ConformanceReport, error) {
+	report := &ConformanceReport{Level: level}
+
+	if d.Encrypted() {
+		report.addf("document is encrypted")
+	}
+
+	cat := d.Catalog()
+	if !d.hasPDFAOutputIntent() {
+		report.addf("no output intent with an embedded ICC profile")
+	}
+	d.checkMetadata(report, level)
+
+	if names := d.GetDict(cat["Names"]); names != nil && names["JavaScript"] != nil {
+		report.addf("document contains JavaScript")
+	}
+	if _, ok := cat["OpenAction"]; ok && !d.allowedAction(cat["OpenAction"]) {
+		report.addf("document open action is not permitted")
+	}
+	if form := d.GetDict(cat["AcroForm"]); form != nil && form["NeedAppearances"] == true {
+		report.addf("interactive form sets NeedAppearances")
+	}
+
+	fonts, err := d.Fonts()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fonts {
+		if !f.Embedded {
+			report.addf("font %s is not embedded", fontLabel(f))
+		}
+		if level.Conformance == "U" && f.Subtype != "Type3" && !d.fontHasUnicode(f.Dict) {
+			report.addf("font %s has no ToUnicode mapping", fontLabel(f))
+		}
+	}
+
+	for _, num := range d.ObjectNumbers() {
+		s, ok := d.objects[num].(*Stream)
+		if !ok {
+			continue
+		}
+		names, _ := s.filters()
+		for _, n := range names {
+			if n == "LZWDecode" || n == "LZW" {
+				report.addf("object %d uses LZW compression", num)
+			}
+		}
+		if s.Dict["F"] != nil {
+			report.addf("object %d refers to external stream data", num)
+		}
+	}
+
+	d.checkEmbeddedFiles(report, level)
+
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range pages {
+		for _, a := range d.GetArray(p.Dict["Annots"]) {
+			annot := d.GetDict(a)
+			subtype := d.GetName(annot["Subtype"])
+			flags, _ := d.GetInt(annot["F"])
+			if flags&4 == 0 {
+				report.addf("page %d: %s annotation is not printable", i+1, subtype)
+			}
+			if subtype != "Link" && subtype != "Popup" && d.GetDict(annot["AP"]) == nil {
+				report.addf("page %d: %s annotation has no appearance", i+1, subtype)
+			}
+		}
+	}
+
+	sort.Strings(report.Violations)
+	return report, nil
+}
+
+func fontLabel(f Font) string {
+	if f.BaseFont != "" {
+		return f.BaseFont
+	}
+	return fmt.Sprintf("object %d", f.Ref.Num)
+}
+
+func (d *Document) fontHasUnicode(font Dict) bool {
+	if font["ToUnicode"] != nil {
+		return true
+	}
+	switch d.GetName(font["Encoding"]) {
+	case "WinAnsiEncoding", "MacRomanEncoding", "StandardEncoding":
+		return true
+	}
+	return false
+}
+
+func (d *Document) hasPDFAOutputIntent() bool {
+	for _, o := range d.GetArray(d.Catalog()["OutputIntents"]) {
+		oi := d.GetDict(o)
+		if d.GetName(oi["S"]) == "GTS_PDFA1" && d.GetStream(oi["DestOutputProfile"]) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Document) checkMetadata(report *ConformanceReport, level PDFALevel) {
+	meta := d.GetStream(d.Catalog()["Metadata"])
+	if meta == nil {
+		report.addf("no XMP metadata stream")
+		return
+	}
+	data, err := meta.Decode()
+	if err != nil {
+		report.addf("XMP metadata cannot be decoded")
+		return
+	}
+	part := fmt.Sprintf("<pdfaid:part>%d</pdfaid:part>", level.Part)
+	conformance := fmt.Sprintf("<pdfaid:conformance>%s</pdfaid:conformance>", level.Conformance)
+	if !bytes.Contains(data, []byte(part)) || !bytes.Contains(data, []byte(conformance)) {
+		report.addf("XMP metadata does not identify the file as %s", level)
+	}
+}
+
+func (d *Document) checkEmbeddedFiles(report *ConformanceReport, level PDFALevel) {
+	files := d.embeddedFileSpecs()
+	if len(files) == 0 {
+		return
+	}
+	if level.Part == 2 {
+		// PDF/A-2 only allows attachments that are themselves PDF/A, which
+		// cannot be verified here.
+		report.addf("PDF/A-2 cannot verify that %d embedded file(s) conform to PDF/A; use PDF/A-3", len(files))
+		return
+	}
+	af := map[int]bool{}
+	for _, o := range d.GetArray(d.Catalog()["AF"]) {
+		if ref, ok := o.(Ref); ok {
+			af[ref.Num] = true
+		}
+	}
+	for _, f := range files {
+		spec := d.GetDict(f)
+		name := DecodeTextString(d.GetString(spec["UF"]))
+		if name == "" {
+			name = DecodeTextString(d.GetString(spec["F"]))
+		}
+		if spec["AFRelationship"] == nil {
+			report.addf("embedded file %s has no AFRelationship", name)
+		}
+		if ref, ok := f.(Ref); !ok || !af[ref.Num] {
+			report.addf("embedded file %s is not listed in the catalog's AF array", name)
+		}
+		ef := d.GetStream(d.GetDict(spec["EF"])["F"])
+		if ef == nil || ef.Dict["Subtype"] == nil {
+			report.addf("embedded file %s has no MIME type", name)
+		}
+	}
+}
+
+// embeddedFileSpecs returns the file specifications in the EmbeddedFiles
+// name tree.
+func (d *Document) embeddedFileSpecs() []Object {
+	names := d.GetDict(d.Catalog()["Names"])
+	if names == nil {
+		return nil
+	}
+	var specs []Object
+	d.walkNameTree(names["EmbeddedFiles"], func(_ string, v Object) {
+		specs = append(specs, v)
+	})
+	return specs
+}
+
+// walkNameTree calls fn for every key and value of a name tree.
+func (d *Document) walkNameTree(root Object, fn func(key string, value Object)) {
+	visited := map[int]bool{}
+	var walk func(o Object, depth int)
+	walk = func(o Object, depth int) {
+		if ref, ok := o.(Ref); ok {
+			if visited[ref.Num] {
+				return
+			}
+			visited[ref.Num] = true
+		}
+		node := d.GetDict(o)
+		if node == nil || depth > maxNesting {
+			return
+		}
+		pairs := d.GetArray(node["Names"])
+		for i := 0; i+1 < len(pairs); i += 2 {
+			fn(DecodeTextString(d.GetString(pairs[i])), pairs[i+1])
+		}
+		for _, kid := range d.GetArray(node["Kids"]) {
+			walk(kid, depth+1)
+		}
+	}
+	walk(root, 0)
+}
+
+func (d *Document) xmpMetadata(level PDFALevel, now time.Time) []byte {
+	info := d.Info(true)
+	text := func(k Name) string {
+		return html.EscapeString(DecodeTextString(d.GetString(info[k])))
+	}
+	xmpDate := func(k Name) string {
+		t, err := ParseDate(string(d.GetString(info[k])))
+		if err != nil {
+			t = now
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+
+	fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">`+"\n"+
+		"<pdfaid:part>%d</pdfaid:part>\n<pdfaid:conformance>%s</pdfaid:conformance>\n</rdf:Description>\n",
+		level.Part, level.Conformance)
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if t := text("Title"); t != "" {
+		fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", t)
+	}
+	if a := text("Author"); a != "" {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", a)
+	}
+	if s := text("Subject"); s != "" {
+		fmt.Fprintf(&b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", s)
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">` + "\n")
+	fmt.Fprintf(&b, "<xmp:CreateDate>%s</xmp:CreateDate>\n", xmpDate("CreationDate"))
+	fmt.Fprintf(&b, "<xmp:ModifyDate>%s</xmp:ModifyDate>\n", xmpDate("ModDate"))
+	fmt.Fprintf(&b, "<xmp:MetadataDate>%s</xmp:MetadataDate>\n", xmpDate("ModDate"))
+	if c := text("Creator"); c != "" {
+		fmt.Fprintf(&b, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", c)
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">` + "\n")
+	if p := text("Producer"); p != "" {
+		fmt.Fprintf(&b, "<pdf:Producer>%s</pdf:Producer>\n", p)
+	}
+	if k := text("Keywords"); k != "" {
+		fmt.Fprintf(&b, "<pdf:Keywords>%s</pdf:Keywords>\n", k)
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString("</rdf:RDF>\n</x:xmpmeta>\n")
+	// Padding lets editors update the packet in place.
+	b.WriteString(strings.Repeat(strings.Repeat(" ", 99)+"\n", 20))
+	b.WriteString(`<?xpacket end="w"?>`)
+	return b.Bytes()
+}
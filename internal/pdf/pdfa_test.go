@@ -0,0 +1,124 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEmbeddedFontDocument builds a one page document whose only font carries
+// an embedded font program, as Chromium's output does.
+func newEmbeddedFontDocument(t *testing.T) *Document {
+	t.Helper()
+	d := New()
+	font := d.Add(Dict{
+		"Type": Name("Font"), "Subtype": Name("TrueType"), "BaseFont": Name("AAAAAA+Vazir"),
+		"ToUnicode": d.Add(NewStream(nil, []byte("begincmap endcmap"))),
+		"FontDescriptor": d.Add(Dict{
+			"Type": Name("FontDescriptor"), "FontName": Name("AAAAAA+Vazir"),
+			"FontFile2": d.Add(NewStream(nil, []byte("glyphs"))),
+		}),
+	})
+	d.AddPage(a4, Dict{"Font": Dict{"F1": font}}, []byte("BT /F1 12 Tf (x) Tj ET"))
+	return d
+}
+
+func TestParsePDFALevel(t *testing.T) {
+	for in, want := range map[string]PDFALevel{
+		"2b":       {2, "B"},
+		"PDF/A-3b": {3, "B"},
+		" 3U ":     {3, "U"},
+	} {
+		got, err := ParsePDFALevel(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got)
+	}
+	for _, in := range []string{"", "1b", "2a", "4", "2bb"} {
+		_, err := ParsePDFALevel(in)
+		assert.ErrorIs(t, err, ErrInvalidPDFALevel, in)
+	}
+	assert.Equal(t, "PDF/A-2b", PDFALevel{2, "B"}.String())
+}
+
+func TestConvertToPDFA_Compliant(t *testing.T) {
+	d := newEmbeddedFontDocument(t)
+	cat := d.Catalog()
+	cat["Names"] = Dict{"JavaScript": Dict{"Names": Array{String("js"), Dict{"S": Name("JavaScript"), "JS": String("app.alert(1)")}}}}
+	cat["OpenAction"] = Dict{"S": Name("JavaScript"), "JS": String("app.alert(2)")}
+	d.Info(true)["Title"] = String("Statement <2026>")
+
+	pages, _ := d.Pages()
+	link := d.Add(Dict{"Type": Name("Annot"), "Subtype": Name("Link"), "F": 2, "A": Dict{"S": Name("URI"), "URI": String("https://example.com")}})
+	movie := d.Add(Dict{"Type": Name("Annot"), "Subtype": Name("Movie")})
+	pages[0].Dict["Annots"] = Array{link, movie}
+
+	report, err := d.ConvertToPDFA(PDFALevel{Part: 2, Conformance: "B"})
+	require.NoError(t, err)
+	assert.True(t, report.Compliant(), "%v", report.Violations)
+
+	d = roundTrip(t, d)
+	assert.Equal(t, "1.7", d.Version)
+	cat = d.Catalog()
+	assert.Nil(t, cat["OpenAction"])
+	assert.Nil(t, d.GetDict(cat["Names"])["JavaScript"])
+
+	pages, _ = d.Pages()
+	annots := d.GetArray(pages[0].Dict["Annots"])
+	require.Len(t, annots, 1)
+	assert.Equal(t, 4, d.GetDict(annots[0])["F"])
+
+	intent := d.GetDict(d.GetArray(cat["OutputIntents"])[0])
+	assert.Equal(t, Name("GTS_PDFA1"), intent["S"])
+	profile := d.GetStream(intent["DestOutputProfile"])
+	require.NotNil(t, profile)
+	assert.Equal(t, 3, profile.Dict["N"])
+
+	meta := d.GetStream(cat["Metadata"])
+	require.NotNil(t, meta)
+	xmp := string(meta.Data)
+	assert.Contains(t, xmp, "<pdfaid:part>2</pdfaid:part>")
+	assert.Contains(t, xmp, "<pdfaid:conformance>B</pdfaid:conformance>")
+	assert.Contains(t, xmp, "Statement &lt;2026&gt;")
+
+	info := d.Info(false)
+	created, err := ParseDate(string(d.GetString(info["CreationDate"])))
+	require.NoError(t, err)
+	assert.Contains(t, xmp, "<xmp:CreateDate>"+created.Format("2006-01-02T15:04:05Z07:00")+"</xmp:CreateDate>")
+
+	// The conversion survives a save and reload.
+	report, err = d.CheckPDFA(PDFALevel{Part: 2, Conformance: "B"})
+	require.NoError(t, err)
+	assert.True(t, report.Compliant(), "%v", report.Violations)
+}
+
+func TestConvertToPDFA_ReportsUnfixableProblems(t *testing.T) {
+	d := newTestDocument(t, 1)
+	d.Trailer["Encrypt"] = Dict{"Filter": Name("Standard")}
+
+	report, err := d.ConvertToPDFA(PDFALevel{Part: 3, Conformance: "U"})
+	require.NoError(t, err)
+	assert.False(t, report.Compliant())
+	assert.Contains(t, report.Violations, "document is encrypted")
+	assert.Contains(t, report.Violations, "font Helvetica is not embedded")
+	assert.Contains(t, report.Violations, "font Helvetica has no ToUnicode mapping")
+}
+
+func TestCheckPDFA_Unconverted(t *testing.T) {
+	report, err := newEmbeddedFontDocument(t).CheckPDFA(PDFALevel{Part: 2, Conformance: "B"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"no XMP metadata stream",
+		"no output intent with an embedded ICC profile",
+	}, report.Violations)
+}
+
+func TestSRGBProfile_Header(t *testing.T) {
+	p := sRGBProfile()
+	assert.Equal(t, uint32(len(p)), binary.BigEndian.Uint32(p[0:4]))
+	assert.Equal(t, "mntrRGB XYZ ", string(p[12:24]))
+	assert.Equal(t, "acsp", string(p[36:40]))
+	assert.Equal(t, uint32(9), binary.BigEndian.Uint32(p[128:132]))
+	assert.Contains(t, string(p), srgbDescription)
+}
@@ -0,0 +1,113 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidPrintMarks = errors.New("invalid print marks")
+
+const (
+	// MaxBleed is the widest bleed accepted, in points (half an inch).
+	MaxBleed = 36
+
+	// slugWidth is the space added around the bleed box to hold marks.
+	slugWidth = 24
+	// registrationRadius is the radius of registration targets.
+	registrationRadius = 5
+)
+
+// PrintMarks prepares pages for commercial printing, where sheets are cut
+// to the trim size after printing.
+type PrintMarks struct {
+	// Bleed is the width, in points, of the area around the trimmed page
+	// that the page content already covers, so that backgrounds reach past
+	// the cut. The trim box is the media box inset by it.
+	Bleed float64
+	// CropMarks draws cutting marks at the corners of the trim box, outside
+	// the bleed.
+	CropMarks bool
+	// RegistrationMarks draws targets at the middle of each side, used to
+	// align the printing plates.
+	RegistrationMarks bool
+}
+
+// Validate checks the options without changing a document.
+func (pm PrintMarks) Validate() error {
+	if pm.Bleed < 0 || pm.Bleed > MaxBleed {
+		return fmt.Errorf("%w: bleed must be between 0 and %d points", ErrInvalidPrintMarks, MaxBleed)
+	}
+	return nil
+}
+
+// AddPrintMarks sets the trim and bleed boxes of every page and, when
+// marks are requested, enlarges the media box to hold them. Marks are drawn
+// in the All separation, so that they appear on every printing plate.
+func (d *Document) AddPrintMarks(pm PrintMarks) error {
+	if err := pm.Validate(); err != nil {
+		return err
+	}
+	if d.Encrypted() {
+		return ErrEncrypted
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+
+	var registration Ref
+	b := pm.Bleed
+	for i, p := range pages {
+		media := p.MediaBox
+		trim := Rect{media.LLX + b, media.LLY + b, media.URX - b, media.URY - b}
+		if trim.Width() <= 0 || trim.Height() <= 0 {
+			return fmt.Errorf("%w: bleed leaves no trimmed area on page %d", ErrInvalidPrintMarks, i+1)
+		}
+		p.Dict["BleedBox"] = media.Array()
+		p.Dict["TrimBox"] = trim.Array()
+		if !pm.CropMarks && !pm.RegistrationMarks {
+			continue
+		}
+
+		p.Dict["MediaBox"] = Rect{media.LLX - slugWidth, media.LLY - slugWidth, media.URX + slugWidth, media.URY + slugWidth}.Array()
+		delete(p.Dict, "CropBox")
+		if registration == (Ref{}) {
+			registration = d.Add(Array{Name("Separation"), Name("All"), Name("DeviceCMYK"), Dict{
+				"FunctionType": 2, "Domain": Array{0, 1},
+				"C0": Array{0, 0, 0, 0}, "C1": Array{1, 1, 1, 1}, "N": 1,
+			}})
+		}
+		name := AddResource(d.OwnResources(p), "ColorSpace", "CS", registration)
+		color := fmt.Sprintf("/%s CS 1 SCN", name)
+
+		var buf bytes.Buffer
+		if pm.CropMarks {
+			writeCropMarks(&buf, []Rect{trim}, nil, b+cropMarkOffset, color)
+		}
+		if pm.RegistrationMarks {
+			writeRegistrationMarks(&buf, trim, b+slugWidth/2, color)
+		}
+		d.WrapContent(p, nil, buf.Bytes())
+	}
+	return nil
+}
+
+// writeRegistrationMarks draws a target centred distance points outside
+// the middle of each side of trim.
+func writeRegistrationMarks(buf *bytes.Buffer, trim Rect, distance float64, color string) {
+	cx, cy := (trim.LLX+trim.URX)/2, (trim.LLY+trim.URY)/2
+	centres := [][2]float64{
+		{cx, trim.URY + distance}, {cx, trim.LLY - distance},
+		{trim.LLX - distance, cy}, {trim.URX + distance, cy},
+	}
+	arm := float64(registrationRadius + 3)
+	buf.WriteString("q " + formatReal(cropMarkWidth) + " w " + color + "\n")
+	for _, c := range centres {
+		writeCircle(buf, c[0], c[1], registrationRadius, "S")
+		fmt.Fprintf(buf, "%s %s m %s %s l %s %s m %s %s l S\n",
+			formatReal(c[0]-arm), formatReal(c[1]), formatReal(c[0]+arm), formatReal(c[1]),
+			formatReal(c[0]), formatReal(c[1]-arm), formatReal(c[0]), formatReal(c[1]+arm))
+	}
+	buf.WriteString("Q\n")
+}
@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddPrintMarks_Boxes(t *testing.T) {
+	d := New()
+	d.AddPage(Rect{URX: 612, URY: 859}, nil, []byte("1 0 0 rg 0 0 612 859 re f"))
+	require.NoError(t, d.AddPrintMarks(PrintMarks{Bleed: 8.5}))
+
+	pages, err := roundTrip(t, d).Pages()
+	require.NoError(t, err)
+	p := pages[0]
+	assert.Equal(t, Rect{URX: 612, URY: 859}, p.MediaBox)
+	assert.Equal(t, Array{0, 0, 612, 859}, p.Dict["BleedBox"])
+	assert.Equal(t, Array{8.5, 8.5, 603.5, 850.5}, p.Dict["TrimBox"])
+	assert.Nil(t, p.Dict["CropBox"], "without marks the media box is the bleed box")
+}
+
+func TestAddPrintMarks_Marks(t *testing.T) {
+	d := newTestDocument(t, 2)
+	require.NoError(t, d.AddPrintMarks(PrintMarks{Bleed: 9, CropMarks: true, RegistrationMarks: true}))
+
+	parsed := roundTrip(t, d)
+	pages, err := parsed.Pages()
+	require.NoError(t, err)
+	for _, p := range pages {
+		assert.Equal(t, Rect{-24, -24, 595.28 + 24, 841.89 + 24}, p.MediaBox)
+		assert.Equal(t, p.MediaBox, p.CropBox)
+		content, err := parsed.Content(p)
+		require.NoError(t, err)
+		// Eight crop marks, and four targets of a circle and two lines.
+		assert.Equal(t, 8+4, strings.Count(string(content), " l S\n"))
+		assert.Equal(t, 4, strings.Count(string(content), " c\nS\n"))
+		// The first crop mark starts beyond the bleed.
+		assert.Contains(t, string(content), "-3 9 m -15 9 l S")
+
+		cs := parsed.GetArray(parsed.GetDict(p.Resources["ColorSpace"])["CS1"])
+		require.Len(t, cs, 4)
+		assert.Equal(t, Name("All"), cs[1])
+	}
+
+	text, err := parsed.ExtractText()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Page 1", "Page 2"}, text)
+}
+
+func TestAddPrintMarks_Invalid(t *testing.T) {
+	assert.ErrorIs(t, newTestDocument(t, 1).AddPrintMarks(PrintMarks{Bleed: -1}), ErrInvalidPrintMarks)
+	assert.ErrorIs(t, newTestDocument(t, 1).AddPrintMarks(PrintMarks{Bleed: 40}), ErrInvalidPrintMarks)
+
+	d := New()
+	d.AddPage(Rect{URX: 20, URY: 20}, nil, nil)
+	assert.ErrorIs(t, d.AddPrintMarks(PrintMarks{Bleed: 10}), ErrInvalidPrintMarks)
+}
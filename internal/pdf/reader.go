@@ -0,0 +1,508 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type xrefEntry struct {
+	kind   int // 0 free, 1 uncompressed, 2 in object stream
+	offset int // byte offset, or object stream number for kind 2
+	index  int // index within the object stream for kind 2
+}
+
+type reader struct {
+	data    []byte
+	xref    map[int]xrefEntry
+	trailer Dict
+	objStms map[int]map[int]Object
+	loading map[int]bool
+	objects map[int]Object
+}
+
+// Parse reads a complete PDF file. Every object reachable through the
+// cross-reference data is loaded into memory. Encrypted files are parsed
+// structurally; their strings and streams are left encrypted.
+func Parse(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, fmt.Errorf("%w: missing %%PDF header", ErrMalformed)
+	}
+
+	r := &reader{
+		data:    data,
+		xref:    map[int]xrefEntry{},
+		objStms: map[int]map[int]Object{},
+		loading: map[int]bool{},
+		objects: map[int]Object{},
+	}
+	if err := r.readXrefChain(); err != nil || r.trailer["Root"] == nil || r.loadAll() != nil {
+		if err := r.reconstruct(); err != nil {
+			return nil, err
+		}
+		// Objects that still cannot be read are treated as null.
+		r.loadAll()
+	}
+
+	doc := &Document{
+		Version: headerVersion(data),
+		Trailer: Dict{},
+		objects: map[int]Object{},
+	}
+	linearization := r.linearizationObjects()
+	for num, o := range r.objects {
+		// Cross-reference streams, object streams and linearization data
+		// are rebuilt on write.
+		if s, ok := o.(*Stream); ok {
+			if t, _ := s.Dict["Type"].(Name); t == "XRef" || t == "ObjStm" {
+				continue
+			}
+		}
+		if linearization[num] {
+			continue
+		}
+		doc.objects[num] = o
+		if num >= doc.nextNum {
+			doc.nextNum = num + 1
+		}
+	}
+	for _, k := range []Name{"Root", "Info", "ID", "Encrypt"} {
+		if v, ok := r.trailer[k]; ok {
+			doc.Trailer[k] = v
+		}
+	}
+	if cat := doc.Catalog(); cat != nil {
+		if v, ok := cat["Version"].(Name); ok && v > Name(doc.Version) {
+			doc.Version = string(v)
+		}
+	}
+	if _, ok := doc.Trailer["Root"]; !ok {
+		return nil, fmt.Errorf("%w: no document catalog", ErrMalformed)
+	}
+	return doc, nil
+}
+
+// linearizationObjects returns the numbers of the linearization parameter
+// dictionary and the primary hint stream it points to, if the file is
+// linearized.
+func (r *reader) linearizationObjects() map[int]bool {
+	found := map[int]bool{}
+	for num, o := range r.objects {
+		dict, ok := o.(Dict)
+		if !ok || dict["Linearized"] == nil {
+			continue
+		}
+		found[num] = true
+		h, _ := dict["H"].(Array)
+		if len(h) < 2 {
+			continue
+		}
+		hintOffset, _ := h[0].(int)
+		for hintNum, e := range r.xref {
+			if e.kind == 1 && e.offset == hintOffset {
+				found[hintNum] = true
+			}
+		}
+	}
+	return found
+}
+
+func (r *reader) loadAll() error {
+	var firstErr error
+	for num, e := range r.xref {
+		if e.kind == 0 || num == 0 {
+			continue
+		}
+		if _, err := r.load(num); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func headerVersion(data []byte) string {
+	i := bytes.Index(data, []byte("%PDF-"))
+	if i < 0 {
+		return "1.4"
+	}
+	v := data[i+5:]
+	end := 0
+	for end < len(v) && end < 4 && (v[end] == '.' || (v[end] >= '0' && v[end] <= '9')) {
+		end++
+	}
+	if end == 0 {
+		return "1.4"
+	}
+	return string(v[:end])
+}
+
+func (r *reader) readXrefChain() error {
+	idx := bytes.LastIndex(r.data, []byte("startxref"))
+	if idx < 0 {
+		return fmt.Errorf("%w: no startxref", ErrMalformed)
+	}
+	p := &parser{data: r.data, pos: idx + len("startxref")}
+	o, err := p.readObject()
+	if err != nil {
+		return err
+	}
+	offset, ok := o.(int)
+	if !ok {
+		return fmt.Errorf("%w: invalid startxref", ErrMalformed)
+	}
+
+	seen := map[int]bool{}
+	for offset > 0 && !seen[offset] {
+		seen[offset] = true
+		trailer, err := r.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		if r.trailer == nil {
+			r.trailer = trailer
+		}
+		// Hybrid files keep the compressed entries in a separate stream.
+		if stm, ok := trailer["XRefStm"].(int); ok && !seen[stm] {
+			seen[stm] = true
+			if _, err := r.readXrefSection(stm); err != nil {
+				return err
+			}
+		}
+		prev, ok := trailer["Prev"].(int)
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+	return nil
+}
+
+func (r *reader) readXrefSection(offset int) (Dict, error) {
+	if offset < 0 || offset >= len(r.data) {
+		return nil, fmt.Errorf("%w: xref offset %d out of range", ErrMalformed, offset)
+	}
+	p := &parser{data: r.data, pos: offset}
+	p.skipSpace()
+	if bytes.HasPrefix(r.data[p.pos:], []byte("xref")) {
+		p.pos += len("xref")
+		return r.readXrefTable(p)
+	}
+	return r.readXrefStream(p)
+}
+
+func (r *reader) addEntry(num int, e xrefEntry) {
+	// Sections are read newest first; older entries never override.
+	if _, ok := r.xref[num]; !ok {
+		r.xref[num] = e
+	}
+}
+
+func (r *reader) readXrefTable(p *parser) (Dict, error) {
+	for {
+		p.skipSpace()
+		if p.peekKeyword("trailer") {
+			p.expectKeyword("trailer")
+			o, err := p.readObject()
+			if err != nil {
+				return nil, err
+			}
+			trailer, ok := o.(Dict)
+			if !ok {
+				return nil, p.errorf("trailer is not a dictionary")
+			}
+			return trailer, nil
+		}
+		startObj, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		countObj, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		start, ok1 := startObj.(int)
+		count, ok2 := countObj.(int)
+		if !ok1 || !ok2 || count < 0 {
+			return nil, p.errorf("invalid xref subsection header")
+		}
+		for i := 0; i < count; i++ {
+			p.skipSpace()
+			offTok := p.readRegular()
+			p.skipSpace()
+			genTok := p.readRegular()
+			p.skipSpace()
+			kind := p.readRegular()
+			off, err1 := strconv.Atoi(string(offTok))
+			_, err2 := strconv.Atoi(string(genTok))
+			if err1 != nil || err2 != nil || (string(kind) != "n" && string(kind) != "f") {
+				return nil, p.errorf("invalid xref entry")
+			}
+			if string(kind) == "n" {
+				r.addEntry(start+i, xrefEntry{kind: 1, offset: off})
+			} else {
+				r.addEntry(start+i, xrefEntry{kind: 0})
+			}
+		}
+	}
+}
+
+func (r *reader) readXrefStream(p *parser) (Dict, error) {
+	_, o, err := p.readIndirect()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := o.(*Stream)
+	if !ok {
+		return nil, p.errorf("xref stream expected")
+	}
+	data, err := s.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	w, _ := s.Dict["W"].(Array)
+	if len(w) != 3 {
+		return nil, p.errorf("invalid xref stream /W")
+	}
+	var widths [3]int
+	rowLen := 0
+	for i, o := range w {
+		widths[i], _ = toInt(o)
+		if widths[i] < 0 || widths[i] > 8 {
+			return nil, p.errorf("invalid xref stream /W")
+		}
+		rowLen += widths[i]
+	}
+	if rowLen == 0 {
+		return nil, p.errorf("invalid xref stream /W")
+	}
+	size, _ := toInt(s.Dict["Size"])
+	index := Array{0, size}
+	if idx, ok := s.Dict["Index"].(Array); ok {
+		index = idx
+	}
+
+	pos := 0
+	field := func(w int, def int) int {
+		if w == 0 {
+			return def
+		}
+		v := 0
+		for i := 0; i < w; i++ {
+			v = v<<8 | int(data[pos])
+			pos++
+		}
+		return v
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := toInt(index[i])
+		count, _ := toInt(index[i+1])
+		for j := 0; j < count && pos+rowLen <= len(data); j++ {
+			kind := field(widths[0], 1)
+			f2 := field(widths[1], 0)
+			f3 := field(widths[2], 0)
+			switch kind {
+			case 0:
+				r.addEntry(start+j, xrefEntry{kind: 0})
+			case 1:
+				r.addEntry(start+j, xrefEntry{kind: 1, offset: f2})
+			case 2:
+				r.addEntry(start+j, xrefEntry{kind: 2, offset: f2, index: f3})
+			}
+		}
+	}
+	return s.Dict, nil
+}
+
+var objHeader = regexp.MustCompile(`(?m)(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+
+// reconstruct rebuilds the cross-reference data by scanning the file for
+// object headers, for files whose xref section is missing or damaged.
+func (r *reader) reconstruct() error {
+	r.xref = map[int]xrefEntry{}
+	r.objects = map[int]Object{}
+	r.objStms = map[int]map[int]Object{}
+	trailer := Dict{}
+
+	for _, m := range objHeader.FindAllSubmatchIndex(r.data, -1) {
+		num, _ := strconv.Atoi(string(r.data[m[2]:m[3]]))
+		// Later definitions win, as with incremental updates.
+		r.xref[num] = xrefEntry{kind: 1, offset: m[2]}
+	}
+	for num, e := range r.xref {
+		o, err := r.load(num)
+		if err != nil || e.kind != 1 {
+			continue
+		}
+		if s, ok := o.(*Stream); ok {
+			if t, _ := s.Dict["Type"].(Name); t == "ObjStm" {
+				objs, err := parseObjectStream(s)
+				if err != nil {
+					continue
+				}
+				for n := range objs {
+					if _, ok := r.xref[n]; !ok {
+						r.xref[n] = xrefEntry{kind: 2, offset: num}
+					}
+				}
+				r.objStms[num] = objs
+			}
+		}
+	}
+	for idx := 0; ; {
+		i := bytes.Index(r.data[idx:], []byte("trailer"))
+		if i < 0 {
+			break
+		}
+		p := &parser{data: r.data, pos: idx + i + len("trailer")}
+		if o, err := p.readObject(); err == nil {
+			if d, ok := o.(Dict); ok {
+				for k, v := range d {
+					trailer[k] = v
+				}
+			}
+		}
+		idx += i + len("trailer")
+	}
+
+	if trailer["Root"] == nil {
+		for num := range r.xref {
+			o, err := r.load(num)
+			if err != nil {
+				continue
+			}
+			switch v := o.(type) {
+			case Dict:
+				if t, _ := v["Type"].(Name); t == "Catalog" {
+					trailer["Root"] = Ref{Num: num}
+				}
+			case *Stream:
+				if t, _ := v.Dict["Type"].(Name); t == "XRef" {
+					for _, k := range []Name{"Root", "Info", "ID", "Encrypt"} {
+						if _, ok := trailer[k]; !ok && v.Dict[k] != nil {
+							trailer[k] = v.Dict[k]
+						}
+					}
+				}
+			}
+		}
+	}
+	if trailer["Root"] == nil {
+		return fmt.Errorf("%w: no document catalog", ErrMalformed)
+	}
+	r.trailer = trailer
+	return nil
+}
+
+func (r *reader) load(num int) (Object, error) {
+	if o, ok := r.objects[num]; ok {
+		return o, nil
+	}
+	e, ok := r.xref[num]
+	if !ok || e.kind == 0 {
+		return nil, nil
+	}
+	if r.loading[num] {
+		return nil, fmt.Errorf("%w: object %d refers to itself while loading", ErrMalformed, num)
+	}
+	r.loading[num] = true
+	defer delete(r.loading, num)
+
+	var o Object
+	var err error
+	if e.kind == 1 {
+		o, err = r.loadAt(num, e.offset)
+	} else {
+		o, err = r.loadCompressed(e.offset, e.index, num)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.objects[num] = o
+	return o, nil
+}
+
+func (r *reader) loadAt(num, offset int) (Object, error) {
+	if offset < 0 || offset >= len(r.data) {
+		return nil, fmt.Errorf("%w: object %d offset %d out of range", ErrMalformed, num, offset)
+	}
+	p := &parser{data: r.data, pos: offset, resolveLength: r.resolveLength}
+	ref, o, err := p.readIndirect()
+	if err != nil {
+		return nil, fmt.Errorf("object %d: %w", num, err)
+	}
+	if ref.Num != num {
+		return nil, fmt.Errorf("%w: expected object %d at offset %d, found %d", ErrMalformed, num, offset, ref.Num)
+	}
+	return o, nil
+}
+
+func (r *reader) resolveLength(ref Ref) (int, bool) {
+	o, err := r.load(ref.Num)
+	if err != nil {
+		return 0, false
+	}
+	return toInt(o)
+}
+
+func (r *reader) loadCompressed(stmNum, index, num int) (Object, error) {
+	objs, ok := r.objStms[stmNum]
+	if !ok {
+		o, err := r.load(stmNum)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := o.(*Stream)
+		if !ok {
+			return nil, fmt.Errorf("%w: object stream %d missing", ErrMalformed, stmNum)
+		}
+		objs, err = parseObjectStream(s)
+		if err != nil {
+			return nil, fmt.Errorf("object stream %d: %w", stmNum, err)
+		}
+		r.objStms[stmNum] = objs
+	}
+	return objs[num], nil
+}
+
+func parseObjectStream(s *Stream) (map[int]Object, error) {
+	data, err := s.Decode()
+	if err != nil {
+		return nil, err
+	}
+	n, _ := toInt(s.Dict["N"])
+	first, _ := toInt(s.Dict["First"])
+	if first < 0 || first > len(data) {
+		return nil, fmt.Errorf("%w: /First outside stream data", ErrMalformed)
+	}
+	// Every header entry takes at least four bytes: "1 0 ".
+	if n < 0 || n > (first+1)/4 {
+		return nil, fmt.Errorf("%w: /N does not fit the object stream header", ErrMalformed)
+	}
+
+	header := newParser(data[:first])
+	header.skipSpace()
+	objs := map[int]Object{}
+	for i := 0; i < n; i++ {
+		// readNumber takes garbage for 0, so the header is read token by
+		// token instead.
+		num, err1 := strconv.Atoi(string(header.readRegular()))
+		header.skipSpace()
+		off, err2 := strconv.Atoi(string(header.readRegular()))
+		header.skipSpace()
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%w: invalid object stream header", ErrMalformed)
+		}
+		if off < 0 || off >= len(data)-first {
+			return nil, fmt.Errorf("%w: object stream offset %d outside stream data", ErrMalformed, off)
+		}
+		p := &parser{data: data, pos: first + off}
+		o, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		objs[num] = o
+	}
+	return objs, nil
+}
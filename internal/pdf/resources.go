@@ -0,0 +1,126 @@
+package pdf
+
+// walkResources calls fn for every resource dictionary in the document:
+// those of pages, of form XObjects and Type 3 fonts they use, and of
+// annotation appearance streams. Each dictionary is visited once.
+func (d *Document) walkResources(fn func(res Dict)) error {
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+
+	visited := map[int]bool{}
+	var visit func(res Dict, depth int)
+	visitObject := func(o Object, depth int) {
+		if ref, ok := o.(Ref); ok {
+			if visited[ref.Num] {
+				return
+			}
+			visited[ref.Num] = true
+		}
+		if res := d.GetDict(d.GetDict(o)["Resources"]); res != nil {
+			visit(res, depth+1)
+		}
+	}
+	visit = func(res Dict, depth int) {
+		if depth > maxNesting {
+			return
+		}
+		fn(res)
+		for _, xobj := range d.GetDict(res["XObject"]) {
+			if d.GetName(d.GetDict(xobj)["Subtype"]) == "Form" {
+				visitObject(xobj, depth)
+			}
+		}
+		for _, font := range d.GetDict(res["Font"]) {
+			if d.GetName(d.GetDict(font)["Subtype"]) == "Type3" {
+				visitObject(font, depth)
+			}
+		}
+		for _, gs := range d.GetDict(res["ExtGState"]) {
+			if smask := d.GetDict(d.GetDict(gs)["SMask"]); smask != nil {
+				visitObject(smask["G"], depth)
+			}
+		}
+		for _, pattern := range d.GetDict(res["Pattern"]) {
+			visitObject(pattern, depth)
+		}
+	}
+
+	for _, p := range pages {
+		if p.Resources != nil {
+			visit(p.Resources, 0)
+		}
+		for _, annot := range d.GetArray(p.Dict["Annots"]) {
+			for _, ap := range d.GetDict(d.GetDict(annot)["AP"]) {
+				if d.GetStream(ap) != nil {
+					visitObject(ap, 0)
+					continue
+				}
+				// Appearance subdictionaries keyed by state, as for checkboxes.
+				for _, state := range d.GetDict(ap) {
+					visitObject(state, 0)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Font is a font resource as used on the document's pages.
+type Font struct {
+	Ref      Ref
+	Dict     Dict
+	BaseFont string
+	Subtype  string
+	Embedded bool
+}
+
+// Fonts returns the distinct fonts used by the document's resources. For
+// composite fonts, embedding is determined by the descendant font.
+func (d *Document) Fonts() ([]Font, error) {
+	var fonts []Font
+	seen := map[int]bool{}
+	err := d.walkResources(func(res Dict) {
+		for _, o := range d.GetDict(res["Font"]) {
+			if ref, ok := o.(Ref); ok {
+				if seen[ref.Num] {
+					continue
+				}
+				seen[ref.Num] = true
+			}
+			dict := d.GetDict(o)
+			if dict == nil {
+				continue
+			}
+			f := Font{Dict: dict, BaseFont: string(d.GetName(dict["BaseFont"])), Subtype: string(d.GetName(dict["Subtype"]))}
+			f.Ref, _ = o.(Ref)
+			f.Embedded = d.fontEmbedded(dict)
+			fonts = append(fonts, f)
+		}
+	})
+	return fonts, err
+}
+
+func (d *Document) fontEmbedded(font Dict) bool {
+	switch d.GetName(font["Subtype"]) {
+	case "Type3":
+		return true
+	case "Type0":
+		descendants := d.GetArray(font["DescendantFonts"])
+		if len(descendants) == 0 {
+			return false
+		}
+		font = d.GetDict(descendants[0])
+	}
+	desc := d.GetDict(font["FontDescriptor"])
+	if desc == nil {
+		return false
+	}
+	for _, k := range []Name{"FontFile", "FontFile2", "FontFile3"} {
+		if d.GetStream(desc[k]) != nil {
+			return true
+		}
+	}
+	return false
+}
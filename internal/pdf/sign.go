@@ -0,0 +1,309 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var ErrSignatureTooLarge = errors.New("signature does not fit the space reserved for it")
+
+// DefaultSignatureSize is the space reserved for the CMS signature when
+// SignOptions.Size is zero: enough for a certificate chain of a few
+// certificates and a timestamp token with its own.
+const DefaultSignatureSize = 16384
+
+// Signature field flags (ISO 32000-1, Table 219): the document contains
+// signatures and must be updated incrementally.
+const sigFlagsAppendOnly = 3
+
+// byteRangePlaceholder reserves room in the signature dictionary for the
+// four ten-digit offsets of the signed byte range.
+const byteRangePlaceholder = "[0 0000000000 0000000000 0000000000]"
+
+// SignOptions describe a signature added with Sign.
+type SignOptions struct {
+	// Name, Reason, Location and ContactInfo are recorded in the signature
+	// dictionary, for display by viewers.
+	Name        string
+	Reason      string
+	Location    string
+	ContactInfo string
+	// Time is the signing time recorded in /M; zero means now.
+	Time time.Time
+	// Size is the number of bytes reserved for the signature; zero means
+	// DefaultSignatureSize.
+	Size int
+	// SubFilter names the encoding of the signature, ETSI.CAdES.detached
+	// when empty.
+	SubFilter string
+	// Sign returns the DER encoded CMS signature of signed: the file with
+	// the signature value itself left out.
+	Sign func(signed []byte) ([]byte, error)
+}
+
+// SignatureInfo describes a signature field of a document.
+type SignatureInfo struct {
+	Field     string
+	Name      string
+	Reason    string
+	Location  string
+	SubFilter string
+	Time      time.Time
+	// ByteRange lists the offset and length of each signed part of the
+	// file; Contents is the signature value, still padded with zeros.
+	ByteRange []int
+	Contents  []byte
+}
+
+// Sign appends to the PDF file data an incremental update holding an
+// invisible signature field on the first page, and returns the signed
+// file. The signature covers every byte of the result except its own value,
+// so the file must not be rewritten afterwards. Encrypted files are
+// rejected.
+func Sign(data []byte, opts SignOptions) ([]byte, error) {
+	if opts.Sign == nil {
+		return nil, errors.New("pdf: SignOptions.Sign is nil")
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Encrypted() {
+		return nil, ErrEncrypted
+	}
+	prev, size, err := lastXref(data)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := doc.Pages()
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages", ErrMalformed)
+	}
+	rootRef, ok := doc.Trailer["Root"].(Ref)
+	if !ok {
+		return nil, fmt.Errorf("%w: document catalog is not an indirect object", ErrMalformed)
+	}
+
+	next := max(doc.nextNum, size)
+	update := map[int]Object{}
+	add := func(o Object) Ref {
+		ref := Ref{Num: next}
+		next++
+		update[ref.Num] = o
+		return ref
+	}
+
+	if opts.Time.IsZero() {
+		opts.Time = time.Now()
+	}
+	if opts.Size <= 0 {
+		opts.Size = DefaultSignatureSize
+	}
+	if opts.SubFilter == "" {
+		opts.SubFilter = "ETSI.CAdES.detached"
+	}
+	sig := Dict{
+		"Type":      Name("Sig"),
+		"Filter":    Name("Adobe.PPKLite"),
+		"SubFilter": Name(opts.SubFilter),
+		"ByteRange": keyword(byteRangePlaceholder),
+		"Contents":  keyword("<" + string(bytes.Repeat([]byte("0"), 2*opts.Size)) + ">"),
+		"M":         FormatDate(opts.Time),
+	}
+	for key, value := range map[Name]string{"Name": opts.Name, "Reason": opts.Reason, "Location": opts.Location, "ContactInfo": opts.ContactInfo} {
+		if value != "" {
+			sig[key] = EncodeTextString(value)
+		}
+	}
+	sigRef := add(sig)
+
+	// The catalog, form and first page are written again with the new
+	// field; everything else stays as it is.
+	cat := Clone(doc.Catalog()).(Dict)
+	update[rootRef.Num] = cat
+	form := Dict{}
+	if f := doc.GetDict(cat["AcroForm"]); f != nil {
+		form = Clone(f).(Dict)
+	}
+	if ref, ok := cat["AcroForm"].(Ref); ok {
+		update[ref.Num] = form
+	} else {
+		cat["AcroForm"] = form
+	}
+
+	names := map[string]bool{}
+	doc.walkFields(form, func(name string, _ Ref, _ Dict) { names[name] = true })
+	field := "Signature1"
+	for i := 2; names[field]; i++ {
+		field = "Signature" + strconv.Itoa(i)
+	}
+	page := pages[0]
+	widget := add(Dict{
+		"Type":    Name("Annot"),
+		"Subtype": Name("Widget"),
+		"FT":      Name("Sig"),
+		"T":       EncodeTextString(field),
+		"V":       sigRef,
+		"Rect":    Array{0, 0, 0, 0},
+		"F":       annotPrint,
+		"P":       page.Ref,
+	})
+	form["Fields"] = append(Clone(doc.GetArray(form["Fields"])).(Array), widget)
+	form["SigFlags"] = sigFlagsAppendOnly
+
+	pageDict := Clone(page.Dict).(Dict)
+	pageDict["Annots"] = append(Clone(doc.GetArray(pageDict["Annots"])).(Array), widget)
+	update[page.Ref.Num] = pageDict
+
+	out, offsets := doc.writeUpdate(data, update, prev, next)
+	return fillSignature(out, offsets[sigRef.Num], sig, opts.Sign)
+}
+
+// lastXref returns the offset of the newest cross-reference section of
+// data and the number of objects it declares.
+func lastXref(data []byte) (offset, size int, err error) {
+	r := &reader{data: data, xref: map[int]xrefEntry{}, objStms: map[int]map[int]Object{}, loading: map[int]bool{}, objects: map[int]Object{}}
+	if err := r.readXrefChain(); err != nil {
+		return 0, 0, fmt.Errorf("%w: cannot update a file with a damaged cross-reference section: %v", ErrMalformed, err)
+	}
+	p := &parser{data: data, pos: bytes.LastIndex(data, []byte("startxref")) + len("startxref")}
+	o, _ := p.readObject()
+	offset, _ = o.(int)
+	size, _ = r.trailer["Size"].(int)
+	for num := range r.xref {
+		size = max(size, num+1)
+	}
+	return offset, size, nil
+}
+
+// writeUpdate appends the objects of update to data as an incremental
+// update, with a cross-reference section of the same kind as the one at
+// prev, and returns the result with the offset of each object written.
+// size is the number of objects the updated file declares.
+func (d *Document) writeUpdate(data []byte, update map[int]Object, prev, size int) ([]byte, map[int]int) {
+	var buf bytes.Buffer
+	buf.Write(data)
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+
+	nums := make([]int, 0, len(update))
+	for num := range update {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	offsets := make(map[int]int, len(nums))
+	for _, num := range nums {
+		offsets[num] = buf.Len()
+		writeIndirect(&buf, num, update[num])
+	}
+
+	trailer := Dict{"Size": size, "Prev": prev}
+	for _, k := range []Name{"Root", "Info", "ID"} {
+		if v, ok := d.Trailer[k]; ok {
+			trailer[k] = v
+		}
+	}
+
+	xrefOffset := buf.Len()
+	p := &parser{data: data, pos: prev}
+	p.skipSpace()
+	if bytes.HasPrefix(data[p.pos:], []byte("xref")) {
+		buf.WriteString("xref\n")
+		for _, num := range nums {
+			fmt.Fprintf(&buf, "%d 1\n%010d 00000 n\r\n", num, offsets[num])
+		}
+		buf.WriteString("trailer\n")
+		writeObject(&buf, trailer)
+		buf.WriteString("\n")
+	} else {
+		// The stream is an object of the update too, and lists its own
+		// offset.
+		stm := size
+		trailer["Size"] = size + 1
+		nums = append(nums, stm)
+		offsets[stm] = xrefOffset
+		index := make(Array, 0, 2*len(nums))
+		rows := make([]byte, 0, 7*len(nums))
+		for _, num := range nums {
+			off := offsets[num]
+			index = append(index, num, 1)
+			rows = append(rows, 1, byte(off>>24), byte(off>>16), byte(off>>8), byte(off), 0, 0)
+		}
+		trailer["Type"] = Name("XRef")
+		trailer["W"] = Array{1, 4, 2}
+		trailer["Index"] = index
+		writeIndirect(&buf, stm, NewStream(trailer, rows))
+	}
+	buf.WriteString("startxref\n" + strconv.Itoa(xrefOffset) + "\n%%EOF\n")
+	return buf.Bytes(), offsets
+}
+
+// fillSignature writes the byte range of the signature dictionary sig,
+// written at offset obj, into out, then the signature sign returns for the
+// bytes it covers.
+func fillSignature(out []byte, obj int, sig Dict, sign func([]byte) ([]byte, error)) ([]byte, error) {
+	contents := Serialize(sig["Contents"])
+	start := obj + bytes.Index(out[obj:], contents)
+	end := start + len(contents)
+	rangeAt := obj + bytes.Index(out[obj:], []byte(byteRangePlaceholder))
+	if start < obj || rangeAt < obj {
+		return nil, fmt.Errorf("%w: signature placeholder not found", ErrMalformed)
+	}
+
+	byteRange := fmt.Sprintf("[0 %d %d %d]", start, end, len(out)-end)
+	copy(out[rangeAt:], fmt.Sprintf("%-*s", len(byteRangePlaceholder), byteRange))
+
+	signed := make([]byte, 0, len(out)-len(contents))
+	signed = append(append(signed, out[:start]...), out[end:]...)
+	value, err := sign(signed)
+	if err != nil {
+		return nil, err
+	}
+	if room := (len(contents) - 2) / 2; len(value) > room {
+		return nil, fmt.Errorf("%w: %d bytes, %d reserved", ErrSignatureTooLarge, len(value), room)
+	}
+	hex.Encode(out[start+1:], value)
+	return out, nil
+}
+
+// Signatures returns the signature fields of the document that hold a
+// signature.
+func (d *Document) Signatures() []SignatureInfo {
+	form := d.GetDict(d.Catalog()["AcroForm"])
+	if form == nil {
+		return nil
+	}
+	var sigs []SignatureInfo
+	d.walkFields(form, func(name string, _ Ref, field Dict) {
+		v := d.GetDict(field["V"])
+		if d.GetName(field["FT"]) != "Sig" || v == nil {
+			return
+		}
+		info := SignatureInfo{
+			Field:     name,
+			Name:      DecodeTextString(d.GetString(v["Name"])),
+			Reason:    DecodeTextString(d.GetString(v["Reason"])),
+			Location:  DecodeTextString(d.GetString(v["Location"])),
+			SubFilter: string(d.GetName(v["SubFilter"])),
+			Contents:  d.GetString(v["Contents"]),
+		}
+		if t, err := ParseDate(string(d.GetString(v["M"]))); err == nil {
+			info.Time = t
+		}
+		for _, o := range d.GetArray(v["ByteRange"]) {
+			n, _ := d.GetInt(o)
+			info.ByteRange = append(info.ByteRange, n)
+		}
+		sigs = append(sigs, info)
+	})
+	return sigs
+}
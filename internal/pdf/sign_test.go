@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// digestSigner stands in for a CMS signer: its signature is the SHA-256
+// digest of the signed bytes.
+func digestSigner(signed []byte) ([]byte, error) {
+	sum := sha256.Sum256(signed)
+	return sum[:], nil
+}
+
+func TestSign(t *testing.T) {
+	signedAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	for name, opts := range map[string]WriteOptions{
+		"xref table":  {},
+		"xref stream": {ObjectStreams: true},
+		"linearized":  {Linearize: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := newTestDocument(t, 2).BytesWith(opts)
+			require.NoError(t, err)
+
+			out, err := Sign(data, SignOptions{Name: "Jane Doe", Reason: "Approval", Time: signedAt, Size: 64, Sign: digestSigner})
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(out, data), "the original revision is kept as it is")
+
+			// The update is read through its cross-reference section,
+			// not by reconstructing a damaged file.
+			_, _, err = lastXref(out)
+			require.NoError(t, err)
+			doc, err := Parse(out)
+			require.NoError(t, err)
+			pages, err := doc.Pages()
+			require.NoError(t, err)
+			assert.Len(t, pages, 2)
+
+			sigs := doc.Signatures()
+			require.Len(t, sigs, 1)
+			sig := sigs[0]
+			assert.Equal(t, "Signature1", sig.Field)
+			assert.Equal(t, "Jane Doe", sig.Name)
+			assert.Equal(t, "Approval", sig.Reason)
+			assert.Equal(t, "ETSI.CAdES.detached", sig.SubFilter)
+			assert.True(t, signedAt.Equal(sig.Time))
+
+			// The byte range covers the whole file but the hex string of
+			// the signature value.
+			br := sig.ByteRange
+			require.Len(t, br, 4)
+			assert.Equal(t, 0, br[0])
+			assert.Equal(t, byte('<'), out[br[1]])
+			assert.Equal(t, byte('>'), out[br[2]-1])
+			assert.Equal(t, len(out), br[2]+br[3])
+			signed := append(append([]byte(nil), out[:br[1]]...), out[br[2]:]...)
+			want, _ := digestSigner(signed)
+			assert.Len(t, sig.Contents, 64)
+			assert.Equal(t, want, sig.Contents[:len(want)])
+
+			widget := doc.GetArray(pages[0].Dict["Annots"])
+			require.Len(t, widget, 1)
+			form := doc.GetDict(doc.Catalog()["AcroForm"])
+			assert.Equal(t, sigFlagsAppendOnly, form["SigFlags"])
+		})
+	}
+}
+
+func TestSign_ExistingFields(t *testing.T) {
+	d := newTestDocument(t, 1)
+	require.NoError(t, d.AddFormFields([]FormField{
+		{Name: "Signature1", Type: FieldText, Rect: Rect{72, 72, 200, 96}},
+	}))
+	data, err := d.Bytes()
+	require.NoError(t, err)
+
+	out, err := Sign(data, SignOptions{Size: 64, Sign: digestSigner})
+	require.NoError(t, err)
+
+	doc, err := Parse(out)
+	require.NoError(t, err)
+	assert.Contains(t, formFields(doc), "Signature1")
+	sigs := doc.Signatures()
+	require.Len(t, sigs, 1)
+	assert.Equal(t, "Signature2", sigs[0].Field)
+}
+
+func TestSign_TooLarge(t *testing.T) {
+	data, err := newTestDocument(t, 1).Bytes()
+	require.NoError(t, err)
+
+	_, err = Sign(data, SignOptions{Size: 16, Sign: digestSigner})
+	assert.ErrorIs(t, err, ErrSignatureTooLarge)
+}
+
+func TestSign_Encrypted(t *testing.T) {
+	d := newTestDocument(t, 1)
+	d.Trailer["Encrypt"] = d.Add(Dict{"Filter": Name("Standard")})
+	data, err := d.Bytes()
+	require.NoError(t, err)
+
+	_, err = Sign(data, SignOptions{Sign: digestSigner})
+	assert.ErrorIs(t, err, ErrEncrypted)
+}
@@ -0,0 +1,102 @@
+package pdf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// pdfDocHigh maps the codes of PDFDocEncoding that differ from ISO 8859-1.
+var pdfDocHigh = map[byte]rune{
+	0x18: '˘', 0x19: 'ˇ', 0x1A: 'ˆ', 0x1B: '˙', 0x1C: '˝', 0x1D: '˛', 0x1E: '˚', 0x1F: '˜',
+	0x80: '•', 0x81: '†', 0x82: '‡', 0x83: '…', 0x84: '—', 0x85: '–', 0x86: 'ƒ', 0x87: '⁄',
+	0x88: '‹', 0x89: '›', 0x8A: '−', 0x8B: '‰', 0x8C: '„', 0x8D: '“', 0x8E: '”', 0x8F: '‘',
+	0x90: '’', 0x91: '‚', 0x92: '™', 0x93: 'ﬁ', 0x94: 'ﬂ', 0x95: 'Ł', 0x96: 'Œ', 0x97: 'Š',
+	0x98: 'Ÿ', 0x99: 'Ž', 0x9A: 'ı', 0x9B: 'ł', 0x9C: 'œ', 0x9D: 'š', 0x9E: 'ž', 0xA0: '€',
+}
+
+// DecodeTextString decodes a PDF text string, which is either UTF-16BE with
+// a byte order mark, UTF-8 with a byte order mark, or PDFDocEncoding.
+func DecodeTextString(s String) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		u := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			u = append(u, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	if len(s) >= 3 && s[0] == 0xef && s[1] == 0xbb && s[2] == 0xbf {
+		return string(s[3:])
+	}
+	rs := make([]rune, 0, len(s))
+	for _, c := range s {
+		if r, ok := pdfDocHigh[c]; ok {
+			rs = append(rs, r)
+		} else {
+			rs = append(rs, rune(c))
+		}
+	}
+	return string(rs)
+}
+
+// EncodeTextString encodes text as a PDF text string, using plain bytes for
+// ASCII and UTF-16BE otherwise.
+func EncodeTextString(text string) String {
+	ascii := true
+	for _, r := range text {
+		if r > 0x7e || (r < 0x20 && r != '\n' && r != '\r' && r != '\t') {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return String(text)
+	}
+	s := String{0xfe, 0xff}
+	for _, u := range utf16.Encode([]rune(text)) {
+		s = append(s, byte(u>>8), byte(u))
+	}
+	return s
+}
+
+var pdfDate = regexp.MustCompile(`^D?:?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?([Zz+\-])?(\d{2})?'?(\d{2})?'?$`)
+
+// ParseDate parses a PDF date string such as D:20240102150405+03'30'.
+func ParseDate(s string) (time.Time, error) {
+	m := pdfDate.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q", s)
+	}
+	num := func(i, def int) int {
+		if m[i] == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+	loc := time.UTC
+	if m[7] == "+" || m[7] == "-" {
+		offset := num(8, 0)*3600 + num(9, 0)*60
+		if m[7] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	return time.Date(num(1, 0), time.Month(num(2, 1)), num(3, 1), num(4, 0), num(5, 0), num(6, 0), 0, loc), nil
+}
+
+// FormatDate formats t as a PDF date string.
+func FormatDate(t time.Time) String {
+	_, offset := t.Zone()
+	if offset == 0 {
+		return String(t.Format("D:20060102150405Z"))
+	}
+	sign := byte('+')
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return String(fmt.Sprintf("D:%s%c%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60))
+}
@@ -0,0 +1,36 @@
+package pdf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextString_RoundTrip(t *testing.T) {
+	for _, s := range []string{"Plain ASCII", "درخواست خدمت", "Café €"} {
+		assert.Equal(t, s, DecodeTextString(EncodeTextString(s)))
+	}
+	assert.Equal(t, String("Plain"), EncodeTextString("Plain"))
+	assert.Equal(t, "• ﬁ", DecodeTextString(String{0x80, ' ', 0x93}))
+}
+
+func TestParseDate(t *testing.T) {
+	got, err := ParseDate("D:20260102030405+03'30'")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2026, 1, 1, 23, 34, 5, 0, time.UTC).Equal(got))
+
+	got, err = ParseDate("D:2026")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), got)
+
+	_, err = ParseDate("yesterday")
+	assert.Error(t, err)
+}
+
+func TestFormatDate(t *testing.T) {
+	tehran := time.FixedZone("", 3*3600+30*60)
+	assert.Equal(t, String("D:20260102030405+03'30'"), FormatDate(time.Date(2026, 1, 2, 3, 4, 5, 0, tehran)))
+	assert.Equal(t, String("D:20260102030405Z"), FormatDate(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+}
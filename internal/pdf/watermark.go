@@ -0,0 +1,209 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidWatermark = errors.New("invalid watermark")
+
+const (
+	DefaultWatermarkFontSize = 72
+	DefaultWatermarkOpacity  = 0.2
+	DefaultWatermarkColor    = "#808080"
+
+	watermarkMargin = 36
+)
+
+// Watermark describes a text or image stamp drawn over page content.
+type Watermark struct {
+	Text     string
+	FontSize float64
+	Color    string
+	// Opacity is between 0 (exclusive) and 1.
+	Opacity float64
+	// Rotation is in degrees, counter-clockwise.
+	Rotation float64
+	// Position is center, top, bottom, left, right, top-left, top-right,
+	// bottom-left or bottom-right.
+	Position string
+	// Pages is a page range as accepted by ParsePageRange.
+	Pages string
+	// Image is a JPEG, PNG or GIF drawn instead of text.
+	Image []byte
+	// ImageWidth is the drawn width in points; it defaults to half the
+	// page width and the height follows the image's aspect ratio.
+	ImageWidth float64
+}
+
+func (wm *Watermark) normalize() error {
+	hasText := strings.TrimSpace(wm.Text) != ""
+	if hasText == (len(wm.Image) > 0) {
+		return fmt.Errorf("%w: exactly one of text or image is required", ErrInvalidWatermark)
+	}
+	if wm.FontSize == 0 {
+		wm.FontSize = DefaultWatermarkFontSize
+	}
+	if wm.Opacity == 0 {
+		wm.Opacity = DefaultWatermarkOpacity
+	}
+	if wm.Color == "" {
+		wm.Color = DefaultWatermarkColor
+	}
+	if wm.Position == "" {
+		wm.Position = "center"
+	}
+	if wm.FontSize < 0 || wm.ImageWidth < 0 {
+		return fmt.Errorf("%w: sizes must be positive", ErrInvalidWatermark)
+	}
+	if wm.Opacity < 0 || wm.Opacity > 1 {
+		return fmt.Errorf("%w: opacity must be between 0 and 1", ErrInvalidWatermark)
+	}
+	if _, _, ok := anchorFactors(wm.Position); !ok {
+		return fmt.Errorf("%w: unknown position %q", ErrInvalidWatermark, wm.Position)
+	}
+	return nil
+}
+
+// anchorFactors returns where a stamp sits on each axis: -1 at the low
+// edge, 0 centred and 1 at the high edge.
+func anchorFactors(position string) (float64, float64, bool) {
+	switch strings.ToLower(position) {
+	case "center", "centre":
+		return 0, 0, true
+	case "top":
+		return 0, 1, true
+	case "bottom":
+		return 0, -1, true
+	case "left":
+		return -1, 0, true
+	case "right":
+		return 1, 0, true
+	case "top-left":
+		return -1, 1, true
+	case "top-right":
+		return 1, 1, true
+	case "bottom-left":
+		return -1, -1, true
+	case "bottom-right":
+		return 1, -1, true
+	}
+	return 0, 0, false
+}
+
+// ParseColor parses #RGB or #RRGGBB into RGB components between 0 and 1.
+func ParseColor(s string) ([3]float64, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	var c [3]float64
+	if len(hex) != 6 {
+		return c, fmt.Errorf("invalid color %q", s)
+	}
+	for i := range c {
+		v, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return c, fmt.Errorf("invalid color %q", s)
+		}
+		c[i] = float64(v) / 255
+	}
+	return c, nil
+}
+
+// ApplyWatermark stamps wm onto the selected pages. Text is set in the
+// standard Helvetica-Bold font and must therefore be representable in
+// WinAnsiEncoding; other scripts need an image watermark.
+func (d *Document) ApplyWatermark(wm Watermark) error {
+	if err := wm.normalize(); err != nil {
+		return err
+	}
+	color, err := ParseColor(wm.Color)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWatermark, err)
+	}
+
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	selected, err := ParsePageRange(wm.Pages, len(pages))
+	if err != nil {
+		return err
+	}
+
+	var text []byte
+	var font, img Ref
+	var imgW, imgH int
+	if len(wm.Image) > 0 {
+		img, imgW, imgH, err = d.AddImage(wm.Image)
+		if err != nil {
+			return err
+		}
+	} else {
+		text, err = EncodeWinAnsi(wm.Text)
+		if err != nil {
+			return fmt.Errorf("%w: text must use Latin characters; use an image watermark for other scripts", ErrInvalidWatermark)
+		}
+		font = d.Add(Dict{
+			"Type": Name("Font"), "Subtype": Name("Type1"),
+			"BaseFont": Name("Helvetica-Bold"), "Encoding": Name("WinAnsiEncoding"),
+		})
+	}
+	gs := d.Add(Dict{"Type": Name("ExtGState"), "ca": wm.Opacity, "CA": wm.Opacity})
+
+	fx, fy, _ := anchorFactors(wm.Position)
+	theta := wm.Rotation * math.Pi / 180
+	cos, sin := math.Cos(theta), math.Sin(theta)
+
+	for _, n := range selected {
+		p := pages[n-1]
+		box := p.CropBox
+
+		var w, h float64
+		if len(text) > 0 {
+			w = textWidth(text, wm.FontSize)
+			h = helveticaBoldCapHeight * wm.FontSize / 1000
+		} else {
+			w = wm.ImageWidth
+			if w == 0 {
+				w = box.Width() / 2
+			}
+			h = w * float64(imgH) / float64(imgW)
+		}
+
+		// Half extents of the rotated stamp's bounding box keep edge
+		// positions inside the margin whatever the rotation.
+		ex := (math.Abs(w*cos) + math.Abs(h*sin)) / 2
+		ey := (math.Abs(w*sin) + math.Abs(h*cos)) / 2
+		cx := box.LLX + box.Width()/2 + fx*(box.Width()/2-watermarkMargin-ex)
+		cy := box.LLY + box.Height()/2 + fy*(box.Height()/2-watermarkMargin-ey)
+
+		res := d.OwnResources(p)
+		gsName := AddResource(res, "ExtGState", "WmGS", gs)
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "/%s gs\n", gsName)
+		fmt.Fprintf(&buf, "%s %s %s %s %s %s cm\n",
+			formatReal(cos), formatReal(sin), formatReal(-sin), formatReal(cos), formatReal(cx), formatReal(cy))
+		if len(text) > 0 {
+			fontName := AddResource(res, "Font", "WmF", font)
+			fmt.Fprintf(&buf, "BT /%s %s Tf %s %s %s rg %s %s Td ",
+				fontName, formatReal(wm.FontSize),
+				formatReal(color[0]), formatReal(color[1]), formatReal(color[2]),
+				formatReal(-w/2), formatReal(-h/2))
+			writeString(&buf, String(text))
+			buf.WriteString(" Tj ET\n")
+		} else {
+			imgName := AddResource(res, "XObject", "WmIm", img)
+			fmt.Fprintf(&buf, "%s 0 0 %s %s %s cm /%s Do\n",
+				formatReal(w), formatReal(h), formatReal(-w/2), formatReal(-h/2), imgName)
+		}
+		d.WrapContent(p, nil, buf.Bytes())
+	}
+	return nil
+}
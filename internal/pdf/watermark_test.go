@@ -0,0 +1,107 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyWatermark_TextOnSelectedPages(t *testing.T) {
+	d := newTestDocument(t, 3)
+
+	err := d.ApplyWatermark(Watermark{Text: "DRAFT", Rotation: 45, Opacity: 0.3, Pages: "1,3"})
+	require.NoError(t, err)
+
+	d = roundTrip(t, d)
+	pages, err := d.Pages()
+	require.NoError(t, err)
+
+	for i, want := range []bool{true, false, true} {
+		content, err := d.Content(pages[i])
+		require.NoError(t, err)
+		assert.Equal(t, want, bytes.Contains(content, []byte("(DRAFT) Tj")), "page %d", i+1)
+		assert.Contains(t, string(content), "(Page", "original content kept on page %d", i+1)
+	}
+
+	res := pages[0].Resources
+	gs := d.GetDict(d.GetDict(res["ExtGState"])["WmGS1"])
+	assert.Equal(t, 0.3, gs["ca"])
+	font := d.GetDict(d.GetDict(res["Font"])["WmF1"])
+	assert.Equal(t, Name("Helvetica-Bold"), font["BaseFont"])
+	assert.Contains(t, d.GetDict(res["Font"]), Name("F1"))
+}
+
+func TestApplyWatermark_Position(t *testing.T) {
+	d := newTestDocument(t, 1)
+	require.NoError(t, d.ApplyWatermark(Watermark{Text: "PAID", FontSize: 20, Position: "bottom-right"}))
+
+	pages, _ := d.Pages()
+	content, err := d.Content(pages[0])
+	require.NoError(t, err)
+
+	// PAID is 2.5 em wide at 20pt, so its centre sits 36pt plus half its
+	// width from the right edge and half its cap height above the bottom margin.
+	w := textWidth([]byte("PAID"), 20)
+	h := helveticaBoldCapHeight * 20.0 / 1000
+	want := "1 0 0 1 " + formatReal(a4.URX-36-w/2) + " " + formatReal(36+h/2) + " cm"
+	assert.Contains(t, string(content), want)
+}
+
+func TestApplyWatermark_Image(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 128})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	d := newTestDocument(t, 2)
+	require.NoError(t, d.ApplyWatermark(Watermark{Image: buf.Bytes(), ImageWidth: 100}))
+
+	d = roundTrip(t, d)
+	pages, _ := d.Pages()
+	xobj := d.GetStream(d.GetDict(pages[1].Resources["XObject"])["WmIm1"])
+	require.NotNil(t, xobj)
+	assert.Equal(t, 4, xobj.Dict["Width"])
+	assert.NotNil(t, d.GetStream(xobj.Dict["SMask"]))
+
+	content, err := d.Content(pages[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "100 0 0 50 -50 -25 cm /WmIm1 Do")
+}
+
+func TestApplyWatermark_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   Watermark
+		err  error
+	}{
+		{"empty", Watermark{}, ErrInvalidWatermark},
+		{"text and image", Watermark{Text: "A", Image: []byte{1}}, ErrInvalidWatermark},
+		{"opacity", Watermark{Text: "A", Opacity: 1.5}, ErrInvalidWatermark},
+		{"position", Watermark{Text: "A", Position: "middle"}, ErrInvalidWatermark},
+		{"color", Watermark{Text: "A", Color: "red"}, ErrInvalidWatermark},
+		{"non latin text", Watermark{Text: "پیش‌نویس"}, ErrInvalidWatermark},
+		{"page range", Watermark{Text: "A", Pages: "9"}, ErrInvalidPageRange},
+		{"image data", Watermark{Image: []byte("not an image")}, ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDocument(t, 1)
+			assert.ErrorIs(t, d.ApplyWatermark(tt.wm), tt.err)
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#f00")
+	require.NoError(t, err)
+	assert.Equal(t, [3]float64{1, 0, 0}, c)
+
+	c, err = ParseColor("808080")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.502, c[1], 0.001)
+}
@@ -0,0 +1,361 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// objectsPerStream caps the number of objects packed into one object
+// stream.
+const objectsPerStream = 100
+
+// WriteOptions control how a document is serialised.
+type WriteOptions struct {
+	// ObjectStreams packs objects other than streams into compressed object
+	// streams indexed by a cross-reference stream, raising the version to
+	// at least 1.5. It is ignored for encrypted documents, whose strings are
+	// encrypted per object.
+	ObjectStreams bool
+	// Linearize orders the file for fast web view, with the first page and
+	// hint tables at the start. It takes precedence over ObjectStreams and
+	// is likewise ignored for encrypted documents.
+	Linearize bool
+}
+
+// Bytes serialises the document.
+func (d *Document) Bytes() ([]byte, error) {
+	return d.BytesWith(WriteOptions{})
+}
+
+// BytesWith serialises the document with the given options.
+func (d *Document) BytesWith(opts WriteOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.WriteWith(&buf, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write serialises the document as a single revision with a classic
+// cross-reference table.
+func (d *Document) Write(w io.Writer) error {
+	return d.WriteWith(w, WriteOptions{})
+}
+
+// WriteWith serialises the document as a single revision.
+func (d *Document) WriteWith(w io.Writer, opts WriteOptions) error {
+	if d.Catalog() == nil {
+		return fmt.Errorf("%w: no document catalog", ErrMalformed)
+	}
+	if opts.Linearize && !d.Encrypted() {
+		return d.writeLinearized(w)
+	}
+	if opts.ObjectStreams && !d.Encrypted() {
+		return d.writeCompressed(w)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-" + d.Version + "\n%\xe2\xe3\xcf\xd3\n")
+
+	nums := d.ObjectNumbers()
+	size := 1
+	if len(nums) > 0 {
+		size = nums[len(nums)-1] + 1
+	}
+	offsets := make([]int, size)
+	for _, num := range nums {
+		offsets[num] = buf.Len()
+		writeIndirect(&buf, num, d.objects[num])
+	}
+
+	xrefOffset := buf.Len()
+	buf.WriteString("xref\n0 " + strconv.Itoa(size) + "\n")
+	buf.WriteString(xrefFreeHead(offsets))
+	for num := 1; num < size; num++ {
+		if _, ok := d.objects[num]; ok {
+			fmt.Fprintf(&buf, "%010d 00000 n\r\n", offsets[num])
+		} else {
+			fmt.Fprintf(&buf, "%010d 00001 f\r\n", nextFree(d.objects, num, size))
+		}
+	}
+
+	buf.WriteString("trailer\n")
+	writeObject(&buf, d.trailerDict(size, buf.Bytes()))
+	buf.WriteString("\nstartxref\n" + strconv.Itoa(xrefOffset) + "\n%%EOF\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeCompressed writes streams directly and every other object into
+// object streams, followed by a cross-reference stream.
+func (d *Document) writeCompressed(w io.Writer) error {
+	if versionLess(d.Version, "1.5") {
+		d.Version = "1.5"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-" + d.Version + "\n%\xe2\xe3\xcf\xd3\n")
+
+	nums := d.ObjectNumbers()
+	size := 1
+	if len(nums) > 0 {
+		size = nums[len(nums)-1] + 1
+	}
+	// Each entry is a type and two fields, as in the W [1 4 2] layout.
+	entries := make([][3]int, size)
+	entries[0] = [3]int{0, 0, 65535}
+	for num := 1; num < size; num++ {
+		entries[num] = [3]int{0, nextFree(d.objects, num, size), 1}
+	}
+
+	var packed []int
+	for _, num := range nums {
+		if _, ok := d.objects[num].(*Stream); ok {
+			entries[num] = [3]int{1, buf.Len(), 0}
+			writeIndirect(&buf, num, d.objects[num])
+		} else {
+			packed = append(packed, num)
+		}
+	}
+
+	next := size
+	for start := 0; start < len(packed); start += objectsPerStream {
+		chunk := packed[start:min(start+objectsPerStream, len(packed))]
+		var header, body bytes.Buffer
+		for i, num := range chunk {
+			fmt.Fprintf(&header, "%d %d ", num, body.Len())
+			writeObject(&body, d.objects[num])
+			body.WriteByte('\n')
+			entries[num] = [3]int{2, next, i}
+		}
+		objStm := NewStream(Dict{
+			"Type":  Name("ObjStm"),
+			"N":     len(chunk),
+			"First": header.Len(),
+		}, append(header.Bytes(), body.Bytes()...))
+		entries = append(entries, [3]int{1, buf.Len(), 0})
+		writeIndirect(&buf, next, objStm)
+		next++
+	}
+
+	xrefOffset := buf.Len()
+	entries = append(entries, [3]int{1, xrefOffset, 0})
+	rows := make([]byte, 0, len(entries)*7)
+	for _, e := range entries {
+		rows = append(rows, byte(e[0]),
+			byte(e[1]>>24), byte(e[1]>>16), byte(e[1]>>8), byte(e[1]),
+			byte(e[2]>>8), byte(e[2]))
+	}
+	dict := d.trailerDict(len(entries), buf.Bytes())
+	dict["Type"] = Name("XRef")
+	dict["W"] = Array{1, 4, 2}
+	writeIndirect(&buf, next, NewStream(dict, rows))
+	buf.WriteString("startxref\n" + strconv.Itoa(xrefOffset) + "\n%%EOF\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// versionLess reports whether PDF version a is older than b.
+func versionLess(a, b string) bool {
+	var amaj, amin, bmaj, bmin int
+	fmt.Sscanf(a, "%d.%d", &amaj, &amin)
+	fmt.Sscanf(b, "%d.%d", &bmaj, &bmin)
+	return amaj < bmaj || (amaj == bmaj && amin < bmin)
+}
+
+// xrefFreeHead returns entry 0, the head of the free list.
+func xrefFreeHead(offsets []int) string {
+	for num := 1; num < len(offsets); num++ {
+		if offsets[num] == 0 {
+			return fmt.Sprintf("%010d 65535 f\r\n", num)
+		}
+	}
+	return "0000000000 65535 f\r\n"
+}
+
+func nextFree(objects map[int]Object, num, size int) int {
+	for n := num + 1; n < size; n++ {
+		if _, ok := objects[n]; !ok {
+			return n
+		}
+	}
+	return 0
+}
+
+func (d *Document) trailerDict(size int, body []byte) Dict {
+	t := Dict{"Size": size}
+	for _, k := range []Name{"Root", "Info", "Encrypt"} {
+		if v, ok := d.Trailer[k]; ok {
+			t[k] = v
+		}
+	}
+	id, ok := d.Trailer["ID"].(Array)
+	if !ok || len(id) != 2 {
+		sum := md5.Sum(body)
+		id = Array{String(sum[:]), String(sum[:])}
+		d.Trailer["ID"] = id
+	}
+	t["ID"] = id
+	return t
+}
+
+func writeIndirect(buf *bytes.Buffer, num int, o Object) {
+	buf.WriteString(strconv.Itoa(num))
+	buf.WriteString(" 0 obj\n")
+	writeObject(buf, o)
+	buf.WriteString("\nendobj\n")
+}
+
+func writeObject(buf *bytes.Buffer, o Object) {
+	switch v := o.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case float64:
+		buf.WriteString(formatReal(v))
+	case Name:
+		writeName(buf, v)
+	case String:
+		writeString(buf, v)
+	case Ref:
+		buf.WriteString(strconv.Itoa(v.Num))
+		buf.WriteString(" 0 R")
+	case Array:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			writeObject(buf, e)
+		}
+		buf.WriteByte(']')
+	case Dict:
+		writeDict(buf, v)
+	case *Stream:
+		dict := make(Dict, len(v.Dict)+1)
+		for k, e := range v.Dict {
+			dict[k] = e
+		}
+		dict["Length"] = len(v.Data)
+		writeDict(buf, dict)
+		buf.WriteString("\nstream\n")
+		buf.Write(v.Data)
+		buf.WriteString("\nendstream")
+	case keyword:
+		buf.WriteString(string(v))
+	default:
+		panic(fmt.Sprintf("pdf: cannot serialise %T", o))
+	}
+}
+
+func writeDict(buf *bytes.Buffer, d Dict) {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	buf.WriteString("<<")
+	for _, k := range keys {
+		writeName(buf, Name(k))
+		buf.WriteByte(' ')
+		writeObject(buf, d[Name(k)])
+	}
+	buf.WriteString(">>")
+}
+
+func formatReal(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	s := strconv.FormatFloat(f, 'f', 5, 64)
+	s = trimZeros(s)
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func trimZeros(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if len(s) > 0 && s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func writeName(buf *bytes.Buffer, n Name) {
+	buf.WriteByte('/')
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(buf, "#%02X", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func writeString(buf *bytes.Buffer, s String) {
+	binary := 0
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			binary++
+		}
+	}
+	if binary > len(s)/4 {
+		buf.WriteByte('<')
+		const hexDigits = "0123456789ABCDEF"
+		for _, c := range s {
+			buf.WriteByte(hexDigits[c>>4])
+			buf.WriteByte(hexDigits[c&0xf])
+		}
+		buf.WriteByte('>')
+		return
+	}
+	buf.WriteByte('(')
+	for _, c := range s {
+		switch c {
+		case '(', ')', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString("\\n")
+		case '\r':
+			buf.WriteString("\\r")
+		default:
+			if c < 0x20 || c > 0x7e {
+				fmt.Fprintf(buf, "\\%03o", c)
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte(')')
+}
+
+// Serialize returns the PDF syntax for a direct object, as used inside
+// content streams and for comparing objects.
+func Serialize(o Object) []byte {
+	var buf bytes.Buffer
+	writeObject(&buf, o)
+	return buf.Bytes()
+}
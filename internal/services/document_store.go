@@ -0,0 +1,110 @@
+package services
+
+import (
+	"container/list"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"pdf-service/internal/models"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDocumentStoreBytes = 256 << 20
+	DefaultDocumentTTL        = 15 * time.Minute
+)
+
+// ErrDocumentNotFound is returned for document IDs that were never stored
+// or have expired.
+var ErrDocumentNotFound = errors.New("document not found")
+
+type DocumentStoreInterface interface {
+	Get(id string) (*models.StoredDocument, error)
+}
+
+// DocumentStore keeps generated documents in memory for a while, so that a
+// viewer can fetch one in parts with range requests: generating it again
+// would not give the same bytes, as Chromium output differs between runs.
+// Documents expire after ttl, and the oldest are evicted when the store
+// holds more than maxBytes.
+type DocumentStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	ttl      time.Duration
+	bytes    int64
+	order    *list.List // of *models.StoredDocument, oldest first
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+// NewDocumentStore returns a store of at most maxBytes of documents, each
+// kept for ttl. A limit or TTL of zero or less disables the store.
+func NewDocumentStore(maxBytes int64, ttl time.Duration) *DocumentStore {
+	return &DocumentStore{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Put stores content and returns the ID to fetch it by. Documents larger
+// than the store are not kept, and Put returns "".
+func (s *DocumentStore) Put(content []byte) (string, error) {
+	size := int64(len(content))
+	if s.maxBytes <= 0 || s.ttl <= 0 || size > s.maxBytes {
+		return "", nil
+	}
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", fmt.Errorf("generating document ID: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	doc := &models.StoredDocument{
+		ID:      hex.EncodeToString(raw[:]),
+		Content: content,
+		ETag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		Created: s.now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	s.entries[doc.ID] = s.order.PushBack(doc)
+	s.bytes += size
+	for s.bytes > s.maxBytes {
+		s.remove(s.order.Front())
+	}
+	return doc.ID, nil
+}
+
+// Get returns the document stored under id, or ErrDocumentNotFound.
+func (s *DocumentStore) Get(id string) (*models.StoredDocument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, id)
+	}
+	return e.Value.(*models.StoredDocument), nil
+}
+
+// expire removes the documents older than the TTL; s.mu must be held.
+func (s *DocumentStore) expire() {
+	cutoff := s.now().Add(-s.ttl)
+	for e := s.order.Front(); e != nil && !e.Value.(*models.StoredDocument).Created.After(cutoff); e = s.order.Front() {
+		s.remove(e)
+	}
+}
+
+// remove drops the document of e; s.mu must be held.
+func (s *DocumentStore) remove(e *list.Element) {
+	doc := s.order.Remove(e).(*models.StoredDocument)
+	delete(s.entries, doc.ID)
+	s.bytes -= int64(len(doc.Content))
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentStore_PutGet(t *testing.T) {
+	store := NewDocumentStore(1<<20, time.Minute)
+
+	id, err := store.Put([]byte("%PDF-1.7 first"))
+	require.NoError(t, err)
+	other, err := store.Put([]byte("%PDF-1.7 first"))
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+
+	doc, err := store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7 first", string(doc.Content))
+	second, err := store.Get(other)
+	require.NoError(t, err)
+	assert.Equal(t, doc.ETag, second.ETag)
+
+	_, err = store.Get("missing")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+}
+
+func TestDocumentStore_Expiry(t *testing.T) {
+	store := NewDocumentStore(1<<20, time.Minute)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	id, err := store.Put([]byte("%PDF-1.7"))
+	require.NoError(t, err)
+	now = now.Add(59 * time.Second)
+	_, err = store.Get(id)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Second)
+	_, err = store.Get(id)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	assert.Equal(t, int64(0), store.bytes)
+}
+
+func TestDocumentStore_Eviction(t *testing.T) {
+	store := NewDocumentStore(10, time.Minute)
+
+	first, _ := store.Put([]byte("aaaaaa"))
+	second, _ := store.Put([]byte("bbbbbb"))
+	_, err := store.Get(first)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, err = store.Get(second)
+	assert.NoError(t, err)
+
+	// Documents larger than the whole store are not kept.
+	id, err := store.Put([]byte("ccccccccccc"))
+	require.NoError(t, err)
+	assert.Empty(t, id)
+
+	disabled := NewDocumentStore(0, time.Minute)
+	id, err = disabled.Put([]byte("a"))
+	require.NoError(t, err)
+	assert.Empty(t, id)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+	"sort"
+	"strconv"
+)
+
+type FormServiceInterface interface {
+	FillForm(req *models.FillRequest) (*models.PDFResult, error)
+	ListFields(content []byte) ([]models.FormField, error)
+}
+
+// FormService fills and inspects existing PDF forms.
+type FormService struct{}
+
+func NewFormService() *FormService {
+	return &FormService{}
+}
+
+var ErrEmptyPDF = &AppError{Message: "PDF file cannot be empty"}
+
+// FillForm sets the fields named by the keys of req.Data, where nested
+// objects address child fields as in "customer.name".
+func (s *FormService) FillForm(req *models.FillRequest) (*models.PDFResult, error) {
+	if req.Data == nil {
+		return nil, ErrNilData
+	}
+	doc, err := parseUpload(req.PDF)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	flattenData("", req.Data, values)
+	unmatched, err := doc.FillForm(values)
+	if err != nil {
+		return nil, asAppError(err)
+	}
+	if req.Flatten {
+		if err := doc.FlattenForm(); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	content, err := doc.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &models.PDFResult{Content: content, UnmatchedFields: unmatched}, nil
+}
+
+// ListFields describes the fields of a PDF form.
+func (s *FormService) ListFields(content []byte) ([]models.FormField, error) {
+	doc, err := parseUpload(content)
+	if err != nil {
+		return nil, err
+	}
+	fields := []models.FormField{}
+	for _, f := range doc.FormFields() {
+		fields = append(fields, models.FormField{
+			Name:      f.Name,
+			Type:      f.Type,
+			Value:     f.Value,
+			Options:   f.Options,
+			ReadOnly:  f.ReadOnly,
+			Required:  f.Required,
+			Multiline: f.Multiline,
+			MaxLength: f.MaxLength,
+		})
+	}
+	return fields, nil
+}
+
+// parseUpload parses a PDF supplied by the client, reporting unreadable
+// files as client errors.
+func parseUpload(content []byte) (*pdf.Document, error) {
+	if len(content) == 0 {
+		return nil, ErrEmptyPDF
+	}
+	doc, err := pdf.Parse(content)
+	if err != nil {
+		return nil, &AppError{Message: "Invalid PDF: " + err.Error()}
+	}
+	return doc, nil
+}
+
+// flattenData converts JSON data to field values, joining the keys of
+// nested objects with periods.
+func flattenData(prefix string, data map[string]interface{}, out map[string]string) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		switch v := data[k].(type) {
+		case map[string]interface{}:
+			flattenData(name, v, out)
+		case nil:
+			out[name] = ""
+		case string:
+			out[name] = v
+		case bool:
+			out[name] = strconv.FormatBool(v)
+		case float64:
+			out[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+}
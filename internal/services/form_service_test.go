@@ -0,0 +1,106 @@
+package services
+
+import (
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleForm(t *testing.T) []byte {
+	t.Helper()
+	doc := pdf.New()
+	doc.AddPage(pdf.Rect{URX: 595.28, URY: 841.89}, nil, []byte("0 0 m 10 10 l S"))
+	err := doc.AddFormFields([]pdf.FormField{
+		{Name: "customer.name", Type: pdf.FieldText, Rect: pdf.Rect{LLX: 50, LLY: 700, URX: 250, URY: 720}},
+		{Name: "amount", Type: pdf.FieldText, Rect: pdf.Rect{LLX: 50, LLY: 650, URX: 250, URY: 670}, ReadOnly: true},
+		{Name: "agree", Type: pdf.FieldCheckbox, Rect: pdf.Rect{LLX: 50, LLY: 600, URX: 62, URY: 612}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestFillForm(t *testing.T) {
+	service := NewFormService()
+	result, err := service.FillForm(&models.FillRequest{
+		PDF: sampleForm(t),
+		Data: map[string]interface{}{
+			"customer": map[string]interface{}{"name": "John Doe"},
+			"amount":   1250.5,
+			"agree":    true,
+			"extra":    "x",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"extra"}, result.UnmatchedFields)
+
+	fields, err := service.ListFields(result.Content)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.FormField{
+		{Name: "customer.name", Type: "text", Value: "John Doe"},
+		{Name: "amount", Type: "text", Value: "1250.5", ReadOnly: true},
+		{Name: "agree", Type: "checkbox", Value: "Yes", Options: []string{"Yes"}},
+	}, fields)
+}
+
+func TestFillForm_Flatten(t *testing.T) {
+	service := NewFormService()
+	result, err := service.FillForm(&models.FillRequest{
+		PDF:     sampleForm(t),
+		Data:    map[string]interface{}{"customer": map[string]interface{}{"name": "John Doe"}},
+		Flatten: true,
+	})
+	assert.NoError(t, err)
+
+	fields, err := service.ListFields(result.Content)
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+}
+
+func TestFillForm_Persian(t *testing.T) {
+	service := NewFormService()
+	result, err := service.FillForm(&models.FillRequest{
+		PDF:  sampleForm(t),
+		Data: map[string]interface{}{"customer": map[string]interface{}{"name": "علی رضایی"}},
+	})
+	assert.NoError(t, err)
+	fields, err := service.ListFields(result.Content)
+	assert.NoError(t, err)
+	assert.Equal(t, "علی رضایی", fields[0].Value)
+}
+
+func TestFillForm_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *models.FillRequest
+		want string
+	}{
+		{"nil data", &models.FillRequest{PDF: []byte("%PDF")}, "Data cannot be nil"},
+		{"empty PDF", &models.FillRequest{Data: map[string]interface{}{}}, "PDF file cannot be empty"},
+		{"not a PDF", &models.FillRequest{PDF: []byte("hello"), Data: map[string]interface{}{}}, "Invalid PDF"},
+		{"bad value", &models.FillRequest{PDF: sampleForm(t), Data: map[string]interface{}{"agree": "maybe"}}, `"maybe" is not one of Yes`},
+		{"flattened Persian", &models.FillRequest{PDF: sampleForm(t), Data: map[string]interface{}{"customer": map[string]interface{}{"name": "علی"}}, Flatten: true}, "without an embedded Unicode font"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormService().FillForm(tt.req)
+			var appErr *AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Contains(t, err.Error(), tt.want)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestListFields_NoForm(t *testing.T) {
+	fields, err := NewFormService().ListFields(samplePDF(t, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.FormField{}, fields)
+}
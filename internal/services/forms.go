@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"pdf-service/internal/pdf"
+)
+
+// formFieldURI prefixes the links that mark form field positions. The
+// reserved .invalid domain keeps them from resolving should one survive.
+const formFieldURI = "https://pdf-form-field.invalid/#"
+
+// formElements detects templates that contain form controls or elements
+// marked with the data-pdf-field attribute.
+var formElements = regexp.MustCompile(`(?i)<(input|textarea|select)\b|\bdata-pdf-field\b`)
+
+var bodyEnd = regexp.MustCompile(`(?i)</body\s*>`)
+
+// formScript runs in the browser before printing. It wraps every form
+// control in a link whose URI carries the field description, so that the
+// printed PDF holds a link annotation at the exact position of the control
+// on whatever page it ended up; postProcess replaces those links with form
+// fields.
+const formScript = `<script>
+(function () {
+  var prefix = "` + formFieldURI + `";
+  var skip = {hidden: 1, submit: 1, reset: 1, button: 1, image: 1, file: 1};
+  function encode(value) {
+    var bytes = new TextEncoder().encode(JSON.stringify(value)), binary = "";
+    for (var i = 0; i < bytes.length; i++) binary += String.fromCharCode(bytes[i]);
+    return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+  }
+  function flag(el, name) {
+    var v = el.getAttribute(name);
+    return v !== null && v !== "false";
+  }
+  var count = 0;
+  document.querySelectorAll("input, textarea, select, [data-pdf-field]").forEach(function (el, index) {
+    var tag = el.tagName.toLowerCase(), marker = el.getAttribute("data-pdf-field");
+    if (marker === "false" || el.closest("a")) return;
+    var style = getComputedStyle(el);
+    var f = {
+      index: index,
+      name: marker || el.name || el.id,
+      readonly: el.readOnly || el.disabled || flag(el, "data-pdf-readonly"),
+      required: !!el.required,
+      font_size: parseFloat(style.fontSize) * 0.75
+    };
+    if (tag === "textarea") {
+      f.type = "text"; f.value = el.value; f.multiline = true;
+    } else if (tag === "select") {
+      f.type = "select"; f.value = el.value;
+      f.options = []; f.labels = [];
+      Array.prototype.forEach.call(el.options, function (o) { f.options.push(o.value); f.labels.push(o.text); });
+      f.list_box = el.multiple || el.size > 1; f.multi_select = el.multiple;
+    } else if (tag === "input") {
+      var type = (el.type || "text").toLowerCase();
+      if (skip[type]) return;
+      if (type === "checkbox" || type === "radio") {
+        f.type = type; f.checked = el.checked; f.export = el.getAttribute("value") || "";
+      } else {
+        f.type = "text"; f.value = el.value; f.password = type === "password";
+      }
+    } else {
+      f.type = el.getAttribute("data-pdf-type") || "text";
+      f.value = el.getAttribute("data-pdf-value") || "";
+      f.checked = flag(el, "data-pdf-checked");
+      f.export = el.getAttribute("data-pdf-export") || "";
+      f.multiline = flag(el, "data-pdf-multiline");
+    }
+    if (el.maxLength > 0) f.max_length = el.maxLength;
+    if (!f.name) f.name = f.type + (++count);
+
+    // The link takes the element's place and margins so that its
+    // annotation covers exactly the control's border box.
+    var a = document.createElement("a");
+    a.href = prefix + encode(f);
+    a.style.cssText = "text-decoration:none;color:inherit;vertical-align:" + style.verticalAlign +
+      ";margin:" + style.margin + ";display:" + (style.display === "block" ? "block" : "inline-block");
+    el.style.margin = "0";
+    el.parentNode.insertBefore(a, el);
+    a.appendChild(el);
+  });
+})();
+</script>`
+
+// withFormScript adds formScript at the end of the document body.
+func withFormScript(html string) string {
+	loc := bodyEnd.FindAllStringIndex(html, -1)
+	if len(loc) == 0 {
+		return html + formScript
+	}
+	at := loc[len(loc)-1][0]
+	return html[:at] + formScript + html[at:]
+}
+
+// formFieldMarker is the field description encoded by formScript.
+type formFieldMarker struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Value       string   `json:"value"`
+	Export      string   `json:"export"`
+	Checked     bool     `json:"checked"`
+	Options     []string `json:"options"`
+	Labels      []string `json:"labels"`
+	ReadOnly    bool     `json:"readonly"`
+	Required    bool     `json:"required"`
+	Multiline   bool     `json:"multiline"`
+	Password    bool     `json:"password"`
+	ListBox     bool     `json:"list_box"`
+	MultiSelect bool     `json:"multi_select"`
+	MaxLength   int      `json:"max_length"`
+	FontSize    float64  `json:"font_size"`
+}
+
+// addFormFields replaces the marker links left by formScript with form
+// fields.
+func addFormFields(doc *pdf.Document) error {
+	links, err := doc.TakeLinks(formFieldURI)
+	if err != nil {
+		return err
+	}
+
+	// An anchor broken across lines or pages yields several links; the
+	// widget covers the fragments on the first page.
+	var fields []pdf.FormField
+	seen := map[string]int{}
+	for _, link := range links {
+		if i, ok := seen[link.URI]; ok {
+			if fields[i].Page == link.Page {
+				fields[i].Rect = union(fields[i].Rect, link.Rect)
+			}
+			continue
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(link.URI, formFieldURI))
+		if err != nil {
+			return fmt.Errorf("form field marker: %w", err)
+		}
+		var m formFieldMarker
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return fmt.Errorf("form field marker: %w", err)
+		}
+		seen[link.URI] = len(fields)
+		fields = append(fields, pdf.FormField{
+			Name:        m.Name,
+			Type:        m.Type,
+			Page:        link.Page,
+			Rect:        link.Rect,
+			Value:       m.Value,
+			Export:      m.Export,
+			Checked:     m.Checked,
+			Options:     m.Options,
+			Labels:      m.Labels,
+			ReadOnly:    m.ReadOnly,
+			Required:    m.Required,
+			Multiline:   m.Multiline,
+			Password:    m.Password,
+			ListBox:     m.ListBox,
+			MultiSelect: m.MultiSelect,
+			MaxLength:   m.MaxLength,
+			FontSize:    m.FontSize,
+		})
+	}
+	return doc.AddFormFields(fields)
+}
+
+func union(a, b pdf.Rect) pdf.Rect {
+	return pdf.Rect{
+		LLX: min(a.LLX, b.LLX), LLY: min(a.LLY, b.LLY),
+		URX: max(a.URX, b.URX), URY: max(a.URY, b.URY),
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// assetAttrPattern matches quoted attributes holding one URL.
+	assetAttrPattern = regexp.MustCompile(`(?i)(\s(?:src|href|poster|data)\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+	// srcsetPattern matches srcset attributes, which hold a list of URLs
+	// each followed by a width or density.
+	srcsetPattern       = regexp.MustCompile(`(?i)(\ssrcset\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+	styleAttrPattern    = regexp.MustCompile(`(?i)(\sstyle\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+	styleElementPattern = regexp.MustCompile(`(?is)(<style\b[^>]*>)(.*?)(</style>)`)
+	cssURLPattern       = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^'"\s)]+))\s*\)`)
+	cssImportPattern    = regexp.MustCompile(`(?i)(@import\s+)(?:"([^"]*)"|'([^']*)')`)
+)
+
+// rewriteAssets resolves the relative URLs in the src, href, poster, data
+// and srcset attributes of rendered HTML, and in url() and @import in its
+// CSS, against base. Fragments, data URLs and absolute URLs are kept.
+func rewriteAssets(doc string, base *url.URL) string {
+	doc = styleElementPattern.ReplaceAllStringFunc(doc, func(m string) string {
+		parts := styleElementPattern.FindStringSubmatch(m)
+		return parts[1] + rewriteCSS(parts[2], base) + parts[3]
+	})
+	doc = replaceAttr(doc, styleAttrPattern, func(css string) string { return rewriteCSS(css, base) })
+	doc = replaceAttr(doc, srcsetPattern, func(srcset string) string {
+		candidates := strings.Split(srcset, ",")
+		for i, c := range candidates {
+			fields := strings.Fields(c)
+			if len(fields) == 0 {
+				continue
+			}
+			fields[0] = resolveAsset(fields[0], base)
+			candidates[i] = strings.Join(fields, " ")
+		}
+		return strings.Join(candidates, ", ")
+	})
+	return replaceAttr(doc, assetAttrPattern, func(ref string) string { return resolveAsset(ref, base) })
+}
+
+// replaceAttr replaces the values of the attributes pattern matches with
+// rewrite applied to their unescaped text. pattern captures the attribute
+// name with its equals sign, then a double- or a single-quoted value.
+func replaceAttr(doc string, pattern *regexp.Regexp, rewrite func(string) string) string {
+	return pattern.ReplaceAllStringFunc(doc, func(m string) string {
+		parts := pattern.FindStringSubmatch(m)
+		quote, value := `"`, parts[2]
+		if strings.HasPrefix(m[len(parts[1]):], "'") {
+			quote, value = "'", parts[3]
+		}
+		return parts[1] + quote + html.EscapeString(rewrite(html.UnescapeString(value))) + quote
+	})
+}
+
+// rewriteCSS resolves the URLs in url() and @import in css against base.
+func rewriteCSS(css string, base *url.URL) string {
+	css = cssURLPattern.ReplaceAllStringFunc(css, func(m string) string {
+		parts := cssURLPattern.FindStringSubmatch(m)
+		return "url(" + cssString(resolveAsset(parts[1]+parts[2]+parts[3], base)) + ")"
+	})
+	return cssImportPattern.ReplaceAllStringFunc(css, func(m string) string {
+		parts := cssImportPattern.FindStringSubmatch(m)
+		return parts[1] + cssString(resolveAsset(parts[2]+parts[3], base))
+	})
+}
+
+// resolveAsset resolves ref against base unless it is empty, a fragment
+// or already absolute, such as a data URL.
+func resolveAsset(ref string, base *url.URL) string {
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ref
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+var cssStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// cssString quotes s as a CSS string.
+func cssString(s string) string {
+	return `"` + cssStringEscaper.Replace(s) + `"`
+}
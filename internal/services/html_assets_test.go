@@ -0,0 +1,53 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteAssets(t *testing.T) {
+	base, _ := url.Parse("https://cdn.example.com/bank/assets/")
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "attributes",
+			html: `<img src="logo.png"><link rel="stylesheet" href='css/print.css'><a href="/terms">Terms</a>`,
+			want: `<img src="https://cdn.example.com/bank/assets/logo.png"><link rel="stylesheet" href='https://cdn.example.com/bank/assets/css/print.css'><a href="https://cdn.example.com/terms">Terms</a>`,
+		},
+		{
+			name: "kept",
+			html: `<a href="#totals"></a><img src="data:image/png;base64,AAAA"><a href="mailto:info@example.com"></a><img src="https://example.org/x.png"><img src="">`,
+			want: `<a href="#totals"></a><img src="data:image/png;base64,AAAA"><a href="mailto:info@example.com"></a><img src="https://example.org/x.png"><img src="">`,
+		},
+		{
+			name: "escaped query",
+			html: `<img src="chart.png?w=100&amp;h=50">`,
+			want: `<img src="https://cdn.example.com/bank/assets/chart.png?w=100&amp;h=50">`,
+		},
+		{
+			name: "srcset",
+			html: `<img srcset="logo.png 1x, logo@2x.png 2x">`,
+			want: `<img srcset="https://cdn.example.com/bank/assets/logo.png 1x, https://cdn.example.com/bank/assets/logo@2x.png 2x">`,
+		},
+		{
+			name: "css",
+			html: `<style>@import "fonts.css"; body { background: url(bg.png) }</style><div style="background-image: url('../stamp.png')"></div>`,
+			want: `<style>@import "https://cdn.example.com/bank/assets/fonts.css"; body { background: url("https://cdn.example.com/bank/assets/bg.png") }</style><div style="background-image: url(&#34;https://cdn.example.com/bank/stamp.png&#34;)"></div>`,
+		},
+		{
+			name: "data attributes",
+			html: `<div data-pdf-field="signature"></div><object data="plan.svg"></object>`,
+			want: `<div data-pdf-field="signature"></div><object data="https://cdn.example.com/bank/assets/plan.svg"></object>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewriteAssets(tt.html, base))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"math"
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+	"strings"
+)
+
+type InspectServiceInterface interface {
+	Inspect(content []byte) (*models.PDFInfo, error)
+	ExtractText(content []byte, pages string) (*models.TextResult, error)
+}
+
+// InspectService describes existing PDFs.
+type InspectService struct{}
+
+func NewInspectService() *InspectService {
+	return &InspectService{}
+}
+
+// Inspect reports the pages, fonts, metadata, encryption, attachments and
+// tagging of a PDF.
+func (s *InspectService) Inspect(content []byte) (*models.PDFInfo, error) {
+	doc, err := parseUpload(content)
+	if err != nil {
+		return nil, err
+	}
+	inspection, err := doc.Inspect()
+	if err != nil {
+		return nil, asAppError(err)
+	}
+
+	info := &models.PDFInfo{
+		Version:     inspection.Version,
+		PageCount:   len(inspection.Pages),
+		Pages:       pageInfos(inspection.Pages),
+		Fonts:       []models.FontInfo{},
+		Metadata:    inspection.Metadata,
+		Encrypted:   inspection.Encrypted,
+		Tagged:      inspection.Tagged,
+		Attachments: []models.AttachmentInfo{},
+	}
+	for _, f := range inspection.Fonts {
+		name, subset := f.BaseFont, false
+		// Subsets are named with a six letter tag, as in ABCDEF+Vazir.
+		if len(name) > 7 && name[6] == '+' && strings.ToUpper(name[:6]) == name[:6] {
+			name, subset = name[7:], true
+		}
+		info.Fonts = append(info.Fonts, models.FontInfo{Name: name, Type: f.Subtype, Embedded: f.Embedded, Subset: subset})
+	}
+	for _, a := range inspection.Attachments {
+		info.Attachments = append(info.Attachments, models.AttachmentInfo{
+			Name:         a.Name,
+			MIMEType:     a.MIMEType,
+			Description:  a.Description,
+			Relationship: a.Relationship,
+			Size:         len(a.Data),
+		})
+	}
+	return info, nil
+}
+
+// ExtractText returns the text of the selected pages, or of every page when
+// pages is empty.
+func (s *InspectService) ExtractText(content []byte, pages string) (*models.TextResult, error) {
+	doc, err := parseUpload(content)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Encrypted() {
+		return nil, asAppError(pdf.ErrEncrypted)
+	}
+	all, err := doc.Pages()
+	if err != nil {
+		return nil, asAppError(err)
+	}
+	selected, err := pdf.ParsePageRange(pages, len(all))
+	if err != nil {
+		return nil, asAppError(err)
+	}
+
+	result := &models.TextResult{PageCount: len(all), Pages: []models.PageText{}}
+	for _, n := range selected {
+		text, err := doc.PageText(all[n-1])
+		if err != nil {
+			return nil, asAppError(err)
+		}
+		result.Pages = append(result.Pages, models.PageText{Number: n, Text: text})
+	}
+	return result, nil
+}
+
+// pageInfos numbers the pages and rounds their sizes to hundredths of a
+// point.
+func pageInfos(pages []pdf.PageInfo) []models.PageInfo {
+	out := make([]models.PageInfo, len(pages))
+	for i, p := range pages {
+		out[i] = models.PageInfo{
+			Number: i + 1,
+			Width:  math.Round(p.Width*100) / 100,
+			Height: math.Round(p.Height*100) / 100,
+			Rotate: p.Rotate,
+			Paper:  p.Paper,
+		}
+	}
+	return out
+}
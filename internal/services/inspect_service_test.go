@@ -0,0 +1,64 @@
+package services
+
+import (
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	doc := pdf.New()
+	font := doc.Add(pdf.Dict{
+		"Type": pdf.Name("Font"), "Subtype": pdf.Name("TrueType"), "BaseFont": pdf.Name("ABCDEF+Vazir"),
+		"FontDescriptor": doc.Add(pdf.Dict{"FontFile2": doc.Add(pdf.NewStream(nil, []byte("glyphs")))}),
+	})
+	doc.AddPage(pdf.Rect{URX: 595.44, URY: 841.68}, pdf.Dict{"Font": pdf.Dict{"F1": font}}, []byte("BT /F1 12 Tf (x) Tj ET"))
+	doc.Info(true)["Producer"] = pdf.String("Skia/PDF m120")
+	if err := doc.AddAttachments(pdf.Attachment{Name: "data.json", Data: []byte(`{"a":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := doc.Bytes()
+	assert.NoError(t, err)
+
+	info, err := NewInspectService().Inspect(content)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.PageCount)
+	assert.Equal(t, []models.PageInfo{{Number: 1, Width: 595.44, Height: 841.68, Paper: "A4"}}, info.Pages)
+	assert.Equal(t, []models.FontInfo{{Name: "Vazir", Type: "TrueType", Embedded: true, Subset: true}}, info.Fonts)
+	assert.Equal(t, "Skia/PDF m120", info.Metadata["Producer"])
+	assert.False(t, info.Encrypted)
+	assert.False(t, info.Tagged)
+	assert.Equal(t, []models.AttachmentInfo{{Name: "data.json", MIMEType: "application/json", Relationship: "Unspecified", Size: 7}}, info.Attachments)
+}
+
+func TestInspect_InvalidPDF(t *testing.T) {
+	info, err := NewInspectService().Inspect([]byte("not a pdf"))
+	var appErr *AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Contains(t, err.Error(), "Invalid PDF")
+	assert.Nil(t, info)
+}
+
+func TestExtractText(t *testing.T) {
+	doc := pdf.New()
+	font := doc.Add(pdf.Dict{"Type": pdf.Name("Font"), "Subtype": pdf.Name("Type1"), "BaseFont": pdf.Name("Helvetica")})
+	for _, text := range []string{"Invoice", "Total 120"} {
+		doc.AddPage(pdf.Rect{URX: 595, URY: 842}, pdf.Dict{"Font": pdf.Dict{"F1": font}}, []byte("BT /F1 12 Tf 72 720 Td ("+text+") Tj ET"))
+	}
+	content, err := doc.Bytes()
+	assert.NoError(t, err)
+
+	result, err := NewInspectService().ExtractText(content, "")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TextResult{PageCount: 2, Pages: []models.PageText{{Number: 1, Text: "Invoice"}, {Number: 2, Text: "Total 120"}}}, result)
+
+	result, err = NewInspectService().ExtractText(content, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PageText{{Number: 2, Text: "Total 120"}}, result.Pages)
+
+	_, err = NewInspectService().ExtractText(content, "5")
+	var appErr *AppError
+	assert.ErrorAs(t, err, &appErr)
+}
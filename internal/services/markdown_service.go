@@ -0,0 +1,123 @@
+package services
+
+import (
+	"html/template"
+	"pdf-service/internal/markdown"
+	"pdf-service/internal/models"
+	"pdf-service/internal/templatefuncs"
+	"strings"
+)
+
+type MarkdownServiceInterface interface {
+	GenerateMarkdownPDF(req *models.MarkdownRequest) (*models.PDFResult, error)
+}
+
+// MarkdownService prints Markdown documents: it converts them to HTML,
+// places the HTML in a layout template and runs the result through the PDF
+// service, with the headings building the PDF outline.
+type MarkdownService struct {
+	store      *TemplateStore
+	pdfService PDFServiceInterface
+}
+
+func NewMarkdownService(store *TemplateStore, pdfService PDFServiceInterface) *MarkdownService {
+	return &MarkdownService{store: store, pdfService: pdfService}
+}
+
+var ErrEmptyMarkdown = &AppError{Message: "Markdown cannot be empty"}
+
+// defaultLayout places a document on plain A4 pages when no layout is
+// chosen. lang and dir come from the front matter or data, so right to
+// left documents set dir: rtl.
+var defaultLayout = template.Must(template.New("markdown").Funcs(templatefuncs.FuncMap()).Parse(`<!DOCTYPE html>
+<html{{with .lang}} lang="{{.}}"{{end}}{{with .dir}} dir="{{.}}"{{end}}>
+<head>
+<meta charset="utf-8">
+<title>{{.title}}</title>
+<style>
+body { font-family: "Helvetica Neue", Arial, sans-serif; font-size: 11pt; line-height: 1.5; color: #222; }
+h1, h2, h3, h4, h5, h6 { line-height: 1.25; margin: 1.2em 0 0.5em; break-after: avoid; }
+h1 { font-size: 2em; }
+h2 { font-size: 1.5em; border-bottom: 1px solid #ddd; padding-bottom: 0.2em; }
+h3 { font-size: 1.25em; }
+p, ul, ol, blockquote, pre, table { margin: 0 0 1em; }
+a { color: #0b5cad; }
+code { font-family: Menlo, Consolas, monospace; font-size: 0.9em; background: #f4f4f4; padding: 0.1em 0.3em; border-radius: 3px; }
+pre { background: #f4f4f4; padding: 0.8em; border-radius: 3px; white-space: pre-wrap; break-inside: avoid; }
+pre code { background: none; padding: 0; }
+blockquote { border-inline-start: 4px solid #ddd; padding-inline-start: 1em; color: #555; margin-inline: 0; }
+table { border-collapse: collapse; width: 100%; break-inside: avoid; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.6em; text-align: start; }
+th { background: #f4f4f4; }
+th[align="center"], td[align="center"] { text-align: center; }
+th[align="right"], td[align="right"] { text-align: right; }
+img { max-width: 100%; }
+hr { border: 0; border-top: 1px solid #ddd; margin: 1.5em 0; }
+</style>
+{{with .stylesheet}}<style>{{.}}</style>{{end}}
+</head>
+<body>
+<main class="markdown">
+{{.content}}
+</main>
+</body>
+</html>
+`))
+
+// GenerateMarkdownPDF converts req.Markdown and prints it in req.Layout, a
+// registered template, or the built-in layout. The layout receives the
+// front matter and req.Data, with req.Data winning, and:
+//
+//   - content: the converted HTML
+//   - headings: the headings, each with level, text and id
+//   - stylesheet: req.Stylesheet
+//   - title: the first heading's text unless a title is given
+func (s *MarkdownService) GenerateMarkdownPDF(req *models.MarkdownRequest) (*models.PDFResult, error) {
+	frontMatter, body, err := markdown.FrontMatter(req.Markdown)
+	if err != nil {
+		return nil, &AppError{Message: err.Error()}
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrEmptyMarkdown
+	}
+	doc := markdown.Convert(body)
+
+	data := make(map[string]interface{}, len(frontMatter)+len(req.Data)+4)
+	for k, v := range frontMatter {
+		data[k] = v
+	}
+	for k, v := range req.Data {
+		data[k] = v
+	}
+	headings := make([]interface{}, len(doc.Headings))
+	for i, h := range doc.Headings {
+		headings[i] = map[string]interface{}{"level": h.Level, "text": h.Text, "id": h.ID}
+	}
+	data["content"] = template.HTML(doc.HTML)
+	data["headings"] = headings
+	data["stylesheet"] = template.CSS(req.Stylesheet)
+	if _, ok := data["title"]; !ok && len(doc.Headings) > 0 {
+		data["title"] = doc.Headings[0].Text
+	}
+
+	pdfReq := req.PDF
+	pdfReq.HTMLTemplate = ""
+	pdfReq.Template = defaultLayout
+	pdfReq.Data = data
+	pdfReq.Outline = true
+	if req.Layout == "" {
+		return s.pdfService.GeneratePDF(&pdfReq)
+	}
+
+	tmpl, info, err := s.store.Get(req.Layout, LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	pdfReq.Template = tmpl
+	result, err := s.pdfService.GeneratePDF(&pdfReq)
+	if err != nil {
+		return nil, s.store.locate(err, info.ID, info.Version)
+	}
+	result.Template = info
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"pdf-service/internal/infrastructure"
+	"pdf-service/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var outlineOptions = infrastructure.PrintOptions{Outline: true}
+
+func TestMarkdownService_DefaultLayout(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewMarkdownService(NewTemplateStore(), NewPDFService(chromedpClient))
+
+	var html string
+	chromedpClient.On("GeneratePDFWithOptions", mock.AnythingOfType("string"), outlineOptions).
+		Run(func(args mock.Arguments) { html = args.String(0) }).
+		Return(samplePDF(t, 1), nil)
+
+	result, err := service.GenerateMarkdownPDF(&models.MarkdownRequest{
+		Markdown:   "---\nlang: fa\ndir: rtl\n---\n# Renewal <2026>\n\n| A | B |\n|---|---|\n| 1 | 2 |\n",
+		Stylesheet: "h1 { color: navy; }",
+		PDF:        models.PDFRequest{Linearize: true},
+	})
+	require.NoError(t, err)
+	assert.Len(t, result.Pages, 1)
+	chromedpClient.AssertExpectations(t)
+
+	assert.Contains(t, html, `<html lang="fa" dir="rtl">`)
+	assert.Contains(t, html, `<title>Renewal &lt;2026&gt;</title>`)
+	assert.Contains(t, html, `<style>h1 { color: navy; }</style>`)
+	assert.Contains(t, html, `<h1 id="renewal-2026">Renewal &lt;2026&gt;</h1>`)
+	assert.Contains(t, html, "<td>1</td>")
+}
+
+func TestMarkdownService_RegisteredLayout(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	store := NewTemplateStore()
+	service := NewMarkdownService(store, NewPDFService(chromedpClient))
+	_, _, err := store.Register("letter", `<html><body><p>{{.customer}} ({{.policy}})</p>`+
+		`<nav>{{range .headings}}<a href="#{{.id}}">{{.text}}</a>{{end}}</nav>{{.content}}</body></html>`)
+	require.NoError(t, err)
+
+	chromedpClient.On("GeneratePDFWithOptions",
+		`<html><body><p>Sara (P-1)</p><nav><a href="#terms">Terms</a></nav><h2 id="terms">Terms</h2>`+"\n"+`<p>Text</p>`+"\n"+`</body></html>`,
+		outlineOptions).Return(samplePDF(t, 1), nil)
+
+	// The request data overrides the front matter.
+	result, err := service.GenerateMarkdownPDF(&models.MarkdownRequest{
+		Markdown: "---\ncustomer: Ali\npolicy: P-1\n---\n## Terms\n\nText\n",
+		Data:     map[string]interface{}{"customer": "Sara"},
+		Layout:   "letter",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.Template)
+	assert.Equal(t, "letter", result.Template.ID)
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestMarkdownService_Errors(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	store := NewTemplateStore()
+	service := NewMarkdownService(store, NewPDFService(chromedpClient))
+	_, _, err := store.Register("broken", `<p>{{index .headings 5}}</p>`)
+	require.NoError(t, err)
+
+	_, err = service.GenerateMarkdownPDF(&models.MarkdownRequest{Markdown: "---\ntitle: x\n---\n  \n"})
+	assert.Equal(t, ErrEmptyMarkdown, err)
+
+	_, err = service.GenerateMarkdownPDF(&models.MarkdownRequest{Markdown: "---\na: 1\na: 2\n---\nText\n"})
+	var appErr *AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, `invalid front matter: line 3: duplicate key "a"`, appErr.Message)
+
+	_, err = service.GenerateMarkdownPDF(&models.MarkdownRequest{Markdown: "Text", Layout: "missing"})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// Errors in a registered layout quote its source.
+	_, err = service.GenerateMarkdownPDF(&models.MarkdownRequest{Markdown: "Text", Layout: "broken"})
+	var tmplErr *TemplateError
+	require.True(t, errors.As(err, &tmplErr))
+	assert.Equal(t, TemplateErrorExecute, tmplErr.Kind)
+	assert.Equal(t, `<p>{{index .headings 5}}</p>`, tmplErr.Source)
+	chromedpClient.AssertNotCalled(t, "GeneratePDFWithOptions", mock.Anything, mock.Anything)
+}
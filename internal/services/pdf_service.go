@@ -2,47 +2,184 @@ package services
 
 import (
 	"bytes"
-	"html/template"
+	"errors"
+	"net/url"
 	"pdf-service/internal/infrastructure"
+	"pdf-service/internal/jsonschema"
 	"pdf-service/internal/models"
+	"strings"
 )
 
 
 type PDFServiceInterface interface {
-	GeneratePDF(req *models.PDFRequest) ([]byte, error)
+	GeneratePDF(req *models.PDFRequest) (*models.PDFResult, error)
+	RenderHTML(req *models.PDFRequest) (*models.HTMLResult, error)
 }
 type PDFService struct {
 	chromedpClient infrastructure.PDFGenerator
+	templates      *TemplateCache
+	documents      *DocumentStore
+	signer         infrastructure.Signer
+	maxRendered    int64
 }
 
+// DefaultMaxRenderedBytes bounds the HTML a template may render.
+const DefaultMaxRenderedBytes = 32 << 20
+
 func NewPDFService(chromedpClient infrastructure.PDFGenerator) *PDFService {
-	return &PDFService{chromedpClient: chromedpClient}
+	return NewPDFServiceWithCache(chromedpClient,
+		NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes),
+		NewDocumentStore(DefaultDocumentStoreBytes, DefaultDocumentTTL), nil, DefaultMaxRenderedBytes)
+}
+
+// NewPDFServiceWithCache returns a PDFService parsing uploaded templates
+// through templates, keeping linearized documents in documents and signing
+// documents with signer, which may be nil when signing is not configured.
+// Templates rendering more than maxRendered bytes of HTML fail; 0 means no
+// limit.
+func NewPDFServiceWithCache(chromedpClient infrastructure.PDFGenerator, templates *TemplateCache, documents *DocumentStore, signer infrastructure.Signer, maxRendered int64) *PDFService {
+	return &PDFService{chromedpClient: chromedpClient, templates: templates, documents: documents, signer: signer, maxRendered: maxRendered}
 }
 
-func (s *PDFService) GeneratePDF(req *models.PDFRequest) ([]byte, error) {
-	if req.HTMLTemplate == "" {
-		return nil, ErrEmptyHTMLTemplate
+func (s *PDFService) GeneratePDF(req *models.PDFRequest) (*models.PDFResult, error) {
+	if err := checkRenderRequest(req); err != nil {
+		return nil, err
 	}
-	if req.Data == nil {
-		return nil, ErrNilData
+	if req.Sign != nil {
+		if s.signer == nil {
+			return nil, ErrSigningNotConfigured
+		}
+		// The signature is appended as an incremental update, which
+		// leaves the linearization dictionary and hint tables stale.
+		if req.Linearize {
+			return nil, ErrSignLinearized
+		}
+	}
+	if req.Print != nil {
+		// Imposed sheets are not trimmed pages; marks for them would be
+		// misplaced.
+		if req.Impose != nil {
+			return nil, ErrPrintMarksWithImposition
+		}
+		if err := printMarksOptions(req.Print).Validate(); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	html, err := s.render(req)
+	if err != nil {
+		return nil, err
+	}
+	forms := formElements.MatchString(html)
+	if forms {
+		html = withFormScript(html)
 	}
 
-	tmpl, err := template.New("dynamic").Parse(req.HTMLTemplate)
+	opts := infrastructure.PrintOptions{Outline: req.Outline}
+	if req.Print != nil {
+		opts.Bleed = req.Print.Bleed / 72
+	}
+	var pdfBuffer []byte
+	if opts != (infrastructure.PrintOptions{}) {
+		pdfBuffer, err = s.chromedpClient.GeneratePDFWithOptions(html, opts)
+	} else {
+		pdfBuffer, err = s.chromedpClient.GeneratePDF(html)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var renderedHTML bytes.Buffer
-	if err := tmpl.Execute(&renderedHTML, req.Data); err != nil {
+	return s.postProcess(pdfBuffer, req, forms)
+}
+
+// RenderHTML runs the template stage of GeneratePDF alone and returns the
+// HTML that would be converted. With an AssetBaseURL, relative asset
+// references are rewritten to absolute URLs.
+func (s *PDFService) RenderHTML(req *models.PDFRequest) (*models.HTMLResult, error) {
+	var base *url.URL
+	if req.AssetBaseURL != "" {
+		var err error
+		if base, err = url.Parse(req.AssetBaseURL); err != nil || !base.IsAbs() {
+			return nil, ErrInvalidAssetBaseURL
+		}
+	}
+	if err := checkRenderRequest(req); err != nil {
 		return nil, err
 	}
+	html, err := s.render(req)
+	if err != nil {
+		return nil, err
+	}
+	if base != nil {
+		html = rewriteAssets(html, base)
+	}
+	return &models.HTMLResult{HTML: html}, nil
+}
+
+// checkRenderRequest checks that req has a template and data, and that the
+// data matches req.Schema.
+func checkRenderRequest(req *models.PDFRequest) error {
+	if req.HTMLTemplate == "" && req.Template == nil {
+		return ErrEmptyHTMLTemplate
+	}
+	if req.Data == nil {
+		return ErrNilData
+	}
+	if req.Schema != nil {
+		if violations := req.Schema.Validate(req.Data); len(violations) > 0 {
+			return newValidationError(violations)
+		}
+	}
+	return nil
+}
+
+// render executes req.Template, or the uploaded HTMLTemplate, with req.Data.
+func (s *PDFService) render(req *models.PDFRequest) (string, error) {
+	var err error
+	var source func(string) string
+	tmpl := req.Template
+	if tmpl == nil {
+		source = func(string) string { return req.HTMLTemplate }
+		if tmpl, err = s.templates.Parse(req.HTMLTemplate, req.Strict); err != nil {
+			return "", newTemplateError(TemplateErrorParse, err, source)
+		}
+	}
+
+	// Each function call is bounded, but loops and nested calls multiply
+	// them, so the output is bounded as it is written.
+	renderedHTML := &limitedBuffer{limit: s.maxRendered}
+	if err := tmpl.Execute(renderedHTML, req.Data); err != nil {
+		if errors.Is(err, ErrRenderedHTMLTooLarge) {
+			return "", ErrRenderedHTMLTooLarge
+		}
+		return "", newTemplateError(TemplateErrorExecute, err, source)
+	}
+	return renderedHTML.String(), nil
+}
+
+// limitedBuffer is a buffer refusing writes past limit bytes, unless limit
+// is 0.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int64
+}
 
-	return s.chromedpClient.GeneratePDF(renderedHTML.String())
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && int64(b.Len()+len(p)) > b.limit {
+		return 0, ErrRenderedHTMLTooLarge
+	}
+	return b.Buffer.Write(p)
 }
 
 var (
 	ErrEmptyHTMLTemplate = &AppError{Message: "HTML template cannot be empty"}
 	ErrNilData           = &AppError{Message: "Data cannot be nil"}
+
+	ErrPrintMarksWithImposition = &AppError{Message: "Print marks cannot be combined with imposition"}
+	ErrInvalidAssetBaseURL      = &AppError{Message: "Asset base URL must be an absolute URL"}
+	ErrSigningNotConfigured     = &AppError{Message: "Signing is not configured on this server"}
+	ErrSignLinearized           = &AppError{Message: "Signed documents cannot be linearized"}
+	ErrRenderedHTMLTooLarge     = &AppError{Message: "Rendered HTML exceeds the size limit"}
 )
 
 type AppError struct {
@@ -51,4 +188,26 @@ type AppError struct {
 
 func (e *AppError) Error() string {
 	return e.Message
+}
+
+// ValidationError reports data that does not match a template's JSON
+// Schema, with every violation found.
+type ValidationError struct {
+	Violations []models.SchemaViolation
+}
+
+func newValidationError(violations []jsonschema.Violation) *ValidationError {
+	err := &ValidationError{Violations: make([]models.SchemaViolation, len(violations))}
+	for i, v := range violations {
+		err.Violations[i] = models.SchemaViolation{Pointer: v.Pointer, Message: v.Message}
+	}
+	return err
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = jsonschema.Violation{Pointer: v.Pointer, Message: v.Message}.String()
+	}
+	return "Data does not match the template schema: " + strings.Join(msgs, "; ")
 }
\ No newline at end of file
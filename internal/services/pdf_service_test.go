@@ -1,12 +1,19 @@
 package services
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"pdf-service/internal/infrastructure"
+	"pdf-service/internal/jsonschema"
 	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockChromedpClient struct {
@@ -18,6 +25,23 @@ func (m *MockChromedpClient) GeneratePDF(htmlContent string) ([]byte, error) {
 	return args.Get(0).([]byte), args.Error(1)
 }
 
+func (m *MockChromedpClient) GeneratePDFWithOptions(htmlContent string, opts infrastructure.PrintOptions) ([]byte, error) {
+	args := m.Called(htmlContent, opts)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+type MockSigner struct {
+	mock.Mock
+}
+
+func (m *MockSigner) Sign(content []byte) ([]byte, error) {
+	args := m.Called(content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
 func TestNewPDFService(t *testing.T) {
 	chromedpClient := &MockChromedpClient{}
 	service := NewPDFService(chromedpClient)
@@ -39,10 +63,116 @@ func TestGeneratePDF_Success(t *testing.T) {
 
 	pdf, err := service.GeneratePDF(req)
 	assert.NoError(t, err)
-	assert.Equal(t, expectedPDF, pdf)
+	assert.Equal(t, expectedPDF, pdf.Content)
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_CachesTemplates(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	cache := NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes)
+	service := NewPDFServiceWithCache(chromedpClient, cache, NewDocumentStore(DefaultDocumentStoreBytes, DefaultDocumentTTL), nil, DefaultMaxRenderedBytes)
+	chromedpClient.On("GeneratePDF", "<p>Sara</p>").Return([]byte("mocked_pdf_content"), nil)
+	chromedpClient.On("GeneratePDF", "<p>Ali</p>").Return([]byte("mocked_pdf_content"), nil)
+
+	for _, name := range []string{"Sara", "Ali"} {
+		_, err := service.GeneratePDF(&models.PDFRequest{
+			HTMLTemplate: "<p>{{.Name}}</p>",
+			Data:         map[string]interface{}{"Name": name},
+		})
+		assert.NoError(t, err)
+	}
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(1), stats.Hits)
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_TemplateFunctions(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: `<p>{{.name | upper}}: {{formatCurrency "USD" .total}}</p>`,
+		Data:         map[string]interface{}{"name": "acme", "total": 1234.5},
+	}
+
+	chromedpClient.On("GeneratePDF", "<p>ACME: $1,234.50</p>").Return([]byte("mocked_pdf_content"), nil)
+
+	_, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
 	chromedpClient.AssertExpectations(t)
 }
 
+func TestGeneratePDF_Strict(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+	chromedpClient.On("GeneratePDF", "<p>Sara </p>").Return([]byte("mocked_pdf_content"), nil)
+
+	// Without strict mode a missing key renders as nothing.
+	_, err := service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<p>{{.name}} {{.customer_number}}</p>",
+		Data:         map[string]interface{}{"name": "Sara"},
+	})
+	assert.NoError(t, err)
+
+	_, err = service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<p>{{.name}} {{.customer_number}}</p>",
+		Data:         map[string]interface{}{"name": "Sara"},
+		Strict:       true,
+	})
+	var tmplErr *TemplateError
+	require.ErrorAs(t, err, &tmplErr)
+	assert.Equal(t, models.TemplateError{
+		Kind:     TemplateErrorExecute,
+		Template: "dynamic",
+		Line:     1,
+		Column:   16,
+		Source:   "<p>{{.name}} {{.customer_number}}</p>",
+		Field:    ".customer_number",
+		Message:  `map has no entry for key "customer_number"`,
+	}, tmplErr.TemplateError)
+	chromedpClient.AssertNumberOfCalls(t, "GeneratePDF", 1)
+}
+
+func TestGeneratePDF_TemplateParseError(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	_, err := service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<h1>Invoice</h1>\n<p>{{.total | formatNumber</p>",
+		Data:         map[string]interface{}{},
+	})
+	var tmplErr *TemplateError
+	require.ErrorAs(t, err, &tmplErr)
+	assert.Equal(t, TemplateErrorParse, tmplErr.Kind)
+	assert.Equal(t, 2, tmplErr.Line)
+	assert.Equal(t, "<p>{{.total | formatNumber</p>", tmplErr.Source)
+	assert.ErrorContains(t, err, "Invalid template: template: dynamic:2:")
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDF_Schema(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+	schema, err := jsonschema.Parse([]byte(`{"required": ["name", "total"], "properties": {"total": {"type": "number"}}}`))
+	assert.NoError(t, err)
+
+	_, err = service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<p>{{.name}}</p>",
+		Data:         map[string]interface{}{"total": "12"},
+		Schema:       schema,
+	})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.SchemaViolation{
+		{Pointer: "/name", Message: "is required"},
+		{Pointer: "/total", Message: "expected number, got string"},
+	}, validationErr.Violations)
+	assert.EqualError(t, err, "Data does not match the template schema: /name: is required; /total: expected number, got string")
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
 func TestGeneratePDF_EmptyTemplate(t *testing.T) {
 	chromedpClient := &MockChromedpClient{}
 	service := NewPDFService(chromedpClient)
@@ -104,4 +234,557 @@ func TestGeneratePDF_ChromedpError(t *testing.T) {
 	assert.Equal(t, "chromedp error", err.Error())
 	assert.Nil(t, pdf)
 	chromedpClient.AssertExpectations(t)
-}
\ No newline at end of file
+}
+func samplePDF(t *testing.T, pages int) []byte {
+	t.Helper()
+	doc := pdf.New()
+	for i := 0; i < pages; i++ {
+		doc.AddPage(pdf.Rect{URX: 595.28, URY: 841.89}, nil, []byte("0 0 m 10 10 l S"))
+	}
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGeneratePDF_Watermark(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Watermark:    &models.Watermark{Text: "COPY", Pages: "2"},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 2), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+
+	doc, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	pages, err := doc.Pages()
+	assert.NoError(t, err)
+	first, _ := doc.Content(pages[0])
+	second, _ := doc.Content(pages[1])
+	assert.NotContains(t, string(first), "(COPY) Tj")
+	assert.Contains(t, string(second), "(COPY) Tj")
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_InvalidWatermark(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Watermark:    &models.Watermark{Text: "COPY", Position: "nowhere"},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.IsType(t, &AppError{}, err)
+	assert.Contains(t, err.Error(), "unknown position")
+	assert.Nil(t, result)
+}
+
+func TestGeneratePDF_PDFA(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		PDFA:         "3b",
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Conformance) {
+		assert.Equal(t, "PDF/A-3b", result.Conformance.Level)
+		assert.True(t, result.Conformance.Compliant, "%v", result.Conformance.Violations)
+	}
+
+	doc, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.7", doc.Version)
+	assert.NotNil(t, doc.Catalog()["OutputIntents"])
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_InvalidPDFALevel(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		PDFA:         "1a",
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.IsType(t, &AppError{}, err)
+	assert.Contains(t, err.Error(), "invalid PDF/A level")
+	assert.Nil(t, result)
+}
+
+func TestGeneratePDF_Attachments(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Attachments: []models.Attachment{
+			{Source: models.AttachmentSourceData, Description: "Template data"},
+			{Source: "invoice", Name: "invoice.xml", MIMEType: "application/xml", Relationship: "Alternative", Data: []byte("<Invoice/>")},
+		},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+
+	doc, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	attachments, err := doc.Attachments()
+	assert.NoError(t, err)
+	if assert.Len(t, attachments, 2) {
+		assert.Equal(t, "data.json", attachments[0].Name)
+		assert.Equal(t, "application/json", attachments[0].MIMEType)
+		assert.Equal(t, "Source", attachments[0].Relationship)
+		assert.Equal(t, "Template data", attachments[0].Description)
+		assert.JSONEq(t, `{"Name":"John Doe"}`, string(attachments[0].Data))
+		assert.Equal(t, "invoice.xml", attachments[1].Name)
+		assert.Equal(t, "<Invoice/>", string(attachments[1].Data))
+	}
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_InvalidAttachment(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Attachments:  []models.Attachment{{Source: "file", Relationship: "parent", Name: "a.txt"}},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.IsType(t, &AppError{}, err)
+	assert.Contains(t, err.Error(), "unknown relationship")
+	assert.Nil(t, result)
+}
+
+func TestGeneratePDF_Optimize(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Optimize:     &models.Optimization{MaxImageDPI: 96},
+	}
+
+	original := samplePDF(t, 20)
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(original, nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Optimization) {
+		assert.Equal(t, len(original), result.Optimization.OriginalSize)
+		assert.Equal(t, len(result.Content), result.Optimization.OptimizedSize)
+		assert.Less(t, result.Optimization.OptimizedSize, result.Optimization.OriginalSize)
+	}
+
+	doc, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	pages, err := doc.Pages()
+	assert.NoError(t, err)
+	assert.Len(t, pages, 20)
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_InvalidOptimize(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Optimize:     &models.Optimization{JPEGQuality: 200},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.IsType(t, &AppError{}, err)
+	assert.Contains(t, err.Error(), "JPEG quality")
+	assert.Nil(t, result)
+}
+
+func TestGeneratePDF_Impose(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Impose:       &models.Imposition{Layout: "n-up", PagesPerSheet: 2},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 3), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	assert.Len(t, result.Pages, 2)
+	assert.Equal(t, "A4", result.Pages[0].Paper)
+	assert.Greater(t, result.Pages[0].Width, result.Pages[0].Height)
+}
+
+func TestGeneratePDF_InvalidImposition(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Impose:       &models.Imposition{Layout: "n-up", PagesPerSheet: 5},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.IsType(t, &AppError{}, err)
+	assert.Contains(t, err.Error(), "pages per sheet")
+	assert.Nil(t, result)
+}
+
+func TestGeneratePDF_PrintMarks(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Print:        &models.PrintMarks{Bleed: 9, CropMarks: true},
+	}
+
+	// Chromium prints on A4 grown by the bleed, 9 points or 0.125 inch.
+	doc := pdf.New()
+	doc.AddPage(pdf.Rect{URX: 595.28 + 18, URY: 841.89 + 18}, nil, nil)
+	rendered, err := doc.Bytes()
+	assert.NoError(t, err)
+	chromedpClient.On("GeneratePDFWithOptions", mock.AnythingOfType("string"), infrastructure.PrintOptions{Bleed: 0.125}).Return(rendered, nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	out, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	pages, err := out.Pages()
+	assert.NoError(t, err)
+	trim, _ := out.GetRect(pages[0].Dict["TrimBox"])
+	assert.InDelta(t, 595.28, trim.Width(), 0.001)
+	assert.Equal(t, -24.0, pages[0].MediaBox.LLX)
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_InvalidPrintMarks(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	for _, req := range []*models.PDFRequest{
+		{Print: &models.PrintMarks{Bleed: 100}},
+		{Print: &models.PrintMarks{CropMarks: true}, Impose: &models.Imposition{Layout: "n-up", PagesPerSheet: 2}},
+	} {
+		req.HTMLTemplate = "<html><body>{{.Name}}</body></html>"
+		req.Data = map[string]interface{}{"Name": "John Doe"}
+		result, err := service.GeneratePDF(req)
+		assert.IsType(t, &AppError{}, err)
+		assert.Nil(t, result)
+	}
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDF_Linearize(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	documents := NewDocumentStore(DefaultDocumentStoreBytes, DefaultDocumentTTL)
+	service := NewPDFServiceWithCache(chromedpClient, NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes), documents, nil, DefaultMaxRenderedBytes)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Linearize:    true,
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 3), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	assert.Contains(t, string(result.Content[:200]), "/Linearized 1")
+
+	doc, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	pages, err := doc.Pages()
+	assert.NoError(t, err)
+	assert.Len(t, pages, 3)
+	chromedpClient.AssertExpectations(t)
+
+	// The document is kept to be fetched in parts.
+	stored, err := documents.Get(result.DocumentID)
+	require.NoError(t, err)
+	assert.Equal(t, result.Content, stored.Content)
+}
+
+func TestGeneratePDF_Sign(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	signer := &MockSigner{}
+	service := NewPDFServiceWithCache(chromedpClient, NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes), nil, signer, DefaultMaxRenderedBytes)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Sign:         &models.Signature{Name: "John Doe", Reason: "Approval"},
+	}
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 2), nil)
+	signer.On("Sign", mock.Anything).Return([]byte("cms signature"), nil).Once()
+
+	result, err := service.GeneratePDF(req)
+	require.NoError(t, err)
+	signer.AssertExpectations(t)
+
+	doc, err := pdf.Parse(result.Content)
+	require.NoError(t, err)
+	sigs := doc.Signatures()
+	require.Len(t, sigs, 1)
+	assert.Equal(t, "John Doe", sigs[0].Name)
+	assert.Equal(t, "Approval", sigs[0].Reason)
+	assert.Equal(t, "cms signature", string(sigs[0].Contents[:len("cms signature")]))
+
+	// The signer was given the whole file but the signature value.
+	br := sigs[0].ByteRange
+	require.Len(t, br, 4)
+	signed := append(append([]byte(nil), result.Content[:br[1]]...), result.Content[br[2]:]...)
+	signer.AssertCalled(t, "Sign", signed)
+}
+
+func TestGeneratePDF_SignLinearized(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	signer := &MockSigner{}
+	service := NewPDFServiceWithCache(chromedpClient, NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes), nil, signer, DefaultMaxRenderedBytes)
+
+	result, err := service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Linearize:    true,
+		Sign:         &models.Signature{},
+	})
+	assert.Equal(t, ErrSignLinearized, err)
+	assert.Nil(t, result)
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+	signer.AssertNotCalled(t, "Sign", mock.Anything)
+}
+
+func TestGeneratePDF_SignNotConfigured(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	result, err := service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Sign:         &models.Signature{},
+	})
+	assert.Equal(t, ErrSigningNotConfigured, err)
+	assert.Nil(t, result)
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestGeneratePDF_SignError(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	signer := &MockSigner{}
+	service := NewPDFServiceWithCache(chromedpClient, NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes),
+		NewDocumentStore(DefaultDocumentStoreBytes, DefaultDocumentTTL), signer, DefaultMaxRenderedBytes)
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+	signer.On("Sign", mock.Anything).Return(nil, infrastructure.ErrTSAUnreachable)
+
+	result, err := service.GeneratePDF(&models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+		Sign:         &models.Signature{},
+	})
+	assert.ErrorIs(t, err, infrastructure.ErrTSAUnreachable)
+	assert.Nil(t, result)
+}
+
+// formPDF returns a page carrying the marker links formScript leaves for
+// the given fields; a field spanning two lines has two links.
+func formPDF(t *testing.T, markers ...formFieldMarker) []byte {
+	t.Helper()
+	doc := pdf.New()
+	doc.AddPage(pdf.Rect{URX: 595.28, URY: 841.89}, nil, []byte("0 0 m 10 10 l S"))
+	pages, err := doc.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var annots pdf.Array
+	for i, m := range markers {
+		payload, _ := json.Marshal(m)
+		uri := pdf.String(formFieldURI + base64.RawURLEncoding.EncodeToString(payload))
+		y := 800 - float64(i)*40
+		for _, rect := range []pdf.Rect{{LLX: 50, LLY: y - 20, URX: 250, URY: y}, {LLX: 50, LLY: y - 40, URX: 150, URY: y - 20}} {
+			annots = append(annots, doc.Add(pdf.Dict{
+				"Type": pdf.Name("Annot"), "Subtype": pdf.Name("Link"), "Rect": rect.Array(),
+				"A": pdf.Dict{"S": pdf.Name("URI"), "URI": uri},
+			}))
+		}
+	}
+	pages[0].Dict["Annots"] = annots
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGeneratePDF_FormFields(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: `<html><body><input name="name" value="{{.Name}}" readonly></body></html>`,
+		Data:         map[string]interface{}{"Name": "John Doe"},
+	}
+
+	withScript := mock.MatchedBy(func(html string) bool {
+		return strings.Contains(html, `value="John Doe"`) && strings.HasSuffix(html, formScript+"</body></html>")
+	})
+	chromedpClient.On("GeneratePDF", withScript).Return(formPDF(t,
+		formFieldMarker{Name: "name", Type: "text", Value: "John Doe", ReadOnly: true, FontSize: 12},
+	), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+
+	doc, err := pdf.Parse(result.Content)
+	assert.NoError(t, err)
+	form := doc.GetDict(doc.Catalog()["AcroForm"])
+	fields := doc.GetArray(form["Fields"])
+	assert.Len(t, fields, 1)
+	field := doc.GetDict(fields[0])
+	assert.Equal(t, pdf.Name("Tx"), field["FT"])
+	assert.Equal(t, "John Doe", pdf.DecodeTextString(doc.GetString(field["V"])))
+
+	// The two fragments of the link merge into one widget.
+	pages, err := doc.Pages()
+	assert.NoError(t, err)
+	annots := doc.GetArray(pages[0].Dict["Annots"])
+	assert.Len(t, annots, 1)
+	rect, _ := doc.GetRect(doc.GetDict(annots[0])["Rect"])
+	assert.Equal(t, pdf.Rect{LLX: 50, LLY: 760, URX: 250, URY: 800}, rect)
+	assert.Equal(t, pdf.Name("Widget"), doc.GetDict(annots[0])["Subtype"])
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_InvalidFormField(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: `<div data-pdf-field="a..b"></div>`,
+		Data:         map[string]interface{}{},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(formPDF(t,
+		formFieldMarker{Name: "a..b", Type: "text"},
+	), nil)
+
+	result, err := service.GeneratePDF(req)
+	var appErr *AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Contains(t, err.Error(), "empty name part")
+	assert.Nil(t, result)
+}
+
+func TestGeneratePDF_NoFormScriptWithoutControls(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+	}
+
+	chromedpClient.On("GeneratePDF", "<html><body>John Doe</body></html>").Return([]byte("pdf"), nil)
+
+	_, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	chromedpClient.AssertExpectations(t)
+}
+
+func TestGeneratePDF_ReportsPages(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	req := &models.PDFRequest{
+		HTMLTemplate: "<html><body>{{.Name}}</body></html>",
+		Data:         map[string]interface{}{"Name": "John Doe"},
+	}
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 2), nil)
+
+	result, err := service.GeneratePDF(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PageInfo{
+		{Number: 1, Width: 595.28, Height: 841.89, Paper: "A4"},
+		{Number: 2, Width: 595.28, Height: 841.89, Paper: "A4"},
+	}, result.Pages)
+}
+
+func TestRenderHTML(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewPDFService(chromedpClient)
+
+	result, err := service.RenderHTML(&models.PDFRequest{
+		HTMLTemplate: `<img src="logo.png"><input name="name" value="{{.name}}">`,
+		Data:         map[string]interface{}{"name": "Sara"},
+		AssetBaseURL: "https://cdn.example.com/assets/",
+	})
+	require.NoError(t, err)
+	// The form script is only added for conversion to PDF.
+	assert.Equal(t, `<img src="https://cdn.example.com/assets/logo.png"><input name="name" value="Sara">`, result.HTML)
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+
+	_, err = service.RenderHTML(&models.PDFRequest{HTMLTemplate: "<p></p>", Data: map[string]interface{}{}, AssetBaseURL: "assets/"})
+	assert.Equal(t, ErrInvalidAssetBaseURL, err)
+	_, err = service.RenderHTML(&models.PDFRequest{HTMLTemplate: "<p></p>"})
+	assert.Equal(t, ErrNilData, err)
+	_, err = service.RenderHTML(&models.PDFRequest{HTMLTemplate: "<p>{{.name}}</p>", Data: map[string]interface{}{}, Strict: true})
+	var tmplErr *TemplateError
+	assert.ErrorAs(t, err, &tmplErr)
+}
+
+func TestRenderHTML_TooLarge(t *testing.T) {
+	service := NewPDFServiceWithCache(&MockChromedpClient{}, NewTemplateCache(DefaultTemplateCacheEntries, DefaultTemplateCacheBytes), nil, nil, 1<<20)
+
+	// Each call is within its own limits; nesting them is not.
+	for _, src := range []string{
+		`{{range seq 10000}}{{range seq 10000}}{{repeat 10000 "x"}}{{end}}{{end}}`,
+		`{{repeat 10000 (repeat 100 "x")}}{{repeat 10000 (repeat 100 "x")}}`,
+	} {
+		result, err := service.RenderHTML(&models.PDFRequest{HTMLTemplate: src, Data: map[string]interface{}{}})
+		assert.Equal(t, ErrRenderedHTMLTooLarge, err, src)
+		assert.Nil(t, result)
+	}
+}
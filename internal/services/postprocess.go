@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"pdf-service/internal/infrastructure"
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+)
+
+// clientErrors are post-processing failures caused by the request options
+// rather than by the service.
+var clientErrors = []error{
+	pdf.ErrInvalidWatermark,
+	pdf.ErrInvalidPageRange,
+	pdf.ErrUnsupportedImage,
+	pdf.ErrInvalidPDFALevel,
+	pdf.ErrInvalidAttachment,
+	pdf.ErrInvalidOptimization,
+	pdf.ErrInvalidFormField,
+	pdf.ErrInvalidFormValue,
+	pdf.ErrEncrypted,
+	pdf.ErrInvalidImposition,
+	pdf.ErrInvalidPrintMarks,
+}
+
+func asAppError(err error) error {
+	for _, target := range clientErrors {
+		if errors.Is(err, target) {
+			return &AppError{Message: err.Error()}
+		}
+	}
+	return err
+}
+
+// postProcess applies the PDF level options of req to the rendered document.
+// forms reports whether the HTML was prepared with formScript.
+func (s *PDFService) postProcess(content []byte, req *models.PDFRequest, forms bool) (*models.PDFResult, error) {
+	result := &models.PDFResult{Content: content}
+	if !forms && req.Watermark == nil && req.PDFA == "" && len(req.Attachments) == 0 && req.Optimize == nil && !req.Linearize && req.Impose == nil && req.Print == nil && len(req.Metadata) == 0 && req.Sign == nil {
+		// The document is only read, to report its pages; failing to do so
+		// does not make the PDF unusable.
+		if doc, err := pdf.Parse(content); err == nil {
+			result.Pages = documentPages(doc)
+		}
+		return result, nil
+	}
+
+	doc, err := pdf.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if forms {
+		if err := addFormFields(doc); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	if req.Watermark != nil {
+		if err := doc.ApplyWatermark(watermarkOptions(req.Watermark)); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	// Imposition follows the page-level stamps so that they are carried
+	// onto the sheets with their pages.
+	if req.Impose != nil {
+		if err := doc.Impose(impositionOptions(req.Impose)); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	if req.Print != nil {
+		if err := doc.AddPrintMarks(printMarksOptions(req.Print)); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	if len(req.Attachments) > 0 {
+		attachments, err := attachmentOptions(req)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.AddAttachments(attachments...); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	if req.Optimize != nil {
+		opts := pdf.OptimizeOptions{
+			MaxImageDPI: req.Optimize.MaxImageDPI,
+			JPEGQuality: req.Optimize.JPEGQuality,
+		}
+		if _, err := doc.Optimize(opts); err != nil {
+			return nil, asAppError(err)
+		}
+	}
+
+	if len(req.Metadata) > 0 {
+		info := doc.Info(true)
+		for key, value := range req.Metadata {
+			info[pdf.Name(key)] = pdf.EncodeTextString(value)
+		}
+	}
+
+	// PDF/A conversion runs last so that the report covers everything added
+	// before it.
+	if req.PDFA != "" {
+		level, err := pdf.ParsePDFALevel(req.PDFA)
+		if err != nil {
+			return nil, asAppError(err)
+		}
+		report, err := doc.ConvertToPDFA(level)
+		if err != nil {
+			return nil, err
+		}
+		result.Conformance = &models.ConformanceReport{
+			Level:      report.Level.String(),
+			Compliant:  report.Compliant(),
+			Violations: report.Violations,
+		}
+	}
+
+	result.Pages = documentPages(doc)
+	opts := pdf.WriteOptions{ObjectStreams: req.Optimize != nil, Linearize: req.Linearize}
+	if result.Content, err = doc.BytesWith(opts); err != nil {
+		return nil, err
+	}
+	if req.Optimize != nil {
+		result.Optimization = &models.OptimizationReport{
+			OriginalSize:  len(content),
+			OptimizedSize: len(result.Content),
+		}
+	}
+	// The signature covers the file as written, so it is added last, as an
+	// incremental update.
+	if req.Sign != nil {
+		if result.Content, err = pdf.Sign(result.Content, signOptions(req.Sign, s.signer)); err != nil {
+			return nil, err
+		}
+	}
+	// A linearized document is read in parts as it is displayed; those
+	// ranges must come from this copy, not from one generated again.
+	if req.Linearize {
+		if result.DocumentID, err = s.documents.Put(result.Content); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// documentPages describes the pages of doc, or returns nil if the page
+// tree cannot be read.
+func documentPages(doc *pdf.Document) []models.PageInfo {
+	pages, err := doc.Pages()
+	if err != nil {
+		return nil
+	}
+	infos := make([]pdf.PageInfo, len(pages))
+	for i, p := range pages {
+		infos[i] = p.Info()
+	}
+	return pageInfos(infos)
+}
+
+func watermarkOptions(wm *models.Watermark) pdf.Watermark {
+	return pdf.Watermark{
+		Text:       wm.Text,
+		FontSize:   wm.FontSize,
+		Color:      wm.Color,
+		Opacity:    wm.Opacity,
+		Rotation:   wm.Rotation,
+		Position:   wm.Position,
+		Pages:      wm.Pages,
+		Image:      wm.Image,
+		ImageWidth: wm.ImageWidth,
+	}
+}
+
+func signOptions(sig *models.Signature, signer infrastructure.Signer) pdf.SignOptions {
+	return pdf.SignOptions{
+		Name:        sig.Name,
+		Reason:      sig.Reason,
+		Location:    sig.Location,
+		ContactInfo: sig.ContactInfo,
+		Sign:        signer.Sign,
+	}
+}
+
+func impositionOptions(imp *models.Imposition) pdf.Imposition {
+	return pdf.Imposition{
+		Layout:        imp.Layout,
+		PagesPerSheet: imp.PagesPerSheet,
+		Columns:       imp.Columns,
+		Rows:          imp.Rows,
+		Sheet:         imp.Sheet,
+		Orientation:   imp.Orientation,
+		Margin:        imp.Margin,
+		Gutter:        imp.Gutter,
+		CropMarks:     imp.CropMarks,
+		Repeat:        imp.Repeat,
+		RightToLeft:   imp.RightToLeft,
+	}
+}
+
+func printMarksOptions(pm *models.PrintMarks) pdf.PrintMarks {
+	return pdf.PrintMarks{
+		Bleed:             pm.Bleed,
+		CropMarks:         pm.CropMarks,
+		RegistrationMarks: pm.RegistrationMarks,
+	}
+}
+
+// attachmentOptions resolves the requested attachments, serialising the
+// template data for those that embed it.
+func attachmentOptions(req *models.PDFRequest) ([]pdf.Attachment, error) {
+	attachments := make([]pdf.Attachment, 0, len(req.Attachments))
+	for _, a := range req.Attachments {
+		opt := pdf.Attachment{
+			Name:         a.Name,
+			Data:         a.Data,
+			MIMEType:     a.MIMEType,
+			Description:  a.Description,
+			Relationship: a.Relationship,
+		}
+		if a.Source == models.AttachmentSourceData {
+			data, err := json.MarshalIndent(req.Data, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			opt.Data = data
+			if opt.Name == "" {
+				opt.Name = "data.json"
+			}
+			if opt.MIMEType == "" {
+				opt.MIMEType = "application/json"
+			}
+			if opt.Relationship == "" {
+				opt.Relationship = "Source"
+			}
+		}
+		attachments = append(attachments, opt)
+	}
+	return attachments, nil
+}
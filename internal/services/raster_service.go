@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"pdf-service/internal/infrastructure"
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+)
+
+type RasterServiceInterface interface {
+	Rasterize(req *models.RasterRequest) ([]models.PageImage, error)
+}
+
+// RasterService renders pages of existing PDFs as images.
+type RasterService struct {
+	rasterizer infrastructure.PageRasterizer
+}
+
+func NewRasterService(rasterizer infrastructure.PageRasterizer) *RasterService {
+	return &RasterService{rasterizer: rasterizer}
+}
+
+const (
+	// DefaultThumbnailWidth is the image width used when none is given.
+	DefaultThumbnailWidth = 200
+	minRasterWidth        = 16
+	maxRasterWidth        = 4000
+	// maxRasterPages bounds the pages rendered by one request, each of
+	// which is loaded into the browser separately.
+	maxRasterPages = 50
+)
+
+var ErrInvalidRasterWidth = &AppError{Message: fmt.Sprintf("Width must be between %d and %d pixels", minRasterWidth, maxRasterWidth)}
+
+// Rasterize renders the selected pages of req.PDF, the first page by
+// default, as PNG images.
+func (s *RasterService) Rasterize(req *models.RasterRequest) ([]models.PageImage, error) {
+	width := req.Width
+	if width == 0 {
+		width = DefaultThumbnailWidth
+	}
+	if width < minRasterWidth || width > maxRasterWidth {
+		return nil, ErrInvalidRasterWidth
+	}
+	doc, err := parseUpload(req.PDF)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Encrypted() {
+		return nil, asAppError(pdf.ErrEncrypted)
+	}
+	pages, err := doc.Pages()
+	if err != nil {
+		return nil, asAppError(err)
+	}
+	spec := req.Pages
+	if spec == "" {
+		spec = "1"
+	}
+	selected, err := pdf.ParsePageRange(spec, len(pages))
+	if err != nil {
+		return nil, asAppError(err)
+	}
+	if len(selected) > maxRasterPages {
+		return nil, &AppError{Message: fmt.Sprintf("At most %d pages can be rendered at once", maxRasterPages)}
+	}
+
+	raster := make([]infrastructure.RasterPage, len(selected))
+	for i, n := range selected {
+		w, h := pages[n-1].Size()
+		raster[i] = infrastructure.RasterPage{Number: n, Width: w, Height: h}
+		// A long, narrow page would make an image of unbounded height.
+		if w > 0 && h > 0 && infrastructure.RasterHeight(raster[i], width) > infrastructure.MaxRasterHeight {
+			return nil, &AppError{Message: fmt.Sprintf("Page %d is too tall to render %d pixels wide; images are at most %d pixels high", n, width, infrastructure.MaxRasterHeight)}
+		}
+	}
+	images, err := s.rasterizer.RasterizePDF(req.PDF, raster, width)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) != len(selected) {
+		return nil, fmt.Errorf("rasterizer returned %d images for %d pages", len(images), len(selected))
+	}
+
+	out := make([]models.PageImage, len(selected))
+	for i, n := range selected {
+		out[i] = models.PageImage{Number: n, Content: images[i]}
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"pdf-service/internal/infrastructure"
+	"pdf-service/internal/models"
+	"pdf-service/internal/pdf"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRasterizer struct {
+	mock.Mock
+}
+
+func (m *MockRasterizer) RasterizePDF(content []byte, pages []infrastructure.RasterPage, width int) ([][]byte, error) {
+	args := m.Called(content, pages, width)
+	return args.Get(0).([][]byte), args.Error(1)
+}
+
+func threePagePDF(t *testing.T) []byte {
+	t.Helper()
+	doc := pdf.New()
+	for i := 0; i < 3; i++ {
+		ref := doc.AddPage(pdf.Rect{URX: 595, URY: 842}, pdf.Dict{}, nil)
+		if i == 2 {
+			doc.GetDict(ref)["Rotate"] = 90
+		}
+	}
+	content, err := doc.Bytes()
+	assert.NoError(t, err)
+	return content
+}
+
+func TestRasterize_FirstPage(t *testing.T) {
+	rasterizer := &MockRasterizer{}
+	content := threePagePDF(t)
+	rasterizer.On("RasterizePDF", content, []infrastructure.RasterPage{{Number: 1, Width: 595, Height: 842}}, DefaultThumbnailWidth).
+		Return([][]byte{[]byte("png1")}, nil)
+
+	images, err := NewRasterService(rasterizer).Rasterize(&models.RasterRequest{PDF: content})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PageImage{{Number: 1, Content: []byte("png1")}}, images)
+}
+
+func TestRasterize_PageRange(t *testing.T) {
+	rasterizer := &MockRasterizer{}
+	content := threePagePDF(t)
+	rasterizer.On("RasterizePDF", content, []infrastructure.RasterPage{
+		{Number: 2, Width: 595, Height: 842},
+		{Number: 3, Width: 842, Height: 595},
+	}, 800).Return([][]byte{[]byte("png2"), []byte("png3")}, nil)
+
+	images, err := NewRasterService(rasterizer).Rasterize(&models.RasterRequest{PDF: content, Pages: "2-", Width: 800})
+	assert.NoError(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, 3, images[1].Number)
+}
+
+func TestRasterize_InvalidRequest(t *testing.T) {
+	service := NewRasterService(&MockRasterizer{})
+	content := threePagePDF(t)
+
+	_, err := service.Rasterize(&models.RasterRequest{PDF: content, Width: 10000})
+	assert.Equal(t, ErrInvalidRasterWidth, err)
+
+	var appErr *AppError
+	_, err = service.Rasterize(&models.RasterRequest{PDF: content, Pages: "7"})
+	assert.ErrorAs(t, err, &appErr)
+
+	_, err = service.Rasterize(&models.RasterRequest{})
+	assert.Equal(t, ErrEmptyPDF, err)
+}
+
+func TestRasterize_TallPage(t *testing.T) {
+	rasterizer := &MockRasterizer{}
+	doc := pdf.New()
+	doc.AddPage(pdf.Rect{URX: 1, URY: 14400}, pdf.Dict{}, nil)
+	content, err := doc.Bytes()
+	assert.NoError(t, err)
+
+	_, err = NewRasterService(rasterizer).Rasterize(&models.RasterRequest{PDF: content})
+	var appErr *AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Contains(t, err.Error(), "too tall")
+	rasterizer.AssertNotCalled(t, "RasterizePDF", mock.Anything, mock.Anything, mock.Anything)
+}
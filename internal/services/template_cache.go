@@ -0,0 +1,132 @@
+package services
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"html/template"
+	"pdf-service/internal/models"
+	"pdf-service/internal/templatefuncs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultTemplateCacheEntries = 128
+	DefaultTemplateCacheBytes   = 64 << 20
+)
+
+// TemplateCache keeps recently used templates parsed, so that a template
+// uploaded with every request, often hundreds of kilobytes of embedded
+// fonts, is parsed only once. Templates are keyed by a hash of their source
+// and of the template function names and options, and the least recently used are
+// evicted when the cache holds more than maxEntries templates or maxBytes
+// of source. Parsed templates are safe to execute concurrently.
+type TemplateCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	funcs      template.FuncMap
+	funcsKey   string
+	order      *list.List // of *cachedTemplate, most recently used first
+	entries    map[[sha256.Size]byte]*list.Element
+	stats      models.TemplateCacheStats
+}
+
+type cachedTemplate struct {
+	key      [sha256.Size]byte
+	size     int64
+	template *template.Template
+}
+
+// NewTemplateCache returns a cache of at most maxEntries templates and
+// maxBytes of template source. A limit of zero or less disables the cache.
+func NewTemplateCache(maxEntries int, maxBytes int64) *TemplateCache {
+	funcs := templatefuncs.FuncMap()
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &TemplateCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		funcs:      funcs,
+		funcsKey:   strings.Join(names, ","),
+		order:      list.New(),
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+		stats:      models.TemplateCacheStats{MaxEntries: maxEntries, MaxBytes: maxBytes},
+	}
+}
+
+// Parse returns source parsed with the template functions, from the cache
+// when it was parsed before. strict templates fail on keys missing from the
+// data. Sources that fail to parse are not cached.
+func (c *TemplateCache) Parse(source string, strict bool) (*template.Template, error) {
+	h := sha256.New()
+	h.Write([]byte(c.funcsKey))
+	if strict {
+		h.Write([]byte("\x00strict"))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(source))
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return e.Value.(*cachedTemplate).template, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// Parsing happens outside the lock so that a large template does not
+	// hold up requests for cached ones. Two requests missing on the same
+	// source both parse it, and the first to finish is kept.
+	tmpl := template.New("dynamic").Funcs(c.funcs)
+	if strict {
+		tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(len(source))
+	if c.maxEntries <= 0 || size > c.maxBytes {
+		return tmpl, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*cachedTemplate).template, nil
+	}
+	c.entries[key] = c.order.PushFront(&cachedTemplate{key: key, size: size, template: tmpl})
+	c.stats.Entries++
+	c.stats.Bytes += size
+	for c.stats.Entries > c.maxEntries || c.stats.Bytes > c.maxBytes {
+		c.evict()
+	}
+	return tmpl, nil
+}
+
+// evict removes the least recently used template; c.mu must be held.
+func (c *TemplateCache) evict() {
+	e := c.order.Back()
+	entry := c.order.Remove(e).(*cachedTemplate)
+	delete(c.entries, entry.key)
+	c.stats.Entries--
+	c.stats.Bytes -= entry.size
+	c.stats.Evictions++
+}
+
+// Stats reports the cache's size and its hits, misses and evictions.
+func (c *TemplateCache) Stats() models.TemplateCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateCache_Parse(t *testing.T) {
+	cache := NewTemplateCache(10, 1<<20)
+
+	first, err := cache.Parse(`<p>{{.total | formatNumber}}</p>`, false)
+	require.NoError(t, err)
+	second, err := cache.Parse(`<p>{{.total | formatNumber}}</p>`, false)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	var out bytes.Buffer
+	require.NoError(t, second.Execute(&out, map[string]interface{}{"total": 1500}))
+	assert.Equal(t, "<p>1,500</p>", out.String())
+
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(32), stats.Bytes)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 10, stats.MaxEntries)
+}
+
+func TestTemplateCache_Strict(t *testing.T) {
+	cache := NewTemplateCache(10, 1<<20)
+	lenient, err := cache.Parse("<p>{{.name}}</p>", false)
+	require.NoError(t, err)
+	strict, err := cache.Parse("<p>{{.name}}</p>", true)
+	require.NoError(t, err)
+	assert.NotSame(t, lenient, strict)
+
+	var out bytes.Buffer
+	assert.NoError(t, lenient.Execute(&out, map[string]interface{}{}))
+	assert.Error(t, strict.Execute(&out, map[string]interface{}{}))
+	assert.Equal(t, int64(2), cache.Stats().Misses)
+}
+
+func TestTemplateCache_ParseError(t *testing.T) {
+	cache := NewTemplateCache(10, 1<<20)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.Parse("{{.Name", false)
+		assert.Error(t, err)
+	}
+	stats := cache.Stats()
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, int64(2), stats.Misses)
+}
+
+func TestTemplateCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewTemplateCache(2, 1<<20)
+	a, _ := cache.Parse("<p>a</p>", false)
+	_, _ = cache.Parse("<p>b</p>", false)
+	_, _ = cache.Parse("<p>a</p>", false) // a is now the most recently used
+	_, _ = cache.Parse("<p>c</p>", false) // evicts b
+
+	again, err := cache.Parse("<p>a</p>", false)
+	require.NoError(t, err)
+	assert.Same(t, a, again)
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(1), stats.Evictions)
+
+	_, _ = cache.Parse("<p>b</p>", false)
+	assert.Equal(t, int64(4), cache.Stats().Misses)
+}
+
+func TestTemplateCache_ByteLimit(t *testing.T) {
+	cache := NewTemplateCache(10, 20)
+	_, _ = cache.Parse("<p>0123456</p>", false) // 14 bytes
+	_, _ = cache.Parse("<p>789</p>", false)     // 10 bytes, evicts the first
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(10), stats.Bytes)
+
+	// A template larger than the limit is parsed but not cached.
+	tmpl, err := cache.Parse("<p>"+strings.Repeat("x", 30)+"</p>", false)
+	require.NoError(t, err)
+	assert.NotNil(t, tmpl)
+	assert.Equal(t, 1, cache.Stats().Entries)
+}
+
+func TestTemplateCache_Disabled(t *testing.T) {
+	cache := NewTemplateCache(0, 1<<20)
+	first, err := cache.Parse("<p>a</p>", false)
+	require.NoError(t, err)
+	second, err := cache.Parse("<p>a</p>", false)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestTemplateCache_Concurrent(t *testing.T) {
+	cache := NewTemplateCache(4, 1<<20)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tmpl, err := cache.Parse(fmt.Sprintf("<p>%d {{.n}}</p>", i%6), false)
+			if assert.NoError(t, err) {
+				var out bytes.Buffer
+				assert.NoError(t, tmpl.Execute(&out, map[string]int{"n": i}))
+			}
+		}(i)
+	}
+	wg.Wait()
+	stats := cache.Stats()
+	assert.LessOrEqual(t, stats.Entries, 4)
+	assert.Equal(t, int64(16), stats.Hits+stats.Misses)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"pdf-service/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	TemplateErrorParse   = "parse"
+	TemplateErrorExecute = "execute"
+)
+
+// TemplateError is a template that fails to parse or to render, located in
+// its source. It is a client error: the template or the data sent is at
+// fault.
+type TemplateError struct {
+	models.TemplateError
+	err error
+	// partial is set for errors in a partial rather than a template.
+	partial bool
+}
+
+var (
+	// execErrorPattern matches text/template execution errors, such as
+	// template: invoice:2:16: executing "invoice" at <.customer.name>: map
+	// has no entry for key "name".
+	execErrorPattern = regexp.MustCompile(`(?s)^template: ([^:]*):(\d+):(\d+): executing ".*?" at <(.*?)>: (.*)$`)
+	// parseErrorPattern matches text/template parse errors, such as
+	// template: invoice:2: unexpected "}" in operand.
+	parseErrorPattern = regexp.MustCompile(`(?s)^template: ([^:]*):(\d+): (.*)$`)
+	// escapeErrorPattern matches html/template errors, such as
+	// html/template:invoice:1:11: no such template "header".
+	escapeErrorPattern = regexp.MustCompile(`(?s)^html/template:([^:]*)(?::(\d+))?(?::(\d+))?: (.*)$`)
+	fieldPattern       = regexp.MustCompile(`^(\$\w*)?(\.\w+)*\.?$`)
+)
+
+// newTemplateError locates err, returned by parsing or executing a
+// template, and quotes the failing line from the source that source returns
+// for the template's name. kind is the step that failed; errors from
+// html/template, found at the first execution, are reported as parse
+// errors since they lie in the template.
+func newTemplateError(kind string, err error, source func(name string) string) *TemplateError {
+	e := &TemplateError{TemplateError: models.TemplateError{Kind: kind, Message: err.Error()}, err: err}
+	msg := err.Error()
+	if m := execErrorPattern.FindStringSubmatch(msg); m != nil {
+		e.Template, e.Message = m[1], m[5]
+		e.Line, _ = strconv.Atoi(m[2])
+		e.Column, _ = strconv.Atoi(m[3])
+		// The column text/template reports is a 0-based byte offset.
+		e.Column++
+		if fieldPattern.MatchString(m[4]) {
+			e.Field = m[4]
+		}
+	} else if m := parseErrorPattern.FindStringSubmatch(msg); m != nil {
+		e.Template, e.Message = m[1], m[3]
+		e.Line, _ = strconv.Atoi(m[2])
+	} else if m := escapeErrorPattern.FindStringSubmatch(msg); m != nil {
+		e.Kind = TemplateErrorParse
+		e.Template, e.Message = m[1], m[4]
+		e.Line, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			e.Column, _ = strconv.Atoi(m[3])
+			e.Column++
+		}
+	}
+	if source != nil {
+		e.quote(source(e.Template))
+	}
+	return e
+}
+
+// quote sets Source to the line of source the error is on.
+func (e *TemplateError) quote(source string) {
+	if e.Line < 1 || source == "" {
+		return
+	}
+	lines := strings.Split(source, "\n")
+	if e.Line <= len(lines) {
+		e.Source = strings.TrimRight(lines[e.Line-1], "\r")
+	}
+}
+
+// Summary describes the failed step without locating the error.
+func (e *TemplateError) Summary() string {
+	if e.Kind == TemplateErrorExecute {
+		return "Failed to render template"
+	}
+	if e.partial {
+		return "Invalid partial"
+	}
+	return "Invalid template"
+}
+
+func (e *TemplateError) Error() string {
+	if e.err == nil {
+		return e.Summary() + ": " + e.Message
+	}
+	return e.Summary() + ": " + e.err.Error()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.err
+}
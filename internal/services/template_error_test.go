@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"pdf-service/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTemplateError(t *testing.T) {
+	funcs := template.FuncMap{"fail": func(int) (string, error) { return "", errors.New("bad value") }}
+	tests := []struct {
+		name   string
+		source string
+		data   interface{}
+		want   models.TemplateError
+	}{
+		{
+			name:   "parse",
+			source: "<p>\n  {{.name}\n</p>",
+			want: models.TemplateError{
+				Kind: TemplateErrorParse, Template: "doc", Line: 2,
+				Source: "  {{.name}", Message: `bad character U+007D '}'`,
+			},
+		},
+		{
+			name:   "missing key",
+			source: "<p>\n  {{.customer.name}}\n</p>",
+			data:   map[string]interface{}{"customer": map[string]interface{}{}},
+			want: models.TemplateError{
+				Kind: TemplateErrorExecute, Template: "doc", Line: 2, Column: 14,
+				Source: "  {{.customer.name}}", Field: ".customer.name", Message: `map has no entry for key "name"`,
+			},
+		},
+		{
+			name:   "function",
+			source: "<p>{{fail 1}}</p>",
+			want: models.TemplateError{
+				Kind: TemplateErrorExecute, Template: "doc", Line: 1, Column: 6,
+				Source: "<p>{{fail 1}}</p>", Message: "error calling fail: bad value",
+			},
+		},
+		{
+			name:   "escaping",
+			source: `<p>{{template "missing"}}</p>`,
+			want: models.TemplateError{
+				Kind: TemplateErrorParse, Template: "doc", Line: 1, Column: 15,
+				Source: `<p>{{template "missing"}}</p>`, Message: `no such template "missing"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := func(string) string { return tt.source }
+			tmpl, err := template.New("doc").Option("missingkey=error").Funcs(funcs).Parse(tt.source)
+			kind := TemplateErrorParse
+			if err == nil {
+				err = tmpl.Execute(io.Discard, tt.data)
+				kind = TemplateErrorExecute
+			}
+			require.Error(t, err)
+			tmplErr := newTemplateError(kind, err, source)
+			assert.Equal(t, tt.want, tmplErr.TemplateError)
+			assert.ErrorIs(t, tmplErr, err)
+		})
+	}
+}
+
+func TestNewTemplateError_Unlocated(t *testing.T) {
+	err := newTemplateError(TemplateErrorExecute, errors.New("write failed"), nil)
+	assert.Equal(t, models.TemplateError{Kind: TemplateErrorExecute, Message: "write failed"}, err.TemplateError)
+	assert.Equal(t, "Failed to render template: write failed", err.Error())
+}
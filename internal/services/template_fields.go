@@ -0,0 +1,255 @@
+package services
+
+import (
+	"html/template"
+	"pdf-service/internal/models"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// escaperPrefix starts the names of the functions html/template inserts
+// into a template's tree when it is first executed.
+const escaperPrefix = "_html_template_"
+
+// InspectTemplate walks the parse tree of tmpl, following {{template}} and
+// {{block}} calls into the templates they name, and reports the data paths
+// it reads and the functions it calls. Paths are tracked through the dot
+// changes of {{with}} and {{range}} and through variables; fields of values
+// computed by functions cannot be traced and are left out.
+func InspectTemplate(tmpl *template.Template) *models.TemplateFields {
+	w := &fieldWalker{
+		root:      &fieldNode{children: map[string]*fieldNode{}},
+		functions: map[string]bool{},
+		visited:   map[templateCall]bool{},
+		active:    map[string]bool{},
+		tmpl:      tmpl,
+	}
+	if tmpl.Tree != nil {
+		w.walk(tmpl.Tree.Root, w.root, map[string]*fieldNode{"$": w.root})
+	}
+
+	result := &models.TemplateFields{Fields: []models.TemplateField{}, Functions: []string{}}
+	w.root.collect("", &result.Fields)
+	for name := range w.functions {
+		result.Functions = append(result.Functions, name)
+	}
+	sort.Strings(result.Functions)
+	return result
+}
+
+// fieldNode is a data path read by a template, with the keys read below it.
+type fieldNode struct {
+	children map[string]*fieldNode
+	order    []string
+	list     bool
+	// elem describes the elements of a list.
+	elem *fieldNode
+}
+
+func (n *fieldNode) child(key string) *fieldNode {
+	c, ok := n.children[key]
+	if !ok {
+		c = &fieldNode{children: map[string]*fieldNode{}}
+		n.children[key] = c
+		n.order = append(n.order, key)
+	}
+	return c
+}
+
+func (n *fieldNode) element() *fieldNode {
+	n.list = true
+	if n.elem == nil {
+		n.elem = &fieldNode{children: map[string]*fieldNode{}}
+	}
+	return n.elem
+}
+
+// collect appends the paths below n, prefixed by path, in the order the
+// template first reads them.
+func (n *fieldNode) collect(path string, fields *[]models.TemplateField) {
+	for _, key := range n.order {
+		c := n.children[key]
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+		*fields = append(*fields, models.TemplateField{Path: p, Type: c.kind()})
+		c.collect(p, fields)
+		if c.elem != nil {
+			c.elem.collect(p+"[]", fields)
+		}
+	}
+}
+
+func (n *fieldNode) kind() string {
+	switch {
+	case n.list:
+		return "list"
+	case len(n.children) > 0:
+		return "object"
+	}
+	return "value"
+}
+
+type fieldWalker struct {
+	root      *fieldNode
+	functions map[string]bool
+	// visited holds the templates already walked with a given dot, and
+	// active those being walked, so that recursive templates end.
+	visited map[templateCall]bool
+	active  map[string]bool
+	tmpl    *template.Template
+}
+
+type templateCall struct {
+	name string
+	dot  *fieldNode
+}
+
+// walk records the fields read by node, with dot the path of {{.}} (nil
+// when it cannot be traced) and vars the paths of the variables in scope.
+func (w *fieldWalker) walk(node parse.Node, dot *fieldNode, vars map[string]*fieldNode) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			w.walk(c, dot, vars)
+		}
+	case *parse.ActionNode:
+		w.pipe(n.Pipe, dot, vars)
+	case *parse.IfNode:
+		w.pipe(n.Pipe, dot, vars)
+		w.walk(n.List, dot, vars)
+		w.walk(n.ElseList, dot, vars)
+	case *parse.WithNode:
+		inner := w.pipe(n.Pipe, dot, vars)
+		w.walk(n.List, inner, vars)
+		w.walk(n.ElseList, dot, vars)
+	case *parse.RangeNode:
+		var elem *fieldNode
+		if list := w.value(n.Pipe, dot, vars); list != nil {
+			elem = list.element()
+		}
+		scope := vars
+		if len(n.Pipe.Decl) > 0 {
+			scope = copyVars(vars)
+			// With two variables the first is the index or key.
+			last := n.Pipe.Decl[len(n.Pipe.Decl)-1]
+			scope[last.Ident[0]] = elem
+			if len(n.Pipe.Decl) == 2 {
+				scope[n.Pipe.Decl[0].Ident[0]] = nil
+			}
+		}
+		w.walk(n.List, elem, scope)
+		w.walk(n.ElseList, dot, vars)
+	case *parse.TemplateNode:
+		// A template called without a pipeline has no dot.
+		var inner *fieldNode
+		if n.Pipe != nil {
+			inner = w.pipe(n.Pipe, dot, vars)
+		}
+		w.template(n.Name, inner)
+	}
+}
+
+// template walks the template called name with dot.
+func (w *fieldWalker) template(name string, dot *fieldNode) {
+	t := w.tmpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return
+	}
+	call := templateCall{name, dot}
+	if w.visited[call] || w.active[name] {
+		return
+	}
+	w.visited[call] = true
+	w.active[name] = true
+	defer delete(w.active, name)
+	w.walk(t.Tree.Root, dot, map[string]*fieldNode{"$": dot})
+}
+
+// pipe records the fields read by a pipeline, assigns its declared
+// variables and returns the path of its value.
+func (w *fieldWalker) pipe(p *parse.PipeNode, dot *fieldNode, vars map[string]*fieldNode) *fieldNode {
+	v := w.value(p, dot, vars)
+	// Variables go out of scope at the end of the enclosing control
+	// structure; keeping them longer only matters for templates that
+	// redeclare a name with another meaning.
+	for _, d := range p.Decl {
+		vars[d.Ident[0]] = v
+	}
+	return v
+}
+
+// value records the fields read by a pipeline and returns the path of its
+// value, when the pipeline is a single field, variable or dot.
+func (w *fieldWalker) value(p *parse.PipeNode, dot *fieldNode, vars map[string]*fieldNode) *fieldNode {
+	if p == nil {
+		return nil
+	}
+	var v *fieldNode
+	for _, cmd := range p.Cmds {
+		v = w.command(cmd, dot, vars)
+	}
+	if len(p.Cmds) != 1 {
+		return nil
+	}
+	return v
+}
+
+// command records the fields and functions of cmd and returns the path of
+// its value when it is a single field, variable or dot.
+func (w *fieldWalker) command(cmd *parse.CommandNode, dot *fieldNode, vars map[string]*fieldNode) *fieldNode {
+	var v *fieldNode
+	for i, arg := range cmd.Args {
+		v = w.arg(arg, dot, vars)
+		if i == 0 {
+			if id, ok := arg.(*parse.IdentifierNode); ok && !strings.HasPrefix(id.Ident, escaperPrefix) {
+				w.functions[id.Ident] = true
+			}
+		}
+	}
+	if len(cmd.Args) != 1 {
+		return nil
+	}
+	return v
+}
+
+// arg records the fields read by an argument and returns its path.
+func (w *fieldWalker) arg(node parse.Node, dot *fieldNode, vars map[string]*fieldNode) *fieldNode {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return w.path(dot, n.Ident)
+	case *parse.VariableNode:
+		return w.path(vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		return w.path(w.arg(n.Node, dot, vars), n.Field)
+	case *parse.PipeNode:
+		return w.value(n, dot, vars)
+	}
+	return nil
+}
+
+// path records the keys read below n and returns the last one.
+func (w *fieldWalker) path(n *fieldNode, keys []string) *fieldNode {
+	if n == nil {
+		return nil
+	}
+	for _, key := range keys {
+		n = n.child(key)
+	}
+	return n
+}
+
+func copyVars(vars map[string]*fieldNode) map[string]*fieldNode {
+	c := make(map[string]*fieldNode, len(vars)+2)
+	for k, v := range vars {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"html/template"
+	"pdf-service/internal/models"
+	"pdf-service/internal/templatefuncs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inspectSource(t *testing.T, source string) *models.TemplateFields {
+	t.Helper()
+	tmpl, err := template.New("test").Funcs(templatefuncs.FuncMap()).Parse(source)
+	require.NoError(t, err)
+	return InspectTemplate(tmpl)
+}
+
+func TestInspectTemplate(t *testing.T) {
+	fields := inspectSource(t, `
+<h1>{{.customer_name}}</h1>
+<p>{{.customer.address.city | title}}</p>
+{{if .paid}}<b>PAID</b>{{end}}
+{{range .items}}<tr><td>{{.name}}</td><td>{{.price | formatCurrency $.currency}}</td></tr>{{end}}
+{{with .bank}}{{.name}} {{.branch}}{{end}}
+{{range $i, $row := .rows}}{{$i}} {{$row.label}}{{end}}
+{{$total := .totals.grand}}{{$total | formatNumber}}
+{{len .tags}} {{printf "%s" (.note)}}`)
+
+	assert.Equal(t, []models.TemplateField{
+		{Path: "customer_name", Type: "value"},
+		{Path: "customer", Type: "object"},
+		{Path: "customer.address", Type: "object"},
+		{Path: "customer.address.city", Type: "value"},
+		{Path: "paid", Type: "value"},
+		{Path: "items", Type: "list"},
+		{Path: "items[].name", Type: "value"},
+		{Path: "items[].price", Type: "value"},
+		{Path: "currency", Type: "value"},
+		{Path: "bank", Type: "object"},
+		{Path: "bank.name", Type: "value"},
+		{Path: "bank.branch", Type: "value"},
+		{Path: "rows", Type: "list"},
+		{Path: "rows[].label", Type: "value"},
+		{Path: "totals", Type: "object"},
+		{Path: "totals.grand", Type: "value"},
+		{Path: "tags", Type: "value"},
+		{Path: "note", Type: "value"},
+	}, fields.Fields)
+	assert.Equal(t, []string{"formatCurrency", "formatNumber", "len", "printf", "title"}, fields.Functions)
+}
+
+func TestInspectTemplate_NestedRanges(t *testing.T) {
+	fields := inspectSource(t, `{{range .orders}}{{.id}}{{range .lines}}{{.sku}}{{end}}{{end}}`)
+
+	assert.Equal(t, []models.TemplateField{
+		{Path: "orders", Type: "list"},
+		{Path: "orders[].id", Type: "value"},
+		{Path: "orders[].lines", Type: "list"},
+		{Path: "orders[].lines[].sku", Type: "value"},
+	}, fields.Fields)
+}
+
+func TestInspectTemplate_FollowsTemplates(t *testing.T) {
+	store := NewTemplateStore()
+	_, _, err := store.RegisterPartial("layout", `<h1>{{.bank_name}}</h1>{{template "brand_header" .header}}{{block "content" .}}{{end}}`)
+	require.NoError(t, err)
+	_, _, err = store.RegisterPartial("brand_header", `<img src="{{.logo}}">`)
+	require.NoError(t, err)
+	_, _, err = store.Register("invoice", `{{template "layout" .}}{{define "content"}}{{.total}}{{with .parent}}{{template "content" .}}{{end}}{{end}}`)
+	require.NoError(t, err)
+	tmpl, _, err := store.Get("invoice", LatestVersion)
+	require.NoError(t, err)
+
+	// Inspecting an executed template ignores the escaping functions
+	// html/template adds.
+	require.NoError(t, tmpl.Execute(new(discard), map[string]interface{}{}))
+
+	fields := InspectTemplate(tmpl)
+	assert.Equal(t, []models.TemplateField{
+		{Path: "bank_name", Type: "value"},
+		{Path: "header", Type: "object"},
+		{Path: "header.logo", Type: "value"},
+		{Path: "total", Type: "value"},
+		{Path: "parent", Type: "value"},
+	}, fields.Fields)
+	assert.Empty(t, fields.Functions)
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
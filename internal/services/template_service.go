@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"pdf-service/internal/models"
+	"pdf-service/internal/templatefuncs"
+	"strconv"
+	"strings"
+)
+
+type TemplateServiceInterface interface {
+	ListTemplates() []models.TemplateInfo
+	RegisterTemplate(id, source string) (*models.TemplateVersion, bool, error)
+	ListVersions(id string) ([]models.TemplateVersion, error)
+	RollbackTemplate(id string, version int) (*models.TemplateVersion, error)
+	PinVersion(id string, version int, pinned bool) (*models.TemplateVersion, error)
+	RenderTemplate(id string, version int, data map[string]interface{}) (*models.PDFResult, error)
+	RenderTemplateHTML(id string, version int, data map[string]interface{}, assetBaseURL string) (*models.HTMLResult, error)
+	ListPartials() []models.PartialInfo
+	RegisterPartial(name, source string) (*models.PartialInfo, bool, error)
+	TemplateFields(source string) (*models.TemplateFields, error)
+	RegisteredTemplateFields(id string, version int) (*models.TemplateFields, error)
+	TemplateSchema(id string) (json.RawMessage, error)
+	SetTemplateSchema(id string, source []byte) error
+	DeleteTemplateSchema(id string) error
+}
+
+// TemplateService renders templates registered on the server, so callers
+// send only the data.
+type TemplateService struct {
+	store      *TemplateStore
+	pdfService PDFServiceInterface
+}
+
+func NewTemplateService(store *TemplateStore, pdfService PDFServiceInterface) *TemplateService {
+	return &TemplateService{store: store, pdfService: pdfService}
+}
+
+func (s *TemplateService) ListTemplates() []models.TemplateInfo {
+	return s.store.List()
+}
+
+// RegisterTemplate adds source as the latest version of id. created is
+// false when source is unchanged from the latest version.
+func (s *TemplateService) RegisterTemplate(id, source string) (*models.TemplateVersion, bool, error) {
+	return s.store.Register(id, source)
+}
+
+func (s *TemplateService) ListVersions(id string) ([]models.TemplateVersion, error) {
+	return s.store.Versions(id)
+}
+
+func (s *TemplateService) RollbackTemplate(id string, version int) (*models.TemplateVersion, error) {
+	return s.store.Rollback(id, version)
+}
+
+// PinVersion pins or unpins a version of id, keeping it or letting it be
+// dropped as newer versions are added.
+func (s *TemplateService) PinVersion(id string, version int, pinned bool) (*models.TemplateVersion, error) {
+	return s.store.Pin(id, version, pinned)
+}
+
+// RenderTemplate generates a PDF from a version of the template registered
+// under id, the latest for LatestVersion, after checking data against the
+// template's JSON Schema if it has one. The version used is returned in the
+// result and written to the PDF's document information.
+func (s *TemplateService) RenderTemplate(id string, version int, data map[string]interface{}) (*models.PDFResult, error) {
+	req, info, err := s.renderRequest(id, version, data)
+	if err != nil {
+		return nil, err
+	}
+	req.Metadata = map[string]string{
+		"TemplateID":      info.ID,
+		"TemplateVersion": strconv.Itoa(info.Version),
+	}
+	result, err := s.pdfService.GeneratePDF(req)
+	if err != nil {
+		return nil, s.store.locate(err, id, info.Version)
+	}
+	result.Template = info
+	return result, nil
+}
+
+// RenderTemplateHTML renders a version of the template registered under id
+// like RenderTemplate, but returns the HTML instead of converting it. With
+// an assetBaseURL, relative asset references are rewritten against it.
+func (s *TemplateService) RenderTemplateHTML(id string, version int, data map[string]interface{}, assetBaseURL string) (*models.HTMLResult, error) {
+	req, info, err := s.renderRequest(id, version, data)
+	if err != nil {
+		return nil, err
+	}
+	req.AssetBaseURL = assetBaseURL
+	result, err := s.pdfService.RenderHTML(req)
+	if err != nil {
+		return nil, s.store.locate(err, id, info.Version)
+	}
+	result.Template = info
+	return result, nil
+}
+
+// renderRequest looks up a version of id and its schema.
+func (s *TemplateService) renderRequest(id string, version int, data map[string]interface{}) (*models.PDFRequest, *models.TemplateVersion, error) {
+	tmpl, info, err := s.store.Get(id, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, schema, err := s.store.Schema(id)
+	if err != nil && !errors.Is(err, ErrSchemaNotFound) {
+		return nil, nil, err
+	}
+	return &models.PDFRequest{Template: tmpl, Data: data, Schema: schema}, info, nil
+}
+
+func (s *TemplateService) ListPartials() []models.PartialInfo {
+	return s.store.Partials()
+}
+
+// RegisterPartial adds a version of the partial name, and a version of every
+// template using it; pinned template versions keep their partials. created
+// is false when the partial already existed.
+func (s *TemplateService) RegisterPartial(name, source string) (*models.PartialInfo, bool, error) {
+	return s.store.RegisterPartial(name, source)
+}
+
+// TemplateFields reports the data fields and functions used by an uploaded
+// template.
+func (s *TemplateService) TemplateFields(source string) (*models.TemplateFields, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, ErrEmptyHTMLTemplate
+	}
+	tmpl, err := template.New("dynamic").Funcs(templatefuncs.FuncMap()).Parse(source)
+	if err != nil {
+		return nil, newTemplateError(TemplateErrorParse, err, func(string) string { return source })
+	}
+	return InspectTemplate(tmpl), nil
+}
+
+// RegisteredTemplateFields reports the data fields and functions used by a
+// version of a registered template, including those of the partials it
+// includes.
+func (s *TemplateService) RegisteredTemplateFields(id string, version int) (*models.TemplateFields, error) {
+	tmpl, _, err := s.store.Get(id, version)
+	if err != nil {
+		return nil, err
+	}
+	return InspectTemplate(tmpl), nil
+}
+
+func (s *TemplateService) TemplateSchema(id string) (json.RawMessage, error) {
+	source, _, err := s.store.Schema(id)
+	return source, err
+}
+
+// SetTemplateSchema sets the JSON Schema that data rendered with the
+// template id must match.
+func (s *TemplateService) SetTemplateSchema(id string, source []byte) error {
+	return s.store.SetSchema(id, source)
+}
+
+func (s *TemplateService) DeleteTemplateSchema(id string) error {
+	return s.store.DeleteSchema(id)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"pdf-service/internal/pdf"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateService_RenderTemplate(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	store := NewTemplateStore()
+	service := NewTemplateService(store, NewPDFService(chromedpClient))
+
+	_, created, err := service.RegisterTemplate("greeting", "<p>Hello {{.name}}</p>")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Len(t, service.ListTemplates(), 1)
+
+	chromedpClient.On("GeneratePDF", "<p>Hello Sara</p>").Return(samplePDF(t, 1), nil)
+	result, err := service.RenderTemplate("greeting", LatestVersion, map[string]interface{}{"name": "Sara"})
+	require.NoError(t, err)
+	require.NotNil(t, result.Template)
+	assert.Equal(t, "greeting", result.Template.ID)
+	assert.Equal(t, 1, result.Template.Version)
+	chromedpClient.AssertExpectations(t)
+
+	// The version used is recorded in the document information.
+	doc, err := pdf.Parse(result.Content)
+	require.NoError(t, err)
+	info := doc.Info(false)
+	assert.Equal(t, "greeting", pdf.DecodeTextString(doc.GetString(info["TemplateID"])))
+	assert.Equal(t, "1", pdf.DecodeTextString(doc.GetString(info["TemplateVersion"])))
+}
+
+func TestTemplateService_RenderPinnedVersion(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(chromedpClient))
+	_, _, err := service.RegisterTemplate("greeting", "<p>Hello {{.name}}</p>")
+	require.NoError(t, err)
+	_, _, err = service.RegisterTemplate("greeting", "<p>Hi {{.name}}</p>")
+	require.NoError(t, err)
+
+	chromedpClient.On("GeneratePDF", "<p>Hello Sara</p>").Return(samplePDF(t, 1), nil)
+	result, err := service.RenderTemplate("greeting", 1, map[string]interface{}{"name": "Sara"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Template.Version)
+	chromedpClient.AssertExpectations(t)
+
+	// Rolling back makes the first source the latest again.
+	restored, err := service.RollbackTemplate("greeting", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, restored.Version)
+	versions, err := service.ListVersions("greeting")
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+
+	chromedpClient.On("GeneratePDF", mock.AnythingOfType("string")).Return(samplePDF(t, 1), nil)
+	result, err = service.RenderTemplate("greeting", LatestVersion, map[string]interface{}{"name": "Ali"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Template.Version)
+	chromedpClient.AssertCalled(t, "GeneratePDF", "<p>Hello Ali</p>")
+}
+
+func TestTemplateService_RenderUnknownTemplate(t *testing.T) {
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(&MockChromedpClient{}))
+
+	_, err := service.RenderTemplate("missing", LatestVersion, map[string]interface{}{})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateService_RenderNilData(t *testing.T) {
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(&MockChromedpClient{}))
+	_, _, err := service.RegisterTemplate("greeting", "<p>Hello</p>")
+	require.NoError(t, err)
+
+	_, err = service.RenderTemplate("greeting", LatestVersion, nil)
+	assert.Equal(t, ErrNilData, err)
+}
+
+func TestTemplateService_TemplateFields(t *testing.T) {
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(&MockChromedpClient{}))
+
+	fields, err := service.TemplateFields(`{{.customer_name}} {{.total | formatNumber}}`)
+	require.NoError(t, err)
+	assert.Len(t, fields.Fields, 2)
+	assert.Equal(t, []string{"formatNumber"}, fields.Functions)
+
+	_, err = service.TemplateFields("  ")
+	assert.Equal(t, ErrEmptyHTMLTemplate, err)
+	_, err = service.TemplateFields("{{.Name")
+	assert.ErrorContains(t, err, "Invalid template")
+
+	_, _, err = service.RegisterTemplate("greeting", "<p>{{.name}}</p>")
+	require.NoError(t, err)
+	fields, err = service.RegisteredTemplateFields("greeting", LatestVersion)
+	require.NoError(t, err)
+	assert.Equal(t, "name", fields.Fields[0].Path)
+	_, err = service.RegisteredTemplateFields("greeting", 2)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateService_RenderWithSchema(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(chromedpClient))
+	_, _, err := service.RegisterTemplate("greeting", "<p>Hello {{.name}}</p>")
+	require.NoError(t, err)
+	require.NoError(t, service.SetTemplateSchema("greeting", []byte(`{"required": ["name"], "properties": {"name": {"type": "string"}}}`)))
+
+	_, err = service.RenderTemplate("greeting", LatestVersion, map[string]interface{}{"name": 1})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "/name", validationErr.Violations[0].Pointer)
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+
+	chromedpClient.On("GeneratePDF", "<p>Hello Sara</p>").Return(samplePDF(t, 1), nil)
+	_, err = service.RenderTemplate("greeting", LatestVersion, map[string]interface{}{"name": "Sara"})
+	assert.NoError(t, err)
+
+	source, err := service.TemplateSchema("greeting")
+	require.NoError(t, err)
+	assert.Contains(t, string(source), `"required"`)
+	require.NoError(t, service.DeleteTemplateSchema("greeting"))
+	_, err = service.TemplateSchema("greeting")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
+
+func TestTemplateService_RenderTemplateError(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(chromedpClient))
+	_, _, err := service.RegisterPartial("rows", "<table>\n{{range .items}}<tr></tr>{{end}}\n</table>")
+	require.NoError(t, err)
+	_, _, err = service.RegisterTemplate("invoice", `<h1>Invoice</h1>{{template "rows" .}}`)
+	require.NoError(t, err)
+
+	_, err = service.RenderTemplate("invoice", LatestVersion, map[string]interface{}{"items": 5.5})
+	var tmplErr *TemplateError
+	require.ErrorAs(t, err, &tmplErr)
+	// The error lies in the partial, whose line is quoted.
+	assert.Equal(t, TemplateErrorExecute, tmplErr.Kind)
+	assert.Equal(t, "rows", tmplErr.Template)
+	assert.Equal(t, 2, tmplErr.Line)
+	assert.Equal(t, "{{range .items}}<tr></tr>{{end}}", tmplErr.Source)
+	assert.Equal(t, ".items", tmplErr.Field)
+	assert.Equal(t, "range can't iterate over 5.5", tmplErr.Message)
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
+
+func TestTemplateService_RenderTemplateHTML(t *testing.T) {
+	chromedpClient := &MockChromedpClient{}
+	service := NewTemplateService(NewTemplateStore(), NewPDFService(chromedpClient))
+	_, _, err := service.RegisterTemplate("greeting", `<img src="logo.png"><p>Hello {{.name}}</p>`)
+	require.NoError(t, err)
+	require.NoError(t, service.SetTemplateSchema("greeting", []byte(`{"required": ["name"]}`)))
+
+	result, err := service.RenderTemplateHTML("greeting", LatestVersion, map[string]interface{}{"name": "Sara"}, "https://cdn.example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, `<img src="https://cdn.example.com/logo.png"><p>Hello Sara</p>`, result.HTML)
+	assert.Equal(t, 1, result.Template.Version)
+
+	_, err = service.RenderTemplateHTML("greeting", LatestVersion, map[string]interface{}{}, "")
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.RenderTemplateHTML("missing", LatestVersion, map[string]interface{}{}, "")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	chromedpClient.AssertNotCalled(t, "GeneratePDF", mock.Anything)
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"pdf-service/internal/jsonschema"
+	"pdf-service/internal/models"
+	"pdf-service/internal/templatefuncs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template/parse"
+	"time"
+)
+
+// ErrTemplateNotFound is returned for template IDs that are not registered
+// and for versions a template does not have.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrSchemaNotFound is returned for templates without a JSON Schema.
+var ErrSchemaNotFound = errors.New("schema not found")
+
+var ErrInvalidTemplateID = &AppError{Message: "Template ID must be 1 to 64 letters, digits, hyphens or underscores"}
+
+var ErrInvalidPartialName = &AppError{Message: "Partial name must be 1 to 64 letters, digits, hyphens or underscores"}
+
+var ErrRollbackVersion = &AppError{Message: "Rollback requires a version number"}
+
+var templateIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// LatestVersion selects the newest version of a template.
+const LatestVersion = 0
+
+// DefaultTemplateVersions is the number of recent versions of a template
+// kept besides the pinned ones.
+const DefaultTemplateVersions = 50
+
+// TemplateStore holds templates registered under an ID. Every change to a
+// template adds an immutable version, so documents can be reproduced with
+// the version they were made from. Each version is parsed once, when
+// registered, and may then be executed concurrently. Only the newest
+// versions of a template are kept, with those pinned to be kept for good;
+// older versions are dropped, keeping their numbers unused.
+//
+// Partials are shared by all templates: a header, a set of @font-face rules
+// or a layout defining {{block}}s. Partials are versioned too, and each
+// template version is parsed with the partial versions current when it was
+// added, so a pinned version renders the same whatever partials change
+// later. Changing a partial adds a version of every template that uses it.
+type TemplateStore struct {
+	mu        sync.RWMutex
+	templates map[string][]*templateVersion
+	partials  map[string][]*partial
+	schemas   map[string]*templateSchema
+	// base holds the parsed partials; templates are parsed into a clone.
+	base *template.Template
+	// retain is the number of unpinned versions kept of each template, or
+	// 0 to keep every version.
+	retain int
+}
+
+type templateVersion struct {
+	info     models.TemplateVersion
+	source   string
+	template *template.Template
+}
+
+type partial struct {
+	info   models.PartialInfo
+	source string
+}
+
+// templateSchema is the JSON Schema that data rendered with a template must
+// match, whichever version is rendered.
+type templateSchema struct {
+	source json.RawMessage
+	schema *jsonschema.Schema
+}
+
+func NewTemplateStore() *TemplateStore {
+	return NewTemplateStoreWithRetention(DefaultTemplateVersions)
+}
+
+// NewTemplateStoreWithRetention returns a TemplateStore keeping the newest
+// retain versions of each template besides the pinned ones; 0 keeps every
+// version.
+func NewTemplateStoreWithRetention(retain int) *TemplateStore {
+	return &TemplateStore{
+		templates: make(map[string][]*templateVersion),
+		partials:  make(map[string][]*partial),
+		schemas:   make(map[string]*templateSchema),
+		base:      template.New("").Funcs(templatefuncs.FuncMap()),
+		retain:    retain,
+	}
+}
+
+// Register parses source and adds it as the latest version of id. When
+// source is the same as the latest version, that version is returned and
+// created is false.
+func (s *TemplateStore) Register(id, source string) (version *models.TemplateVersion, created bool, err error) {
+	if !templateIDPattern.MatchString(id) {
+		return nil, false, ErrInvalidTemplateID
+	}
+	if strings.TrimSpace(source) == "" {
+		return nil, false, ErrEmptyHTMLTemplate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.templates[id]
+	if n := len(versions); n > 0 && versions[n-1].source == source {
+		info := versions[n-1].info
+		return &info, false, nil
+	}
+	tmpl, err := parseTemplate(s.base, id, source)
+	if err != nil {
+		return nil, false, err
+	}
+	info := s.add(id, source, tmpl, s.latestPartials()).info
+	return &info, true, nil
+}
+
+// add appends a version of id, parsed as tmpl with the given versions of
+// the partials, and drops the versions no longer retained; s.mu must be
+// held.
+func (s *TemplateStore) add(id, source string, tmpl *template.Template, partials map[string]int) *templateVersion {
+	number := 1
+	if versions := s.templates[id]; len(versions) > 0 {
+		number = versions[len(versions)-1].info.Version + 1
+	}
+	sum := sha256.Sum256([]byte(source))
+	v := &templateVersion{
+		info: models.TemplateVersion{
+			ID:        id,
+			Version:   number,
+			Size:      len(source),
+			SHA256:    hex.EncodeToString(sum[:]),
+			CreatedAt: time.Now().UTC(),
+			Partials:  partials,
+		},
+		source:   source,
+		template: tmpl,
+	}
+	s.templates[id] = append(s.templates[id], v)
+	s.evict(id)
+	return v
+}
+
+// evict drops the versions of id older than the newest s.retain that are
+// not pinned; s.mu must be held.
+func (s *TemplateStore) evict(id string) {
+	versions := s.templates[id]
+	if s.retain <= 0 || len(versions) <= s.retain {
+		return
+	}
+	recent := len(versions) - s.retain
+	kept := make([]*templateVersion, 0, len(versions))
+	for i, v := range versions {
+		if i >= recent || v.info.Pinned {
+			kept = append(kept, v)
+		}
+	}
+	s.templates[id] = kept
+}
+
+// latestPartials maps the name of each partial to its latest version; s.mu
+// must be held.
+func (s *TemplateStore) latestPartials() map[string]int {
+	if len(s.partials) == 0 {
+		return nil
+	}
+	partials := make(map[string]int, len(s.partials))
+	for name, versions := range s.partials {
+		partials[name] = len(versions)
+	}
+	return partials
+}
+
+// parseTemplate parses source as the template id in a copy of the partials
+// in base.
+func parseTemplate(base *template.Template, id, source string) (*template.Template, error) {
+	set, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := set.New(id).Parse(source)
+	if err != nil {
+		return nil, newTemplateError(TemplateErrorParse, err, func(string) string { return source })
+	}
+	return tmpl, nil
+}
+
+// RegisterPartial parses source as the latest version of the partial name
+// and adds a version, with its latest source parsed with it, of every
+// template that calls the partial or a template it defines, directly or
+// through other partials. Other templates, and earlier versions, keep the
+// partials they were parsed with. created is false when a partial of that
+// name already existed.
+func (s *TemplateStore) RegisterPartial(name, source string) (info *models.PartialInfo, created bool, err error) {
+	if !templateIDPattern.MatchString(name) {
+		return nil, false, ErrInvalidPartialName
+	}
+	if strings.TrimSpace(source) == "" {
+		return nil, false, ErrEmptyHTMLTemplate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.partials[name]
+	if n := len(versions); n > 0 && versions[n-1].source == source {
+		info := versions[n-1].info
+		return &info, false, nil
+	}
+
+	sources := map[string]string{name: source}
+	for n, versions := range s.partials {
+		if n != name {
+			sources[n] = versions[len(versions)-1].source
+		}
+	}
+	base, err := parsePartials(sources)
+	if err != nil {
+		return nil, false, err
+	}
+	// The templates the partial defines, in its old version as well as
+	// the new one: a template calling one it no longer defines changes too.
+	defined := definedTemplates(name, source)
+	if n := len(versions); n > 0 {
+		for t := range definedTemplates(name, versions[n-1].source) {
+			defined[t] = true
+		}
+	}
+	// Every template is parsed before any version is added, so that a
+	// partial breaking one template leaves the store unchanged.
+	parsed := make(map[string]*template.Template)
+	for id, versions := range s.templates {
+		tmpl, err := parseTemplate(base, id, versions[len(versions)-1].source)
+		if err != nil {
+			return nil, false, &AppError{Message: fmt.Sprintf("Invalid partial: template %s: %v", id, err)}
+		}
+		for t := range calledTemplates(tmpl) {
+			if defined[t] {
+				parsed[id] = tmpl
+				break
+			}
+		}
+	}
+
+	p := &partial{
+		info:   models.PartialInfo{Name: name, Version: len(versions) + 1, Size: len(source), UpdatedAt: time.Now().UTC()},
+		source: source,
+	}
+	s.partials[name] = append(versions, p)
+	s.base = base
+	partials := s.latestPartials()
+	for id, tmpl := range parsed {
+		versions := s.templates[id]
+		s.add(id, versions[len(versions)-1].source, tmpl, partials)
+	}
+	partialInfo := p.info
+	return &partialInfo, len(versions) == 0, nil
+}
+
+// definedTemplates returns the names of the templates the partial name
+// defines: itself and those of its {{define}} and {{block}} actions.
+func definedTemplates(name, source string) map[string]bool {
+	defined := map[string]bool{name: true}
+	if set, err := template.New(name).Funcs(templatefuncs.FuncMap()).Parse(source); err == nil {
+		for _, t := range set.Templates() {
+			defined[t.Name()] = true
+		}
+	}
+	return defined
+}
+
+// calledTemplates returns the names of the templates tmpl calls with
+// {{template}} or {{block}}, directly or through the templates it calls.
+func calledTemplates(tmpl *template.Template) map[string]bool {
+	called := map[string]bool{}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			if called[n.Name] {
+				return
+			}
+			called[n.Name] = true
+			if t := tmpl.Lookup(n.Name); t != nil && t.Tree != nil {
+				walk(t.Tree.Root)
+			}
+		}
+	}
+	if tmpl.Tree != nil {
+		walk(tmpl.Tree.Root)
+	}
+	return called
+}
+
+// parsePartials parses each source under its name into one set. Names are
+// parsed in order so that, when two partials {{define}} the same template,
+// the result does not depend on map iteration.
+func parsePartials(sources map[string]string) (*template.Template, error) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	base := template.New("").Funcs(templatefuncs.FuncMap())
+	for _, name := range names {
+		if _, err := base.New(name).Parse(sources[name]); err != nil {
+			tmplErr := newTemplateError(TemplateErrorParse, err, func(name string) string { return sources[name] })
+			tmplErr.partial = true
+			return nil, tmplErr
+		}
+	}
+	return base, nil
+}
+
+// SetSchema sets the JSON Schema that data rendered with the template id
+// must match, replacing any previous one.
+func (s *TemplateStore) SetSchema(id string, source []byte) error {
+	schema, err := jsonschema.Parse(source)
+	if err != nil {
+		return &AppError{Message: err.Error()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.templates[id]) == 0 {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	s.schemas[id] = &templateSchema{source: append(json.RawMessage(nil), source...), schema: schema}
+	return nil
+}
+
+// Schema returns the source and parsed JSON Schema of the template id, or
+// ErrSchemaNotFound when it has none.
+func (s *TemplateStore) Schema(id string) (json.RawMessage, *jsonschema.Schema, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.templates[id]) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	ts, ok := s.schemas[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+	return ts.source, ts.schema, nil
+}
+
+// DeleteSchema removes the JSON Schema of the template id.
+func (s *TemplateStore) DeleteSchema(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schemas[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+	delete(s.schemas, id)
+	return nil
+}
+
+// Partials describes the registered partials, ordered by name.
+func (s *TemplateStore) Partials() []models.PartialInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	infos := make([]models.PartialInfo, 0, len(s.partials))
+	for _, versions := range s.partials {
+		infos = append(infos, versions[len(versions)-1].info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Rollback makes an earlier version the latest again by adding a copy of
+// it as a new version; history is never rewritten.
+func (s *TemplateStore) Rollback(id string, version int) (*models.TemplateVersion, error) {
+	if version == LatestVersion {
+		return nil, ErrRollbackVersion
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.find(id, version)
+	if err != nil {
+		return nil, err
+	}
+	// The copy keeps the partials the version was parsed with.
+	v := s.add(id, old.source, old.template, old.info.Partials)
+	v.info.RestoredFrom = old.info.Version
+	info := v.info
+	return &info, nil
+}
+
+// Pin keeps a version of id, the latest for LatestVersion, however many
+// versions are added after it, or with pinned false lets it be dropped
+// again once it is no longer among the newest.
+func (s *TemplateStore) Pin(id string, version int, pinned bool) (*models.TemplateVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.find(id, version)
+	if err != nil {
+		return nil, err
+	}
+	v.info.Pinned = pinned
+	info := v.info
+	s.evict(id)
+	return &info, nil
+}
+
+// find returns a version of id, the latest for LatestVersion; s.mu must be
+// held. Versions dropped from the store are not found.
+func (s *TemplateStore) find(id string, version int) (*templateVersion, error) {
+	versions := s.templates[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	if version == LatestVersion {
+		return versions[len(versions)-1], nil
+	}
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].info.Version >= version })
+	if i == len(versions) || versions[i].info.Version != version {
+		return nil, fmt.Errorf("%w: %s version %d", ErrTemplateNotFound, id, version)
+	}
+	return versions[i], nil
+}
+
+// LoadDir registers every .html file in dir under its name without the
+// extension, so templates/service_request.html becomes service_request.
+// The .html files in dir/partials are registered first, as partials, and
+// a file such as service_request.schema.json sets the JSON Schema of the
+// template of that name.
+func (s *TemplateStore) LoadDir(dir string) error {
+	if err := loadHTML(filepath.Join(dir, "partials"), s.RegisterPartial); err != nil {
+		return err
+	}
+	if err := loadHTML(dir, s.Register); err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.schema.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".schema.json")
+		if err := s.SetSchema(id, source); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func loadHTML[T any](dir string, register func(name, source string) (T, bool, error)) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if _, _, err := register(name, string(source)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Get returns a parsed version of the template registered under id, the
+// latest for LatestVersion.
+func (s *TemplateStore) Get(id string, version int) (*template.Template, *models.TemplateVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, err := s.find(id, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := v.info
+	return v.template, &info, nil
+}
+
+// source returns the source of name as parsed into a version of id: the
+// version's own source, or that of the version of the partial name it was
+// parsed with.
+func (s *TemplateStore) source(id string, version int, name string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, err := s.find(id, version)
+	if err != nil {
+		return ""
+	}
+	if name == id {
+		return v.source
+	}
+	if n, ok := v.info.Partials[name]; ok {
+		return s.partials[name][n-1].source
+	}
+	return ""
+}
+
+// locate quotes the failing line of a template error from rendering a
+// version of id, which the PDF service, knowing only the parsed template,
+// cannot do.
+func (s *TemplateStore) locate(err error, id string, version int) error {
+	var tmplErr *TemplateError
+	if errors.As(err, &tmplErr) && tmplErr.Source == "" {
+		tmplErr.quote(s.source(id, version, tmplErr.Template))
+	}
+	return err
+}
+
+// Versions describes every version of id still kept, oldest first.
+func (s *TemplateStore) Versions(id string) ([]models.TemplateVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := s.templates[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	infos := make([]models.TemplateVersion, len(versions))
+	for i, v := range versions {
+		infos[i] = v.info
+	}
+	return infos, nil
+}
+
+// List describes the registered templates, ordered by ID.
+func (s *TemplateStore) List() []models.TemplateInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	infos := make([]models.TemplateInfo, 0, len(s.templates))
+	for id, versions := range s.templates {
+		latest := versions[len(versions)-1].info
+		infos = append(infos, models.TemplateInfo{
+			ID:            id,
+			LatestVersion: latest.Version,
+			Size:          latest.Size,
+			UpdatedAt:     latest.CreatedAt,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
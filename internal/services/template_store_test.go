@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateStore_Register(t *testing.T) {
+	store := NewTemplateStore()
+
+	info, created, err := store.Register("invoice", `<p>{{.total | formatNumber}}</p>`)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "invoice", info.ID)
+	assert.Equal(t, 1, info.Version)
+	assert.Equal(t, 32, info.Size)
+	assert.Len(t, info.SHA256, 64)
+	assert.False(t, info.CreatedAt.IsZero())
+
+	tmpl, got, err := store.Get("invoice", LatestVersion)
+	require.NoError(t, err)
+	assert.Equal(t, *info, *got)
+	var out bytes.Buffer
+	require.NoError(t, tmpl.Execute(&out, map[string]interface{}{"total": 1500}))
+	assert.Equal(t, "<p>1,500</p>", out.String())
+}
+
+func TestTemplateStore_Versions(t *testing.T) {
+	store := NewTemplateStore()
+	_, _, err := store.Register("invoice", "<p>v1</p>")
+	require.NoError(t, err)
+	v2, created, err := store.Register("invoice", "<p>v2</p>")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 2, v2.Version)
+
+	// Uploading the latest source again adds no version.
+	same, created, err := store.Register("invoice", "<p>v2</p>")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, *v2, *same)
+
+	assert.Equal(t, "<p>v2</p>", execute(t, store, "invoice", LatestVersion))
+	assert.Equal(t, "<p>v1</p>", execute(t, store, "invoice", 1))
+
+	versions, err := store.Versions("invoice")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].Version)
+	assert.NotEqual(t, versions[0].SHA256, versions[1].SHA256)
+	assert.Equal(t, 2, store.List()[0].LatestVersion)
+
+	_, _, err = store.Get("invoice", 3)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	assert.EqualError(t, err, "template not found: invoice version 3")
+	_, err = store.Versions("missing")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateStore_Rollback(t *testing.T) {
+	store := NewTemplateStore()
+	v1, _, err := store.Register("invoice", "<p>v1</p>")
+	require.NoError(t, err)
+	_, _, err = store.Register("invoice", "<p>v2</p>")
+	require.NoError(t, err)
+
+	v3, err := store.Rollback("invoice", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v3.Version)
+	assert.Equal(t, 1, v3.RestoredFrom)
+	assert.Equal(t, v1.SHA256, v3.SHA256)
+	assert.Equal(t, "<p>v1</p>", execute(t, store, "invoice", LatestVersion))
+	// Earlier versions are kept.
+	assert.Equal(t, "<p>v2</p>", execute(t, store, "invoice", 2))
+
+	_, err = store.Rollback("invoice", LatestVersion)
+	assert.Equal(t, ErrRollbackVersion, err)
+	_, err = store.Rollback("invoice", 9)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = store.Rollback("missing", 1)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateStore_Retention(t *testing.T) {
+	store := NewTemplateStoreWithRetention(2)
+	for i := 1; i <= 3; i++ {
+		_, _, err := store.Register("invoice", fmt.Sprintf("<p>v%d</p>", i))
+		require.NoError(t, err)
+	}
+	numbers := func() []int {
+		versions, err := store.Versions("invoice")
+		require.NoError(t, err)
+		var n []int
+		for _, v := range versions {
+			n = append(n, v.Version)
+		}
+		return n
+	}
+	assert.Equal(t, []int{2, 3}, numbers())
+	_, _, err := store.Get("invoice", 1)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = store.Rollback("invoice", 1)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// A pinned version outlives the newer ones; numbers are not reused.
+	info, err := store.Pin("invoice", 2, true)
+	require.NoError(t, err)
+	assert.True(t, info.Pinned)
+	for i := 4; i <= 6; i++ {
+		_, _, err := store.Register("invoice", fmt.Sprintf("<p>v%d</p>", i))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{2, 5, 6}, numbers())
+	assert.Equal(t, "<p>v2</p>", execute(t, store, "invoice", 2))
+	assert.Equal(t, "<p>v6</p>", execute(t, store, "invoice", LatestVersion))
+
+	// Unpinned, it is dropped like any old version.
+	info, err = store.Pin("invoice", 2, false)
+	require.NoError(t, err)
+	assert.False(t, info.Pinned)
+	assert.Equal(t, []int{5, 6}, numbers())
+	_, err = store.Pin("invoice", 2, true)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// Rolling back adds a version like any other.
+	v7, err := store.Rollback("invoice", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 7, v7.Version)
+	assert.Equal(t, []int{6, 7}, numbers())
+
+	// Without a limit every version is kept.
+	store = NewTemplateStoreWithRetention(0)
+	for i := 1; i <= 3; i++ {
+		_, _, err := store.Register("invoice", fmt.Sprintf("<p>v%d</p>", i))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{1, 2, 3}, numbers())
+}
+
+// execute renders a version of a stored template without data.
+func execute(t *testing.T, store *TemplateStore, id string, version int) string {
+	t.Helper()
+	tmpl, _, err := store.Get(id, version)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, tmpl.Execute(&out, nil))
+	return out.String()
+}
+
+func TestTemplateStore_Partials(t *testing.T) {
+	store := NewTemplateStore()
+	_, created, err := store.RegisterPartial("layout", `<html><title>{{block "title" .}}Document{{end}}</title>{{template "brand_header" .}}{{block "content" .}}{{end}}</html>`)
+	require.NoError(t, err)
+	assert.True(t, created)
+	_, _, err = store.RegisterPartial("brand_header", `<header>Bank</header>`)
+	require.NoError(t, err)
+
+	_, _, err = store.Register("invoice", `{{template "layout" .}}{{define "title"}}Invoice{{end}}{{define "content"}}<p>{{.total}}</p>{{end}}`)
+	require.NoError(t, err)
+	_, _, err = store.Register("receipt", `{{template "layout" .}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "<html><title>Invoice</title><header>Bank</header><p></p></html>", execute(t, store, "invoice", LatestVersion))
+	assert.Equal(t, "<html><title>Document</title><header>Bank</header></html>", execute(t, store, "receipt", LatestVersion))
+
+	// Changing a partial adds a version of every template using it;
+	// pinned versions render as before.
+	info, created, err := store.RegisterPartial("brand_header", `<header>New Bank</header>`)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 2, info.Version)
+	assert.Equal(t, "<html><title>Invoice</title><header>Bank</header><p></p></html>", execute(t, store, "invoice", 1))
+	assert.Equal(t, "<html><title>Document</title><header>Bank</header></html>", execute(t, store, "receipt", 1))
+	assert.Equal(t, "<html><title>Invoice</title><header>New Bank</header><p></p></html>", execute(t, store, "invoice", LatestVersion))
+	assert.Equal(t, "<html><title>Document</title><header>New Bank</header></html>", execute(t, store, "receipt", 2))
+	assert.Equal(t, "<header>Bank</header>", store.source("invoice", 1, "brand_header"))
+	assert.Equal(t, "<header>New Bank</header>", store.source("invoice", 2, "brand_header"))
+
+	versions, err := store.Versions("invoice")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, map[string]int{"brand_header": 1, "layout": 1}, versions[0].Partials)
+	assert.Equal(t, map[string]int{"brand_header": 2, "layout": 1}, versions[1].Partials)
+	assert.Equal(t, versions[0].SHA256, versions[1].SHA256)
+
+	// Registering the same partial again changes nothing.
+	_, created, err = store.RegisterPartial("brand_header", `<header>New Bank</header>`)
+	require.NoError(t, err)
+	assert.False(t, created)
+	versions, _ = store.Versions("invoice")
+	assert.Len(t, versions, 2)
+
+	partials := store.Partials()
+	require.Len(t, partials, 2)
+	assert.Equal(t, "brand_header", partials[0].Name)
+	assert.Equal(t, 2, partials[0].Version)
+	assert.Equal(t, 25, partials[0].Size)
+	assert.Equal(t, "layout", partials[1].Name)
+	assert.Equal(t, 1, partials[1].Version)
+
+	// A rollback restores the partials the version was parsed with.
+	v3, err := store.Rollback("invoice", 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"brand_header": 1, "layout": 1}, v3.Partials)
+	assert.Equal(t, "<html><title>Invoice</title><header>Bank</header><p></p></html>", execute(t, store, "invoice", LatestVersion))
+}
+
+func TestTemplateStore_PartialsReversionUsers(t *testing.T) {
+	store := NewTemplateStore()
+	_, _, err := store.RegisterPartial("brand_header", `<header>Bank</header>`)
+	require.NoError(t, err)
+	_, _, err = store.RegisterPartial("layout", `{{template "brand_header" .}}{{block "content" .}}{{end}}`)
+	require.NoError(t, err)
+	_, _, err = store.RegisterPartial("styles", `{{define "fonts"}}@font-face {}{{end}}`)
+	require.NoError(t, err)
+
+	_, _, err = store.Register("direct", `{{template "brand_header" .}}`)
+	require.NoError(t, err)
+	_, _, err = store.Register("layout_user", `{{template "layout" .}}{{define "content"}}<p></p>{{end}}`)
+	require.NoError(t, err)
+	_, _, err = store.Register("defined_user", `<style>{{template "fonts"}}</style>{{if .x}}{{template "brand_header"}}{{end}}`)
+	require.NoError(t, err)
+	_, _, err = store.Register("plain", `<p>{{.x}}</p>`)
+	require.NoError(t, err)
+
+	latest := func(id string) int {
+		versions, err := store.Versions(id)
+		require.NoError(t, err)
+		return len(versions)
+	}
+
+	// brand_header is called directly, through the layout and inside an
+	// {{if}}; plain does not use it.
+	_, _, err = store.RegisterPartial("brand_header", `<header>New Bank</header>`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, latest("direct"))
+	assert.Equal(t, 2, latest("layout_user"))
+	assert.Equal(t, 2, latest("defined_user"))
+	assert.Equal(t, 1, latest("plain"))
+	assert.Equal(t, "<header>New Bank</header><p></p>", execute(t, store, "layout_user", LatestVersion))
+
+	// A template defined by a partial counts as the partial.
+	_, _, err = store.RegisterPartial("styles", `{{define "fonts"}}@font-face { font-family: x }{{end}}`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, latest("direct"))
+	assert.Equal(t, 3, latest("defined_user"))
+	assert.Equal(t, 1, latest("plain"))
+
+	// A template registered later is bound to the latest partials.
+	_, _, err = store.Register("plain", `<p>{{template "brand_header"}}</p>`)
+	require.NoError(t, err)
+	assert.Equal(t, "<p><header>New Bank</header></p>", execute(t, store, "plain", LatestVersion))
+}
+
+func TestTemplateStore_RegisterInvalidPartial(t *testing.T) {
+	store := NewTemplateStore()
+	_, _, err := store.RegisterPartial("brand_header", `<header>Bank</header>`)
+	require.NoError(t, err)
+
+	_, _, err = store.RegisterPartial("../header", "<p></p>")
+	assert.Equal(t, ErrInvalidPartialName, err)
+	_, _, err = store.RegisterPartial("empty", " ")
+	assert.Equal(t, ErrEmptyHTMLTemplate, err)
+	_, _, err = store.RegisterPartial("brand_header", "{{if}}")
+	assert.ErrorContains(t, err, "Invalid partial")
+	// A failed registration keeps the previous partial.
+	_, _, err = store.Register("receipt", `{{template "brand_header" .}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "<header>Bank</header>", execute(t, store, "receipt", LatestVersion))
+}
+
+func TestTemplateStore_Schema(t *testing.T) {
+	store := NewTemplateStore()
+	_, _, err := store.Register("invoice", "<p>{{.total}}</p>")
+	require.NoError(t, err)
+
+	_, _, err = store.Schema("invoice")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+
+	require.NoError(t, store.SetSchema("invoice", []byte(`{"required": ["total"]}`)))
+	source, schema, err := store.Schema("invoice")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"required": ["total"]}`, string(source))
+	assert.Len(t, schema.Validate(map[string]interface{}{}), 1)
+
+	err = store.SetSchema("invoice", []byte(`{"type": "text"}`))
+	assert.IsType(t, &AppError{}, err)
+	assert.ErrorContains(t, err, "invalid JSON schema")
+	// A self-referencing schema would never finish validating.
+	err = store.SetSchema("invoice", []byte(`{"$ref": "#"}`))
+	assert.IsType(t, &AppError{}, err)
+	assert.ErrorContains(t, err, "$ref cycle")
+	assert.ErrorIs(t, store.SetSchema("missing", []byte(`{}`)), ErrTemplateNotFound)
+	_, _, err = store.Schema("missing")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	require.NoError(t, store.DeleteSchema("invoice"))
+	_, _, err = store.Schema("invoice")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+	assert.ErrorIs(t, store.DeleteSchema("invoice"), ErrSchemaNotFound)
+}
+
+func TestTemplateStore_RegisterInvalid(t *testing.T) {
+	store := NewTemplateStore()
+
+	_, _, err := store.Register("../etc", "<p></p>")
+	assert.Equal(t, ErrInvalidTemplateID, err)
+	_, _, err = store.Register("empty", "  ")
+	assert.Equal(t, ErrEmptyHTMLTemplate, err)
+	_, _, err = store.Register("broken", "<p>\n{{.Name")
+	var tmplErr *TemplateError
+	require.ErrorAs(t, err, &tmplErr)
+	assert.Equal(t, "broken", tmplErr.Template)
+	assert.Equal(t, 2, tmplErr.Line)
+	assert.Equal(t, "{{.Name", tmplErr.Source)
+	assert.Contains(t, err.Error(), "Invalid template")
+	assert.Empty(t, store.List())
+}
+
+func TestTemplateStore_GetUnknown(t *testing.T) {
+	_, _, err := NewTemplateStore().Get("missing", LatestVersion)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateStore_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.html"), []byte("<p>b</p>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.html"), []byte("<p>a</p>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "partials"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partials", "header.html"), []byte("<h1>Bank</h1>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.html"), []byte(`{{template "header"}}<p>c</p>`), 0o644))
+
+	store := NewTemplateStore()
+	require.NoError(t, store.LoadDir(dir))
+	list := store.List()
+	require.Len(t, list, 3)
+	assert.Equal(t, "a", list[0].ID)
+	assert.Equal(t, "b", list[1].ID)
+	assert.Len(t, store.Partials(), 1)
+	assert.Equal(t, "<h1>Bank</h1><p>c</p>", execute(t, store, "c", LatestVersion))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.schema.json"), []byte(`{"required": ["name"]}`), 0o644))
+	store = NewTemplateStore()
+	require.NoError(t, store.LoadDir(dir))
+	_, _, err := store.Schema("a")
+	assert.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "z.schema.json"), []byte(`{}`), 0o644))
+	assert.ErrorContains(t, NewTemplateStore().LoadDir(dir), "z.schema.json")
+	require.NoError(t, os.Remove(filepath.Join(dir, "z.schema.json")))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partials", "footer.html"), []byte("{{if}}"), 0o644))
+	assert.ErrorContains(t, NewTemplateStore().LoadDir(dir), "footer.html")
+	require.NoError(t, os.Remove(filepath.Join(dir, "partials", "footer.html")))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "d.html"), []byte("{{end}}"), 0o644))
+	assert.ErrorContains(t, store.LoadDir(dir), "d.html")
+}
+
+func TestTemplateStore_LoadRepositoryTemplates(t *testing.T) {
+	store := NewTemplateStore()
+	require.NoError(t, store.LoadDir(filepath.Join("..", "..", "templates")))
+	_, info, err := store.Get("service_request", LatestVersion)
+	require.NoError(t, err)
+	assert.Greater(t, info.Size, 0)
+	assert.Equal(t, map[string]int{"brand_fonts": 1, "brand_header": 1}, info.Partials)
+}
@@ -0,0 +1,266 @@
+package templatefuncs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// maxSeq bounds the sequences and repetitions a template can ask for.
+const maxSeq = 10000
+
+// maxRepeat bounds the length of a string built by repeat. The count alone
+// does not: repeating the result of repeat multiplies the lengths.
+const maxRepeat = 1 << 20
+
+var errDivisionByZero = errors.New("division by zero")
+
+// arithmetic applies op to numeric operands.
+func arithmetic(name string, a, b interface{}, op func(x, y float64) (float64, error)) (interface{}, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	r, err := op(x, y)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return number(r), nil
+}
+
+func add(a, b interface{}) (interface{}, error) {
+	return arithmetic("add", a, b, func(x, y float64) (float64, error) { return x + y, nil })
+}
+
+// sub subtracts b from a: {{sub .total .discount}}.
+func sub(a, b interface{}) (interface{}, error) {
+	return arithmetic("sub", a, b, func(x, y float64) (float64, error) { return x - y, nil })
+}
+
+func mul(a, b interface{}) (interface{}, error) {
+	return arithmetic("mul", a, b, func(x, y float64) (float64, error) { return x * y, nil })
+}
+
+// div divides a by b.
+func div(a, b interface{}) (interface{}, error) {
+	return arithmetic("div", a, b, func(x, y float64) (float64, error) {
+		if y == 0 {
+			return 0, errDivisionByZero
+		}
+		return x / y, nil
+	})
+}
+
+// mod returns the remainder of dividing a by b, useful for striping rows:
+// {{if eq (mod $i 2) 0}}.
+func mod(a, b interface{}) (interface{}, error) {
+	return arithmetic("mod", a, b, func(x, y float64) (float64, error) {
+		if y == 0 {
+			return 0, errDivisionByZero
+		}
+		return math.Mod(x, y), nil
+	})
+}
+
+// round rounds v to the given number of decimals, halves away from zero.
+func round(decimals, v interface{}) (interface{}, error) {
+	return arithmetic("round", decimals, v, func(d, x float64) (float64, error) {
+		if d != math.Trunc(d) || d < 0 || d > 10 {
+			return 0, errors.New("decimals must be a whole number from 0 to 10")
+		}
+		// Rounding as the formatting functions do keeps a rounded value
+		// and its formatted figure the same.
+		return strconv.ParseFloat(fixed(x, int(d)), 64)
+	})
+}
+
+func minOf(a, b interface{}) (interface{}, error) {
+	return arithmetic("min", a, b, func(x, y float64) (float64, error) { return math.Min(x, y), nil })
+}
+
+func maxOf(a, b interface{}) (interface{}, error) {
+	return arithmetic("max", a, b, func(x, y float64) (float64, error) { return math.Max(x, y), nil })
+}
+
+// list builds a list from its arguments.
+func list(items ...interface{}) []interface{} {
+	return items
+}
+
+// dict builds a map from alternating keys and values, for passing several
+// values to a nested template: {{template "row" dict "item" . "index" $i}}.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: expected pairs of keys and values")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v (%T) is not a string", pairs[i], pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// first returns the first element of a list, or nil when it is empty.
+func first(v interface{}) (interface{}, error) {
+	items, err := toList(v)
+	if err != nil {
+		return nil, fmt.Errorf("first: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
+
+// last returns the last element of a list, or nil when it is empty.
+func last(v interface{}) (interface{}, error) {
+	items, err := toList(v)
+	if err != nil {
+		return nil, fmt.Errorf("last: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[len(items)-1], nil
+}
+
+// reverse returns a list in reverse order.
+func reverse(v interface{}) ([]interface{}, error) {
+	items, err := toList(v)
+	if err != nil {
+		return nil, fmt.Errorf("reverse: %w", err)
+	}
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		out[len(items)-1-i] = item
+	}
+	return out, nil
+}
+
+// seq returns the numbers 1 to n, for repeating markup a number of times.
+func seq(n interface{}) ([]int, error) {
+	count, err := toInt(n)
+	if err != nil || count < 0 || count > maxSeq {
+		return nil, fmt.Errorf("seq: count must be a whole number from 0 to %d", maxSeq)
+	}
+	out := make([]int, count)
+	for i := range out {
+		out[i] = i + 1
+	}
+	return out, nil
+}
+
+// sum adds the numbers in a list or, given a key first, the values of that
+// key in a list of maps: {{sum "price" .items}}.
+func sum(args ...interface{}) (interface{}, error) {
+	key := ""
+	switch len(args) {
+	case 1:
+	case 2:
+		k, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("sum: key %v (%T) is not a string", args[0], args[0])
+		}
+		key = k
+	default:
+		return nil, fmt.Errorf("sum: expected [key] list, got %d arguments", len(args))
+	}
+	items, err := toList(args[len(args)-1])
+	if err != nil {
+		return nil, fmt.Errorf("sum: %w", err)
+	}
+	total := 0.0
+	for _, item := range items {
+		if key != "" {
+			item = lookup(item, key)
+		}
+		f, err := toFloat(item)
+		if err != nil {
+			return nil, fmt.Errorf("sum: %w", err)
+		}
+		total += f
+	}
+	return number(total), nil
+}
+
+// keys returns the keys of a map in sorted order, so that ranging over
+// them gives stable output.
+func keys(v interface{}) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("keys: %v (%T) is not a map with string keys", v, v)
+	}
+	out := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		out = append(out, k.String())
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// hasKey reports whether a map has the given key.
+func hasKey(key string, v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return false
+	}
+	return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid()
+}
+
+// lookup returns the value of key in a map, or nil.
+func lookup(v interface{}, key string) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+	if val := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())); val.IsValid() {
+		return val.Interface()
+	}
+	return nil
+}
+
+// sortBy sorts a list of maps by the value of key, numerically when every
+// value is a number and as text otherwise. The sort is stable.
+func sortBy(key string, v interface{}) ([]interface{}, error) {
+	items, err := toList(v)
+	if err != nil {
+		return nil, fmt.Errorf("sortBy: %w", err)
+	}
+	out := append([]interface{}(nil), items...)
+	numeric := true
+	values := make([]float64, len(out))
+	for i, item := range out {
+		f, err := toFloat(lookup(item, key))
+		if err != nil {
+			numeric = false
+			break
+		}
+		values[i] = f
+	}
+	idx := make([]int, len(out))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		if numeric {
+			return values[idx[a]] < values[idx[b]]
+		}
+		return toString(lookup(out[idx[a]], key)) < toString(lookup(out[idx[b]], key))
+	})
+	sorted := make([]interface{}, len(out))
+	for i, j := range idx {
+		sorted[i] = out[j]
+	}
+	return sorted, nil
+}
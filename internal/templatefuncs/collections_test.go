@@ -0,0 +1,85 @@
+package templatefuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArithmetic(t *testing.T) {
+	data := map[string]interface{}{"price": 19.99, "qty": 3.0, "total": "100"}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{add 1 2}}`, "3"},
+		{`{{add .qty 0.5}}`, "3.5"},
+		{`{{sub .total 40}}`, "60"},
+		{`{{mul .price .qty | round 2}}`, "59.97"},
+		{`{{div 10 4}}`, "2.5"},
+		{`{{mod 7 3}}`, "1"},
+		{`{{round 0 2.5}} {{round 0 -2.5}} {{round 1 1.25}}`, "3 -3 1.3"},
+		{`{{round 2 1.005}} {{round 0 0.4}}`, "1.01 0"},
+		{`{{min 3 .qty}} {{max 1 2.5}}`, "3 2.5"},
+		{`{{if eq (mod 4 2) 0}}even{{end}}`, "even"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, data)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+
+	_, err := render(t, `{{div 1 0}}`, nil)
+	assert.ErrorContains(t, err, "div: division by zero")
+	_, err = render(t, `{{mod 1 0}}`, nil)
+	assert.ErrorContains(t, err, "mod: division by zero")
+	_, err = render(t, `{{add 1 "x"}}`, nil)
+	assert.ErrorContains(t, err, `add: "x" is not a number`)
+}
+
+func TestCollections(t *testing.T) {
+	data := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "pear", "price": 3.0},
+			map[string]interface{}{"name": "apple", "price": 10.0},
+			map[string]interface{}{"name": "fig", "price": 1.5},
+		},
+		"totals": map[string]interface{}{"b": 2.0, "a": 1.0},
+		"empty":  []interface{}{},
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{range list 1 "a" 2.5}}[{{.}}]{{end}}`, "[1][a][2.5]"},
+		{`{{with dict "a" 1 "b" "x"}}{{.a}}{{.b}}{{end}}`, "1x"},
+		{`{{(first .items).name}} {{(last .items).name}}`, "pear fig"},
+		{`{{first .empty}}`, ""},
+		{`{{range reverse (list 1 2 3)}}{{.}}{{end}}`, "321"},
+		{`{{range seq 3}}{{.}}{{end}}`, "123"},
+		{`{{sum (list 1 2 3.5)}}`, "6.5"},
+		{`{{sum "price" .items}}`, "14.5"},
+		{`{{.items | sum "price" | formatCurrency "USD"}}`, "$14.50"},
+		{`{{range keys .totals}}{{.}}{{end}}`, "ab"},
+		{`{{hasKey "a" .totals}} {{hasKey "c" .totals}} {{hasKey "a" "x"}}`, "true false false"},
+		{`{{range sortBy "price" .items}}{{.name}} {{end}}`, "fig pear apple "},
+		{`{{range sortBy "name" .items}}{{.name}} {{end}}`, "apple fig pear "},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, data)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+
+	for _, src := range []string{
+		`{{dict "a"}}`,
+		`{{dict 1 2}}`,
+		`{{seq -1}}`,
+		`{{keys "abc"}}`,
+		`{{sum (list "a")}}`,
+		`{{first 3}}`,
+	} {
+		_, err := render(t, src, nil)
+		assert.Error(t, err, src)
+	}
+}
@@ -0,0 +1,86 @@
+package templatefuncs
+
+import "strings"
+
+const (
+	// persianThousandsSeparator and persianDecimalSeparator are the Arabic
+	// thousands and decimal separators, used in Persian and Arabic text.
+	persianThousandsSeparator = "٬"
+	persianDecimalSeparator   = "٫"
+)
+
+// latinDigits replaces Persian and Arabic-Indic digits with ASCII digits.
+func latinDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '۰' && r <= '۹':
+			return '0' + r - '۰'
+		case r >= '٠' && r <= '٩':
+			return '0' + r - '٠'
+		}
+		return r
+	}, s)
+}
+
+// localDigits replaces the ASCII digits in s with those starting at zero,
+// and commas and periods between two digits with the Arabic thousands and
+// decimal separators, so that 1,000.5 becomes ۱٬۰۰۰٫۵ and a sentence's
+// full stop is kept.
+func localDigits(s string, zero rune) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(zero + r - '0')
+		case (r == ',' || r == '.') && i > 0 && i+1 < len(runes) && isDigit(runes[i-1]) && isDigit(runes[i+1]):
+			if r == ',' {
+				b.WriteString(persianThousandsSeparator)
+			} else {
+				b.WriteString(persianDecimalSeparator)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(r rune) bool { return r >= '0' && r <= '9' }
+
+// persianDigits writes the digits of a value in Persian (۰۱۲…), for
+// example {{.service_request_details | persianDigits}}.
+func persianDigits(v interface{}) string {
+	return localDigits(latinDigits(toString(v)), '۰')
+}
+
+// arabicDigits writes the digits of a value in Arabic-Indic (٠١٢…).
+func arabicDigits(v interface{}) string {
+	return localDigits(latinDigits(toString(v)), '٠')
+}
+
+// toLatinDigits writes Persian and Arabic-Indic digits of a value as ASCII
+// digits, for example to read numbers typed on a Persian keyboard.
+func toLatinDigits(v interface{}) string {
+	return latinDigits(toString(v))
+}
+
+// persianNumber writes a number with Persian digits and separators:
+// persianNumber 1234567 gives ۱٬۲۳۴٬۵۶۷.
+func persianNumber(args ...interface{}) (string, error) {
+	decimals, f, err := decimalsAndValue("persianNumber", args)
+	if err != nil {
+		return "", err
+	}
+	return localDigits(groupThousands(fixed(f, decimals), ","), '۰'), nil
+}
+
+// persianCurrency writes an amount like formatCurrency, with Persian digits
+// and separators: persianCurrency "IRR" 1000000 gives ۱٬۰۰۰٬۰۰۰ ریال.
+func persianCurrency(code string, v interface{}) (string, error) {
+	s, err := formatCurrency(code, v)
+	if err != nil {
+		return "", err
+	}
+	return localDigits(s, '۰'), nil
+}
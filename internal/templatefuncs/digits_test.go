@@ -0,0 +1,35 @@
+package templatefuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigits(t *testing.T) {
+	data := map[string]interface{}{
+		"details": "مبلغ 1,000,000,000 ریال در تاریخ 1399/04/10.",
+		"rate":    12.5,
+		"typed":   "۱۲۳٤٥",
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{.details | persianDigits}}`, "مبلغ ۱٬۰۰۰٬۰۰۰٬۰۰۰ ریال در تاریخ ۱۳۹۹/۰۴/۱۰."},
+		{`{{.details | arabicDigits}}`, "مبلغ ١٬٠٠٠٬٠٠٠٬٠٠٠ ریال در تاریخ ١٣٩٩/٠٤/١٠."},
+		{`{{.rate | persianDigits}}`, "۱۲٫۵"},
+		{`{{.typed | latinDigits}}`, "12345"},
+		{`{{.typed | persianDigits}}`, "۱۲۳۴۵"},
+		{`{{persianNumber 1234567}}`, "۱٬۲۳۴٬۵۶۷"},
+		{`{{persianNumber 2 -1234.5}}`, "-۱٬۲۳۴٫۵۰"},
+		{`{{persianCurrency "IRR" 1000000000}}`, "۱٬۰۰۰٬۰۰۰٬۰۰۰ ریال"},
+		{`{{persianCurrency "IRT" "۱۲۵۰۰"}}`, "۱۲٬۵۰۰ تومان"},
+		{`{{formatJalali "long" "2020-06-30" | persianDigits}}`, "سه‌شنبه ۱۰ تیر ۱۳۹۹"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, data)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+}
@@ -0,0 +1,254 @@
+package templatefuncs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// currency describes how amounts in a currency are written.
+type currency struct {
+	symbol   string
+	decimals int
+	// prefix puts the symbol before the amount rather than after it.
+	prefix bool
+}
+
+var currencies = map[string]currency{
+	"IRR": {"ریال", 0, false},
+	"IRT": {"تومان", 0, false},
+	"USD": {"$", 2, true},
+	"EUR": {"€", 2, true},
+	"GBP": {"£", 2, true},
+}
+
+// layouts name common date layouts; other layouts are Go reference layouts
+// such as "02 Jan 2006".
+var layouts = map[string]string{
+	"date":     "2006-01-02",
+	"time":     "15:04",
+	"datetime": "2006-01-02 15:04",
+	"iso":      time.RFC3339,
+	"rfc3339":  time.RFC3339,
+}
+
+// inputLayouts are tried in order when reading dates given as strings.
+var inputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// groupThousands inserts sep between groups of three digits in the
+// integer part of a formatted number.
+func groupThousands(s, sep string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + frac
+}
+
+// fixed formats f with the given number of decimals, or as many as needed
+// when decimals is negative. Halves are rounded away from zero in the
+// number as written, so 1234.5 gives 1235 and 1.005 gives 1.01 with two
+// decimals, as on invoices, where binary rounding would give 1234 and 1.00.
+func fixed(f float64, decimals int) string {
+	if decimals < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		if f == 0 {
+			f = 0 // drops the sign of -0
+		}
+		return strconv.FormatFloat(f, 'f', decimals, 64)
+	}
+	s := strconv.FormatFloat(math.Abs(f), 'f', -1, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+	var up bool
+	if len(frac) > decimals {
+		up = frac[decimals] >= '5'
+		frac = frac[:decimals]
+	} else {
+		frac += strings.Repeat("0", decimals-len(frac))
+	}
+	digits := []byte(intPart + frac)
+	for i := len(digits) - 1; up && i >= 0; i-- {
+		if digits[i] == '9' {
+			digits[i] = '0'
+			continue
+		}
+		digits[i]++
+		up = false
+	}
+	if up {
+		digits = append([]byte{'1'}, digits...)
+	}
+	s = string(digits[:len(digits)-decimals])
+	if decimals > 0 {
+		s += "." + string(digits[len(digits)-decimals:])
+	}
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		s = "-" + s
+	}
+	return s
+}
+
+// decimalsAndValue splits the optional leading decimals argument from the
+// value of a formatting function.
+func decimalsAndValue(name string, args []interface{}) (int, float64, error) {
+	decimals := -1
+	switch len(args) {
+	case 1:
+	case 2:
+		d, err := toInt(args[0])
+		if err != nil || d < 0 || d > 10 {
+			return 0, 0, fmt.Errorf("%s: decimals must be a whole number from 0 to 10", name)
+		}
+		decimals = d
+	default:
+		return 0, 0, fmt.Errorf("%s: expected [decimals] value, got %d arguments", name, len(args))
+	}
+	f, err := toFloat(args[len(args)-1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return decimals, f, nil
+}
+
+// formatNumber writes a number with thousands separators: formatNumber
+// 1234567.891 gives 1,234,567.891 and formatNumber 2 1234.5 gives 1,234.50.
+func formatNumber(args ...interface{}) (string, error) {
+	decimals, f, err := decimalsAndValue("formatNumber", args)
+	if err != nil {
+		return "", err
+	}
+	return groupThousands(fixed(f, decimals), ","), nil
+}
+
+// formatCurrency writes an amount in the currency with the given ISO code,
+// with the currency's usual decimals: formatCurrency "USD" 1234.5 gives
+// $1,234.50 and formatCurrency "IRR" 1000000 gives 1,000,000 ریال. IRT
+// stands for toman. Other codes are written after the amount.
+func formatCurrency(code string, v interface{}) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	c, ok := currencies[code]
+	if !ok {
+		c = currency{symbol: code, decimals: 2}
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return "", fmt.Errorf("formatCurrency: %w", err)
+	}
+	amount := groupThousands(fixed(math.Abs(f), c.decimals), ",")
+	sign := ""
+	if f < 0 && strings.Trim(amount, "0.,") != "" {
+		sign = "-"
+	}
+	if c.prefix {
+		return sign + c.symbol + amount, nil
+	}
+	return sign + amount + " " + c.symbol, nil
+}
+
+// formatPercent writes a fraction as a percentage: formatPercent 1 0.125
+// gives 12.5%.
+func formatPercent(args ...interface{}) (string, error) {
+	decimals, f, err := decimalsAndValue("formatPercent", args)
+	if err != nil {
+		return "", err
+	}
+	return groupThousands(fixed(f*100, decimals), ",") + "%", nil
+}
+
+// toTime converts a time.Time, a JalaliDate, a date string in one of
+// inputLayouts, or a Unix time in seconds.
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case JalaliDate:
+		return t.Time(time.UTC)
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		s := strings.TrimSpace(t)
+		for _, layout := range inputLayouts {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a recognised date", t)
+	default:
+		if f, err := toFloat(v); err == nil {
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%v (%T) is not a date", v, v)
+}
+
+func layoutFor(name string) string {
+	if l, ok := layouts[strings.ToLower(name)]; ok {
+		return l
+	}
+	return name
+}
+
+// formatDate writes a date with a named layout (date, time, datetime, iso)
+// or a Go reference layout: formatDate "02 Jan 2006" .created_at.
+func formatDate(layout string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", fmt.Errorf("formatDate: %w", err)
+	}
+	return t.Format(layoutFor(layout)), nil
+}
+
+// parseDate reads a date in the given layout, or in any of the accepted
+// input layouts when layout is empty.
+func parseDate(layout, s string) (time.Time, error) {
+	if layout == "" {
+		t, err := toTime(s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parseDate: %w", err)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(layoutFor(layout), strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parseDate: %w", err)
+	}
+	return t, nil
+}
+
+// now is the time of rendering.
+var now = time.Now
+
+// addDays moves a date by a number of days, which may be negative.
+func addDays(days interface{}, v interface{}) (time.Time, error) {
+	n, err := toInt(days)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("addDays: %w", err)
+	}
+	t, err := toTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("addDays: %w", err)
+	}
+	return t.AddDate(0, 0, n), nil
+}
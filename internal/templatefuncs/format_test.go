@@ -0,0 +1,114 @@
+package templatefuncs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{formatNumber 1234567}}`, "1,234,567"},
+		{`{{formatNumber 1234567.891}}`, "1,234,567.891"},
+		{`{{formatNumber 2 1234.5}}`, "1,234.50"},
+		{`{{formatNumber 0 999.5}}`, "1,000"},
+		{`{{formatNumber 0 2.5}}`, "3"},
+		{`{{formatNumber 2 1.005}}`, "1.01"},
+		{`{{formatNumber 0 -2.5}}`, "-3"},
+		{`{{formatNumber -1234}}`, "-1,234"},
+		{`{{formatNumber 2 -0.001}}`, "0.00"},
+		{`{{formatNumber 123}}`, "123"},
+		{`{{"98765.4" | formatNumber 1}}`, "98,765.4"},
+		{`{{formatPercent 1 0.125}}`, "12.5%"},
+		{`{{formatPercent 0.5}}`, "50%"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, nil)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+
+	_, err := render(t, `{{formatNumber "abc"}}`, nil)
+	assert.ErrorContains(t, err, `formatNumber: "abc" is not a number`)
+	_, err = render(t, `{{formatNumber 11 1}}`, nil)
+	assert.ErrorContains(t, err, "decimals must be")
+}
+
+func TestGroupThousands(t *testing.T) {
+	assert.Equal(t, "1", groupThousands("1", ","))
+	assert.Equal(t, "100", groupThousands("100", ","))
+	assert.Equal(t, "1,000", groupThousands("1000", ","))
+	assert.Equal(t, "-100,000.125", groupThousands("-100000.125", ","))
+	assert.Equal(t, "1 000 000", groupThousands("1000000", " "))
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		code   string
+		amount interface{}
+		want   string
+	}{
+		{"USD", 1234.5, "$1,234.50"},
+		{"usd", -3, "-$3.00"},
+		{"EUR", "99.999", "€100.00"},
+		{"IRR", 1500000, "1,500,000 ریال"},
+		{"IRT", 150000.4, "150,000 تومان"},
+		{"CHF", 12, "12.00 CHF"},
+		{"USD", -0.001, "$0.00"},
+	}
+	for _, tt := range tests {
+		got, err := formatCurrency(tt.code, tt.amount)
+		assert.NoError(t, err, tt.code)
+		assert.Equal(t, tt.want, got, tt.code)
+	}
+
+	out, err := render(t, `{{.price | formatCurrency "USD"}}`, map[string]interface{}{"price": 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "$5.00", out)
+
+	_, err = formatCurrency("USD", nil)
+	assert.Error(t, err)
+}
+
+func TestFormatDate(t *testing.T) {
+	data := map[string]interface{}{
+		"created": "2024-03-05T14:30:00Z",
+		"due":     time.Date(2024, 12, 31, 9, 5, 0, 0, time.UTC),
+		"unix":    1700000000.0,
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{formatDate "date" .created}}`, "2024-03-05"},
+		{`{{formatDate "datetime" .created}}`, "2024-03-05 14:30"},
+		{`{{formatDate "02 Jan 2006" .due}}`, "31 Dec 2024"},
+		{`{{.due | formatDate "time"}}`, "09:05"},
+		{`{{formatDate "iso" .unix}}`, "2023-11-14T22:13:20Z"},
+		{`{{formatDate "date" "2024/02/29"}}`, "2024-02-29"},
+		{`{{formatDate "date" (addDays 30 .created)}}`, "2024-04-04"},
+		{`{{formatDate "date" (addDays -1 "2024-03-01")}}`, "2024-02-29"},
+		{`{{formatDate "January 2, 2006" (parseDate "02/01/2006" "25/12/2023")}}`, "December 25, 2023"},
+		{`{{formatDate "date" (parseDate "" "2023-12-25 10:00:00")}}`, "2023-12-25"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, data)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+
+	_, err := render(t, `{{formatDate "date" "yesterday"}}`, nil)
+	assert.ErrorContains(t, err, `"yesterday" is not a recognised date`)
+	_, err = render(t, `{{parseDate "date" "2023-13-01"}}`, nil)
+	assert.ErrorContains(t, err, "parseDate")
+}
+
+func TestNow(t *testing.T) {
+	out, err := render(t, `{{formatDate "2006" now}}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().Format("2006"), out)
+}
@@ -0,0 +1,202 @@
+// Package templatefuncs provides the functions available to document
+// templates: number, currency and date formatting, the Jalali calendar,
+// Persian digits and amounts in words, string and collection helpers,
+// arithmetic and wrappers that mark trusted content as safe.
+//
+// Functions take the value being worked on last, so that they read well in
+// pipelines: {{.amount | formatNumber 2}} or {{.title | truncate 40}}.
+// Values decoded from JSON arrive as float64, string, []interface{} and
+// map[string]interface{}; numeric arguments accept any Go number or a
+// numeric string.
+package templatefuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FuncMap returns the functions for use with template.Funcs. Each call
+// returns a new map, which callers may extend.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Numbers and dates.
+		"formatNumber":   formatNumber,
+		"formatCurrency": formatCurrency,
+		"formatPercent":  formatPercent,
+		"formatDate":     formatDate,
+		"parseDate":      parseDate,
+		"now":            now,
+		"addDays":        addDays,
+
+		// Jalali (Solar Hijri) calendar.
+		"jalali":          jalali,
+		"gregorian":       gregorian,
+		"formatJalali":    formatJalali,
+		"parseJalali":     parseJalali,
+		"jalaliMonthName": jalaliMonthName,
+		"persianWeekday":  persianWeekday,
+		"daysBetween":     daysBetween,
+		"jalaliDiff":      jalaliDiff,
+
+		// Persian and Arabic digits, and amounts in words.
+		"persianDigits":      persianDigits,
+		"arabicDigits":       arabicDigits,
+		"latinDigits":        toLatinDigits,
+		"persianNumber":      persianNumber,
+		"persianCurrency":    persianCurrency,
+		"persianWords":       persianWords,
+		"englishWords":       englishWords,
+		"persianAmountWords": persianAmountWords,
+		"englishAmountWords": englishAmountWords,
+
+		// Strings.
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"title":     title,
+		"trim":      strings.TrimSpace,
+		"truncate":  truncate,
+		"padLeft":   padLeft,
+		"padRight":  padRight,
+		"default":   defaultValue,
+		"replace":   replace,
+		"contains":  contains,
+		"hasPrefix": hasPrefix,
+		"hasSuffix": hasSuffix,
+		"split":     split,
+		"join":      join,
+		"repeat":    repeat,
+
+		// Arithmetic.
+		"add":   add,
+		"sub":   sub,
+		"mul":   mul,
+		"div":   div,
+		"mod":   mod,
+		"round": round,
+		"min":   minOf,
+		"max":   maxOf,
+
+		// Lists and maps.
+		"list":    list,
+		"dict":    dict,
+		"first":   first,
+		"last":    last,
+		"reverse": reverse,
+		"seq":     seq,
+		"sum":     sum,
+		"keys":    keys,
+		"hasKey":  hasKey,
+		"sortBy":  sortBy,
+
+		// Trusted content, inserted without escaping.
+		"safeHTML": safeHTML,
+		"safeCSS":  safeCSS,
+		"safeURL":  safeURL,
+	}
+}
+
+// toFloat converts a number, or a string holding one, to float64. Strings
+// may use thousands separators and Persian or Arabic-Indic digits.
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		s := strings.NewReplacer(",", "", persianThousandsSeparator, "", persianDecimalSeparator, ".").Replace(latinDigits(n))
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", n)
+		}
+		return f, nil
+	case nil:
+		return 0, fmt.Errorf("missing number")
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("%v (%T) is not a number", v, v)
+}
+
+// toInt converts a whole number argument such as a count or a width.
+func toInt(v interface{}) (int, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%v is not a whole number", v)
+	}
+	return int(f), nil
+}
+
+// number returns whole results as int64, which templates print without an
+// exponent, and others as float64.
+func number(f float64) interface{} {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+	return f
+}
+
+// toString formats any value as templates print it, with numbers in plain
+// notation.
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case template.HTML:
+		return string(s)
+	}
+	return fmt.Sprint(v)
+}
+
+// isEmpty reports whether v is missing, zero or an empty string, list or
+// map.
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+func safeHTML(v interface{}) template.HTML { return template.HTML(toString(v)) }
+func safeCSS(v interface{}) template.CSS   { return template.CSS(toString(v)) }
+func safeURL(v interface{}) template.URL   { return template.URL(toString(v)) }
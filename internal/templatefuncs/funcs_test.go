@@ -0,0 +1,71 @@
+package templatefuncs
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// render executes src with the function map and data.
+func render(t *testing.T, src string, data interface{}) (string, error) {
+	t.Helper()
+	tmpl, err := template.New("test").Funcs(FuncMap()).Parse(src)
+	require.NoError(t, err)
+	var b strings.Builder
+	err = tmpl.Execute(&b, data)
+	return b.String(), err
+}
+
+func TestToFloat(t *testing.T) {
+	for _, v := range []interface{}{12.5, float32(12.5), "12.5", " 12.5 ", "1,2.5"} {
+		f, err := toFloat(v)
+		assert.NoError(t, err, v)
+		assert.Equal(t, 12.5, f, v)
+	}
+	for _, v := range []interface{}{int8(3), uint16(3), 3, int64(3)} {
+		f, err := toFloat(v)
+		assert.NoError(t, err, v)
+		assert.Equal(t, 3.0, f, v)
+	}
+	for _, v := range []interface{}{nil, "abc", []int{1}, true} {
+		_, err := toFloat(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	for _, v := range []interface{}{nil, "", 0, 0.0, false, []interface{}{}, map[string]interface{}{}} {
+		assert.True(t, isEmpty(v), v)
+	}
+	for _, v := range []interface{}{"a", 1, true, []int{0}} {
+		assert.False(t, isEmpty(v), v)
+	}
+}
+
+func TestSafeFunctions(t *testing.T) {
+	data := map[string]interface{}{"html": "<b>x</b>", "css": "color: red", "url": "javascript:void(0)"}
+
+	out, err := render(t, `{{.html}}|{{safeHTML .html}}`, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "&lt;b&gt;x&lt;/b&gt;|<b>x</b>", out)
+
+	out, err = render(t, `<p style="{{safeCSS .css}}"></p>`, data)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p style="color: red"></p>`, out)
+
+	out, err = render(t, `<a href="{{.url}}"></a><a href="{{safeURL .url}}"></a>`, data)
+	assert.NoError(t, err)
+	assert.Equal(t, `<a href="#ZgotmplZ"></a><a href="javascript:void%280%29"></a>`, out)
+}
+
+func TestFuncMapReturnsNewMap(t *testing.T) {
+	m := FuncMap()
+	m["upper"] = strings.ToLower
+	assert.NotNil(t, FuncMap()["upper"])
+	out, err := render(t, `{{upper "a"}}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "A", out)
+}
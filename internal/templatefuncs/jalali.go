@@ -0,0 +1,421 @@
+package templatefuncs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidJalaliDate is returned for Jalali dates that do not exist or
+// fall outside the supported years.
+var ErrInvalidJalaliDate = errors.New("invalid Jalali date")
+
+var jalaliMonths = [12]string{
+	"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور",
+	"مهر", "آبان", "آذر", "دی", "بهمن", "اسفند",
+}
+
+var persianWeekdays = [7]string{
+	time.Sunday:    "یکشنبه",
+	time.Monday:    "دوشنبه",
+	time.Tuesday:   "سه‌شنبه",
+	time.Wednesday: "چهارشنبه",
+	time.Thursday:  "پنجشنبه",
+	time.Friday:    "جمعه",
+	time.Saturday:  "شنبه",
+}
+
+// jalaliBreaks are the years in which the 33-year leap cycle of the
+// astronomical Solar Hijri calendar shifts. Conversion is supported from
+// the first break up to the year before the last.
+var jalaliBreaks = [...]int{
+	-61, 9, 38, 199, 426, 686, 756, 818, 1111, 1181, 1210,
+	1635, 2060, 2097, 2192, 2262, 2324, 2394, 2456, 3178,
+}
+
+// jalaliLayouts name common Jalali layouts.
+var jalaliLayouts = map[string]string{
+	"date":     "2006/01/02",
+	"time":     "15:04",
+	"datetime": "2006/01/02 15:04",
+	"long":     "Monday 2 January 2006",
+}
+
+// maxJalaliInputYear separates Jalali from Gregorian years in dates given
+// as strings to the Jalali functions: 1399/04/10 is read as Jalali and
+// 2020/06/30 as Gregorian.
+const maxJalaliInputYear = 1700
+
+// JalaliDate is a day in the Solar Hijri calendar used in Iran. It prints
+// as 1399/04/10.
+type JalaliDate struct {
+	Year, Month, Day int
+}
+
+func (j JalaliDate) String() string {
+	return fmt.Sprintf("%04d/%02d/%02d", j.Year, j.Month, j.Day)
+}
+
+// MonthName is the Persian name of the month.
+func (j JalaliDate) MonthName() string {
+	if j.Month < 1 || j.Month > 12 {
+		return ""
+	}
+	return jalaliMonths[j.Month-1]
+}
+
+// Valid reports whether the date exists.
+func (j JalaliDate) Valid() bool {
+	if j.Year <= jalaliBreaks[0] || j.Year >= jalaliBreaks[len(jalaliBreaks)-1] {
+		return false
+	}
+	return j.Month >= 1 && j.Month <= 12 && j.Day >= 1 && j.Day <= JalaliMonthDays(j.Year, j.Month)
+}
+
+// Time returns midnight at the start of the date in loc.
+func (j JalaliDate) Time(loc *time.Location) (time.Time, error) {
+	if !j.Valid() {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidJalaliDate, j)
+	}
+	gy, march := nowruz(j.Year)
+	days := (j.Month-1)*31 - j.Month/7*(j.Month-7) + j.Day - 1
+	return time.Date(gy, time.March, march+days, 0, 0, 0, 0, loc), nil
+}
+
+// ToJalali returns the Jalali date of the day t falls on in its location.
+func ToJalali(t time.Time) JalaliDate {
+	gy := t.Year()
+	jy := gy - 621
+	_, march := nowruz(jy)
+	day := civilDays(t)
+	k := day - civilDays(time.Date(gy, time.March, march, 0, 0, 0, 0, time.UTC))
+	if k < 0 {
+		// Before Nowruz: the last months of the previous year.
+		jy--
+		k += 365
+		if IsJalaliLeap(jy) {
+			k++
+		}
+	}
+	if k < 186 {
+		return JalaliDate{jy, 1 + k/31, k%31 + 1}
+	}
+	k -= 186
+	return JalaliDate{jy, 7 + k/30, k%30 + 1}
+}
+
+// ParseJalali reads a Jalali date written as 1399/04/10 or 1399-04-10.
+// Persian and Arabic-Indic digits are accepted.
+func ParseJalali(s string) (JalaliDate, error) {
+	s = strings.TrimSpace(latinDigits(s))
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' })
+	if len(parts) != 3 {
+		return JalaliDate{}, fmt.Errorf("%w: %q", ErrInvalidJalaliDate, s)
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return JalaliDate{}, fmt.Errorf("%w: %q", ErrInvalidJalaliDate, s)
+		}
+		n[i] = v
+	}
+	j := JalaliDate{n[0], n[1], n[2]}
+	if !j.Valid() {
+		return JalaliDate{}, fmt.Errorf("%w: %q", ErrInvalidJalaliDate, s)
+	}
+	return j, nil
+}
+
+// IsJalaliLeap reports whether the Jalali year has 366 days.
+func IsJalaliLeap(year int) bool {
+	if year <= jalaliBreaks[0] || year+1 >= jalaliBreaks[len(jalaliBreaks)-1] {
+		return false
+	}
+	gy, march := nowruz(year)
+	_, next := nowruz(year + 1)
+	start := time.Date(gy, time.March, march, 0, 0, 0, 0, time.UTC)
+	end := time.Date(gy+1, time.March, next, 0, 0, 0, 0, time.UTC)
+	return civilDays(end)-civilDays(start) == 366
+}
+
+// JalaliMonthDays returns the number of days in a month: 31 in the first
+// six months, 30 in the next five, and 29 or, in leap years, 30 in Esfand.
+func JalaliMonthDays(year, month int) int {
+	switch {
+	case month <= 6:
+		return 31
+	case month <= 11:
+		return 30
+	case IsJalaliLeap(year):
+		return 30
+	}
+	return 29
+}
+
+// nowruz returns the Gregorian year in which a Jalali year starts and the
+// day of March of its first day, Nowruz.
+func nowruz(year int) (gy, march int) {
+	gy = year + 621
+	leapJ := -14
+	jp := jalaliBreaks[0]
+	jump := 0
+	for _, jm := range jalaliBreaks[1:] {
+		jump = jm - jp
+		if year < jm {
+			break
+		}
+		leapJ += jump/33*8 + jump%33/4
+		jp = jm
+	}
+	n := year - jp
+	leapJ += n/33*8 + (n%33+3)/4
+	if jump%33 == 4 && jump-n == 4 {
+		leapJ++
+	}
+	leapG := gy/4 - (gy/100+1)*3/4 - 150
+	return gy, 20 + leapJ - leapG
+}
+
+// civilDays counts days from the Unix epoch to the date of t in its
+// location.
+func civilDays(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// toJalaliTime converts the argument of a Jalali function: a Jalali date
+// string such as 1399/04/10, or anything toTime accepts.
+func toJalaliTime(v interface{}) (time.Time, error) {
+	if d, ok := v.(string); ok {
+		if j, err := ParseJalali(d); err == nil && j.Year < maxJalaliInputYear {
+			return j.Time(time.UTC)
+		}
+		if j, err := parseJalaliDateTime(d); err == nil {
+			return j, nil
+		}
+	}
+	return toTime(v)
+}
+
+// parseJalaliDateTime reads a Jalali date followed by a time, such as
+// 1399/04/10 11:50.
+func parseJalaliDateTime(s string) (time.Time, error) {
+	date, clock, ok := strings.Cut(strings.TrimSpace(latinDigits(s)), " ")
+	if !ok {
+		return time.Time{}, ErrInvalidJalaliDate
+	}
+	j, err := ParseJalali(date)
+	if err != nil || j.Year >= maxJalaliInputYear {
+		return time.Time{}, ErrInvalidJalaliDate
+	}
+	var tod time.Time
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if tod, err = time.Parse(layout, strings.TrimSpace(clock)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, ErrInvalidJalaliDate
+	}
+	t, err := j.Time(time.UTC)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Add(time.Duration(tod.Hour())*time.Hour + time.Duration(tod.Minute())*time.Minute + time.Duration(tod.Second())*time.Second), nil
+}
+
+// jalali converts a date to the Jalali calendar: {{jalali .created_at}}
+// prints 1399/04/10, and {{(jalali .created_at).Year}} the year.
+func jalali(v interface{}) (JalaliDate, error) {
+	t, err := toJalaliTime(v)
+	if err != nil {
+		return JalaliDate{}, fmt.Errorf("jalali: %w", err)
+	}
+	return ToJalali(t), nil
+}
+
+// gregorian converts a Jalali date to a time.Time at midnight UTC, for use
+// with formatDate and addDays.
+func gregorian(v interface{}) (time.Time, error) {
+	t, err := toJalaliTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("gregorian: %w", err)
+	}
+	return t, nil
+}
+
+// jalaliTokens are the layout elements formatJalali replaces, longest
+// first.
+var jalaliTokens = []string{
+	"2006", "January", "Monday", "15", "01", "02", "03", "04", "05", "06",
+	"_2", "1", "2", "3", "4", "5", "PM", "pm",
+}
+
+// formatJalali writes a date in the Jalali calendar using the tokens of
+// Go reference layouts: 2006 (year), 06, January (Persian month name), 01,
+// 1, 02, 2, Monday (Persian weekday name), and the time tokens 15, 03, 3,
+// 04, 05 and PM. Named layouts are date (1399/04/10), datetime, time and
+// long (سه‌شنبه 10 تیر 1399).
+func formatJalali(layout string, v interface{}) (string, error) {
+	t, err := toJalaliTime(v)
+	if err != nil {
+		return "", fmt.Errorf("formatJalali: %w", err)
+	}
+	if l, ok := jalaliLayouts[strings.ToLower(layout)]; ok {
+		layout = l
+	}
+	j := ToJalali(t)
+	var b strings.Builder
+	for rest := layout; rest != ""; {
+		token := ""
+		for _, tok := range jalaliTokens {
+			if strings.HasPrefix(rest, tok) {
+				token = tok
+				break
+			}
+		}
+		switch token {
+		case "":
+			b.WriteString(rest[:1])
+			rest = rest[1:]
+			continue
+		case "2006":
+			b.WriteString(strconv.Itoa(j.Year))
+		case "06":
+			fmt.Fprintf(&b, "%02d", j.Year%100)
+		case "January":
+			b.WriteString(j.MonthName())
+		case "01":
+			fmt.Fprintf(&b, "%02d", j.Month)
+		case "1":
+			b.WriteString(strconv.Itoa(j.Month))
+		case "02":
+			fmt.Fprintf(&b, "%02d", j.Day)
+		case "_2":
+			fmt.Fprintf(&b, "%2d", j.Day)
+		case "2":
+			b.WriteString(strconv.Itoa(j.Day))
+		case "Monday":
+			b.WriteString(persianWeekdays[t.Weekday()])
+		default:
+			b.WriteString(t.Format(token))
+		}
+		rest = rest[len(token):]
+	}
+	return b.String(), nil
+}
+
+// parseJalali reads a Jalali date such as 1399/04/10, optionally followed
+// by a time, and returns it as a time.Time in UTC.
+func parseJalali(s string) (time.Time, error) {
+	if t, err := parseJalaliDateTime(s); err == nil {
+		return t, nil
+	}
+	j, err := ParseJalali(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parseJalali: %w", err)
+	}
+	return j.Time(time.UTC)
+}
+
+// jalaliMonthName returns the Persian name of a month numbered from 1.
+func jalaliMonthName(month interface{}) (string, error) {
+	m, err := toInt(month)
+	if err != nil || m < 1 || m > 12 {
+		return "", fmt.Errorf("jalaliMonthName: month must be a whole number from 1 to 12")
+	}
+	return jalaliMonths[m-1], nil
+}
+
+// persianWeekday returns the Persian name of the day of the week of a
+// date.
+func persianWeekday(v interface{}) (string, error) {
+	t, err := toJalaliTime(v)
+	if err != nil {
+		return "", fmt.Errorf("persianWeekday: %w", err)
+	}
+	return persianWeekdays[t.Weekday()], nil
+}
+
+// daysBetween counts the calendar days from a to b, negative when b is
+// earlier.
+func daysBetween(a, b interface{}) (int, error) {
+	from, err := toJalaliTime(a)
+	if err != nil {
+		return 0, fmt.Errorf("daysBetween: %w", err)
+	}
+	to, err := toJalaliTime(b)
+	if err != nil {
+		return 0, fmt.Errorf("daysBetween: %w", err)
+	}
+	return civilDays(to) - civilDays(from), nil
+}
+
+// JalaliPeriod is the time between two dates in Jalali years, months and
+// days. It prints in Persian, as 1 سال و 2 ماه و 3 روز.
+type JalaliPeriod struct {
+	Years, Months, Days int
+	// Negative is set when the end date is before the start date.
+	Negative bool
+}
+
+func (p JalaliPeriod) String() string {
+	var parts []string
+	for _, part := range []struct {
+		n    int
+		unit string
+	}{{p.Years, "سال"}, {p.Months, "ماه"}, {p.Days, "روز"}} {
+		if part.n != 0 {
+			parts = append(parts, strconv.Itoa(part.n)+" "+part.unit)
+		}
+	}
+	if len(parts) == 0 {
+		return "0 روز"
+	}
+	s := strings.Join(parts, " و ")
+	if p.Negative {
+		s = "-" + s
+	}
+	return s
+}
+
+// jalaliDiff returns the Jalali years, months and days from a to b, the
+// way ages and contract terms are counted: from 1399/04/10 to 1400/05/09
+// is 1 year, 0 months and 30 days.
+func jalaliDiff(a, b interface{}) (JalaliPeriod, error) {
+	from, err := toJalaliTime(a)
+	if err != nil {
+		return JalaliPeriod{}, fmt.Errorf("jalaliDiff: %w", err)
+	}
+	to, err := toJalaliTime(b)
+	if err != nil {
+		return JalaliPeriod{}, fmt.Errorf("jalaliDiff: %w", err)
+	}
+	negative := civilDays(to) < civilDays(from)
+	if negative {
+		from, to = to, from
+	}
+	start, end := ToJalali(from), ToJalali(to)
+	p := JalaliPeriod{
+		Years:    end.Year - start.Year,
+		Months:   end.Month - start.Month,
+		Days:     end.Day - start.Day,
+		Negative: negative,
+	}
+	if p.Days < 0 {
+		p.Months--
+		prevYear, prevMonth := end.Year, end.Month-1
+		if prevMonth == 0 {
+			prevYear, prevMonth = prevYear-1, 12
+		}
+		p.Days += JalaliMonthDays(prevYear, prevMonth)
+	}
+	if p.Months < 0 {
+		p.Years--
+		p.Months += 12
+	}
+	return p, nil
+}
@@ -0,0 +1,144 @@
+package templatefuncs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jalaliTable lists published Gregorian and Jalali equivalents, including
+// Nowruz on both sides of the equinox and the last day of leap years.
+var jalaliTable = []struct {
+	gregorian string
+	jalali    JalaliDate
+}{
+	{"1921-03-21", JalaliDate{1300, 1, 1}},
+	{"1979-02-11", JalaliDate{1357, 11, 22}},
+	{"1991-03-21", JalaliDate{1370, 1, 1}},
+	{"2000-01-01", JalaliDate{1378, 10, 11}},
+	{"2017-03-20", JalaliDate{1395, 12, 30}},
+	{"2017-03-21", JalaliDate{1396, 1, 1}},
+	{"2020-03-19", JalaliDate{1398, 12, 29}},
+	{"2020-03-20", JalaliDate{1399, 1, 1}},
+	{"2020-06-30", JalaliDate{1399, 4, 10}},
+	{"2020-09-21", JalaliDate{1399, 6, 31}},
+	{"2020-09-22", JalaliDate{1399, 7, 1}},
+	{"2021-03-20", JalaliDate{1399, 12, 30}},
+	{"2021-03-21", JalaliDate{1400, 1, 1}},
+	{"2024-03-20", JalaliDate{1403, 1, 1}},
+	{"2025-03-20", JalaliDate{1403, 12, 30}},
+	{"2025-03-21", JalaliDate{1404, 1, 1}},
+	{"2026-10-18", JalaliDate{1405, 7, 26}},
+}
+
+func TestToJalali(t *testing.T) {
+	for _, tt := range jalaliTable {
+		g, err := time.Parse("2006-01-02", tt.gregorian)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.jalali, ToJalali(g), tt.gregorian)
+
+		back, err := tt.jalali.Time(time.UTC)
+		assert.NoError(t, err)
+		assert.Equal(t, g, back, tt.jalali.String())
+	}
+}
+
+func TestToJalali_RoundTrip(t *testing.T) {
+	day := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := ToJalali(day.AddDate(0, 0, -1))
+	for i := 0; i < 200*366; i++ {
+		j := ToJalali(day)
+		assert.True(t, j.Valid(), j.String())
+		back, err := j.Time(time.UTC)
+		if !assert.NoError(t, err) || !assert.Equal(t, day, back, j.String()) {
+			return
+		}
+		// Consecutive days are consecutive Jalali dates.
+		if j.Day != 1 {
+			assert.Equal(t, JalaliDate{prev.Year, prev.Month, prev.Day + 1}, j)
+		} else if j.Month != 1 {
+			assert.Equal(t, JalaliMonthDays(prev.Year, prev.Month), prev.Day)
+		} else {
+			assert.Equal(t, JalaliDate{j.Year - 1, 12, JalaliMonthDays(j.Year-1, 12)}, prev)
+		}
+		prev = j
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+func TestIsJalaliLeap(t *testing.T) {
+	var leap []int
+	for y := 1390; y <= 1410; y++ {
+		if IsJalaliLeap(y) {
+			leap = append(leap, y)
+		}
+	}
+	assert.Equal(t, []int{1391, 1395, 1399, 1403, 1408}, leap)
+}
+
+func TestParseJalali(t *testing.T) {
+	for _, s := range []string{"1399/04/10", "1399-4-10", " ۱۳۹۹/۰۴/۱۰ ", "١٣٩٩/٤/١٠"} {
+		j, err := ParseJalali(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, JalaliDate{1399, 4, 10}, j, s)
+	}
+	for _, s := range []string{"", "1399/04", "1399/13/01", "1398/12/30", "1399/07/31", "abc/01/01"} {
+		_, err := ParseJalali(s)
+		assert.ErrorIs(t, err, ErrInvalidJalaliDate, s)
+	}
+}
+
+func TestJalaliFunctions(t *testing.T) {
+	data := map[string]interface{}{
+		"created":   "2020-06-30T11:50:00Z",
+		"requested": "1399/04/10",
+		"at":        "1399/04/10 11:50",
+		"due":       time.Date(2021, 3, 20, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{jalali .created}}`, "1399/04/10"},
+		{`{{(jalali .due).Year}} {{(jalali .due).Month}} {{(jalali .due).Day}}`, "1399 12 30"},
+		{`{{(jalali .due).MonthName}}`, "اسفند"},
+		{`{{formatDate "date" (gregorian .requested)}}`, "2020-06-30"},
+		{`{{formatDate "date" (jalali "2020-06-30")}}`, "2020-06-30"},
+		{`{{formatJalali "date" .created}}`, "1399/04/10"},
+		{`{{formatJalali "datetime" .created}}`, "1399/04/10 11:50"},
+		{`{{formatJalali "long" .requested}}`, "سه‌شنبه 10 تیر 1399"},
+		{`{{formatJalali "2 January 06, 15:04:05" .at}}`, "10 تیر 99, 11:50:00"},
+		{`{{formatJalali "Monday 2006/1/2 3:04PM" .created}}`, "سه‌شنبه 1399/4/10 11:50AM"},
+		{`{{formatDate "datetime" (parseJalali "1399/04/10 11:50")}}`, "2020-06-30 11:50"},
+		{`{{formatDate "date" (parseJalali "۱۴۰۳/۱۲/۳۰")}}`, "2025-03-20"},
+		{`{{jalaliMonthName 1}} {{jalaliMonthName 12}}`, "فروردین اسفند"},
+		{`{{persianWeekday .requested}}`, "سه‌شنبه"},
+		{`{{persianWeekday "2024-03-22"}}`, "جمعه"},
+		{`{{daysBetween .requested .due}}`, "263"},
+		{`{{daysBetween "1399/12/30" "1400/01/01"}}`, "1"},
+		{`{{daysBetween .due .created}}`, "-263"},
+		{`{{jalaliDiff "1399/04/10" "1400/05/09"}}`, "1 سال و 30 روز"},
+		{`{{jalaliDiff "1399/12/30" "1400/01/01"}}`, "1 روز"},
+		{`{{(jalaliDiff "1370/06/15" "1399/04/10").Years}}`, "28"},
+		{`{{jalaliDiff "1399/04/10" "1399/04/10"}}`, "0 روز"},
+		{`{{jalaliDiff "1399/05/01" "1399/04/10"}}`, "-22 روز"},
+		{`{{with jalaliDiff "1398/11/10" "1399/01/05"}}{{.Months}} {{.Days}}{{end}}`, "1 24"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, data)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+
+	for _, src := range []string{
+		`{{jalali "1399/13/01"}}`,
+		`{{formatJalali "date" "not a date"}}`,
+		`{{parseJalali "1399/12/31"}}`,
+		`{{jalaliMonthName 13}}`,
+		`{{daysBetween "x" "1399/01/01"}}`,
+	} {
+		_, err := render(t, src, data)
+		assert.Error(t, err, src)
+	}
+}
@@ -0,0 +1,127 @@
+package templatefuncs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// title capitalises the first letter of each word.
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		start := unicode.IsSpace(prev) || prev == '-'
+		prev = r
+		if start {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+// truncate shortens s to at most n characters, ending with an ellipsis
+// when anything was cut.
+func truncate(n interface{}, s interface{}) (string, error) {
+	limit, err := toInt(n)
+	if err != nil || limit < 1 {
+		return "", fmt.Errorf("truncate: length must be a positive whole number")
+	}
+	str := toString(s)
+	if utf8.RuneCountInString(str) <= limit {
+		return str, nil
+	}
+	runes := []rune(str)
+	return strings.TrimRightFunc(string(runes[:limit-1]), unicode.IsSpace) + "…", nil
+}
+
+func padding(name string, n, s interface{}) (string, int, error) {
+	width, err := toInt(n)
+	if err != nil || width < 0 || width > maxSeq {
+		return "", 0, fmt.Errorf("%s: width must be a whole number from 0 to %d", name, maxSeq)
+	}
+	str := toString(s)
+	return str, max(0, width-utf8.RuneCountInString(str)), nil
+}
+
+// padLeft pads s with spaces on the left to n characters: padLeft 5 "42"
+// gives "   42".
+func padLeft(n interface{}, s interface{}) (string, error) {
+	str, pad, err := padding("padLeft", n, s)
+	return strings.Repeat(" ", pad) + str, err
+}
+
+// padRight pads s with spaces on the right to n characters.
+func padRight(n interface{}, s interface{}) (string, error) {
+	str, pad, err := padding("padRight", n, s)
+	return str + strings.Repeat(" ", pad), err
+}
+
+// defaultValue returns v, or def when v is missing or empty:
+// {{.nickname | default "friend"}}.
+func defaultValue(def interface{}, v ...interface{}) interface{} {
+	if len(v) == 0 || isEmpty(v[0]) {
+		return def
+	}
+	return v[0]
+}
+
+// replace replaces every occurrence of old in s.
+func replace(old, new string, s interface{}) string {
+	return strings.ReplaceAll(toString(s), old, new)
+}
+
+func contains(sub string, s interface{}) bool  { return strings.Contains(toString(s), sub) }
+func hasPrefix(pre string, s interface{}) bool { return strings.HasPrefix(toString(s), pre) }
+func hasSuffix(suf string, s interface{}) bool { return strings.HasSuffix(toString(s), suf) }
+
+// split cuts s at each sep.
+func split(sep string, s interface{}) []string {
+	return strings.Split(toString(s), sep)
+}
+
+// join writes the elements of a list separated by sep.
+func join(sep string, v interface{}) (string, error) {
+	items, err := toList(v)
+	if err != nil {
+		return "", fmt.Errorf("join: %w", err)
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// repeat writes s n times.
+func repeat(n interface{}, s interface{}) (string, error) {
+	count, err := toInt(n)
+	if err != nil || count < 0 || count > maxSeq {
+		return "", fmt.Errorf("repeat: count must be a whole number from 0 to %d", maxSeq)
+	}
+	str := toString(s)
+	if len(str)*count > maxRepeat {
+		return "", fmt.Errorf("repeat: result would be longer than %d bytes", maxRepeat)
+	}
+	return strings.Repeat(str, count), nil
+}
+
+// toList converts any slice or array to []interface{}.
+func toList(v interface{}) ([]interface{}, error) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, nil
+	case nil:
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%v (%T) is not a list", v, v)
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
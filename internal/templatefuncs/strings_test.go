@@ -0,0 +1,63 @@
+package templatefuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringFunctions(t *testing.T) {
+	data := map[string]interface{}{
+		"name":  "  ada lovelace ",
+		"title": "An Introduction to the Analytical Engine",
+		"fa":    "سلام دنیا",
+		"empty": "",
+		"zero":  0.0,
+		"tags":  []interface{}{"pdf", "go", 3.5},
+		"code":  42,
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{upper "abc"}}|{{lower "ABC"}}`, "ABC|abc"},
+		{`{{.name | trim | title}}`, "Ada Lovelace"},
+		{`{{title "jean-luc picard"}}`, "Jean-Luc Picard"},
+		{`{{.title | truncate 16}}`, "An Introduction…"},
+		{`{{.title | truncate 100}}`, "An Introduction to the Analytical Engine"},
+		{`{{.fa | truncate 5}}`, "سلام…"},
+		{`[{{.code | padLeft 5}}]`, "[   42]"},
+		{`[{{"ab" | padRight 4}}]`, "[ab  ]"},
+		{`[{{"abcdef" | padLeft 3}}]`, "[abcdef]"},
+		{`{{.missing | default "n/a"}}`, "n/a"},
+		{`{{.empty | default "n/a"}}`, "n/a"},
+		{`{{.zero | default 1}}`, "1"},
+		{`{{.code | default 1}}`, "42"},
+		{`{{default "n/a" .name}}`, "  ada lovelace "},
+		{`{{replace "-" "/" "2024-01-02"}}`, "2024/01/02"},
+		{`{{contains "Engine" .title}} {{hasPrefix "An" .title}} {{hasSuffix "An" .title}}`, "true true false"},
+		{`{{range split "," "a,b,c"}}[{{.}}]{{end}}`, "[a][b][c]"},
+		{`{{join ", " .tags}}`, "pdf, go, 3.5"},
+		{`{{repeat 3 "ab"}}`, "ababab"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, data)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+}
+
+func TestStringFunctions_Errors(t *testing.T) {
+	for _, src := range []string{
+		`{{truncate 0 "abc"}}`,
+		`{{padLeft -1 "abc"}}`,
+		`{{padLeft 1000000000 "abc"}}`,
+		`{{padRight 1000000000 "abc"}}`,
+		`{{repeat 1000000 "abc"}}`,
+		`{{repeat 10000 (repeat 10000 (repeat 10000 "x"))}}`,
+		`{{join "," "abc"}}`,
+	} {
+		_, err := render(t, src, nil)
+		assert.Error(t, err, src)
+	}
+}
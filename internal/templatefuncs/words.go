@@ -0,0 +1,259 @@
+package templatefuncs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxWords bounds the numbers spelled out, up to the trillions.
+const maxWords = 1e15
+
+// ErrTooLargeForWords is returned for numbers of 10^15 or more, beyond the
+// trillions.
+var ErrTooLargeForWords = errors.New("number is too large to spell out")
+
+var (
+	persianOnes = [...]string{"", "یک", "دو", "سه", "چهار", "پنج", "شش", "هفت", "هشت", "نه",
+		"ده", "یازده", "دوازده", "سیزده", "چهارده", "پانزده", "شانزده", "هفده", "هجده", "نوزده"}
+	persianTens     = [...]string{"", "", "بیست", "سی", "چهل", "پنجاه", "شصت", "هفتاد", "هشتاد", "نود"}
+	persianHundreds = [...]string{"", "صد", "دویست", "سیصد", "چهارصد", "پانصد", "ششصد", "هفتصد", "هشتصد", "نهصد"}
+	persianScales   = [...]string{"", "هزار", "میلیون", "میلیارد", "تریلیون"}
+
+	englishOnes = [...]string{"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	englishTens   = [...]string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	englishScales = [...]string{"", "thousand", "million", "billion", "trillion"}
+)
+
+// currencyName holds the words for a currency's main and minor units.
+type currencyName struct {
+	persian, persianMinor string
+	english, englishMinor [2]string // singular, plural
+}
+
+var currencyNames = map[string]currencyName{
+	"IRR": {persian: "ریال", english: [2]string{"rial", "rials"}},
+	"IRT": {persian: "تومان", english: [2]string{"toman", "tomans"}},
+	"USD": {"دلار", "سنت", [2]string{"dollar", "dollars"}, [2]string{"cent", "cents"}},
+	"EUR": {"یورو", "سنت", [2]string{"euro", "euros"}, [2]string{"cent", "cents"}},
+	"GBP": {"پوند", "پنی", [2]string{"pound", "pounds"}, [2]string{"penny", "pence"}},
+}
+
+// groups splits n into groups of three digits, least significant first.
+func groups(n int64) []int {
+	var out []int
+	for ; n > 0; n /= 1000 {
+		out = append(out, int(n%1000))
+	}
+	return out
+}
+
+// checkWords rejects numbers PersianWords and EnglishWords cannot spell.
+func checkWords(n int64) error {
+	if n <= -maxWords || n >= maxWords {
+		return fmt.Errorf("%w: %d is not below 10^15", ErrTooLargeForWords, n)
+	}
+	return nil
+}
+
+// PersianWords spells out a whole number below 10^15 in Persian: 1250 is
+// یک هزار و دویست و پنجاه.
+func PersianWords(n int64) (string, error) {
+	if err := checkWords(n); err != nil {
+		return "", err
+	}
+	return spellPersian(n), nil
+}
+
+func spellPersian(n int64) string {
+	if n == 0 {
+		return "صفر"
+	}
+	if n < 0 {
+		return "منفی " + spellPersian(-n)
+	}
+	var parts []string
+	g := groups(n)
+	for i := len(g) - 1; i >= 0; i-- {
+		if g[i] == 0 {
+			continue
+		}
+		words := persianHundredsWords(g[i])
+		if persianScales[i] != "" {
+			words += " " + persianScales[i]
+		}
+		parts = append(parts, words)
+	}
+	return strings.Join(parts, " و ")
+}
+
+func persianHundredsWords(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, persianHundreds[n/100])
+	}
+	switch n %= 100; {
+	case n >= 20:
+		parts = append(parts, persianTens[n/10])
+		if n%10 != 0 {
+			parts = append(parts, persianOnes[n%10])
+		}
+	case n > 0:
+		parts = append(parts, persianOnes[n])
+	}
+	return strings.Join(parts, " و ")
+}
+
+// EnglishWords spells out a whole number below 10^15 in English: 1250 is
+// one thousand two hundred fifty.
+func EnglishWords(n int64) (string, error) {
+	if err := checkWords(n); err != nil {
+		return "", err
+	}
+	return spellEnglish(n), nil
+}
+
+func spellEnglish(n int64) string {
+	if n == 0 {
+		return "zero"
+	}
+	if n < 0 {
+		return "minus " + spellEnglish(-n)
+	}
+	var parts []string
+	g := groups(n)
+	for i := len(g) - 1; i >= 0; i-- {
+		if g[i] == 0 {
+			continue
+		}
+		parts = append(parts, englishHundredsWords(g[i]))
+		if englishScales[i] != "" {
+			parts = append(parts, englishScales[i])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func englishHundredsWords(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, englishOnes[n/100]+" hundred")
+	}
+	switch n %= 100; {
+	case n >= 20 && n%10 != 0:
+		parts = append(parts, englishTens[n/10]+"-"+englishOnes[n%10])
+	case n >= 20:
+		parts = append(parts, englishTens[n/10])
+	case n > 0:
+		parts = append(parts, englishOnes[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+// wholeNumber converts the argument of a words function.
+func wholeNumber(name string, v interface{}) (int64, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	if f != math.Trunc(f) || math.Abs(f) >= maxWords {
+		return 0, fmt.Errorf("%s: %v is not a whole number below 10^15", name, v)
+	}
+	return int64(f), nil
+}
+
+func persianWords(v interface{}) (string, error) {
+	n, err := wholeNumber("persianWords", v)
+	if err != nil {
+		return "", err
+	}
+	return spellPersian(n), nil
+}
+
+func englishWords(v interface{}) (string, error) {
+	n, err := wholeNumber("englishWords", v)
+	if err != nil {
+		return "", err
+	}
+	return spellEnglish(n), nil
+}
+
+// amountUnits splits an amount into main and minor units of a currency,
+// rounded to the currency's decimals as formatCurrency rounds it, so the
+// words match the figure.
+func amountUnits(name, code string, v interface{}) (currencyName, int64, int64, bool, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	words, ok := currencyNames[code]
+	if !ok {
+		return currencyName{}, 0, 0, false, fmt.Errorf("%s: no words for currency %q", name, code)
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return currencyName{}, 0, 0, false, fmt.Errorf("%s: %w", name, err)
+	}
+	if math.Abs(f) >= maxWords {
+		return currencyName{}, 0, 0, false, fmt.Errorf("%s: %v is too large to spell out", name, v)
+	}
+	whole, frac, _ := strings.Cut(fixed(math.Abs(f), currencies[code].decimals), ".")
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || major >= maxWords {
+		return currencyName{}, 0, 0, false, fmt.Errorf("%s: %v is too large to spell out", name, v)
+	}
+	var minor int64
+	if frac != "" {
+		minor, _ = strconv.ParseInt(frac, 10, 64)
+	}
+	return words, major, minor, f < 0 && (major != 0 || minor != 0), nil
+}
+
+// persianAmountWords spells out an amount in Persian for cheques and
+// transfer confirmations: persianAmountWords "IRR" 1000000000 gives
+// یک میلیارد ریال.
+func persianAmountWords(code string, v interface{}) (string, error) {
+	words, major, minor, negative, err := amountUnits("persianAmountWords", code, v)
+	if err != nil {
+		return "", err
+	}
+	s := spellPersian(major) + " " + words.persian
+	if minor != 0 {
+		s += " و " + spellPersian(minor) + " " + words.persianMinor
+		if major == 0 {
+			s = spellPersian(minor) + " " + words.persianMinor
+		}
+	}
+	if negative {
+		s = "منفی " + s
+	}
+	return s, nil
+}
+
+// englishAmountWords spells out an amount in English: englishAmountWords
+// "USD" 1234.5 gives one thousand two hundred thirty-four dollars and fifty
+// cents.
+func englishAmountWords(code string, v interface{}) (string, error) {
+	words, major, minor, negative, err := amountUnits("englishAmountWords", code, v)
+	if err != nil {
+		return "", err
+	}
+	s := spellEnglish(major) + " " + plural(words.english, major)
+	if minor != 0 {
+		s += " and " + spellEnglish(minor) + " " + plural(words.englishMinor, minor)
+		if major == 0 {
+			s = spellEnglish(minor) + " " + plural(words.englishMinor, minor)
+		}
+	}
+	if negative {
+		s = "minus " + s
+	}
+	return s, nil
+}
+
+func plural(forms [2]string, n int64) string {
+	if n == 1 {
+		return forms[0]
+	}
+	return forms[1]
+}
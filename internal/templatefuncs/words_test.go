@@ -0,0 +1,124 @@
+package templatefuncs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersianWords(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "صفر"},
+		{7, "هفت"},
+		{15, "پانزده"},
+		{40, "چهل"},
+		{99, "نود و نه"},
+		{100, "صد"},
+		{205, "دویست و پنج"},
+		{1250, "یک هزار و دویست و پنجاه"},
+		{20000, "بیست هزار"},
+		{1000001, "یک میلیون و یک"},
+		{1000000000, "یک میلیارد"},
+		{2500000000000, "دو تریلیون و پانصد میلیارد"},
+		{-312, "منفی سیصد و دوازده"},
+	}
+	for _, tt := range tests {
+		got, err := PersianWords(tt.n)
+		require.NoError(t, err, tt.n)
+		assert.Equal(t, tt.want, got, tt.n)
+	}
+}
+
+func TestEnglishWords(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "zero"},
+		{13, "thirteen"},
+		{21, "twenty-one"},
+		{90, "ninety"},
+		{100, "one hundred"},
+		{1250, "one thousand two hundred fifty"},
+		{1000001, "one million one"},
+		{1000000000, "one billion"},
+		{-45, "minus forty-five"},
+	}
+	for _, tt := range tests {
+		got, err := EnglishWords(tt.n)
+		require.NoError(t, err, tt.n)
+		assert.Equal(t, tt.want, got, tt.n)
+	}
+}
+
+func TestWords_OutOfRange(t *testing.T) {
+	for _, n := range []int64{1e15, -1e15, 1e18, math.MaxInt64, math.MinInt64} {
+		_, err := PersianWords(n)
+		assert.ErrorIs(t, err, ErrTooLargeForWords, n)
+		_, err = EnglishWords(n)
+		assert.ErrorIs(t, err, ErrTooLargeForWords, n)
+	}
+	got, err := EnglishWords(1e15 - 1)
+	require.NoError(t, err)
+	assert.Equal(t, "nine hundred ninety-nine trillion nine hundred ninety-nine billion nine hundred ninety-nine million nine hundred ninety-nine thousand nine hundred ninety-nine", got)
+}
+
+// The words for an amount must match the figure formatCurrency prints for
+// it, halves included.
+func TestAmountWords_Rounding(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{formatCurrency "IRR" 1234.5}} {{englishAmountWords "IRR" 1234.5}}`, "1,235 ریال one thousand two hundred thirty-five rials"},
+		{`{{formatCurrency "IRR" 2.5}} {{persianAmountWords "IRR" 2.5}}`, "3 ریال سه ریال"},
+		{`{{formatCurrency "USD" 1.005}} {{englishAmountWords "USD" 1.005}}`, "$1.01 one dollar and one cent"},
+		{`{{formatCurrency "USD" -0.125}} {{englishAmountWords "USD" -0.125}}`, "-$0.13 minus thirteen cents"},
+		{`{{formatCurrency "USD" 9.995}} {{englishAmountWords "USD" 9.995}}`, "$10.00 ten dollars"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, nil)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+}
+
+func TestAmountWords(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{persianWords 1250}}`, "یک هزار و دویست و پنجاه"},
+		{`{{englishWords "1,250"}}`, "one thousand two hundred fifty"},
+		{`{{persianAmountWords "IRR" 1000000000}}`, "یک میلیارد ریال"},
+		{`{{persianAmountWords "irt" 12500}}`, "دوازده هزار و پانصد تومان"},
+		{`{{persianAmountWords "USD" 12.5}}`, "دوازده دلار و پنجاه سنت"},
+		{`{{persianAmountWords "USD" 0.05}}`, "پنج سنت"},
+		{`{{englishAmountWords "USD" 1234.5}}`, "one thousand two hundred thirty-four dollars and fifty cents"},
+		{`{{englishAmountWords "USD" 1.01}}`, "one dollar and one cent"},
+		{`{{englishAmountWords "GBP" 0.02}}`, "two pence"},
+		{`{{englishAmountWords "IRR" 1000000000}}`, "one billion rials"},
+		{`{{englishAmountWords "IRR" -1}}`, "minus one rial"},
+		{`{{englishAmountWords "IRT" 0}}`, "zero tomans"},
+	}
+	for _, tt := range tests {
+		out, err := render(t, tt.src, nil)
+		assert.NoError(t, err, tt.src)
+		assert.Equal(t, tt.want, out, tt.src)
+	}
+
+	for _, src := range []string{
+		`{{persianWords 1.5}}`,
+		`{{englishWords 1e15}}`,
+		`{{persianAmountWords "CHF" 1}}`,
+		`{{englishAmountWords "USD" "abc"}}`,
+	} {
+		_, err := render(t, src, nil)
+		assert.Error(t, err, src)
+	}
+}
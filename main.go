@@ -1,22 +1,118 @@
 package main
 
 import (
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
+	"os"
 	"pdf-service/internal/handlers"
 	"pdf-service/internal/infrastructure"
 	"pdf-service/internal/services"
+	"strconv"
+	"time"
 )
 
 func main() {
 	chromedpClient := infrastructure.NewChromedpClient()
-	pdfService := services.NewPDFService(chromedpClient)
+	templateCache := services.NewTemplateCache(
+		int(envInt("TEMPLATE_CACHE_ENTRIES", services.DefaultTemplateCacheEntries)),
+		envInt("TEMPLATE_CACHE_BYTES", services.DefaultTemplateCacheBytes),
+	)
+	// The cache statistics are served with the other expvar variables at
+	// /debug/vars on the admin listener.
+	expvar.Publish("template_cache", expvar.Func(func() any { return templateCache.Stats() }))
+	documentStore := services.NewDocumentStore(
+		envInt("DOCUMENT_STORE_BYTES", services.DefaultDocumentStoreBytes),
+		time.Duration(envInt("DOCUMENT_TTL_SECONDS", int64(services.DefaultDocumentTTL/time.Second)))*time.Second,
+	)
+	// Signing is optional; requests asking for it are refused without a
+	// certificate.
+	var signer infrastructure.Signer
+	if pdfSigner, err := infrastructure.NewPDFSigner(); err == nil {
+		signer = pdfSigner
+	} else if !errors.Is(err, infrastructure.ErrSignerNotConfigured) {
+		log.Fatalf("Failed to load the signing certificate: %v", err)
+	}
+	pdfService := services.NewPDFServiceWithCache(chromedpClient, templateCache, documentStore, signer,
+		envInt("RENDER_MAX_BYTES", services.DefaultMaxRenderedBytes))
 	pdfHandler := handlers.NewPDFHandler(pdfService)
+	documentHandler := handlers.NewDocumentHandler(documentStore)
+	formHandler := handlers.NewFormHandler(services.NewFormService())
+	inspectHandler := handlers.NewInspectHandler(services.NewInspectService())
+	rasterHandler := handlers.NewRasterHandler(services.NewRasterService(chromedpClient))
+
+	templatesDir := os.Getenv("TEMPLATES_DIR")
+	if templatesDir == "" {
+		templatesDir = "templates"
+	}
+	templateStore := services.NewTemplateStoreWithRetention(int(envInt("TEMPLATE_VERSIONS", services.DefaultTemplateVersions)))
+	if err := templateStore.LoadDir(templatesDir); err != nil {
+		log.Fatalf("Failed to load templates: %v", err)
+	}
+	templateHandler := handlers.NewTemplateHandler(services.NewTemplateService(templateStore, pdfService))
+	markdownHandler := handlers.NewMarkdownHandler(services.NewMarkdownService(templateStore, pdfService))
+
+	// Routes go on their own mux: DefaultServeMux also holds /debug/vars,
+	// registered by expvar, which must not be public.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate-pdf", pdfHandler.GeneratePDFHandler)
+	mux.HandleFunc("/render-html", pdfHandler.RenderHTMLHandler)
+	mux.HandleFunc("/markdown-pdf", markdownHandler.MarkdownPDFHandler)
+	mux.HandleFunc("/documents/{id}", documentHandler.GetDocumentHandler)
+	mux.HandleFunc("/fill-pdf", formHandler.FillPDFHandler)
+	mux.HandleFunc("/form-fields", formHandler.FormFieldsHandler)
+	mux.HandleFunc("/inspect", inspectHandler.InspectHandler)
+	mux.HandleFunc("/extract-text", inspectHandler.ExtractTextHandler)
+	mux.HandleFunc("/rasterize", rasterHandler.RasterizeHandler)
+	mux.HandleFunc("/templates", templateHandler.ListTemplatesHandler)
+	mux.HandleFunc("/templates/{id}", templateHandler.RegisterTemplateHandler)
+	mux.HandleFunc("/templates/{id}/render", templateHandler.RenderTemplateHandler)
+	mux.HandleFunc("/templates/{id}/render-html", templateHandler.RenderTemplateHTMLHandler)
+	mux.HandleFunc("/templates/{id}/versions", templateHandler.ListVersionsHandler)
+	mux.HandleFunc("/templates/{id}/rollback", templateHandler.RollbackTemplateHandler)
+	mux.HandleFunc("/templates/{id}/versions/{version}/pin", templateHandler.PinVersionHandler)
+	mux.HandleFunc("/templates/{id}/fields", templateHandler.RegisteredTemplateFieldsHandler)
+	mux.HandleFunc("/templates/{id}/schema", templateHandler.TemplateSchemaHandler)
+	mux.HandleFunc("/template-fields", templateHandler.TemplateFieldsHandler)
+	mux.HandleFunc("/partials", templateHandler.ListPartialsHandler)
+	mux.HandleFunc("/partials/{name}", templateHandler.RegisterPartialHandler)
 
-	http.HandleFunc("/generate-pdf", pdfHandler.GeneratePDFHandler)
+	adminAddr := os.Getenv("ADMIN_ADDR")
+	if adminAddr == "" {
+		adminAddr = "localhost:8081"
+	}
+	go func() {
+		log.Printf("Admin server starting on %s...", adminAddr)
+		if err := http.ListenAndServe(adminAddr, adminMux()); err != nil {
+			log.Fatalf("Failed to start admin server: %v", err)
+		}
+	}()
 
 	log.Println("Server starting on :8080...")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	if err := http.ListenAndServe(":8080", mux); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// adminMux serves operational endpoints, kept off the public listener:
+// /debug/vars exposes the command line and memory statistics.
+func adminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// envInt reads an integer from the environment variable name, or returns
+// def when it is not set.
+func envInt(name string, def int64) int64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return n
+}
@@ -27,9 +27,14 @@ type MockPDFService struct {
 	mock.Mock
 }
 
-func (m *MockPDFService) GeneratePDF(req *models.PDFRequest) ([]byte, error) {
+func (m *MockPDFService) GeneratePDF(req *models.PDFRequest) (*models.PDFResult, error) {
 	args := m.Called(req)
-	return args.Get(0).([]byte), args.Error(1)
+	return args.Get(0).(*models.PDFResult), args.Error(1)
+}
+
+func (m *MockPDFService) RenderHTML(req *models.PDFRequest) (*models.HTMLResult, error) {
+	args := m.Called(req)
+	return args.Get(0).(*models.HTMLResult), args.Error(1)
 }
 
 func TestMainHandler(t *testing.T) {
@@ -37,7 +42,7 @@ func TestMainHandler(t *testing.T) {
 	pdfHandler := handlers.NewPDFHandler(pdfService)
 
 	expectedPDF := []byte("%PDF-1.4 mock")
-	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return(expectedPDF, nil)
+	pdfService.On("GeneratePDF", mock.AnythingOfType("*models.PDFRequest")).Return(&models.PDFResult{Content: expectedPDF}, nil)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/generate-pdf", pdfHandler.GeneratePDFHandler)
@@ -94,4 +99,11 @@ func TestMainHandler_InvalidMethod(t *testing.T) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, "Method not allowed\n", string(bodyBytes))
-}
\ No newline at end of file
+}
+
+func TestAdminMux(t *testing.T) {
+	rr := httptest.NewRecorder()
+	adminMux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"memstats"`)
+}